@@ -9,6 +9,7 @@ import (
 
 	"github.com/chrispotter/trace/internal/color"
 	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
 	vmath "github.com/chrispotter/trace/internal/math"
 )
 
@@ -22,6 +23,9 @@ type Camera struct {
 	// sx, sy, image width, image heigh, depth of field, focus length, focal radius
 	Sx, Sy, XMax, YMax, Depth, Focus, Radius float64
 
+	// Lines are inked over the render when set
+	Lines *Lines
+
 	// depth of field enabled
 	dof bool
 
@@ -213,37 +217,81 @@ func (cam *Camera) GetImage() *image.RGBA {
 
 // GetPickRay cast a ray from x,y image plane of Camera
 func (cam *Camera) GetPickRay(x int, y int) (*vmath.Ray, error) {
-	pp := cam.getPixel(float64(x)/cam.XMax, float64(y)/cam.YMax)
+	return cam.rayThrough(float64(x), float64(y))
+}
 
-	pDirection := pp.Subtract(cam.P)
-	err := pDirection.Normalize()
-	if err != nil {
-		return nil, err
+// Project returns where p lands on the image plane as a uv of the image, v
+// runs up from the bottom, false when p is behind the camera, off the image
+// or on a surface with normal n facing away from the camera
+// Satisfies color.Projector interface
+func (cam *Camera) Project(p vmath.Vector3d, n vmath.Vector3d) (vmath.Vector2d, bool) {
+	d := p.Subtract(cam.P)
+	z := -d.Dot(cam.N)
+	if z <= 0 || n.Dot(d) >= 0 {
+		return vmath.Vector2d{}, false
+	}
+	uv := vmath.Vector2d{
+		X: d.Dot(cam.U)*cam.Depth/(z*cam.Sx) + 0.5,
+		Y: d.Dot(cam.V)*cam.Depth/(z*cam.Sy) + 0.5,
 	}
+	if uv.X < 0 || uv.X > 1 || uv.Y < 0 || uv.Y > 1 {
+		return vmath.Vector2d{}, false
+	}
+	return uv, true
+}
 
+// rayThrough casts a ray through any point of the image plane, x and y are
+// in pixels and need not be whole, the differentials of the ray are cast
+// through the points a pixel across and a pixel down from the same origin
+func (cam *Camera) rayThrough(x float64, y float64) (*vmath.Ray, error) {
+	origin := cam.P
 	if cam.dof {
 		r := cam.U.SMultiply(vmath.RandNum(-cam.Radius, cam.Radius)).Add(cam.V.SMultiply(vmath.RandNum(-cam.Radius, cam.Radius)))
-		pRand := cam.P.Add(r)
+		origin = cam.P.Add(r)
+	}
 
-		C := cam.P.Add(pp.SMultiply(cam.Focus)).Subtract(pRand)
-		err := C.Normalize()
-		if err != nil {
-			return nil, err
-		} else {
-			return &vmath.Ray{
-				Origin:    pRand,
-				Direction: C,
-			}, nil
+	direction := func(x float64, y float64) (vmath.Vector3d, error) {
+		pp := cam.getPixel(x/cam.XMax, y/cam.YMax)
+
+		pDirection := pp.Subtract(cam.P)
+		err := pDirection.Normalize()
+		if err != nil || !cam.dof {
+			return pDirection, err
 		}
+
+		C := cam.P.Add(pp.SMultiply(cam.Focus)).Subtract(origin)
+		err = C.Normalize()
+		return C, err
+	}
+
+	d, err := direction(x, y)
+	if err != nil {
+		return nil, err
+	}
+	dx, err := direction(x+1, y)
+	if err != nil {
+		return nil, err
+	}
+	dy, err := direction(x, y+1)
+	if err != nil {
+		return nil, err
 	}
 
 	return &vmath.Ray{
-		Origin:    cam.P,
-		Direction: pDirection,
+		Origin:    origin,
+		Direction: d,
+		Differentials: &vmath.RayDifferentials{
+			XOrigin:    origin,
+			XDirection: dx,
+			YOrigin:    origin,
+			YDirection: dy,
+		},
 	}, nil
 }
 
-func (c *Camera) trace(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+// trace returns the color seen along ray through pixel x,y and the shape it
+// hits, nil when it hits nothing, the hit is recorded in g first when g is set
+func (c *Camera) trace(x int, y int, ray *vmath.Ray, objs *common.RenderableObjects, g *gBuffer) (color.Color, common.Traceable) {
 	// determine if ray intersects any shapes in scene
 	// if no hit, return background color
 	// if hit then return material color
@@ -267,13 +315,54 @@ func (c *Camera) trace(ray *vmath.Ray, objs *common.RenderableObjects) color.Col
 		backgroundColor := &color.ColorValue{
 			Color: vmath.Vector3d{0.0, 0.0, 0.0},
 		}
-		return backgroundColor
+		return backgroundColor, nil
 	}
 
 	hitShape := objs.Shapes[int(c.hit.X)]
+	if g != nil {
+		g.record(x, y, ray, hitShape)
+	}
 	materialColor := hitShape.ReturnColor(ray, objs)
 	c.hit = nil
-	return materialColor
+	return materialColor, hitShape
+}
+
+// nearest returns the shape ray hits first, nil when it hits nothing
+func nearest(ray *vmath.Ray, objs *common.RenderableObjects) common.Traceable {
+	var hit common.Traceable
+	t := math.Inf(1)
+	for _, shape := range objs.Shapes {
+		if shape.Intersect(ray) && shape.GetIntersectionRatio() < t {
+			hit, t = shape, shape.GetIntersectionRatio()
+		}
+	}
+	return hit
+}
+
+// outline returns the outline color of hit, the shape seen through pixel
+// x,y, when its material is Outlined and a ray the outline width away in any
+// direction on the screen hits something else, ok is false otherwise
+func (c *Camera) outline(x float64, y float64, hit common.Traceable, objs *common.RenderableObjects) (vmath.Vector3d, bool) {
+	shape, ok := hit.(interface{ GetMaterial() material.Material })
+	if !ok {
+		return vmath.Vector3d{}, false
+	}
+	outlined, ok := shape.GetMaterial().(material.Outlined)
+	if !ok || outlined.OutlineWidth() <= 0 {
+		return vmath.Vector3d{}, false
+	}
+
+	w := outlined.OutlineWidth()
+	for _, offset := range [][2]float64{{-w, 0}, {w, 0}, {0, -w}, {0, w}} {
+		probe, err := c.rayThrough(x+offset[0], y+offset[1])
+		if err != nil {
+			continue
+		}
+		if nearest(probe, objs) != hit {
+			return outlined.OutlineColor(), true
+		}
+	}
+	return vmath.Vector3d{}, false
 }
 
 // RenderImage will render the objects provided to the file name
@@ -281,6 +370,10 @@ func (c *Camera) RenderImage(filename string, objs *common.RenderableObjects) er
 	// we'll generate & render image when RenderImage called rather than at
 	// NewCamera to limit info passed with Cameras
 	image := c.GetImage()
+	var g *gBuffer
+	if c.Lines != nil {
+		g = newGBuffer(image.Rect.Max.X, image.Rect.Max.Y)
+	}
 	for x := 0; x < image.Rect.Max.X; x++ {
 		for y := 0; y < image.Rect.Max.Y; y++ {
 			ray, err := c.GetPickRay(x, y)
@@ -288,12 +381,19 @@ func (c *Camera) RenderImage(filename string, objs *common.RenderableObjects) er
 				return err
 			}
 
-			color := c.trace(ray, objs)
+			pixel, hit := c.trace(x, y, ray, objs, g)
+			if ink, ok := c.outline(float64(x), float64(y), hit, objs); ok {
+				pixel = &color.ColorValue{Color: ink}
+			}
 
-			image.Set(x, y, color.GetRGBA())
+			image.Set(x, y, pixel.GetRGBA())
 		}
 	}
 
+	if c.Lines != nil {
+		c.Lines.composite(image, g)
+	}
+
 	output := fmt.Sprintf("output/%s-%s.png", c.Name, filename)
 
 	f, err := os.Create(output)
@@ -1,14 +1,25 @@
 package camera
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
 	vmath "github.com/chrispotter/trace/internal/math"
+	"github.com/chrispotter/trace/internal/shapes"
 )
 
+func assertVector3dInDelta(t *testing.T, expected vmath.Vector3d, result vmath.Vector3d) {
+	assert.InDelta(t, expected.X, result.X, 1e-12)
+	assert.InDelta(t, expected.Y, result.Y, 1e-12)
+	assert.InDelta(t, expected.Z, result.Z, 1e-12)
+}
+
 func TestCameraNew(t *testing.T) {
 	tests := []struct {
 		Expected    *Camera
@@ -174,6 +185,7 @@ func TestCameraGetPixel(t *testing.T) {
 }
 
 func TestCameraGetPickRay(t *testing.T) {
+	corner := 1 / math.Sqrt(1.5)
 	tests := []struct {
 		Description      string
 		CameraPosition   vmath.Vector3d
@@ -186,9 +198,17 @@ func TestCameraGetPickRay(t *testing.T) {
 			CameraPosition:   vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0},
 			CameraResolution: vmath.Vector2d{X: 1, Y: 1},
 			XY:               vmath.Vector2d{X: 0, Y: 0},
+			// directions are unit length through the corner of the pixel and
+			// its neighbours a pixel across and down
 			ExpectedRay: vmath.Ray{
 				Origin:    vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0},
-				Direction: vmath.Vector3d{X: 0.0, Y: 0.0, Z: -5.0},
+				Direction: vmath.Vector3d{X: -0.5 * corner, Y: 0.5 * corner, Z: -corner},
+				Differentials: &vmath.RayDifferentials{
+					XOrigin:    vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0},
+					XDirection: vmath.Vector3d{X: 0.5 * corner, Y: 0.5 * corner, Z: -corner},
+					YOrigin:    vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0},
+					YDirection: vmath.Vector3d{X: -0.5 * corner, Y: -0.5 * corner, Z: -corner},
+				},
 			},
 		},
 	}
@@ -200,7 +220,146 @@ func TestCameraGetPickRay(t *testing.T) {
 			require.NoError(t, err)
 			actualRay, err := camera.GetPickRay(int(test.XY.X), int(test.XY.Y))
 			require.NoError(t, err)
-			assert.Equal(t, test.ExpectedRay, actualRay)
+			assertVector3dInDelta(t, test.ExpectedRay.Origin, actualRay.Origin)
+			assertVector3dInDelta(t, test.ExpectedRay.Direction, actualRay.Direction)
+			require.NotNil(t, actualRay.Differentials)
+			assertVector3dInDelta(t, test.ExpectedRay.Differentials.XOrigin, actualRay.Differentials.XOrigin)
+			assertVector3dInDelta(t, test.ExpectedRay.Differentials.XDirection, actualRay.Differentials.XDirection)
+			assertVector3dInDelta(t, test.ExpectedRay.Differentials.YOrigin, actualRay.Differentials.YOrigin)
+			assertVector3dInDelta(t, test.ExpectedRay.Differentials.YDirection, actualRay.Differentials.YDirection)
+		})
+	}
+}
+
+func TestCameraOutline(t *testing.T) {
+	blue := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 255.0}
+	// the sphere covers about 20 pixels either side of the middle of the image
+	tests := []struct {
+		Description string
+		X, Y        float64
+		Width       float64
+		Expected    bool
+	}{
+		{
+			Description: "Test the middle of the sphere has no outline",
+			X:           50,
+			Y:           50,
+			Width:       2.0,
+			Expected:    false,
+		},
+		{
+			Description: "Test the edge of the sphere is outlined",
+			X:           69,
+			Y:           50,
+			Width:       2.0,
+			Expected:    true,
+		},
+		{
+			Description: "Test the outline is as wide as asked for",
+			X:           66,
+			Y:           50,
+			Width:       5.0,
+			Expected:    true,
+		},
+		{
+			Description: "Test the background is not outlined",
+			X:           72,
+			Y:           50,
+			Width:       2.0,
+			Expected:    false,
+		},
+		{
+			Description: "Test no width draws no outline",
+			X:           69,
+			Y:           50,
+			Width:       0.0,
+			Expected:    false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			camera, err := NewCamera(vmath.Vector3d{X: 0.0, Y: 0.0, Z: 5.0}, vmath.Vector2d{X: 100, Y: 100})
+			require.NoError(t, err)
+			sphere := shapes.NewSphere(vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}, 1.0)
+			sphere.Material = &material.Cartoon{Outline: &color.ColorValue{Color: blue}, Width: test.Width}
+			objs := &common.RenderableObjects{Shapes: []common.Traceable{sphere}}
+
+			ray, err := camera.rayThrough(test.X, test.Y)
+			require.NoError(t, err)
+			ink, ok := camera.outline(test.X, test.Y, nearest(ray, objs), objs)
+			assert.Equal(t, test.Expected, ok)
+			if test.Expected {
+				assert.Equal(t, blue, ink)
+			}
+		})
+	}
+}
+
+func TestCameraRayDifferentials(t *testing.T) {
+	camera, err := NewCamera(vmath.Vector3d{X: 0.0, Y: 0.0, Z: 5.0}, vmath.Vector2d{X: 100, Y: 50})
+	require.NoError(t, err)
+
+	ray, err := camera.GetPickRay(20, 10)
+	require.NoError(t, err)
+	across, err := camera.GetPickRay(21, 10)
+	require.NoError(t, err)
+	down, err := camera.GetPickRay(20, 11)
+	require.NoError(t, err)
+
+	require.NotNil(t, ray.Differentials)
+	assert.Equal(t, ray.Origin, ray.Differentials.XOrigin)
+	assert.Equal(t, ray.Origin, ray.Differentials.YOrigin)
+	assert.Equal(t, across.Direction, ray.Differentials.XDirection)
+	assert.Equal(t, down.Direction, ray.Differentials.YDirection)
+}
+
+func TestCameraProject(t *testing.T) {
+	camera, err := NewCamera(vmath.Vector3d{X: 0.0, Y: 0.0, Z: 5.0}, vmath.Vector2d{X: 100, Y: 50})
+	require.NoError(t, err)
+	facing := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}
+
+	tests := map[string]struct {
+		Point, Normal vmath.Vector3d
+		UV            vmath.Vector2d
+		Seen          bool
+	}{
+		"center": {
+			Point:  vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0},
+			Normal: facing,
+			UV:     vmath.Vector2d{X: 0.5, Y: 0.5},
+			Seen:   true,
+		},
+		"pixel": {
+			// the pick ray through pixel 20, 10 runs 3 units down its
+			// direction
+			Point: func() vmath.Vector3d {
+				ray, _ := camera.GetPickRay(20, 10)
+				return ray.Origin.Add(ray.Direction.SMultiply(3))
+			}(),
+			Normal: facing,
+			UV:     vmath.Vector2d{X: 0.2, Y: 0.8},
+			Seen:   true,
+		},
+		"behind": {
+			Point:  vmath.Vector3d{X: 0.0, Y: 0.0, Z: 6.0},
+			Normal: facing,
+		},
+		"off the image": {
+			Point:  vmath.Vector3d{X: 5.0, Y: 0.0, Z: 0.0},
+			Normal: facing,
+		},
+		"facing away": {
+			Point:  vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0},
+			Normal: vmath.Vector3d{X: 0.0, Y: 0.0, Z: -1.0},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			uv, seen := camera.Project(test.Point, test.Normal)
+			assert.Equal(t, test.Seen, seen)
+			assert.InDelta(t, test.UV.X, uv.X, 1e-9)
+			assert.InDelta(t, test.UV.Y, uv.Y, 1e-9)
 		})
 	}
 }
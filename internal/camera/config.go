@@ -11,6 +11,7 @@ type Config struct {
 	Name     string
 	Position vmath.Vector3d `yaml:"position"`
 	Ratio    vmath.Vector2d `yaml:"ratio"`
+	Lines    *Lines         `yaml:"lines"`
 }
 
 // FromYaml updates the CameraConfig with it's definition from the input yaml
@@ -28,5 +29,24 @@ func (cc *Config) FromYaml(config *simpleyaml.Yaml) error {
 			Y: (ratio[1]).(float64),
 		}
 	}
+	if config.Get("lines").IsFound() {
+		lines, err := LinesFromYaml(config.Get("lines"))
+		if err != nil {
+			return err
+		}
+		cc.Lines = lines
+	}
 	return nil
 }
+
+// floatFromYaml reads a number written with or without a decimal point
+func floatFromYaml(config *simpleyaml.Yaml) (float64, error) {
+	if f, err := config.Float(); err == nil {
+		return f, nil
+	}
+	i, err := config.Int()
+	if err != nil {
+		return 0, err
+	}
+	return float64(i), nil
+}
@@ -41,6 +41,16 @@ func TestCameraConfigFromYaml(t *testing.T) {
     ratio: 
       - 1280.0
       - 720.0
+`),
+		},
+		{
+			Description: "Lines block adds ink lines",
+			Expected: &Config{
+				Lines: &Lines{Width: 2.0, Depth: 0.1, Crease: 45.0, Materials: true},
+			},
+			Bytes: []byte(`
+    lines:
+      width: 2
 `),
 		},
 	}
@@ -9,6 +9,7 @@ func Factory(configs []*Config) ([]*Camera, error) {
 			return nil, err
 		}
 		camera.Name = config.Name
+		camera.Lines = config.Lines
 		cameraMap = append(cameraMap, camera)
 	}
 
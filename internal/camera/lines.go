@@ -0,0 +1,218 @@
+package camera
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// Lines inks the edges of a render found from the depth, normal and object
+// of every pixel, unlike the outline of a material they do not depend on
+// the angle to the camera so flat faces and planes are lined too
+type Lines struct {
+	// Width is how many pixels thick a line is, 0 draws none
+	Width float64
+	// Color is the rgb of the ink from 0 to 255
+	Color vmath.Vector3d
+	// Depth is how far off the surface of one pixel its neighbour on the
+	// same object must be, as a fraction of the distance to the camera, to be
+	// split by a line, so a plane running to the horizon is not lined
+	Depth float64
+	// Crease is the angle in degrees two neighbouring normals must be apart
+	// to be split by a line, 0 leaves out creases
+	Crease float64
+	// Materials lines where one material meets another
+	Materials bool
+}
+
+// LinesFromYaml reads the lines block of a camera, width defaults to 1,
+// color to black, depth to 0.1, crease to 45 and materials to true
+func LinesFromYaml(config *simpleyaml.Yaml) (*Lines, error) {
+	l := &Lines{Width: 1.0, Depth: 0.1, Crease: 45.0, Materials: true}
+	for _, field := range []struct {
+		key   string
+		value *float64
+	}{
+		{"width", &l.Width},
+		{"depth", &l.Depth},
+		{"crease", &l.Crease},
+	} {
+		if !config.Get(field.key).IsFound() {
+			continue
+		}
+		value, err := floatFromYaml(config.Get(field.key))
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("lines %s is not a number", field.key))
+		}
+		if value < 0 {
+			return nil, errors.New(fmt.Sprintf("lines %s must be 0 or more", field.key))
+		}
+		*field.value = value
+	}
+	if l.Crease > 180 {
+		return nil, errors.New("lines crease must be 180 degrees or less")
+	}
+
+	if config.Get("color").IsFound() {
+		rgb, err := config.Get("color").Array()
+		if err != nil || len(rgb) != 3 {
+			return nil, errors.New("lines color must be red, green and blue")
+		}
+		channels := make([]float64, 3)
+		for index := range rgb {
+			channel, err := floatFromYaml(config.Get("color").GetIndex(index))
+			if err != nil {
+				return nil, errors.New("lines color must be red, green and blue")
+			}
+			channels[index] = channel
+		}
+		l.Color = vmath.Vector3d{X: channels[0], Y: channels[1], Z: channels[2]}
+	}
+
+	if config.Get("materials").IsFound() {
+		materials, err := config.Get("materials").Bool()
+		if err != nil {
+			return nil, errors.New("lines materials must be true or false")
+		}
+		l.Materials = materials
+	}
+
+	return l, nil
+}
+
+// gBuffer is what the camera sees through every pixel, an id of -1 and an
+// infinite depth are the background
+type gBuffer struct {
+	width, height int
+	depth         []float64
+	position      []vmath.Vector3d
+	normal        []vmath.Vector3d
+	id            []int
+	material      []material.Material
+	// ids numbers every part of a shape seen so far
+	ids map[interface{}]int
+}
+
+// newGBuffer makes an empty gBuffer for an image of width by height
+func newGBuffer(width int, height int) *gBuffer {
+	g := &gBuffer{
+		width:    width,
+		height:   height,
+		depth:    make([]float64, width*height),
+		position: make([]vmath.Vector3d, width*height),
+		normal:   make([]vmath.Vector3d, width*height),
+		id:       make([]int, width*height),
+		material: make([]material.Material, width*height),
+		ids:      map[interface{}]int{},
+	}
+	for p := range g.id {
+		g.depth[p], g.id[p] = math.Inf(1), -1
+	}
+	return g
+}
+
+// record saves hit, the shape ray through pixel x,y hits first, it must be
+// called before hit is shaded as shading may intersect it again, every
+// part of a group or instance gets an id of its own
+func (g *gBuffer) record(x int, y int, ray *vmath.Ray, hit common.Traceable) {
+	p := y*g.width + x
+	var part interface{} = hit
+	if parted, ok := hit.(interface{ Part() interface{} }); ok {
+		part = parted.Part()
+	}
+	id, ok := g.ids[part]
+	if !ok {
+		id = len(g.ids)
+		g.ids[part] = id
+	}
+	g.id[p] = id
+	g.depth[p] = hit.GetIntersectionRatio()
+	g.position[p] = ray.Origin.Add(ray.Direction.SMultiply(g.depth[p]))
+	if surface, ok := hit.(interface {
+		CalculateNorm(vmath.Vector3d) vmath.Vector3d
+	}); ok {
+		g.normal[p] = surface.CalculateNorm(g.position[p])
+	}
+	if shaded, ok := hit.(interface{ GetMaterial() material.Material }); ok {
+		g.material[p] = shaded.GetMaterial()
+	}
+}
+
+// edge is whether a line runs along pixel p where it meets its neighbour n,
+// lines are kept on the nearer of the two pixels
+func (l *Lines) edge(g *gBuffer, p int, n int) bool {
+	if g.id[p] < 0 || g.depth[p] > g.depth[n] {
+		return false
+	}
+	if g.id[p] != g.id[n] {
+		return true
+	}
+	if math.Abs(g.normal[p].Dot(g.position[n].Subtract(g.position[p])))/g.depth[p] > l.Depth {
+		return true
+	}
+	if l.Materials && g.material[p] != g.material[n] {
+		return true
+	}
+	return l.Crease > 0 && g.normal[p].Dot(g.normal[n]) < math.Cos(l.Crease*math.Pi/180)
+}
+
+// edges marks every pixel of g a line runs along
+func (l *Lines) edges(g *gBuffer) []bool {
+	edges := make([]bool, g.width*g.height)
+	for x := 0; x < g.width; x++ {
+		for y := 0; y < g.height; y++ {
+			p := y*g.width + x
+			for _, offset := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+				nx, ny := x+offset[0], y+offset[1]
+				if nx < 0 || ny < 0 || nx >= g.width || ny >= g.height {
+					continue
+				}
+				if l.edge(g, p, ny*g.width+nx) {
+					edges[p] = true
+					break
+				}
+			}
+		}
+	}
+	return edges
+}
+
+// composite draws the lines of g over img, every edge pixel is grown to a
+// dot Width pixels across
+func (l *Lines) composite(img *image.RGBA, g *gBuffer) {
+	if l.Width <= 0 {
+		return
+	}
+	edges := l.edges(g)
+	radius := l.Width / 2
+	reach := int(radius)
+	ink := color.RGBA{
+		R: uint8(math.Min(math.Max(l.Color.X, 0), 255)),
+		G: uint8(math.Min(math.Max(l.Color.Y, 0), 255)),
+		B: uint8(math.Min(math.Max(l.Color.Z, 0), 255)),
+		A: 255,
+	}
+	for x := 0; x < g.width; x++ {
+		for y := 0; y < g.height; y++ {
+			if !edges[y*g.width+x] {
+				continue
+			}
+			for dx := -reach; dx <= reach; dx++ {
+				for dy := -reach; dy <= reach; dy++ {
+					if float64(dx*dx+dy*dy) > radius*radius {
+						continue
+					}
+					img.Set(x+dx, y+dy, ink)
+				}
+			}
+		}
+	}
+}
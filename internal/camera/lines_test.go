@@ -0,0 +1,287 @@
+package camera
+
+import (
+	"errors"
+	"image"
+	"math"
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+	"github.com/chrispotter/trace/internal/shapes"
+)
+
+func TestLinesFromYaml(t *testing.T) {
+	tests := []struct {
+		Description string
+		Expected    *Lines
+		Bytes       []byte
+		ExpectedErr error
+	}{
+		{
+			Description: "Test defaults",
+			Expected:    &Lines{Width: 1.0, Depth: 0.1, Crease: 45.0, Materials: true},
+			Bytes:       []byte(`{}`),
+		},
+		{
+			Description: "Test every setting",
+			Expected: &Lines{
+				Width:     3.0,
+				Color:     vmath.Vector3d{X: 0.0, Y: 0.0, Z: 255.0},
+				Depth:     0.25,
+				Crease:    0.0,
+				Materials: false,
+			},
+			Bytes: []byte(`
+    width: 3
+    color: [0, 0, 255.0]
+    depth: 0.25
+    crease: 0
+    materials: false
+`),
+		},
+		{
+			Description: "Test negative width returns error",
+			Bytes: []byte(`
+    width: -1
+`),
+			ExpectedErr: errors.New("lines width must be 0 or more"),
+		},
+		{
+			Description: "Test crease past a half turn returns error",
+			Bytes: []byte(`
+    crease: 200
+`),
+			ExpectedErr: errors.New("lines crease must be 180 degrees or less"),
+		},
+		{
+			Description: "Test color without blue returns error",
+			Bytes: []byte(`
+    color: [0, 0]
+`),
+			ExpectedErr: errors.New("lines color must be red, green and blue"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			lines, err := LinesFromYaml(yaml)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, lines)
+		})
+	}
+}
+
+func TestLinesEdges(t *testing.T) {
+	up := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}
+	side := vmath.Vector3d{X: 1.0, Y: 0.0, Z: 0.0}
+	one, two := &material.Lambert{}, &material.Lambert{}
+
+	// each buffer is a row of 3 pixels looking down the z axis, a Position
+	// left out is the pixel straight down from the camera by its depth
+	tests := []struct {
+		Description string
+		Depth       []float64
+		Position    []vmath.Vector3d
+		Normal      []vmath.Vector3d
+		ID          []int
+		Material    []material.Material
+		Expected    []bool
+	}{
+		{
+			Description: "Test the object is lined where it meets the background",
+			Depth:       []float64{math.Inf(1), 5.0, 5.0},
+			Normal:      []vmath.Vector3d{{}, up, up},
+			ID:          []int{-1, 0, 0},
+			Material:    []material.Material{nil, one, one},
+			Expected:    []bool{false, true, false},
+		},
+		{
+			Description: "Test the nearer of two objects is lined",
+			Depth:       []float64{5.0, 4.0, 4.0},
+			Normal:      []vmath.Vector3d{up, up, up},
+			ID:          []int{0, 1, 1},
+			Material:    []material.Material{one, one, one},
+			Expected:    []bool{false, true, false},
+		},
+		{
+			Description: "Test a jump in depth of one object is lined",
+			Depth:       []float64{5.0, 5.1, 8.0},
+			Normal:      []vmath.Vector3d{up, up, up},
+			ID:          []int{0, 0, 0},
+			Material:    []material.Material{one, one, one},
+			Expected:    []bool{false, true, false},
+		},
+		{
+			Description: "Test a plane running away from the camera is not lined",
+			Depth:       []float64{5.0, 10.0, 20.0},
+			Position: []vmath.Vector3d{
+				{X: 0.0, Y: -1.0, Z: -5.0},
+				{X: 0.0, Y: -1.0, Z: -10.0},
+				{X: 0.0, Y: -1.0, Z: -20.0},
+			},
+			Normal:   []vmath.Vector3d{{Y: 1.0}, {Y: 1.0}, {Y: 1.0}},
+			ID:       []int{0, 0, 0},
+			Material: []material.Material{one, one, one},
+			Expected: []bool{false, false, false},
+		},
+		{
+			Description: "Test a crease is lined on both faces",
+			Depth:       []float64{5.0, 5.0, 5.0},
+			Normal:      []vmath.Vector3d{up, up, side},
+			ID:          []int{0, 0, 0},
+			Material:    []material.Material{one, one, one},
+			Expected:    []bool{false, true, true},
+		},
+		{
+			Description: "Test materials meeting on one object are lined",
+			Depth:       []float64{5.0, 5.0, 5.0},
+			Normal:      []vmath.Vector3d{up, up, up},
+			ID:          []int{0, 0, 0},
+			Material:    []material.Material{one, two, two},
+			Expected:    []bool{true, true, false},
+		},
+	}
+
+	lines := &Lines{Width: 1.0, Depth: 0.1, Crease: 45.0, Materials: true}
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			g := &gBuffer{
+				width:    3,
+				height:   1,
+				depth:    test.Depth,
+				position: test.Position,
+				normal:   test.Normal,
+				id:       test.ID,
+				material: test.Material,
+			}
+			if g.position == nil {
+				for _, depth := range test.Depth {
+					g.position = append(g.position, vmath.Vector3d{X: 0.0, Y: 0.0, Z: -depth})
+				}
+			}
+			assert.Equal(t, test.Expected, lines.edges(g))
+		})
+	}
+}
+
+// render traces every pixel of an image of width by height recording the
+// gBuffer on the way
+func render(t *testing.T, camera *Camera, objs *common.RenderableObjects, width int, height int) *gBuffer {
+	g := newGBuffer(width, height)
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			ray, err := camera.GetPickRay(x, y)
+			require.NoError(t, err)
+			camera.trace(x, y, ray, objs, g)
+		}
+	}
+	return g
+}
+
+func TestGBufferParts(t *testing.T) {
+	camera, err := NewCamera(vmath.Vector3d{X: 0.0, Y: 0.0, Z: 5.0}, vmath.Vector2d{X: 100, Y: 100})
+	require.NoError(t, err)
+	left := shapes.NewSphere(vmath.Vector3d{X: -0.5, Y: 0.0, Z: 0.0}, 0.6)
+	right := shapes.NewSphere(vmath.Vector3d{X: 0.5, Y: 0.0, Z: 0.0}, 0.6)
+	left.Material, right.Material = &material.Lambert{}, &material.Lambert{}
+	// the middle of each sphere is about 10 pixels either side of the middle
+	// of the image
+	middles := func(objs *common.RenderableObjects) (int, int) {
+		g := render(t, camera, objs, 100, 100)
+		return g.id[50*100+40], g.id[50*100+60]
+	}
+
+	t.Run("Test the children of a group are told apart", func(t *testing.T) {
+		group := &shapes.Group{Children: []shapes.Surface{left, right}}
+		a, b := middles(&common.RenderableObjects{Shapes: []common.Traceable{group}})
+		assert.NotEqual(t, -1, a)
+		assert.NotEqual(t, -1, b)
+		assert.NotEqual(t, a, b)
+	})
+
+	t.Run("Test instances of one shape are told apart", func(t *testing.T) {
+		moved, err := shapes.NewTransformed(&shapes.Instance{Shape: left}, vmath.Translate(vmath.Vector3d{X: 1.0}))
+		require.NoError(t, err)
+		group := &shapes.Group{Children: []shapes.Surface{&shapes.Instance{Shape: left}, moved}}
+		a, b := middles(&common.RenderableObjects{Shapes: []common.Traceable{group}})
+		assert.NotEqual(t, -1, a)
+		assert.NotEqual(t, -1, b)
+		assert.NotEqual(t, a, b)
+	})
+
+	t.Run("Test one shape keeps one id", func(t *testing.T) {
+		a, b := middles(&common.RenderableObjects{Shapes: []common.Traceable{
+			&shapes.Group{Children: []shapes.Surface{left}},
+		}})
+		assert.NotEqual(t, -1, a)
+		assert.Equal(t, -1, b)
+		g := render(t, camera, &common.RenderableObjects{Shapes: []common.Traceable{left}}, 100, 100)
+		assert.Equal(t, g.id[50*100+40], g.id[50*100+45])
+	})
+}
+
+func TestLinesComposite(t *testing.T) {
+	camera, err := NewCamera(vmath.Vector3d{X: 0.0, Y: 0.0, Z: 5.0}, vmath.Vector2d{X: 100, Y: 100})
+	require.NoError(t, err)
+	sphere := shapes.NewSphere(vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}, 1.0)
+	sphere.Material = &material.Lambert{}
+	objs := &common.RenderableObjects{Shapes: []common.Traceable{sphere}}
+
+	g := render(t, camera, objs, 100, 100)
+	assert.Equal(t, -1, g.id[0])
+	assert.Equal(t, 0, g.id[50*100+50])
+	assert.InDelta(t, 4.0, g.depth[50*100+50], 1e-2)
+	assert.InDelta(t, 1.0, g.normal[50*100+50].Z, 1e-2)
+
+	// the sphere reaches about 20 pixels right of the middle of the image,
+	// Expected is how many pixels are inked from the middle to the right
+	tests := []struct {
+		Description string
+		Width       float64
+		Expected    int
+	}{
+		{
+			Description: "Test no width draws no line",
+			Width:       0.0,
+			Expected:    0,
+		},
+		{
+			Description: "Test a thin line is one pixel",
+			Width:       1.0,
+			Expected:    1,
+		},
+		{
+			Description: "Test a thick line is grown round the edge",
+			Width:       4.0,
+			Expected:    5,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+			lines := &Lines{Width: test.Width, Color: vmath.Vector3d{X: 255.0, Y: 0.0, Z: 0.0}, Depth: 0.1, Crease: 45.0}
+			lines.composite(img, g)
+
+			inked := 0
+			for x := 50; x < 100; x++ {
+				if img.RGBAAt(x, 50).R == 255 {
+					inked++
+				}
+			}
+			assert.Equal(t, test.Expected, inked)
+			assert.Equal(t, uint8(0), img.RGBAAt(50, 50).R)
+		})
+	}
+}
@@ -14,21 +14,6 @@ type Color interface {
 	GetRGBA() color.RGBA
 }
 
-/*
-type ImageValue struct {
-	Image vmath.Vector3d
-}
-
-func NewImageValue(c vmath.Vector3d) *ImageValue {
-	return &ImageValue{
-		Image: c,
-	}
-}
-
-func (iv *ImageValue) GetColor(u float64, v float64) vmath.Vector3d {
-	return iv.Image
-} */
-
 type ColorValue struct {
 	Name  string
 	Color vmath.Vector3d
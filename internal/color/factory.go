@@ -1,6 +1,9 @@
 package color
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/smallfish/simpleyaml"
 
 	vmath "github.com/chrispotter/trace/internal/math"
@@ -9,9 +12,33 @@ import (
 // ColorConfig is a yaml definition of the Constructor to be read from
 // util/scene.go
 type ColorConfig struct {
-	Name      string
-	Color     vmath.Vector3d `yaml:"color"`
-	ImagePath string         `yaml:"path"`
+	Name  string
+	Color vmath.Vector3d `yaml:"color"`
+	// ImagePath is the png or jpeg of an ImageValue, read with Wrap, Filter
+	// and Mip
+	ImagePath string `yaml:"path"`
+	Wrap      string `yaml:"wrap"`
+	Filter    string `yaml:"filter"`
+	Mip       string `yaml:"mip"`
+	// Pattern is one of the Patterns for a Procedural, empty for a plain
+	// color
+	Pattern string   `yaml:"type"`
+	Colors  []string `yaml:"colors"`
+	// Scale and Offset move the point a Procedural reads, or the uv of an
+	// image in X and Y
+	Scale   vmath.Vector3d `yaml:"scale"`
+	Offset  vmath.Vector3d `yaml:"offset"`
+	Space   string         `yaml:"space"`
+	Octaves int            `yaml:"octaves"`
+	// Mapping is one of the Mappings read from type like Pattern, it reads
+	// the color named Texture with the blend Sharpness of a triplanar or
+	// through the image of Camera for a projection, which shows Background
+	// where it does not land
+	Mapping    string
+	Texture    string  `yaml:"texture"`
+	Sharpness  float64 `yaml:"sharpness"`
+	Camera     string  `yaml:"camera"`
+	Background string  `yaml:"background"`
 }
 
 //FromYaml updates the ColorConfig with it's definition from the input yaml
@@ -23,16 +50,350 @@ func (cc *ColorConfig) FromYaml(config *simpleyaml.Yaml) error {
 			Z: (color[2]).(float64),
 		}
 	}
+	if config.Get("type").IsFound() && config.Get("path").IsFound() {
+		return errors.New("color takes a type or a path, not both")
+	}
+	if config.Get("type").IsFound() {
+		if mapping, err := config.Get("type").String(); err == nil && Mappings[mapping] {
+			return cc.mappingFromYaml(config)
+		}
+		return cc.proceduralFromYaml(config)
+	}
+	if config.Get("path").IsFound() {
+		return cc.imageFromYaml(config)
+	}
+	return nil
+}
+
+// imageFromYaml reads an ImageValue, wrap is repeat, clamp or mirror and
+// defaults to repeat, filter is nearest, bilinear or bicubic and defaults to
+// bilinear, mip is none, trilinear or ewa and defaults to trilinear, scale is a number or u and v and defaults to 1 and offset is u
+// and v
+func (cc *ColorConfig) imageFromYaml(config *simpleyaml.Yaml) error {
+	path, err := config.Get("path").String()
+	if err != nil {
+		return errors.New("image path is not a path")
+	}
+	cc.ImagePath = path
+
+	cc.Wrap = "repeat"
+	if config.Get("wrap").IsFound() {
+		cc.Wrap, _ = config.Get("wrap").String()
+		if !Wraps[cc.Wrap] {
+			return errors.New(fmt.Sprintf("image %s wrap must be repeat, clamp or mirror", path))
+		}
+	}
+	cc.Filter = "bilinear"
+	if config.Get("filter").IsFound() {
+		cc.Filter, _ = config.Get("filter").String()
+		if !Filters[cc.Filter] {
+			return errors.New(fmt.Sprintf("image %s filter must be nearest, bilinear or bicubic", path))
+		}
+	}
+	cc.Mip = "trilinear"
+	if config.Get("mip").IsFound() {
+		cc.Mip, _ = config.Get("mip").String()
+		if !Mips[cc.Mip] {
+			return errors.New(fmt.Sprintf("image %s mip must be none, trilinear or ewa", path))
+		}
+	}
+
+	cc.Scale = vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}
+	if config.Get("scale").IsFound() {
+		if scale, err := floatFromYaml(config.Get("scale")); err == nil {
+			cc.Scale = vmath.Vector3d{X: scale, Y: scale, Z: 1.0}
+		} else if uv, err := uvFromYaml(config.Get("scale")); err == nil {
+			cc.Scale = vmath.Vector3d{X: uv.X, Y: uv.Y, Z: 1.0}
+		} else {
+			return errors.New(fmt.Sprintf("image %s scale must be a number or u and v", path))
+		}
+	}
+	if config.Get("offset").IsFound() {
+		uv, err := uvFromYaml(config.Get("offset"))
+		if err != nil {
+			return errors.New(fmt.Sprintf("image %s offset must be u and v", path))
+		}
+		cc.Offset = vmath.Vector3d{X: uv.X, Y: uv.Y, Z: 0.0}
+	}
+
+	return nil
+}
+
+// proceduralFromYaml reads a Procedural made of two or more colors of the
+// scene, scale is a number or x, y and z and defaults to 1, offset is x, y
+// and z, space is uv, world or object and defaults to uv and octaves
+// defaults to 4
+func (cc *ColorConfig) proceduralFromYaml(config *simpleyaml.Yaml) error {
+	pattern, err := config.Get("type").String()
+	if err != nil || !Patterns[pattern] {
+		return errors.New(fmt.Sprintf("color type %v does not exist.", pattern))
+	}
+	cc.Pattern = pattern
+
+	names, err := config.Get("colors").Array()
+	if err != nil || len(names) < 2 {
+		return errors.New(fmt.Sprintf("%s requires 2 or more colors", pattern))
+	}
+	for _, name := range names {
+		n, ok := name.(string)
+		if !ok {
+			return errors.New(fmt.Sprintf("%s color %v is not a name", pattern, name))
+		}
+		cc.Colors = append(cc.Colors, n)
+	}
+
+	cc.Scale = vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}
+	if config.Get("scale").IsFound() {
+		if scale, err := floatFromYaml(config.Get("scale")); err == nil {
+			cc.Scale = vmath.Vector3d{X: scale, Y: scale, Z: scale}
+		} else if cc.Scale, err = vectorFromYaml(config.Get("scale")); err != nil {
+			return errors.New(fmt.Sprintf("%s scale must be a number or x, y and z", pattern))
+		}
+	}
+	if config.Get("offset").IsFound() {
+		if cc.Offset, err = vectorFromYaml(config.Get("offset")); err != nil {
+			return errors.New(fmt.Sprintf("%s offset must be x, y and z", pattern))
+		}
+	}
+
+	cc.Space = "uv"
+	if config.Get("space").IsFound() {
+		cc.Space, _ = config.Get("space").String()
+		if cc.Space != "uv" && cc.Space != "world" && cc.Space != "object" {
+			return errors.New(fmt.Sprintf("%s space must be uv, world or object", pattern))
+		}
+	}
+
+	cc.Octaves = 4
+	if config.Get("octaves").IsFound() {
+		cc.Octaves, err = config.Get("octaves").Int()
+		if err != nil || cc.Octaves < 1 {
+			return errors.New(fmt.Sprintf("%s octaves must be 1 or more", pattern))
+		}
+	}
+
+	return nil
+}
+
+// mappingFromYaml reads a Triplanar or Projection of the color named by
+// texture, a triplanar takes scale and offset like a Procedural, space is
+// world or object and defaults to world and sharpness defaults to 4, a
+// projection takes the name of a camera and of a background color that
+// defaults to black
+func (cc *ColorConfig) mappingFromYaml(config *simpleyaml.Yaml) error {
+	cc.Mapping, _ = config.Get("type").String()
+
+	texture, err := config.Get("texture").String()
+	if err != nil {
+		return errors.New(fmt.Sprintf("%s requires a texture", cc.Mapping))
+	}
+	cc.Texture = texture
+
+	if cc.Mapping == "projection" {
+		if cc.Camera, err = config.Get("camera").String(); err != nil {
+			return errors.New("projection requires a camera")
+		}
+		if config.Get("background").IsFound() {
+			if cc.Background, err = config.Get("background").String(); err != nil {
+				return errors.New("projection background is not a name")
+			}
+		}
+		return nil
+	}
+
+	cc.Scale = vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}
+	if config.Get("scale").IsFound() {
+		if scale, err := floatFromYaml(config.Get("scale")); err == nil {
+			cc.Scale = vmath.Vector3d{X: scale, Y: scale, Z: scale}
+		} else if cc.Scale, err = vectorFromYaml(config.Get("scale")); err != nil {
+			return errors.New("triplanar scale must be a number or x, y and z")
+		}
+	}
+	if config.Get("offset").IsFound() {
+		if cc.Offset, err = vectorFromYaml(config.Get("offset")); err != nil {
+			return errors.New("triplanar offset must be x, y and z")
+		}
+	}
+
+	cc.Space = "world"
+	if config.Get("space").IsFound() {
+		cc.Space, _ = config.Get("space").String()
+		if cc.Space != "world" && cc.Space != "object" {
+			return errors.New("triplanar space must be world or object")
+		}
+	}
+
+	cc.Sharpness = 4
+	if config.Get("sharpness").IsFound() {
+		cc.Sharpness, err = floatFromYaml(config.Get("sharpness"))
+		if err != nil || cc.Sharpness <= 0 {
+			return errors.New("triplanar sharpness must be more than 0")
+		}
+	}
+
 	return nil
 }
 
-// ColorFactory returns a map of Colors from an array of ColorConfigs
-func ColorFactory(configs []*ColorConfig) (map[string]Color, error) {
+// floatFromYaml reads a number written with or without a decimal point
+func floatFromYaml(config *simpleyaml.Yaml) (float64, error) {
+	if f, err := config.Float(); err == nil {
+		return f, nil
+	}
+	i, err := config.Int()
+	if err != nil {
+		return 0, err
+	}
+	return float64(i), nil
+}
+
+// vectorFromYaml reads a list of x, y and z
+func vectorFromYaml(config *simpleyaml.Yaml) (vmath.Vector3d, error) {
+	values, err := config.Array()
+	if err != nil || len(values) != 3 {
+		return vmath.Vector3d{}, errors.New("requires x, y and z")
+	}
+	xyz := make([]float64, 3)
+	for index := range values {
+		xyz[index], err = floatFromYaml(config.GetIndex(index))
+		if err != nil {
+			return vmath.Vector3d{}, err
+		}
+	}
+	return vmath.Vector3d{X: xyz[0], Y: xyz[1], Z: xyz[2]}, nil
+}
+
+// uvFromYaml reads a list of u and v
+func uvFromYaml(config *simpleyaml.Yaml) (vmath.Vector2d, error) {
+	values, err := config.Array()
+	if err != nil || len(values) != 2 {
+		return vmath.Vector2d{}, errors.New("requires u and v")
+	}
+	u, err := floatFromYaml(config.GetIndex(0))
+	if err != nil {
+		return vmath.Vector2d{}, err
+	}
+	v, err := floatFromYaml(config.GetIndex(1))
+	if err != nil {
+		return vmath.Vector2d{}, err
+	}
+	return vmath.Vector2d{X: u, Y: v}, nil
+}
+
+// ColorFactory returns a map of Colors from an array of ColorConfigs, the
+// colors a Procedural or mapping reads are built before it, images are
+// loaded from their path and a Projection looks its camera up in projectors
+func ColorFactory(configs []*ColorConfig, projectors map[string]Projector) (map[string]Color, error) {
+	named := map[string]*ColorConfig{}
+	for _, config := range configs {
+		named[config.Name] = config
+	}
+
 	colorMap := make(map[string]Color)
+	building := map[string]bool{}
+	var build func(config *ColorConfig) (Color, error)
+	build = func(config *ColorConfig) (Color, error) {
+		if c, ok := colorMap[config.Name]; ok {
+			return c, nil
+		}
+		if config.ImagePath != "" {
+			image, err := LoadImageValue(config.ImagePath)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("color %s image %s: %s", config.Name, config.ImagePath, err.Error()))
+			}
+			image.Name = config.Name
+			image.Wrap = config.Wrap
+			image.Filter = config.Filter
+			image.Mip = config.Mip
+			image.Scale = vmath.Vector2d{X: config.Scale.X, Y: config.Scale.Y}
+			image.Offset = vmath.Vector2d{X: config.Offset.X, Y: config.Offset.Y}
+			colorMap[image.Name] = image
+			return image, nil
+		}
+		if config.Pattern == "" && config.Mapping == "" {
+			color := NewColorValue(config.Color)
+			color.Name = config.Name
+			colorMap[color.Name] = color
+			return color, nil
+		}
+		if building[config.Name] {
+			return nil, errors.New(fmt.Sprintf("color %s references itself.", config.Name))
+		}
+		building[config.Name] = true
+		defer delete(building, config.Name)
+
+		reference := func(name string) (Color, error) {
+			child, ok := named[name]
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("color %s does not exist in scene.", name))
+			}
+			return build(child)
+		}
+
+		switch config.Mapping {
+		case "triplanar":
+			texture, err := reference(config.Texture)
+			if err != nil {
+				return nil, err
+			}
+			triplanar := &Triplanar{
+				Name:      config.Name,
+				Texture:   texture,
+				Space:     config.Space,
+				Sharpness: config.Sharpness,
+				Scale:     config.Scale,
+				Offset:    config.Offset,
+			}
+			colorMap[config.Name] = triplanar
+			return triplanar, nil
+		case "projection":
+			camera, ok := projectors[config.Camera]
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("color %s camera %s does not exist in scene.", config.Name, config.Camera))
+			}
+			texture, err := reference(config.Texture)
+			if err != nil {
+				return nil, err
+			}
+			var background Color = NewColorValue(vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0})
+			if config.Background != "" {
+				if background, err = reference(config.Background); err != nil {
+					return nil, err
+				}
+			}
+			projection := &Projection{
+				Name:       config.Name,
+				Texture:    texture,
+				Background: background,
+				Camera:     camera,
+			}
+			colorMap[config.Name] = projection
+			return projection, nil
+		}
+
+		procedural := &Procedural{
+			Name:    config.Name,
+			Pattern: config.Pattern,
+			Scale:   config.Scale,
+			Offset:  config.Offset,
+			Space:   config.Space,
+			Octaves: config.Octaves,
+		}
+		for _, name := range config.Colors {
+			c, err := reference(name)
+			if err != nil {
+				return nil, err
+			}
+			procedural.Colors = append(procedural.Colors, c)
+		}
+		colorMap[config.Name] = procedural
+		return procedural, nil
+	}
+
 	for _, config := range configs {
-		color := NewColorValue(config.Color)
-		color.Name = config.Name
-		colorMap[color.Name] = color
+		_, err := build(config)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return colorMap, nil
@@ -1,6 +1,12 @@
 package color
 
 import (
+	"errors"
+	"image"
+	stdcolor "image/color"
+	"image/png"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/smallfish/simpleyaml"
@@ -88,9 +94,511 @@ func TestColorFactory(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.Description, func(t *testing.T) {
-			colors, err := ColorFactory(test.Configs)
+			colors, err := ColorFactory(test.Configs, nil)
 			require.NoError(t, err)
 			assert.Equal(t, test.Expected, colors)
 		})
 	}
 }
+
+func TestProceduralConfigFromYaml(t *testing.T) {
+	var tests = []struct {
+		Description string
+		Expected    *ColorConfig
+		Bytes       []byte
+		ExpectedErr error
+	}{
+		{
+			Description: "Test defaults of a procedural",
+			Expected: &ColorConfig{
+				Pattern: "checker",
+				Colors:  []string{"black", "white"},
+				Scale:   vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0},
+				Space:   "uv",
+				Octaves: 4,
+			},
+			Bytes: []byte(`
+    type: checker
+    colors: [black, white]
+`),
+		},
+		{
+			Description: "Test every setting of a procedural",
+			Expected: &ColorConfig{
+				Pattern: "marble",
+				Colors:  []string{"black", "grey", "white"},
+				Scale:   vmath.Vector3d{X: 1.0, Y: 2.0, Z: 3.0},
+				Offset:  vmath.Vector3d{X: 0.5, Y: 0.0, Z: 0.0},
+				Space:   "object",
+				Octaves: 6,
+			},
+			Bytes: []byte(`
+    type: marble
+    colors: [black, grey, white]
+    scale: [1, 2, 3]
+    offset: [0.5, 0, 0]
+    space: object
+    octaves: 6
+`),
+		},
+		{
+			Description: "Test a number scales every axis",
+			Expected: &ColorConfig{
+				Pattern: "wood",
+				Colors:  []string{"black", "white"},
+				Scale:   vmath.Vector3d{X: 4.0, Y: 4.0, Z: 4.0},
+				Space:   "world",
+				Octaves: 4,
+			},
+			Bytes: []byte(`
+    type: wood
+    colors: [black, white]
+    scale: 4
+    space: world
+`),
+		},
+		{
+			Description: "Test unknown type returns error",
+			Bytes: []byte(`
+    type: plaid
+    colors: [black, white]
+`),
+			ExpectedErr: errors.New("color type plaid does not exist."),
+		},
+		{
+			Description: "Test one color returns error",
+			Bytes: []byte(`
+    type: stripes
+    colors: [black]
+`),
+			ExpectedErr: errors.New("stripes requires 2 or more colors"),
+		},
+		{
+			Description: "Test a color that is not a name returns error",
+			Bytes: []byte(`
+    type: stripes
+    colors: [black, [0, 0, 0]]
+`),
+			ExpectedErr: errors.New("stripes color [0 0 0] is not a name"),
+		},
+		{
+			Description: "Test scale of two axes returns error",
+			Bytes: []byte(`
+    type: noise
+    colors: [black, white]
+    scale: [1, 2]
+`),
+			ExpectedErr: errors.New("noise scale must be a number or x, y and z"),
+		},
+		{
+			Description: "Test offset of a number returns error",
+			Bytes: []byte(`
+    type: noise
+    colors: [black, white]
+    offset: 1
+`),
+			ExpectedErr: errors.New("noise offset must be x, y and z"),
+		},
+		{
+			Description: "Test unknown space returns error",
+			Bytes: []byte(`
+    type: fbm
+    colors: [black, white]
+    space: camera
+`),
+			ExpectedErr: errors.New("fbm space must be uv, world or object"),
+		},
+		{
+			Description: "Test no octaves returns error",
+			Bytes: []byte(`
+    type: fbm
+    colors: [black, white]
+    octaves: 0
+`),
+			ExpectedErr: errors.New("fbm octaves must be 1 or more"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			colorConfig := &ColorConfig{}
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			err = colorConfig.FromYaml(yaml)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, colorConfig)
+		})
+	}
+}
+
+func TestColorFactoryProcedural(t *testing.T) {
+	black := &ColorConfig{Name: "black"}
+	white := &ColorConfig{Name: "white", Color: vmath.Vector3d{X: 255.0, Y: 255.0, Z: 255.0}}
+	checker := &ColorConfig{
+		Name:    "checker",
+		Pattern: "checker",
+		Colors:  []string{"black", "white"},
+		Scale:   vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0},
+		Space:   "uv",
+	}
+	nested := &ColorConfig{
+		Name:    "nested",
+		Pattern: "stripes",
+		Colors:  []string{"checker", "white"},
+		Scale:   vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0},
+		Space:   "uv",
+	}
+
+	var tests = []struct {
+		Description string
+		Configs     []*ColorConfig
+		ExpectedErr error
+	}{
+		{
+			Description: "Test procedurals are built after the colors they use",
+			Configs:     []*ColorConfig{nested, checker, white, black},
+		},
+		{
+			Description: "Test a missing color returns error",
+			Configs:     []*ColorConfig{checker, black},
+			ExpectedErr: errors.New("color white does not exist in scene."),
+		},
+		{
+			Description: "Test a procedural using itself returns error",
+			Configs: []*ColorConfig{black, {
+				Name:    "loop",
+				Pattern: "stripes",
+				Colors:  []string{"black", "loop"},
+			}},
+			ExpectedErr: errors.New("color loop references itself."),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			colors, err := ColorFactory(test.Configs, nil)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, colors, 4)
+			stripes := colors["nested"].(*Procedural)
+			assert.Same(t, colors["checker"], stripes.Colors[0])
+			assert.Same(t, colors["white"], stripes.Colors[1])
+		})
+	}
+}
+
+func TestImageConfigFromYaml(t *testing.T) {
+	var tests = []struct {
+		Description string
+		Expected    *ColorConfig
+		Bytes       []byte
+		ExpectedErr error
+	}{
+		{
+			Description: "Test defaults of an image",
+			Expected: &ColorConfig{
+				ImagePath: "grid.png",
+				Wrap:      "repeat",
+				Filter:    "bilinear",
+				Mip:       "trilinear",
+				Scale:     vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0},
+			},
+			Bytes: []byte(`
+    path: grid.png
+`),
+		},
+		{
+			Description: "Test every setting of an image",
+			Expected: &ColorConfig{
+				ImagePath: "grid.jpg",
+				Wrap:      "mirror",
+				Filter:    "bicubic",
+				Mip:       "ewa",
+				Scale:     vmath.Vector3d{X: 2.0, Y: 4.0, Z: 1.0},
+				Offset:    vmath.Vector3d{X: 0.5, Y: 0.25, Z: 0.0},
+			},
+			Bytes: []byte(`
+    path: grid.jpg
+    wrap: mirror
+    filter: bicubic
+    mip: ewa
+    scale: [2, 4]
+    offset: [0.5, 0.25]
+`),
+		},
+		{
+			Description: "Test a number scales u and v",
+			Expected: &ColorConfig{
+				ImagePath: "grid.png",
+				Wrap:      "clamp",
+				Filter:    "nearest",
+				Mip:       "none",
+				Scale:     vmath.Vector3d{X: 3.0, Y: 3.0, Z: 1.0},
+			},
+			Bytes: []byte(`
+    path: grid.png
+    wrap: clamp
+    filter: nearest
+    mip: none
+    scale: 3
+`),
+		},
+		{
+			Description: "Test a type and a path returns error",
+			Bytes: []byte(`
+    path: grid.png
+    type: checker
+`),
+			ExpectedErr: errors.New("color takes a type or a path, not both"),
+		},
+		{
+			Description: "Test unknown wrap returns error",
+			Bytes: []byte(`
+    path: grid.png
+    wrap: tile
+`),
+			ExpectedErr: errors.New("image grid.png wrap must be repeat, clamp or mirror"),
+		},
+		{
+			Description: "Test unknown filter returns error",
+			Bytes: []byte(`
+    path: grid.png
+    filter: linear
+`),
+			ExpectedErr: errors.New("image grid.png filter must be nearest, bilinear or bicubic"),
+		},
+		{
+			Description: "Test unknown mip returns error",
+			Bytes: []byte(`
+    path: grid.png
+    mip: anisotropic
+`),
+			ExpectedErr: errors.New("image grid.png mip must be none, trilinear or ewa"),
+		},
+		{
+			Description: "Test scale of three axes returns error",
+			Bytes: []byte(`
+    path: grid.png
+    scale: [1, 2, 3]
+`),
+			ExpectedErr: errors.New("image grid.png scale must be a number or u and v"),
+		},
+		{
+			Description: "Test offset of a number returns error",
+			Bytes: []byte(`
+    path: grid.png
+    offset: 1
+`),
+			ExpectedErr: errors.New("image grid.png offset must be u and v"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			colorConfig := &ColorConfig{}
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			err = colorConfig.FromYaml(yaml)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, colorConfig)
+		})
+	}
+}
+
+func TestColorFactoryImage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grid.png")
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, stdcolor.RGBA{255, 0, 0, 0xff})
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(file, img))
+	require.NoError(t, file.Close())
+
+	colors, err := ColorFactory([]*ColorConfig{{
+		Name:      "grid",
+		ImagePath: path,
+		Wrap:      "clamp",
+		Filter:    "nearest",
+		Mip:       "trilinear",
+		Scale:     vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0},
+	}, {
+		Name:      "again",
+		ImagePath: path,
+		Scale:     vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0},
+	}}, nil)
+	require.NoError(t, err)
+	grid := colors["grid"].(*ImageValue)
+	assert.Equal(t, "grid", grid.Name)
+	assert.Equal(t, "clamp", grid.Wrap)
+	assert.Equal(t, 2, grid.Pyramid[0].Width)
+	assert.Len(t, grid.Pyramid, 2)
+	// the pyramid of a path is built once
+	assert.Same(t, grid.Pyramid[0], colors["again"].(*ImageValue).Pyramid[0])
+	// the top left of the image is the start of u and the end of v
+	assert.Equal(t, vmath.Vector3d{X: 255.0, Y: 0.0, Z: 0.0}, grid.GetColor(0.1, 0.9))
+	assert.Equal(t, vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}, grid.GetColor(0.1, 0.1))
+
+	_, err = ColorFactory([]*ColorConfig{{Name: "missing", ImagePath: "missing.png"}}, nil)
+	assert.EqualError(t, err, "color missing image missing.png: open missing.png: no such file or directory")
+}
+
+func TestMappingConfigFromYaml(t *testing.T) {
+	var tests = []struct {
+		Description string
+		Expected    *ColorConfig
+		Bytes       []byte
+		ExpectedErr error
+	}{
+		{
+			Description: "Test triplanar defaults",
+			Bytes: []byte(`
+    type: triplanar
+    texture: grid
+`),
+			Expected: &ColorConfig{
+				Mapping:   "triplanar",
+				Texture:   "grid",
+				Scale:     vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0},
+				Space:     "world",
+				Sharpness: 4,
+			},
+		},
+		{
+			Description: "Test triplanar in object space",
+			Bytes: []byte(`
+    type: triplanar
+    texture: grid
+    space: object
+    sharpness: 8
+    scale: 0.5
+    offset: [1, 2, 3]
+`),
+			Expected: &ColorConfig{
+				Mapping:   "triplanar",
+				Texture:   "grid",
+				Scale:     vmath.Vector3d{X: 0.5, Y: 0.5, Z: 0.5},
+				Offset:    vmath.Vector3d{X: 1.0, Y: 2.0, Z: 3.0},
+				Space:     "object",
+				Sharpness: 8,
+			},
+		},
+		{
+			Description: "Test projection",
+			Bytes: []byte(`
+    type: projection
+    texture: grid
+    camera: projector
+    background: black
+`),
+			Expected: &ColorConfig{
+				Mapping:    "projection",
+				Texture:    "grid",
+				Camera:     "projector",
+				Background: "black",
+			},
+		},
+		{
+			Description: "Test no texture returns error",
+			Bytes: []byte(`
+    type: triplanar
+`),
+			ExpectedErr: errors.New("triplanar requires a texture"),
+		},
+		{
+			Description: "Test uv space returns error",
+			Bytes: []byte(`
+    type: triplanar
+    texture: grid
+    space: uv
+`),
+			ExpectedErr: errors.New("triplanar space must be world or object"),
+		},
+		{
+			Description: "Test no sharpness returns error",
+			Bytes: []byte(`
+    type: triplanar
+    texture: grid
+    sharpness: 0
+`),
+			ExpectedErr: errors.New("triplanar sharpness must be more than 0"),
+		},
+		{
+			Description: "Test projection without a camera returns error",
+			Bytes: []byte(`
+    type: projection
+    texture: grid
+`),
+			ExpectedErr: errors.New("projection requires a camera"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			colorConfig := &ColorConfig{}
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			err = colorConfig.FromYaml(yaml)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, colorConfig)
+		})
+	}
+}
+
+func TestColorFactoryMapping(t *testing.T) {
+	black := &ColorConfig{Name: "black"}
+	white := &ColorConfig{Name: "white", Color: vmath.Vector3d{X: 255.0, Y: 255.0, Z: 255.0}}
+	triplanar := &ColorConfig{
+		Name:      "triplanar",
+		Mapping:   "triplanar",
+		Texture:   "white",
+		Scale:     vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0},
+		Space:     "world",
+		Sharpness: 4,
+	}
+	projection := &ColorConfig{
+		Name:       "projection",
+		Mapping:    "projection",
+		Texture:    "triplanar",
+		Camera:     "projector",
+		Background: "black",
+	}
+	projectors := map[string]Projector{"projector": projector{}}
+
+	colors, err := ColorFactory([]*ColorConfig{projection, triplanar, white, black}, projectors)
+	require.NoError(t, err)
+	require.Len(t, colors, 4)
+	assert.Same(t, colors["white"], colors["triplanar"].(*Triplanar).Texture)
+	assert.Same(t, colors["triplanar"], colors["projection"].(*Projection).Texture)
+	assert.Same(t, colors["black"], colors["projection"].(*Projection).Background)
+	assert.Equal(t, projector{}, colors["projection"].(*Projection).Camera)
+
+	// the background defaults to black
+	colors, err = ColorFactory([]*ColorConfig{white, {
+		Name:    "plain",
+		Mapping: "projection",
+		Texture: "white",
+		Camera:  "projector",
+	}}, projectors)
+	require.NoError(t, err)
+	assert.Equal(t, vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}, colors["plain"].(*Projection).Background.GetColor(0, 0))
+
+	_, err = ColorFactory([]*ColorConfig{white, projection}, nil)
+	assert.EqualError(t, err, "color projection camera projector does not exist in scene.")
+
+	_, err = ColorFactory([]*ColorConfig{triplanar}, nil)
+	assert.EqualError(t, err, "color white does not exist in scene.")
+}
@@ -0,0 +1,144 @@
+package color
+
+import (
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// Wraps are the ways an ImageValue is read outside of uv 0 to 1
+var Wraps = map[string]bool{
+	"repeat": true,
+	"clamp":  true,
+	"mirror": true,
+}
+
+// Filters are the ways an ImageValue blends the pixels around uv
+var Filters = map[string]bool{
+	"nearest":  true,
+	"bilinear": true,
+	"bicubic":  true,
+}
+
+// ImageValue is a Color read from a png or jpeg image by uv, v runs up from
+// the bottom of the image and the uv is scaled by Scale then moved by
+// Offset before it is read
+type ImageValue struct {
+	Name string
+	// Pyramid is the image and its halvings, shared by every ImageValue
+	// loaded from the same path
+	Pyramid      Pyramid
+	Wrap, Filter string
+	// Mip is how the image is averaged over the area of a pixel, one of
+	// Mips
+	Mip           string
+	Scale, Offset vmath.Vector2d
+	// Intensity and Tint are applied to what is read so copies made by
+	// SMultiply share the Pyramid
+	Intensity float64
+	Tint      vmath.Vector3d
+}
+
+// LoadImageValue reads a png or jpeg image, the pyramid of a path is only
+// built the first time it is loaded
+func LoadImageValue(path string) (*ImageValue, error) {
+	pyramid, err := LoadPyramid(path)
+	if err != nil {
+		return nil, err
+	}
+	return newImageValue(pyramid), nil
+}
+
+// NewImageValue copies img into an ImageValue that repeats and is read
+// bilinearly between trilinearly blended levels
+func NewImageValue(img image.Image) *ImageValue {
+	return newImageValue(NewPyramid(NewLevel(img)))
+}
+
+func newImageValue(pyramid Pyramid) *ImageValue {
+	return &ImageValue{
+		Pyramid:   pyramid,
+		Wrap:      "repeat",
+		Filter:    "bilinear",
+		Mip:       "trilinear",
+		Scale:     vmath.Vector2d{X: 1.0, Y: 1.0},
+		Intensity: 1.0,
+	}
+}
+
+// uv is where u and v are read from the image
+func (iv *ImageValue) uv(u float64, v float64) vmath.Vector2d {
+	return vmath.Vector2d{X: u*iv.Scale.X + iv.Offset.X, Y: v*iv.Scale.Y + iv.Offset.Y}
+}
+
+// GetColor returns the full image at uv by Filter
+// Satisfies Color interface
+func (iv *ImageValue) GetColor(u float64, v float64) vmath.Vector3d {
+	if len(iv.Pyramid) == 0 {
+		return iv.Tint
+	}
+	c := iv.Pyramid[0].sample(iv.uv(u, v), iv.Filter, iv.Wrap)
+	return c.SMultiply(iv.Intensity).Add(iv.Tint)
+}
+
+// GetSolidColor returns the image at the uv of p averaged over its
+// footprint when it is known
+// Satisfies Solid interface
+func (iv *ImageValue) GetSolidColor(p *Point) vmath.Vector3d {
+	if p.UVDx == (vmath.Vector2d{}) && p.UVDy == (vmath.Vector2d{}) {
+		return iv.GetColor(p.UV.X, p.UV.Y)
+	}
+	return iv.GetFilteredColor(p.UV, p.UVDx, p.UVDy)
+}
+
+// GetFilteredColor returns the image at uv averaged over the footprint of
+// duvdx and duvdy by Mip
+func (iv *ImageValue) GetFilteredColor(uv vmath.Vector2d, duvdx vmath.Vector2d, duvdy vmath.Vector2d) vmath.Vector3d {
+	if len(iv.Pyramid) == 0 {
+		return iv.Tint
+	}
+	duvdx, duvdy = duvdx.Compt(iv.Scale), duvdy.Compt(iv.Scale)
+	var c vmath.Vector3d
+	switch iv.Mip {
+	case "none":
+		return iv.GetColor(uv.X, uv.Y)
+	case "ewa":
+		c = iv.Pyramid.EWA(iv.uv(uv.X, uv.Y), duvdx, duvdy, iv.Filter, iv.Wrap)
+	default:
+		c = iv.Pyramid.Trilinear(iv.uv(uv.X, uv.Y), duvdx, duvdy, iv.Filter, iv.Wrap)
+	}
+	return c.SMultiply(iv.Intensity).Add(iv.Tint)
+}
+
+// Height is the average of the channels of the image at uv from 0 to 1, for
+// images of heights, averaged over the footprint of duvdx and duvdy by Mip
+// unless both are zero
+func (iv *ImageValue) Height(uv vmath.Vector2d, duvdx vmath.Vector2d, duvdy vmath.Vector2d) float64 {
+	c := iv.GetSolidColor(&Point{UV: uv, UVDx: duvdx, UVDy: duvdy})
+	return (c.X + c.Y + c.Z) / (3 * 255)
+}
+
+func (iv *ImageValue) GetRGBA() color.RGBA {
+	c := iv.GetColor(0, 0)
+	return color.RGBA{
+		uint8(math.Min(math.Max(c.X, 0), 255)),
+		uint8(math.Min(math.Max(c.Y, 0), 255)),
+		uint8(math.Min(math.Max(c.Z, 0), 255)),
+		0xff,
+	}
+}
+
+func (iv *ImageValue) Add(c Color) {
+	iv.Tint = iv.Tint.Add(c.GetColor(0, 0))
+}
+
+func (iv *ImageValue) SMultiply(intensity float64) Color {
+	scaled := *iv
+	scaled.Intensity *= intensity
+	scaled.Tint = iv.Tint.SMultiply(intensity)
+	return &scaled
+}
@@ -0,0 +1,163 @@
+package color
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// rampImage is 4 pixels across and 1 down with red of 0, 60, 120 and 180
+func rampImage() *ImageValue {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	for x := 0; x < 4; x++ {
+		img.Set(x, 0, color.RGBA{uint8(60 * x), 0, 0, 0xff})
+	}
+	return NewImageValue(img)
+}
+
+func TestImageValueGetColor(t *testing.T) {
+	tests := []struct {
+		Description string
+		Wrap        string
+		Filter      string
+		Scale       vmath.Vector2d
+		Offset      vmath.Vector2d
+		U           float64
+		Expected    float64
+	}{
+		{
+			Description: "Test nearest reads the pixel under uv",
+			Filter:      "nearest",
+			U:           0.3,
+			Expected:    60.0,
+		},
+		{
+			Description: "Test bilinear blends neighbouring pixels",
+			Filter:      "bilinear",
+			U:           0.25,
+			Expected:    30.0,
+		},
+		{
+			Description: "Test bicubic holds a straight ramp",
+			Filter:      "bicubic",
+			U:           0.5,
+			Expected:    90.0,
+		},
+		{
+			Description: "Test bicubic is read at the pixel centers",
+			Filter:      "bicubic",
+			U:           0.625,
+			Expected:    120.0,
+		},
+		{
+			Description: "Test repeat starts the image over",
+			Wrap:        "repeat",
+			Filter:      "nearest",
+			U:           1.125,
+			Expected:    0.0,
+		},
+		{
+			Description: "Test clamp holds the last pixel",
+			Wrap:        "clamp",
+			Filter:      "nearest",
+			U:           1.125,
+			Expected:    180.0,
+		},
+		{
+			Description: "Test mirror reads the image backwards",
+			Wrap:        "mirror",
+			Filter:      "nearest",
+			U:           1.375,
+			Expected:    120.0,
+		},
+		{
+			Description: "Test clamp stops bilinear blending past the edge",
+			Wrap:        "clamp",
+			Filter:      "bilinear",
+			U:           0.0,
+			Expected:    0.0,
+		},
+		{
+			Description: "Test scale and offset move the uv",
+			Filter:      "nearest",
+			Scale:       vmath.Vector2d{X: 0.5, Y: 1.0},
+			Offset:      vmath.Vector2d{X: 0.5, Y: 0.0},
+			U:           0.3,
+			Expected:    120.0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			iv := rampImage()
+			if test.Wrap != "" {
+				iv.Wrap = test.Wrap
+			}
+			iv.Filter = test.Filter
+			if test.Scale != (vmath.Vector2d{}) {
+				iv.Scale = test.Scale
+			}
+			iv.Offset = test.Offset
+			c := iv.GetColor(test.U, 0.5)
+			assert.InDelta(t, test.Expected, c.X, 1e-9)
+			assert.InDelta(t, 0.0, c.Y, 1e-9)
+		})
+	}
+}
+
+func TestImageValueHeight(t *testing.T) {
+	// black on the left column and white on the right
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+	img.Set(1, 0, color.White)
+	img.Set(1, 1, color.White)
+	iv := NewImageValue(img)
+
+	var tests = []struct {
+		Description string
+		UV          vmath.Vector2d
+		Footprint   vmath.Vector2d
+		Expected    float64
+	}{
+		{
+			Description: "Test pixel center",
+			UV:          vmath.Vector2d{X: 0.25, Y: 0.25},
+			Expected:    0.0,
+		},
+		{
+			Description: "Test between pixels blends",
+			UV:          vmath.Vector2d{X: 0.5, Y: 0.5},
+			Expected:    0.5,
+		},
+		{
+			Description: "Test uv outside of 0 to 1 repeats",
+			UV:          vmath.Vector2d{X: 1.75, Y: -0.25},
+			Expected:    1.0,
+		},
+		{
+			Description: "Test a footprint wider than the image averages it",
+			UV:          vmath.Vector2d{X: 0.25, Y: 0.25},
+			Footprint:   vmath.Vector2d{X: 4.0, Y: 0.0},
+			Expected:    0.5,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			assert.InDelta(t, test.Expected, iv.Height(test.UV, test.Footprint, test.Footprint), 1e-9)
+		})
+	}
+}
+
+func TestImageValueSMultiply(t *testing.T) {
+	iv := rampImage()
+	iv.Filter = "nearest"
+	iv.Add(NewColorValue(vmath.Vector3d{X: 10.0, Y: 10.0, Z: 10.0}))
+
+	half := iv.SMultiply(0.5)
+	assert.Equal(t, vmath.Vector3d{X: 35.0, Y: 5.0, Z: 5.0}, half.GetColor(0.375, 0.5))
+	assert.Equal(t, vmath.Vector3d{X: 70.0, Y: 10.0, Z: 10.0}, iv.GetColor(0.375, 0.5))
+}
@@ -0,0 +1,168 @@
+package color
+
+import (
+	"image/color"
+	"math"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// Mappings are the color types that read another color by a uv of their own
+// making for surfaces without one
+var Mappings = map[string]bool{
+	"triplanar":  true,
+	"projection": true,
+}
+
+// Projector finds where a point of a surface with normal n lands on an image,
+// v runs up from the bottom of the image and false is returned when the
+// point is not seen, a camera is a Projector
+type Projector interface {
+	Project(p vmath.Vector3d, n vmath.Vector3d) (vmath.Vector2d, bool)
+}
+
+// Triplanar reads Texture three times by the point of Space, once flat on
+// each of the planes across the x, y and z axes, and blends them by how much
+// the surface faces along each axis raised to Sharpness
+//
+// the point is scaled by Scale then moved by Offset, the plane across x
+// reads z and y as u and v, across y x and z, and across z x and y
+//
+// the footprint Texture is filtered by is always taken in world space, in
+// object space it is only right for shapes that are moved but not scaled
+// or turned
+type Triplanar struct {
+	Name          string
+	Texture       Color
+	Space         string
+	Sharpness     float64
+	Scale, Offset vmath.Vector3d
+}
+
+// GetColor returns Texture at uv as a surface facing z
+// Satisfies Color interface
+func (tp *Triplanar) GetColor(u float64, v float64) vmath.Vector3d {
+	return tp.GetSolidColor(uvPoint(u, v))
+}
+
+// GetSolidColor satisfies Solid interface
+func (tp *Triplanar) GetSolidColor(p *Point) vmath.Vector3d {
+	point, n := p.Position, p.Normal
+	if tp.Space == "object" {
+		point, n = p.ObjectPosition, p.ObjectNormal
+	}
+	q := point.Compt(tp.Scale).Add(tp.Offset)
+	dx, dy := p.PositionDx.Compt(tp.Scale), p.PositionDy.Compt(tp.Scale)
+
+	weights := vmath.Vector3d{
+		X: math.Pow(math.Abs(n.X), tp.Sharpness),
+		Y: math.Pow(math.Abs(n.Y), tp.Sharpness),
+		Z: math.Pow(math.Abs(n.Z), tp.Sharpness),
+	}
+	total := weights.X + weights.Y + weights.Z
+	if total == 0 {
+		return At(tp.Texture, &Point{UV: vmath.Vector2d{X: q.X, Y: q.Y}})
+	}
+
+	c := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	// each plane is read as a surface facing along its axis
+	planes := []struct {
+		weight float64
+		uv     func(v vmath.Vector3d) vmath.Vector2d
+	}{
+		{weights.X, func(v vmath.Vector3d) vmath.Vector2d { return vmath.Vector2d{X: v.Z, Y: v.Y} }},
+		{weights.Y, func(v vmath.Vector3d) vmath.Vector2d { return vmath.Vector2d{X: v.X, Y: v.Z} }},
+		{weights.Z, func(v vmath.Vector3d) vmath.Vector2d { return vmath.Vector2d{X: v.X, Y: v.Y} }},
+	}
+	for _, plane := range planes {
+		if plane.weight == 0 {
+			continue
+		}
+		read := *p
+		read.UV, read.UVDx, read.UVDy = plane.uv(q), plane.uv(dx), plane.uv(dy)
+		c = c.Add(At(tp.Texture, &read).SMultiply(plane.weight / total))
+	}
+	return c
+}
+
+func (tp *Triplanar) GetRGBA() color.RGBA {
+	return rgba(tp.GetColor(0, 0))
+}
+
+// Add adds c to a copy of Texture as it may be shared with the rest of the
+// scene
+func (tp *Triplanar) Add(c Color) {
+	tp.Texture = tp.Texture.SMultiply(1.0)
+	tp.Texture.Add(c)
+}
+
+func (tp *Triplanar) SMultiply(intensity float64) Color {
+	scaled := *tp
+	scaled.Texture = tp.Texture.SMultiply(intensity)
+	return &scaled
+}
+
+// Projection reads Texture by where a point lands on the image of Camera,
+// like a slide projected onto the scene, and Background where it does not
+// land on the image or faces away from Camera, nothing blocks the light of
+// the projection
+type Projection struct {
+	Name                string
+	Texture, Background Color
+	Camera              Projector
+}
+
+// GetColor returns Texture at uv
+// Satisfies Color interface
+func (pr *Projection) GetColor(u float64, v float64) vmath.Vector3d {
+	return pr.Texture.GetColor(u, v)
+}
+
+// GetSolidColor satisfies Solid interface
+func (pr *Projection) GetSolidColor(p *Point) vmath.Vector3d {
+	uv, ok := pr.Camera.Project(p.Position, p.Normal)
+	if !ok {
+		return At(pr.Background, p)
+	}
+	read := *p
+	read.UV = uv
+	// the footprint of the pixel is where its neighbours land on the image
+	read.UVDx, read.UVDy = vmath.Vector2d{}, vmath.Vector2d{}
+	if next, ok := pr.Camera.Project(p.Position.Add(p.PositionDx), p.Normal); ok && p.PositionDx != (vmath.Vector3d{}) {
+		read.UVDx = next.Subtract(uv)
+	}
+	if next, ok := pr.Camera.Project(p.Position.Add(p.PositionDy), p.Normal); ok && p.PositionDy != (vmath.Vector3d{}) {
+		read.UVDy = next.Subtract(uv)
+	}
+	return At(pr.Texture, &read)
+}
+
+func (pr *Projection) GetRGBA() color.RGBA {
+	return rgba(pr.GetColor(0, 0))
+}
+
+// Add adds c to copies of Texture and Background as they may be shared with
+// the rest of the scene
+func (pr *Projection) Add(c Color) {
+	pr.Texture = pr.Texture.SMultiply(1.0)
+	pr.Texture.Add(c)
+	pr.Background = pr.Background.SMultiply(1.0)
+	pr.Background.Add(c)
+}
+
+func (pr *Projection) SMultiply(intensity float64) Color {
+	scaled := *pr
+	scaled.Texture = pr.Texture.SMultiply(intensity)
+	scaled.Background = pr.Background.SMultiply(intensity)
+	return &scaled
+}
+
+// rgba clamps c to a color.RGBA
+func rgba(c vmath.Vector3d) color.RGBA {
+	return color.RGBA{
+		uint8(math.Min(math.Max(c.X, 0), 255)),
+		uint8(math.Min(math.Max(c.Y, 0), 255)),
+		uint8(math.Min(math.Max(c.Z, 0), 255)),
+		0xff,
+	}
+}
@@ -0,0 +1,132 @@
+package color
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// uvColor returns the uv it is read at as its color
+type uvColor struct{ ColorValue }
+
+func (c *uvColor) GetColor(u float64, v float64) vmath.Vector3d {
+	return vmath.Vector3d{X: u, Y: v, Z: 0.0}
+}
+
+func TestTriplanar(t *testing.T) {
+	one := vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}
+	point := vmath.Vector3d{X: 1.0, Y: 2.0, Z: 3.0}
+
+	tests := []struct {
+		Description string
+		Triplanar   *Triplanar
+		Point       *Point
+		Expected    vmath.Vector3d
+	}{
+		{
+			Description: "Test a surface facing x reads z and y",
+			Triplanar:   &Triplanar{Texture: &uvColor{}, Space: "world", Sharpness: 4, Scale: one},
+			Point:       &Point{Position: point, Normal: vmath.Vector3d{X: -1.0, Y: 0.0, Z: 0.0}},
+			Expected:    vmath.Vector3d{X: 3.0, Y: 2.0, Z: 0.0},
+		},
+		{
+			Description: "Test a surface facing y reads x and z",
+			Triplanar:   &Triplanar{Texture: &uvColor{}, Space: "world", Sharpness: 4, Scale: one},
+			Point:       &Point{Position: point, Normal: vmath.Vector3d{X: 0.0, Y: 1.0, Z: 0.0}},
+			Expected:    vmath.Vector3d{X: 1.0, Y: 3.0, Z: 0.0},
+		},
+		{
+			Description: "Test a surface facing z reads x and y scaled and moved",
+			Triplanar: &Triplanar{
+				Texture:   &uvColor{},
+				Space:     "world",
+				Sharpness: 4,
+				Scale:     one.SMultiply(2.0),
+				Offset:    vmath.Vector3d{X: 1.0, Y: 0.0, Z: 0.0},
+			},
+			Point:    &Point{Position: point, Normal: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}},
+			Expected: vmath.Vector3d{X: 3.0, Y: 4.0, Z: 0.0},
+		},
+		{
+			Description: "Test a surface between x and z blends them evenly",
+			Triplanar:   &Triplanar{Texture: &uvColor{}, Space: "world", Sharpness: 4, Scale: one},
+			Point:       &Point{Position: point, Normal: vmath.Vector3d{X: 0.6, Y: 0.0, Z: 0.6}},
+			Expected:    vmath.Vector3d{X: 2.0, Y: 2.0, Z: 0.0},
+		},
+		{
+			Description: "Test sharpness weighs the axis the surface faces most",
+			Triplanar:   &Triplanar{Texture: &uvColor{}, Space: "world", Sharpness: 1, Scale: one},
+			Point:       &Point{Position: point, Normal: vmath.Vector3d{X: 0.75, Y: 0.0, Z: 0.25}},
+			Expected:    vmath.Vector3d{X: 2.5, Y: 2.0, Z: 0.0},
+		},
+		{
+			Description: "Test object space reads the object point and normal",
+			Triplanar:   &Triplanar{Texture: &uvColor{}, Space: "object", Sharpness: 4, Scale: one},
+			Point: &Point{
+				Position:       point,
+				Normal:         vmath.Vector3d{X: 1.0, Y: 0.0, Z: 0.0},
+				ObjectPosition: vmath.Vector3d{X: 5.0, Y: 6.0, Z: 7.0},
+				ObjectNormal:   vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0},
+			},
+			Expected: vmath.Vector3d{X: 5.0, Y: 6.0, Z: 0.0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			c := At(test.Triplanar, test.Point)
+			assert.InDelta(t, test.Expected.X, c.X, 1e-9)
+			assert.InDelta(t, test.Expected.Y, c.Y, 1e-9)
+			assert.InDelta(t, test.Expected.Z, c.Z, 1e-9)
+		})
+	}
+}
+
+// projector lands every point facing z on its x and y
+type projector struct{}
+
+func (projector) Project(p vmath.Vector3d, n vmath.Vector3d) (vmath.Vector2d, bool) {
+	if n.Z <= 0 {
+		return vmath.Vector2d{}, false
+	}
+	return vmath.Vector2d{X: p.X, Y: p.Y}, true
+}
+
+func TestProjection(t *testing.T) {
+	background := NewColorValue(vmath.Vector3d{X: 9.0, Y: 9.0, Z: 9.0})
+	projection := &Projection{Texture: &uvColor{}, Background: background, Camera: projector{}}
+
+	seen := At(projection, &Point{
+		Position: vmath.Vector3d{X: 0.25, Y: 0.75, Z: 0.0},
+		Normal:   vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0},
+	})
+	assert.Equal(t, vmath.Vector3d{X: 0.25, Y: 0.75, Z: 0.0}, seen)
+
+	away := At(projection, &Point{
+		Position: vmath.Vector3d{X: 0.25, Y: 0.75, Z: 0.0},
+		Normal:   vmath.Vector3d{X: 0.0, Y: 0.0, Z: -1.0},
+	})
+	assert.Equal(t, background.Color, away)
+}
+
+func TestProjectionFootprint(t *testing.T) {
+	image := newImageValue(NewPyramid(&Level{
+		Width:  2,
+		Height: 1,
+		Pixels: [][]vmath.Vector3d{{{X: 0.0, Y: 0.0, Z: 0.0}, {X: 200.0, Y: 200.0, Z: 200.0}}},
+	}))
+	projection := &Projection{Texture: image, Background: NewColorValue(vmath.Vector3d{}), Camera: projector{}}
+	at := &Point{
+		Position: vmath.Vector3d{X: 0.25, Y: 0.5, Z: 0.0},
+		Normal:   vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0},
+	}
+
+	// a point is read sharp
+	assert.InDelta(t, 0.0, At(projection, at).X, 1e-9)
+	// a footprint across the whole image averages it
+	at.PositionDx = vmath.Vector3d{X: 4.0, Y: 0.0, Z: 0.0}
+	at.PositionDy = vmath.Vector3d{X: 0.0, Y: 4.0, Z: 0.0}
+	assert.InDelta(t, 100.0, At(projection, at).X, 1e-9)
+}
@@ -0,0 +1,299 @@
+package color
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"sync"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// Mips are the ways an ImageValue is averaged over the area of a pixel,
+// none reads the full image by Filter alone
+var Mips = map[string]bool{
+	"none":      true,
+	"trilinear": true,
+	"ewa":       true,
+}
+
+// maxAnisotropy is how many times longer than wide the area ewa averages
+// over can be, longer areas are widened to keep the cost down
+const maxAnisotropy = 8.0
+
+// Level is one image of a Pyramid
+type Level struct {
+	Width, Height int
+	// Pixels are rows from the top of the image with every channel from 0
+	// to 255
+	Pixels [][]vmath.Vector3d
+}
+
+// NewLevel copies img into a Level
+func NewLevel(img image.Image) *Level {
+	bounds := img.Bounds()
+	l := &Level{
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+		Pixels: make([][]vmath.Vector3d, bounds.Dy()),
+	}
+	for y := range l.Pixels {
+		l.Pixels[y] = make([]vmath.Vector3d, bounds.Dx())
+		for x := range l.Pixels[y] {
+			c := color.RGBA64Model.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA64)
+			l.Pixels[y][x] = vmath.Vector3d{
+				X: float64(c.R) * 255 / math.MaxUint16,
+				Y: float64(c.G) * 255 / math.MaxUint16,
+				Z: float64(c.B) * 255 / math.MaxUint16,
+			}
+		}
+	}
+	return l
+}
+
+// halve averages every two by two pixels of the level into one, a side of
+// one pixel stays one pixel
+func (l *Level) halve() *Level {
+	half := &Level{
+		Width:  int(math.Max(float64(l.Width/2), 1)),
+		Height: int(math.Max(float64(l.Height/2), 1)),
+	}
+	half.Pixels = make([][]vmath.Vector3d, half.Height)
+	for y := range half.Pixels {
+		half.Pixels[y] = make([]vmath.Vector3d, half.Width)
+		for x := range half.Pixels[y] {
+			sum := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+			for _, corner := range [][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				sx := int(math.Min(float64(2*x+corner[0]), float64(l.Width-1)))
+				sy := int(math.Min(float64(2*y+corner[1]), float64(l.Height-1)))
+				sum = sum.Add(l.Pixels[sy][sx])
+			}
+			half.Pixels[y][x] = sum.Divide(4)
+		}
+	}
+	return half
+}
+
+// wrapIndex moves the pixel index i onto a side n pixels long
+func wrapIndex(i int, n int, wrap string) int {
+	switch wrap {
+	case "clamp":
+		if i < 0 {
+			return 0
+		}
+		if i >= n {
+			return n - 1
+		}
+		return i
+	case "mirror":
+		i %= 2 * n
+		if i < 0 {
+			i += 2 * n
+		}
+		if i >= n {
+			i = 2*n - 1 - i
+		}
+		return i
+	}
+	i %= n
+	if i < 0 {
+		i += n
+	}
+	return i
+}
+
+// texel returns the pixel at x, y wrapped onto the level
+func (l *Level) texel(x int, y int, wrap string) vmath.Vector3d {
+	return l.Pixels[wrapIndex(y, l.Height, wrap)][wrapIndex(x, l.Width, wrap)]
+}
+
+// catmullRom returns the weights of the four pixels around a point t of the
+// way from the second to the third
+func catmullRom(t float64) [4]float64 {
+	t2, t3 := t*t, t*t*t
+	return [4]float64{
+		0.5 * (-t3 + 2*t2 - t),
+		0.5 * (3*t3 - 5*t2 + 2),
+		0.5 * (-3*t3 + 4*t2 + t),
+		0.5 * (t3 - t2),
+	}
+}
+
+// sample reads the level at uv by filter, v runs up from the bottom
+func (l *Level) sample(uv vmath.Vector2d, filter string, wrap string) vmath.Vector3d {
+	// pixel centers are on whole numbers
+	x := uv.X*float64(l.Width) - 0.5
+	y := (1-uv.Y)*float64(l.Height) - 0.5
+	fx, fy := math.Floor(x), math.Floor(y)
+	tx, ty := x-fx, y-fy
+	ix, iy := int(fx), int(fy)
+
+	switch filter {
+	case "nearest":
+		return l.texel(int(math.Floor(x+0.5)), int(math.Floor(y+0.5)), wrap)
+	case "bicubic":
+		wx, wy := catmullRom(tx), catmullRom(ty)
+		c := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+		for j := 0; j < 4; j++ {
+			row := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+			for i := 0; i < 4; i++ {
+				row = row.Add(l.texel(ix+i-1, iy+j-1, wrap).SMultiply(wx[i]))
+			}
+			c = c.Add(row.SMultiply(wy[j]))
+		}
+		// the weights overshoot at hard edges
+		return vmath.Vector3d{
+			X: math.Min(math.Max(c.X, 0), 255),
+			Y: math.Min(math.Max(c.Y, 0), 255),
+			Z: math.Min(math.Max(c.Z, 0), 255),
+		}
+	}
+	top := l.texel(ix, iy, wrap).SMultiply(1 - tx).Add(l.texel(ix+1, iy, wrap).SMultiply(tx))
+	bottom := l.texel(ix, iy+1, wrap).SMultiply(1 - tx).Add(l.texel(ix+1, iy+1, wrap).SMultiply(tx))
+	return top.SMultiply(1 - ty).Add(bottom.SMultiply(ty))
+}
+
+// ewa averages the pixels of the level inside the ellipse around uv with
+// the axes duvdx and duvdy, nearer pixels weigh more
+func (l *Level) ewa(uv vmath.Vector2d, duvdx vmath.Vector2d, duvdy vmath.Vector2d, wrap string) vmath.Vector3d {
+	w, h := float64(l.Width), float64(l.Height)
+	s, t := uv.X*w-0.5, (1-uv.Y)*h-0.5
+	ds0, dt0 := duvdx.X*w, -duvdx.Y*h
+	ds1, dt1 := duvdy.X*w, -duvdy.Y*h
+
+	// the ellipse is A s^2 + B s t + C t^2 < 1, grown by a pixel so it
+	// always covers one
+	a := dt0*dt0 + dt1*dt1 + 1
+	b := -2 * (ds0*dt0 + ds1*dt1)
+	c := ds0*ds0 + ds1*ds1 + 1
+	f := 1 / (a*c - b*b/4)
+	a, b, c = a*f, b*f, c*f
+
+	det := 4*a*c - b*b
+	sRadius, tRadius := 2*math.Sqrt(det*c)/det, 2*math.Sqrt(det*a)/det
+	s0, s1 := int(math.Ceil(s-sRadius)), int(math.Floor(s+sRadius))
+	t0, t1 := int(math.Ceil(t-tRadius)), int(math.Floor(t+tRadius))
+
+	// a gaussian that falls to 0 at the edge of the ellipse
+	const alpha = 2.0
+	sum := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	weights := 0.0
+	for y := t0; y <= t1; y++ {
+		tt := float64(y) - t
+		for x := s0; x <= s1; x++ {
+			ss := float64(x) - s
+			r2 := a*ss*ss + b*ss*tt + c*tt*tt
+			if r2 < 1 {
+				weight := math.Exp(-alpha*r2) - math.Exp(-alpha)
+				sum = sum.Add(l.texel(x, y, wrap).SMultiply(weight))
+				weights += weight
+			}
+		}
+	}
+	if weights == 0 {
+		return l.sample(uv, "bilinear", wrap)
+	}
+	return sum.Divide(weights)
+}
+
+// Pyramid is an image at level 0 followed by it halved over and over down
+// to a single pixel
+type Pyramid []*Level
+
+// NewPyramid halves base down to a single pixel
+func NewPyramid(base *Level) Pyramid {
+	pyramid := Pyramid{base}
+	for level := base; level.Width > 1 || level.Height > 1; {
+		level = level.halve()
+		pyramid = append(pyramid, level)
+	}
+	return pyramid
+}
+
+// level returns where a footprint width pixels of level 0 across falls
+// between the levels of the pyramid
+func (p Pyramid) level(width float64) float64 {
+	if width <= 1 {
+		return 0
+	}
+	return math.Min(math.Log2(width), float64(len(p)-1))
+}
+
+// texels is how many pixels of level 0 duv spans
+func (p Pyramid) texels(duv vmath.Vector2d) float64 {
+	return math.Hypot(duv.X*float64(p[0].Width), duv.Y*float64(p[0].Height))
+}
+
+// Trilinear reads the two levels either side of where a footprint of duvdx
+// and duvdy falls by filter and blends them
+func (p Pyramid) Trilinear(uv vmath.Vector2d, duvdx vmath.Vector2d, duvdy vmath.Vector2d, filter string, wrap string) vmath.Vector3d {
+	level := p.level(math.Max(p.texels(duvdx), p.texels(duvdy)))
+	if level == 0 {
+		return p[0].sample(uv, filter, wrap)
+	}
+	i := int(math.Floor(level))
+	if i >= len(p)-1 {
+		return p[len(p)-1].sample(uv, filter, wrap)
+	}
+	f := level - float64(i)
+	return p[i].sample(uv, filter, wrap).SMultiply(1 - f).Add(p[i+1].sample(uv, filter, wrap).SMultiply(f))
+}
+
+// EWA averages the ellipse of duvdx and duvdy on the level its shorter
+// axis is about a pixel long, blending the two levels either side of it
+func (p Pyramid) EWA(uv vmath.Vector2d, duvdx vmath.Vector2d, duvdy vmath.Vector2d, filter string, wrap string) vmath.Vector3d {
+	major, minor := duvdx, duvdy
+	if p.texels(minor) > p.texels(major) {
+		major, minor = minor, major
+	}
+	majorLength, minorLength := p.texels(major), p.texels(minor)
+	if minorLength == 0 {
+		return p.Trilinear(uv, duvdx, duvdy, filter, wrap)
+	}
+	if minorLength*maxAnisotropy < majorLength {
+		minor = minor.SMultiply(majorLength / (minorLength * maxAnisotropy))
+		minorLength = majorLength / maxAnisotropy
+	}
+
+	level := p.level(minorLength)
+	i := int(math.Floor(level))
+	if i >= len(p)-1 {
+		return p[len(p)-1].sample(uv, filter, wrap)
+	}
+	f := level - float64(i)
+	c := p[i].ewa(uv, major, minor, wrap)
+	if f == 0 {
+		return c
+	}
+	return c.SMultiply(1 - f).Add(p[i+1].ewa(uv, major, minor, wrap).SMultiply(f))
+}
+
+var (
+	pyramids     = map[string]Pyramid{}
+	pyramidsLock sync.Mutex
+)
+
+// LoadPyramid reads a png or jpeg image into a Pyramid once, every later
+// load of the same path returns the same Pyramid
+func LoadPyramid(path string) (Pyramid, error) {
+	pyramidsLock.Lock()
+	defer pyramidsLock.Unlock()
+	if pyramid, ok := pyramids[path]; ok {
+		return pyramid, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	pyramid := NewPyramid(NewLevel(img))
+	pyramids[path] = pyramid
+	return pyramid, nil
+}
@@ -0,0 +1,130 @@
+package color
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// stripesImage is 8 by 8 pixels of columns of red 0 and 200, so it is
+// the same all the way down v and changes every pixel across u
+func stripesImage() *ImageValue {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, color.RGBA{uint8(200 * (x % 2)), 0, 0, 0xff})
+		}
+	}
+	return NewImageValue(img)
+}
+
+func TestNewPyramid(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	img.Set(0, 0, color.RGBA{200, 0, 0, 0xff})
+	pyramid := NewPyramid(NewLevel(img))
+
+	require.Len(t, pyramid, 3)
+	assert.Equal(t, []int{4, 2, 1}, []int{pyramid[0].Width, pyramid[1].Width, pyramid[2].Width})
+	assert.Equal(t, []int{2, 1, 1}, []int{pyramid[0].Height, pyramid[1].Height, pyramid[2].Height})
+	assert.InDelta(t, 50.0, pyramid[1].Pixels[0][0].X, 1e-9)
+	assert.InDelta(t, 0.0, pyramid[1].Pixels[0][1].X, 1e-9)
+	assert.InDelta(t, 25.0, pyramid[2].Pixels[0][0].X, 1e-9)
+}
+
+func TestImageValueGetFilteredColor(t *testing.T) {
+	// the middle of the first pixel, which is black
+	uv := vmath.Vector2d{X: 1.0 / 16, Y: 1.0 / 16}
+	pixel := 1.0 / 8
+
+	tests := []struct {
+		Description string
+		Mip         string
+		Scale       vmath.Vector2d
+		DUVDx       vmath.Vector2d
+		DUVDy       vmath.Vector2d
+		Expected    float64
+		Delta       float64
+	}{
+		{
+			Description: "Test a footprint of a pixel reads the full image",
+			Mip:         "trilinear",
+			DUVDx:       vmath.Vector2d{X: pixel},
+			DUVDy:       vmath.Vector2d{Y: pixel},
+			Expected:    0.0,
+		},
+		{
+			Description: "Test a footprint of two pixels reads the average",
+			Mip:         "trilinear",
+			DUVDx:       vmath.Vector2d{X: 2 * pixel},
+			DUVDy:       vmath.Vector2d{Y: 2 * pixel},
+			Expected:    100.0,
+		},
+		{
+			Description: "Test trilinear blends between levels",
+			Mip:         "trilinear",
+			DUVDx:       vmath.Vector2d{X: 1.5 * pixel},
+			DUVDy:       vmath.Vector2d{Y: 1.5 * pixel},
+			Expected:    58.5,
+			Delta:       0.1,
+		},
+		{
+			Description: "Test scale grows the footprint",
+			Mip:         "trilinear",
+			Scale:       vmath.Vector2d{X: 2.0, Y: 2.0},
+			DUVDx:       vmath.Vector2d{X: pixel},
+			DUVDy:       vmath.Vector2d{Y: pixel},
+			Expected:    100.0,
+		},
+		{
+			Description: "Test none ignores the footprint",
+			Mip:         "none",
+			DUVDx:       vmath.Vector2d{X: 4 * pixel},
+			DUVDy:       vmath.Vector2d{Y: 4 * pixel},
+			Expected:    0.0,
+		},
+		{
+			Description: "Test ewa averages a long footprint across the stripes",
+			Mip:         "ewa",
+			DUVDx:       vmath.Vector2d{X: 4 * pixel},
+			DUVDy:       vmath.Vector2d{Y: 0.5 * pixel},
+			Expected:    100.0,
+			Delta:       10.0,
+		},
+		{
+			Description: "Test ewa keeps the stripes under a long footprint along them",
+			Mip:         "ewa",
+			DUVDx:       vmath.Vector2d{X: 0.25 * pixel},
+			DUVDy:       vmath.Vector2d{Y: 4 * pixel},
+			Expected:    0.0,
+			Delta:       25.0,
+		},
+		{
+			Description: "Test trilinear blurs the stripes under a long footprint along them",
+			Mip:         "trilinear",
+			DUVDx:       vmath.Vector2d{X: 0.25 * pixel},
+			DUVDy:       vmath.Vector2d{Y: 4 * pixel},
+			Expected:    100.0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			iv := stripesImage()
+			iv.Mip = test.Mip
+			if test.Scale != (vmath.Vector2d{}) {
+				iv.Scale = test.Scale
+				iv.Offset = vmath.Vector2d{X: 1.0 / 16, Y: 1.0 / 16}
+			}
+			at := uv
+			if test.Scale != (vmath.Vector2d{}) {
+				at = vmath.Vector2d{X: 0.0, Y: 0.0}
+			}
+			c := iv.GetFilteredColor(at, test.DUVDx, test.DUVDy)
+			assert.InDelta(t, test.Expected, c.X, test.Delta+1e-9)
+		})
+	}
+}
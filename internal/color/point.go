@@ -0,0 +1,51 @@
+package color
+
+import (
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// Point is where on a surface a color is read, every direction in it is
+// unit length
+type Point struct {
+	UV vmath.Vector2d
+	// UVDx and UVDy are how much UV changes to the next pixel across and
+	// down, zero when unknown
+	UVDx, UVDy vmath.Vector2d
+	// Position is in the world and ObjectPosition in the space of the shape
+	// before it was moved into the world by a transform
+	Position, ObjectPosition vmath.Vector3d
+	// PositionDx and PositionDy are how much Position changes to the next
+	// pixel across and down, zero when unknown
+	PositionDx, PositionDy vmath.Vector3d
+	// Normal is the true normal of the surface in the world and
+	// ObjectNormal in the space of the shape
+	Normal, ObjectNormal vmath.Vector3d
+}
+
+// Solid is a Color that is read by more of where on a surface it is than
+// its uv
+type Solid interface {
+	Color
+	GetSolidColor(p *Point) vmath.Vector3d
+}
+
+// At reads c at p, colors that are not Solid only read the uv
+func At(c Color, p *Point) vmath.Vector3d {
+	if solid, ok := c.(Solid); ok {
+		return solid.GetSolidColor(p)
+	}
+	return c.GetColor(p.UV.X, p.UV.Y)
+}
+
+// uvPoint is a Point of a color read only by u and v, a position is taken
+// to be u and v as x and y
+func uvPoint(u float64, v float64) *Point {
+	position := vmath.Vector3d{X: u, Y: v, Z: 0.0}
+	return &Point{
+		UV:             vmath.Vector2d{X: u, Y: v},
+		Position:       position,
+		ObjectPosition: position,
+		Normal:         vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0},
+		ObjectNormal:   vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0},
+	}
+}
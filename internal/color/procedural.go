@@ -0,0 +1,129 @@
+package color
+
+import (
+	"image/color"
+	"math"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// Patterns are the procedural color types
+var Patterns = map[string]bool{
+	"checker":    true,
+	"stripes":    true,
+	"gradient":   true,
+	"noise":      true,
+	"fbm":        true,
+	"turbulence": true,
+	"marble":     true,
+	"wood":       true,
+	"voronoi":    true,
+}
+
+// Procedural is a Color made from a Pattern of two or more Colors, the point
+// read is in Space, uv, world or object, and is scaled by Scale then moved
+// by Offset before the pattern is found
+//
+// checker and stripes step through Colors by cell, the rest blend along
+// Colors as a ramp by a number from 0 to 1, gradient by x, noise, fbm and
+// turbulence by Perlin noise, marble by sine waves bent by turbulence, wood
+// by rings around the z axis and voronoi by the distance to the nearest cell
+type Procedural struct {
+	Name          string
+	Pattern       string
+	Colors        []Color
+	Scale, Offset vmath.Vector3d
+	Space         string
+	// Octaves is how many octaves of noise fbm, turbulence and marble sum
+	Octaves int
+}
+
+// GetColor returns the pattern at uv, a pattern in world or object space
+// reads u and v as x and y when it is not given a position
+// Satisfies Color interface
+func (p *Procedural) GetColor(u float64, v float64) vmath.Vector3d {
+	return p.GetSolidColor(uvPoint(u, v))
+}
+
+// GetSolidColor returns the pattern at the point of Space, the Colors it is
+// made from are read at the same point so patterns can be nested
+// Satisfies Solid interface
+func (p *Procedural) GetSolidColor(at *Point) vmath.Vector3d {
+	point := vmath.Vector3d{X: at.UV.X, Y: at.UV.Y, Z: 0.0}
+	switch p.Space {
+	case "world":
+		point = at.Position
+	case "object":
+		point = at.ObjectPosition
+	}
+	q := point.Compt(p.Scale).Add(p.Offset)
+	n := len(p.Colors)
+	if n == 0 {
+		return vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	}
+	pick := func(index int) vmath.Vector3d {
+		return At(p.Colors[((index%n)+n)%n], at)
+	}
+
+	var t float64
+	switch p.Pattern {
+	case "checker":
+		return pick(int(math.Floor(q.X) + math.Floor(q.Y) + math.Floor(q.Z)))
+	case "stripes":
+		return pick(int(math.Floor(q.X)))
+	case "gradient":
+		t = q.X
+	case "noise":
+		t = 0.5 + 0.5*vmath.Perlin(q)
+	case "fbm":
+		t = 0.5 + 0.5*vmath.FBM(q, p.Octaves, 2.0, 0.5)
+	case "turbulence":
+		t = vmath.Turbulence(q, p.Octaves, 2.0, 0.5)
+	case "marble":
+		t = 0.5 + 0.5*math.Sin(math.Pi*(q.X+4*vmath.Turbulence(q, p.Octaves, 2.0, 0.5)))
+	case "wood":
+		rings := math.Sqrt(q.X*q.X+q.Y*q.Y) + 0.5*vmath.Perlin(q)
+		t = rings - math.Floor(rings)
+	case "voronoi":
+		t = vmath.Worley(q)
+	}
+
+	// blend between the two nearest Colors of the ramp
+	t = math.Min(math.Max(t, 0), 1) * float64(n-1)
+	index := int(math.Min(math.Floor(t), float64(n-2)))
+	if index < 0 {
+		return pick(0)
+	}
+	f := t - float64(index)
+	return pick(index).SMultiply(1 - f).Add(pick(index + 1).SMultiply(f))
+}
+
+func (p *Procedural) GetRGBA() color.RGBA {
+	c := p.GetColor(0, 0)
+	return color.RGBA{
+		uint8(math.Min(math.Max(c.X, 0), 255)),
+		uint8(math.Min(math.Max(c.Y, 0), 255)),
+		uint8(math.Min(math.Max(c.Z, 0), 255)),
+		0xff,
+	}
+}
+
+// Add adds c to every one of Colors, the Colors are copied first as they
+// may be shared with the rest of the scene
+func (p *Procedural) Add(c Color) {
+	colors := make([]Color, len(p.Colors))
+	for index, pc := range p.Colors {
+		colors[index] = pc.SMultiply(1.0)
+		colors[index].Add(c)
+	}
+	p.Colors = colors
+}
+
+func (p *Procedural) SMultiply(intensity float64) Color {
+	scaled := *p
+	scaled.Colors = make([]Color, len(p.Colors))
+	for index, pc := range p.Colors {
+		scaled.Colors[index] = pc.SMultiply(intensity)
+	}
+	return &scaled
+}
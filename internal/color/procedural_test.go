@@ -0,0 +1,154 @@
+package color
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestProceduralPatterns(t *testing.T) {
+	black := NewColorValue(vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0})
+	grey := NewColorValue(vmath.Vector3d{X: 100.0, Y: 100.0, Z: 100.0})
+	white := NewColorValue(vmath.Vector3d{X: 200.0, Y: 200.0, Z: 200.0})
+	one := vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}
+
+	tests := []struct {
+		Description string
+		Procedural  *Procedural
+		U, V        float64
+		Expected    vmath.Vector3d
+	}{
+		{
+			Description: "Test checker starts on the first color",
+			Procedural:  &Procedural{Pattern: "checker", Colors: []Color{black, white}, Scale: one},
+			U:           0.5,
+			V:           0.5,
+			Expected:    black.Color,
+		},
+		{
+			Description: "Test checker steps to the next color by cell",
+			Procedural:  &Procedural{Pattern: "checker", Colors: []Color{black, white}, Scale: one},
+			U:           1.5,
+			V:           0.5,
+			Expected:    white.Color,
+		},
+		{
+			Description: "Test checker wraps back to the first color",
+			Procedural:  &Procedural{Pattern: "checker", Colors: []Color{black, white}, Scale: one},
+			U:           1.5,
+			V:           1.5,
+			Expected:    black.Color,
+		},
+		{
+			Description: "Test stripes are scaled",
+			Procedural:  &Procedural{Pattern: "stripes", Colors: []Color{black, grey, white}, Scale: one.SMultiply(4.0)},
+			U:           0.6,
+			V:           0.0,
+			Expected:    white.Color,
+		},
+		{
+			Description: "Test stripes below zero step back through the colors",
+			Procedural:  &Procedural{Pattern: "stripes", Colors: []Color{black, grey, white}, Scale: one},
+			U:           -0.5,
+			V:           0.0,
+			Expected:    white.Color,
+		},
+		{
+			Description: "Test gradient blends between the nearest colors",
+			Procedural:  &Procedural{Pattern: "gradient", Colors: []Color{black, grey, white}, Scale: one},
+			U:           0.75,
+			V:           0.0,
+			Expected:    vmath.Vector3d{X: 150.0, Y: 150.0, Z: 150.0},
+		},
+		{
+			Description: "Test gradient is moved by offset",
+			Procedural: &Procedural{
+				Pattern: "gradient",
+				Colors:  []Color{black, white},
+				Scale:   one,
+				Offset:  vmath.Vector3d{X: 0.5, Y: 0.0, Z: 0.0},
+			},
+			U:        0.0,
+			V:        0.0,
+			Expected: vmath.Vector3d{X: 100.0, Y: 100.0, Z: 100.0},
+		},
+		{
+			Description: "Test gradient holds the last color past 1",
+			Procedural:  &Procedural{Pattern: "gradient", Colors: []Color{black, white}, Scale: one},
+			U:           3.0,
+			V:           0.0,
+			Expected:    white.Color,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			actual := test.Procedural.GetColor(test.U, test.V)
+			assert.InDelta(t, test.Expected.X, actual.X, 1e-9)
+			assert.InDelta(t, test.Expected.Y, actual.Y, 1e-9)
+			assert.InDelta(t, test.Expected.Z, actual.Z, 1e-9)
+		})
+	}
+}
+
+func TestProceduralNoiseStaysInRamp(t *testing.T) {
+	black := NewColorValue(vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0})
+	white := NewColorValue(vmath.Vector3d{X: 200.0, Y: 200.0, Z: 200.0})
+	for _, pattern := range []string{"noise", "fbm", "turbulence", "marble", "wood", "voronoi"} {
+		t.Run(pattern, func(t *testing.T) {
+			p := &Procedural{
+				Pattern: pattern,
+				Colors:  []Color{black, white},
+				Scale:   vmath.Vector3d{X: 3.0, Y: 3.0, Z: 3.0},
+				Octaves: 4,
+			}
+			for i := 0; i < 50; i++ {
+				c := p.GetColor(float64(i)*0.137, float64(i)*0.071)
+				assert.GreaterOrEqual(t, c.X, 0.0)
+				assert.LessOrEqual(t, c.X, 200.0)
+			}
+		})
+	}
+}
+
+func TestProceduralSpace(t *testing.T) {
+	black := NewColorValue(vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0})
+	white := NewColorValue(vmath.Vector3d{X: 200.0, Y: 200.0, Z: 200.0})
+	uv := vmath.Vector2d{X: 0.5, Y: 0.5}
+	world := vmath.Vector3d{X: 1.5, Y: 0.5, Z: 0.5}
+	object := vmath.Vector3d{X: 2.5, Y: 0.5, Z: 0.5}
+
+	tests := []struct {
+		Space    string
+		Expected vmath.Vector3d
+	}{
+		{Space: "uv", Expected: black.Color},
+		{Space: "world", Expected: white.Color},
+		{Space: "object", Expected: black.Color},
+	}
+	for _, test := range tests {
+		t.Run(test.Space, func(t *testing.T) {
+			p := &Procedural{
+				Pattern: "checker",
+				Colors:  []Color{black, white},
+				Scale:   vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0},
+				Space:   test.Space,
+			}
+			assert.Equal(t, test.Expected, At(p, &Point{UV: uv, Position: world, ObjectPosition: object}))
+		})
+	}
+
+	// colors that are not procedural only read the uv
+	assert.Equal(t, white.Color, At(white, &Point{UV: uv, Position: world, ObjectPosition: object}))
+}
+
+func TestProceduralSMultiplyCopies(t *testing.T) {
+	white := NewColorValue(vmath.Vector3d{X: 200.0, Y: 200.0, Z: 200.0})
+	p := &Procedural{Pattern: "stripes", Colors: []Color{white, white}, Scale: vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}}
+
+	half := p.SMultiply(0.5)
+	assert.Equal(t, vmath.Vector3d{X: 100.0, Y: 100.0, Z: 100.0}, half.GetColor(0.5, 0.5))
+	assert.Equal(t, vmath.Vector3d{X: 200.0, Y: 200.0, Z: 200.0}, white.Color)
+}
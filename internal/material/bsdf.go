@@ -0,0 +1,121 @@
+package material
+
+import (
+	"math"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// ShadingContext is everything known about the point of a surface being
+// shaded, every direction in it is unit length and in world space
+type ShadingContext struct {
+	Position vmath.Vector3d
+	// ObjectPosition is Position in the space of the shape before it was
+	// moved into the world by a transform, ObjectNormal is the true normal
+	// in that space
+	ObjectPosition, ObjectNormal vmath.Vector3d
+	// PositionDx and PositionDy are how much Position changes to the next
+	// pixel across and down, zero when unknown
+	PositionDx, PositionDy vmath.Vector3d
+	// Normal is bent by the normal and bump maps of the material,
+	// GeometricNormal is the true normal of the surface
+	Normal, GeometricNormal vmath.Vector3d
+	// Tangent and Bitangent are the directions u and v increase in across
+	// the surface, zero when the surface has no uvs, on strands Tangent is
+	// the direction along the strand
+	Tangent, Bitangent vmath.Vector3d
+	UV                 vmath.Vector2d
+	// UVDx and UVDy are how much UV changes to the next pixel across and
+	// down, zero when unknown
+	UVDx, UVDy vmath.Vector2d
+	// In points at the light being evaluated and Out back along the ray
+	// that hit the surface, both away from the surface
+	In, Out vmath.Vector3d
+	// LightDistance is how far away the light In points at is, infinite for
+	// lights that are not anywhere like directional lights
+	LightDistance float64
+	// Depth is how many times the ray has bounced, 0 for camera rays
+	Depth int
+}
+
+// BSDF is how light scatters at one point of a surface, a Material makes
+// one from a ShadingContext for every hit
+type BSDF interface {
+	// Evaluate returns the share of the light arriving from in that leaves
+	// towards out, the BSDF times the cosine of in to the normal
+	Evaluate(in vmath.Vector3d, out vmath.Vector3d) vmath.Vector3d
+	// Sample picks a direction for light to arrive from that leaves towards
+	// out, u1 and u2 are uniform from 0 to 1, the weight is Evaluate over
+	// the pdf and is zero when no light arrives from the direction
+	Sample(out vmath.Vector3d, u1 float64, u2 float64) (vmath.Vector3d, vmath.Vector3d, float64)
+	// Pdf is the chance Sample picks in for out
+	Pdf(in vmath.Vector3d, out vmath.Vector3d) float64
+}
+
+// Ambient is a BSDF that gives off light no light in the scene has to reach,
+// it is added once however many lights there are
+type Ambient interface {
+	Ambient() vmath.Vector3d
+}
+
+// toon is the share Evaluate returns for a material that draws c under a
+// white light, scene colors are from 0 to 255 and lights shine on a surface
+// facing them with pi times their color so a white diffuse surface returns
+// its own color
+func toon(c vmath.Vector3d) vmath.Vector3d {
+	return c.Divide(255.0 * math.Pi)
+}
+
+// colorAt reads c at the point of the surface ctx is of
+func colorAt(c color.Color, ctx *ShadingContext) vmath.Vector3d {
+	return color.At(c, &color.Point{
+		UV:             ctx.UV,
+		UVDx:           ctx.UVDx,
+		UVDy:           ctx.UVDy,
+		Position:       ctx.Position,
+		ObjectPosition: ctx.ObjectPosition,
+		PositionDx:     ctx.PositionDx,
+		PositionDy:     ctx.PositionDy,
+		Normal:         ctx.GeometricNormal,
+		ObjectNormal:   ctx.ObjectNormal,
+	})
+}
+
+// cosineSample picks a direction around n more often the closer it is to n,
+// the pdf of the direction is its cosine to n over pi
+func cosineSample(n vmath.Vector3d, u1 float64, u2 float64) vmath.Vector3d {
+	t, b := tangentFrame(n, vmath.Vector3d{}, vmath.Vector3d{})
+	r, phi := math.Sqrt(u1), 2*math.Pi*u2
+	in := t.SMultiply(r * math.Cos(phi)).Add(b.SMultiply(r * math.Sin(phi))).Add(n.SMultiply(math.Sqrt(math.Max(1-u1, 0))))
+	in.Normalize()
+	return in
+}
+
+// cosinePdf is the chance cosineSample picks in around n
+func cosinePdf(n vmath.Vector3d, in vmath.Vector3d) float64 {
+	return math.Max(n.Dot(in), 0) / math.Pi
+}
+
+// weigh finishes Sample for a BSDF that picks in with the pdf given
+func weigh(bsdf BSDF, in vmath.Vector3d, out vmath.Vector3d, pdf float64) (vmath.Vector3d, vmath.Vector3d, float64) {
+	if pdf <= 0 {
+		return in, vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}, 0.0
+	}
+	return in, bsdf.Evaluate(in, out).Divide(pdf), pdf
+}
+
+// schlick is the share of light a dielectric of index of refraction ior
+// reflects at the cosine to its normal
+func schlick(cos float64, ior float64) float64 {
+	f0 := math.Pow((ior-1)/(ior+1), 2)
+	return f0 + (1-f0)*math.Pow(1-math.Min(math.Max(cos, 0), 1), 5)
+}
+
+// ambientOf is the Ambient of bsdf, black when it has none
+func ambientOf(bsdf BSDF) vmath.Vector3d {
+	if ambient, ok := bsdf.(Ambient); ok {
+		return ambient.Ambient()
+	}
+	return vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+}
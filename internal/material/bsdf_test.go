@@ -0,0 +1,134 @@
+package material
+
+import (
+	"math"
+	"testing"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// direction is the unit vector degrees from the z axis towards the x axis
+func direction(degrees float64) vmath.Vector3d {
+	radians := degrees * math.Pi / 180
+	return vmath.Vector3d{X: math.Sin(radians), Y: 0.0, Z: math.Cos(radians)}
+}
+
+// litColor is what Evaluate of bsdf gives from in to out under a white light
+func litColor(bsdf BSDF, in vmath.Vector3d, out vmath.Vector3d) vmath.Vector3d {
+	return bsdf.Evaluate(in, out).SMultiply(255.0 * math.Pi)
+}
+
+func TestBSDFSample(t *testing.T) {
+	flat := &color.ColorValue{Color: vmath.Vector3d{X: 100.0, Y: 100.0, Z: 100.0}}
+	ctx := &ShadingContext{
+		Normal:          vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0},
+		GeometricNormal: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0},
+		Tangent:         vmath.Vector3d{X: 1.0, Y: 0.0, Z: 0.0},
+		Bitangent:       vmath.Vector3d{X: 0.0, Y: 1.0, Z: 0.0},
+	}
+	out := direction(30)
+
+	var tests = []struct {
+		Description string
+		Material    Material
+	}{
+		{
+			Description: "Test lambert",
+			Material:    &Lambert{Ambient: flat, Diffuse: flat, SH: 1.0},
+		},
+		{
+			Description: "Test cartoon",
+			Material:    &Cartoon{Shadow: flat, Diffuse: flat, Specular: flat, Outline: flat, Rim: flat, Segments: 3, Shininess: 10.0, SpecularBands: 2, RimWidth: 0.5},
+		},
+		{
+			Description: "Test phong",
+			Material:    &Phong{Ambient: flat, Diffuse: flat, Specular: flat, Shininess: 10.0},
+		},
+		{
+			Description: "Test blinn",
+			Material:    &Phong{Blinn: true, Ambient: flat, Diffuse: flat, Specular: flat, Shininess: 10.0},
+		},
+		{
+			Description: "Test hair",
+			Material:    &Hair{Ambient: flat, Diffuse: flat, Specular: flat, Shininess: 10.0},
+		},
+		{
+			Description: "Test pbr",
+			Material: &PBR{
+				BaseColor: constantParam(0.5),
+				Metallic:  constantParam(0.5),
+				Roughness: constantParam(0.3),
+				Specular:  constantParam(0.5),
+			},
+		},
+		{
+			Description: "Test mix",
+			Material: &Mix{
+				A:      &Lambert{Ambient: flat, Diffuse: flat},
+				B:      &Phong{Ambient: flat, Diffuse: flat, Specular: flat, Shininess: 10.0},
+				Factor: constantParam(0.3),
+			},
+		},
+		{
+			Description: "Test fresnel mix",
+			Material: &Mix{
+				A:   &Hair{Ambient: flat, Diffuse: flat, Specular: flat, Shininess: 10.0},
+				B:   &Lambert{Ambient: flat, Diffuse: flat},
+				IOR: 1.5,
+			},
+		},
+		{
+			Description: "Test layered",
+			Material: &Layered{
+				Base:      &Lambert{Ambient: flat, Diffuse: flat},
+				IOR:       1.5,
+				Roughness: 0.2,
+			},
+		},
+		{
+			Description: "Test thin film",
+			Material: &ThinFilm{
+				Base:         &Lambert{Ambient: flat, Diffuse: flat},
+				Thickness:    constantParam(0.5),
+				MinThickness: 200,
+				MaxThickness: 600,
+				IOR:          1.33,
+				BaseIOR:      1.0,
+				Roughness:    0.2,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			bsdf := test.Material.BSDF(ctx)
+			for _, u := range [][2]float64{{0.1, 0.2}, {0.5, 0.5}, {0.7, 0.9}, {0.95, 0.05}} {
+				in, weight, pdf := bsdf.Sample(out, u[0], u[1])
+				require.InDelta(t, 1.0, in.Norm(), 1e-9)
+				if pdf == 0 {
+					assert.Equal(t, vmath.Vector3d{}, weight)
+					continue
+				}
+				assert.InDelta(t, bsdf.Pdf(in, out), pdf, 1e-9*pdf)
+				f := bsdf.Evaluate(in, out).Divide(pdf)
+				assert.InDelta(t, f.X, weight.X, 1e-9)
+				assert.InDelta(t, f.Y, weight.Y, 1e-9)
+				assert.InDelta(t, f.Z, weight.Z, 1e-9)
+			}
+		})
+	}
+}
+
+func TestCosineSample(t *testing.T) {
+	n := vmath.Vector3d{X: 0.0, Y: 1.0, Z: 0.0}
+	// the middle of the square is half way out along a circle of radius
+	// sqrt(0.5) and the first corner is straight along n
+	in := cosineSample(n, 0.5, 0.5)
+	assert.InDelta(t, math.Sqrt(0.5), in.Y, 1e-9)
+	assert.InDelta(t, math.Sqrt(0.5)/math.Pi, cosinePdf(n, in), 1e-9)
+	assert.InDelta(t, 1.0, cosineSample(n, 0.0, 0.0).Y, 1e-9)
+	assert.Equal(t, 0.0, cosinePdf(n, n.UNegate()))
+}
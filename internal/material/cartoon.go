@@ -3,18 +3,35 @@ package material
 import (
 	"errors"
 	"fmt"
+	"math"
 
 	"github.com/smallfish/simpleyaml"
 
 	"github.com/chrispotter/trace/internal/color"
-	"github.com/chrispotter/trace/internal/lights"
+	vmath "github.com/chrispotter/trace/internal/math"
 )
 
-// LambertConfig defines a sphere for the ShapeFactory
+// Outlined is a Material inked with a line around its silhouette, the
+// camera draws the line since only it knows how wide a pixel is
+type Outlined interface {
+	Material
+	// OutlineWidth is how many pixels wide the line is, 0 for none
+	OutlineWidth() float64
+	OutlineColor() vmath.Vector3d
+}
+
+// CartoonConfig defines a toon material for the MaterialFactory
 type CartoonConfig struct {
-	Name     string
-	Segments int
-	Colors   []color.Color
+	Name          string
+	Segments      int
+	Thresholds    []float64
+	Shininess     float64
+	SpecularBands int
+	RimWidth      float64
+	OutlineWidth  float64
+	Colors        []color.Color
+	Rim           color.Color
+	Maps          *SurfaceMaps
 }
 
 func (cc *CartoonConfig) GetName() string {
@@ -24,25 +41,35 @@ func (cc *CartoonConfig) GetName() string {
 // NewShape generates a Shape from the config object
 // satisfies the MaterialConfig interface  (1/2)
 func (cc *CartoonConfig) NewMaterial() (Material, error) {
+	rim := cc.Rim
+	if rim == nil {
+		rim = cc.Colors[2]
+	}
 	return &Cartoon{
-		Name:     cc.Name,
-		Ambient:  cc.Colors[0],
-		Diffuse:  cc.Colors[1],
-		Specular: cc.Colors[2],
-		Outline:  cc.Colors[3],
-		Segments: cc.Segments,
-		SH:       1.0,
+		Name:          cc.Name,
+		Shadow:        cc.Colors[0],
+		Diffuse:       cc.Colors[1],
+		Specular:      cc.Colors[2],
+		Outline:       cc.Colors[3],
+		Rim:           rim,
+		Segments:      cc.Segments,
+		Thresholds:    cc.Thresholds,
+		Shininess:     cc.Shininess,
+		SpecularBands: cc.SpecularBands,
+		RimWidth:      cc.RimWidth,
+		Width:         cc.OutlineWidth,
+		Maps:          cc.Maps,
 	}, nil
 }
 
-// FromYaml generates Config from input yaml
+// FromYaml generates Config from input yaml, segments is the number of
+// bands from the shadow to the diffuse color and thresholds the cosines to
+// the light each band after the first starts at, evenly spread when missing,
+// specular_bands steps the highlight of the given shininess, rim_width is
+// the cosine to the camera below which the rim color is added and
+// outline_width is in pixels
 // satisfies the interface ShapesConfig (2/2)
 func (cc *CartoonConfig) FromYaml(config *simpleyaml.Yaml, colors map[string]color.Color) error {
-	if segments, err := config.Get("segments").Int(); err == nil {
-		cc.Segments = segments
-	} else {
-		cc.Segments = 3
-	}
 	if len(colors) < 2 {
 		return errors.New("not enough colors for cartoon shader")
 	}
@@ -51,7 +78,7 @@ func (cc *CartoonConfig) FromYaml(config *simpleyaml.Yaml, colors map[string]col
 			return errors.New("not enough colors in cartoon config")
 		}
 
-		// color[0] is ambient
+		// color[0] is the shadow band
 		// color[1] is diffuse
 		// color[2] is specular
 		// color[3] is outline
@@ -67,54 +94,177 @@ func (cc *CartoonConfig) FromYaml(config *simpleyaml.Yaml, colors map[string]col
 		return errors.New("not enough colors in cartoon config")
 	}
 
+	if rim, err := config.Get("rim").String(); err == nil {
+		c, ok := colors[rim]
+		if !ok {
+			return errors.New(fmt.Sprintf("color %s does not exist in scene.", rim))
+		}
+		cc.Rim = c
+	}
+
+	cc.Segments = 3
+	if config.Get("segments").IsFound() {
+		segments, err := config.Get("segments").Int()
+		if err != nil || segments < 1 {
+			return errors.New("cartoon segments must be 1 or more")
+		}
+		cc.Segments = segments
+	}
+	if config.Get("thresholds").IsFound() {
+		thresholds, err := config.Get("thresholds").Array()
+		if err != nil {
+			return errors.New("cartoon thresholds must be a list of cosines")
+		}
+		if !config.Get("segments").IsFound() {
+			cc.Segments = len(thresholds) + 1
+		}
+		if len(thresholds) != cc.Segments-1 {
+			return errors.New("cartoon needs one threshold less than it has segments")
+		}
+		previous := -1.0
+		for index := range thresholds {
+			threshold, err := floatFromYaml(config.Get("thresholds").GetIndex(index))
+			if err != nil || threshold <= previous || threshold >= 1 {
+				return errors.New("cartoon thresholds must be increasing cosines from -1 to 1")
+			}
+			cc.Thresholds = append(cc.Thresholds, threshold)
+			previous = threshold
+		}
+	}
+
+	cc.Shininess, cc.SpecularBands, cc.OutlineWidth = 32.0, 1, 2.0
+	for _, field := range []struct {
+		key   string
+		value *float64
+	}{
+		{"shininess", &cc.Shininess},
+		{"rim_width", &cc.RimWidth},
+		{"outline_width", &cc.OutlineWidth},
+	} {
+		if !config.Get(field.key).IsFound() {
+			continue
+		}
+		value, err := floatFromYaml(config.Get(field.key))
+		if err != nil || value < 0 {
+			return errors.New(fmt.Sprintf("cartoon %s must be a number of 0 or more", field.key))
+		}
+		*field.value = value
+	}
+	if config.Get("specular_bands").IsFound() {
+		bands, err := config.Get("specular_bands").Int()
+		if err != nil || bands < 0 {
+			return errors.New("cartoon specular_bands must be 0 or more")
+		}
+		cc.SpecularBands = bands
+	}
+
+	maps, err := mapsFromYaml(config)
+	if err != nil {
+		return err
+	}
+	cc.Maps = maps
+
 	return nil
 }
 
-// Cartoon is a default flat shader with a Ambient, Diffuse, Specular, and
-// Outline color
+// Cartoon is a toon shader stepping from the Shadow to the Diffuse color in
+// Segments bands by the angle to the light, with a highlight of the Specular
+// color stepped into SpecularBands, a Rim of light along the edge facing
+// away from the camera and an Outline drawn by the camera Width pixels wide,
+// every color is tinted by the light
 type Cartoon struct {
-	Name                                             string
-	Ambient, Diffuse, Specular, Outline              color.Color
-	LightIndex, DistanceLightHit, U, V, N, SH        float64
-	Reflect, Iridesent, Refract, Glossy, Transparent bool
-	Segments                                         int
+	Name                                    string
+	Shadow, Diffuse, Specular, Outline, Rim color.Color
+	Segments                                int
+	// Thresholds are the cosines to the light each band after the first
+	// starts at, the bands are evenly spread from -1 to 1 when nil
+	Thresholds      []float64
+	Shininess       float64
+	SpecularBands   int
+	RimWidth, Width float64
+	Maps            *SurfaceMaps
+}
+
+// BSDF satisfies the Material interface
+func (c *Cartoon) BSDF(ctx *ShadingContext) BSDF {
+	return &cartoonBSDF{cartoon: c, ctx: ctx}
 }
 
-func (c *Cartoon) ReturnColor(angle float64, cam float64, ref float64, light lights.Light) color.Color {
-	normalizedAngle := ((angle + 1.0) / 2.0)
+// GetMaps satisfies the Mapped interface
+func (c *Cartoon) GetMaps() *SurfaceMaps {
+	return c.Maps
+}
 
-	if cam < .5 {
-		fmt.Println(cam)
+// OutlineWidth satisfies the Outlined interface
+func (c *Cartoon) OutlineWidth() float64 {
+	return c.Width
+}
+
+// OutlineColor satisfies the Outlined interface
+func (c *Cartoon) OutlineColor() vmath.Vector3d {
+	return c.Outline.GetColor(0, 0)
+}
+
+// band returns which of the Segments the cosine to the light falls in
+func (c *Cartoon) band(cos float64) int {
+	if c.Thresholds == nil {
+		band := int(math.Floor((cos + 1) / 2 * float64(c.Segments)))
+		return int(math.Max(0, math.Min(float64(band), float64(c.Segments-1))))
+	}
+	band := 0
+	for _, threshold := range c.Thresholds {
+		if cos >= threshold {
+			band++
+		}
 	}
-	// split Ambient and Diffuse colors in X number of Segments
-	fract := normalizedAngle * float64(c.Segments)
-	rest := int(fract) % c.Segments
-
-	// set fraction of the color blend between ambient and diffuse
-	matColor := c.Ambient.GetColor(c.U, c.V).SMultiply(float64(rest) / float64(c.Segments)).
-		Add(c.Diffuse.GetColor(c.U, c.V).SMultiply(float64(c.Segments-rest) / float64(c.Segments)))
-		/*
-			      int seg2 = 4;
-			      float s = (ref+1.0)/2.0;
-			      float sa = pow(s,30);
-			      int fract2 = sa * seg2;
-			      int rest2 = fract2 % seg2;
-
-			      matColor = specular->rtnColor() * (float)rest2/(float)seg2 +
-							 matColor * (float)(seg2 - abs(rest2))/(float)seg2;
-
-			      int seg3 = 4;
-			      float sha = pow(s,30);
-			      int fract3 = sha * seg3;
-			      int rest3 = fract3 % seg3;
-
-			      matColor = matColor ^light->getColor(light->getPH())* light ->getRatio();
-		*/
-	if cam <= 0.6 {
-		matColor = c.Outline.GetColor(c.U, c.V)
+	return band
+}
+
+// highlight steps the strength s of the highlight into SpecularBands
+func (c *Cartoon) highlight(s float64) float64 {
+	if c.SpecularBands == 0 {
+		return 0.0
 	}
+	bands := float64(c.SpecularBands)
+	return math.Min(math.Floor(s*(bands+1)), bands) / bands
+}
 
-	//matColor = matColor.Add(light.GetColor().GetColor(0, 0))
+// cartoonBSDF is a Cartoon at one point of a surface
+type cartoonBSDF struct {
+	cartoon *Cartoon
+	ctx     *ShadingContext
+}
+
+func (b *cartoonBSDF) Evaluate(in vmath.Vector3d, out vmath.Vector3d) vmath.Vector3d {
+	c, n := b.cartoon, b.ctx.Normal
+	angle, cam := n.Dot(in), n.Dot(out)
+
+	// blend from the shadow to the diffuse color by the band of the light
+	matColor := colorAt(c.Diffuse, b.ctx)
+	if c.Segments > 1 {
+		lit := float64(c.band(angle)) / float64(c.Segments-1)
+		matColor = colorAt(c.Shadow, b.ctx).SMultiply(1 - lit).Add(matColor.SMultiply(lit))
+	}
+
+	if angle > 0 && cam > 0 {
+		half := in.Add(out)
+		half.Normalize()
+		s := c.highlight(math.Pow(math.Max(n.Dot(half), 0), c.Shininess))
+		matColor = matColor.SMultiply(1 - s).Add(colorAt(c.Specular, b.ctx).SMultiply(s))
+
+		if cam < c.RimWidth {
+			matColor = matColor.Add(colorAt(c.Rim, b.ctx))
+		}
+	}
+
+	return toon(matColor)
+}
+
+func (b *cartoonBSDF) Sample(out vmath.Vector3d, u1 float64, u2 float64) (vmath.Vector3d, vmath.Vector3d, float64) {
+	in := cosineSample(b.ctx.Normal, u1, u2)
+	return weigh(b, in, out, cosinePdf(b.ctx.Normal, in))
+}
 
-	return color.NewColorValue(matColor)
+func (b *cartoonBSDF) Pdf(in vmath.Vector3d, out vmath.Vector3d) float64 {
+	return cosinePdf(b.ctx.Normal, in)
 }
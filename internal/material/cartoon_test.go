@@ -0,0 +1,228 @@
+package material
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCartoonConfigFromYaml(t *testing.T) {
+	colors := map[string]color.Color{
+		"one":   &color.ColorValue{Name: "one"},
+		"two":   &color.ColorValue{Name: "two"},
+		"three": &color.ColorValue{Name: "three"},
+		"four":  &color.ColorValue{Name: "four"},
+	}
+	four := []color.Color{colors["one"], colors["two"], colors["three"], colors["four"]}
+	var tests = []struct {
+		Description string
+		Expected    *CartoonConfig
+		Config      []byte
+		ExpectedErr error
+	}{
+		{
+			Description: "Test defaults",
+			Expected: &CartoonConfig{
+				Segments:      3,
+				Shininess:     32.0,
+				SpecularBands: 1,
+				OutlineWidth:  2.0,
+				Colors:        four,
+			},
+			Config: []byte(`
+    color: [one, two, three, four]
+`),
+		},
+		{
+			Description: "Test thresholds set the number of segments",
+			Expected: &CartoonConfig{
+				Segments:      3,
+				Thresholds:    []float64{0.0, 0.5},
+				Shininess:     8.0,
+				SpecularBands: 3,
+				RimWidth:      0.25,
+				OutlineWidth:  0.0,
+				Colors:        four,
+				Rim:           colors["two"],
+			},
+			Config: []byte(`
+    thresholds: [0, 0.5]
+    shininess: 8
+    specular_bands: 3
+    rim: two
+    rim_width: 0.25
+    outline_width: 0
+    color: [one, two, three, four]
+`),
+		},
+		{
+			Description: "Test a threshold for every segment returns error",
+			Config: []byte(`
+    segments: 2
+    thresholds: [0, 0.5]
+    color: [one, two, three, four]
+`),
+			ExpectedErr: errors.New("cartoon needs one threshold less than it has segments"),
+		},
+		{
+			Description: "Test thresholds out of order return error",
+			Config: []byte(`
+    thresholds: [0.5, 0]
+    color: [one, two, three, four]
+`),
+			ExpectedErr: errors.New("cartoon thresholds must be increasing cosines from -1 to 1"),
+		},
+		{
+			Description: "Test missing rim color returns error",
+			Config: []byte(`
+    rim: five
+    color: [one, two, three, four]
+`),
+			ExpectedErr: errors.New("color five does not exist in scene."),
+		},
+		{
+			Description: "Test negative outline width returns error",
+			Config: []byte(`
+    outline_width: -1
+    color: [one, two, three, four]
+`),
+			ExpectedErr: errors.New("cartoon outline_width must be a number of 0 or more"),
+		},
+		{
+			Description: "Test missing outline color returns error",
+			Config: []byte(`
+    color: [one, two, three]
+`),
+			ExpectedErr: errors.New("not enough colors in cartoon config"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Config)
+			require.NoError(t, err)
+			config := &CartoonConfig{}
+			err = config.FromYaml(yaml, colors)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, config)
+		})
+	}
+}
+
+func TestCartoonBand(t *testing.T) {
+	var tests = []struct {
+		Description string
+		Thresholds  []float64
+		Cos         float64
+		Expected    int
+	}{
+		{
+			Description: "Test light behind the surface is the first band",
+			Cos:         -1.0,
+			Expected:    0,
+		},
+		{
+			Description: "Test even bands split at a third of the way round",
+			Cos:         -0.2,
+			Expected:    1,
+		},
+		{
+			Description: "Test light along the normal is the last band",
+			Cos:         1.0,
+			Expected:    2,
+		},
+		{
+			Description: "Test thresholds move where a band starts",
+			Thresholds:  []float64{0.5, 0.9},
+			Cos:         0.6,
+			Expected:    1,
+		},
+		{
+			Description: "Test a band starts on its threshold",
+			Thresholds:  []float64{0.5, 0.9},
+			Cos:         0.9,
+			Expected:    2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			cartoon := &Cartoon{Segments: 3, Thresholds: test.Thresholds}
+			assert.Equal(t, test.Expected, cartoon.band(test.Cos))
+		})
+	}
+}
+
+func TestCartoonEvaluate(t *testing.T) {
+	var tests = []struct {
+		Description string
+		In, Out     vmath.Vector3d
+		Expected    vmath.Vector3d
+	}{
+		{
+			Description: "Test light behind the surface is the shadow color",
+			In:          direction(120),
+			Out:         direction(0),
+			Expected:    vmath.Vector3d{X: 10.0, Y: 10.0, Z: 10.0},
+		},
+		{
+			Description: "Test light from the side is half way to the diffuse color",
+			In:          direction(80),
+			Out:         direction(0),
+			Expected:    vmath.Vector3d{X: 55.0, Y: 5.0, Z: 5.0},
+		},
+		{
+			Description: "Test light and camera along the normal see the full highlight",
+			In:          direction(0),
+			Out:         direction(0),
+			Expected:    vmath.Vector3d{X: 0.0, Y: 100.0, Z: 0.0},
+		},
+		{
+			Description: "Test camera 40 degrees from the light sees the first highlight band",
+			In:          direction(0),
+			Out:         direction(40),
+			Expected:    vmath.Vector3d{X: 50.0, Y: 50.0, Z: 0.0},
+		},
+		{
+			Description: "Test camera 60 degrees from the light sees no highlight",
+			In:          direction(0),
+			Out:         direction(60),
+			Expected:    vmath.Vector3d{X: 100.0, Y: 0.0, Z: 0.0},
+		},
+		{
+			Description: "Test camera grazing the lit surface sees the rim",
+			In:          direction(0),
+			Out:         direction(80),
+			Expected:    vmath.Vector3d{X: 100.0, Y: 0.0, Z: 50.0},
+		},
+	}
+
+	cartoon := &Cartoon{
+		Shadow:        &color.ColorValue{Color: vmath.Vector3d{X: 10.0, Y: 10.0, Z: 10.0}},
+		Diffuse:       &color.ColorValue{Color: vmath.Vector3d{X: 100.0, Y: 0.0, Z: 0.0}},
+		Specular:      &color.ColorValue{Color: vmath.Vector3d{X: 0.0, Y: 100.0, Z: 0.0}},
+		Rim:           &color.ColorValue{Color: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 50.0}},
+		Segments:      3,
+		Shininess:     8.0,
+		SpecularBands: 2,
+		RimWidth:      0.3,
+	}
+	ctx := &ShadingContext{Normal: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}}
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			c := litColor(cartoon.BSDF(ctx), test.In, test.Out)
+			assert.InDelta(t, test.Expected.X, c.X, 1e-9)
+			assert.InDelta(t, test.Expected.Y, c.Y, 1e-9)
+			assert.InDelta(t, test.Expected.Z, c.Z, 1e-9)
+		})
+	}
+}
@@ -1,16 +1,16 @@
 package material
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/chrispotter/trace/internal/color"
-	"github.com/chrispotter/trace/internal/lights"
 	"github.com/smallfish/simpleyaml"
 )
 
+// Material makes the BSDF of a surface at the point described by ctx
 type Material interface {
-	ReturnColor(angle float64,
-		cam float64,
-		ref float64,
-		light lights.Light) color.Color
+	BSDF(ctx *ShadingContext) BSDF
 }
 
 // MaterialConfig is a yaml definition of the Constructor to be read from
@@ -21,6 +21,14 @@ type MaterialConfig interface {
 	GetName() string
 }
 
+// parentConfig is a MaterialConfig made from other materials in the scene,
+// the materials it names are built first and handed to it
+type parentConfig interface {
+	MaterialConfig
+	ChildNames() []string
+	SetChildren([]Material) error
+}
+
 // MaterialConfigFactory generates configs for any shape
 func ConfigFactory(yaml *simpleyaml.Yaml, colors map[string]color.Color) ([]MaterialConfig, error) {
 	configs := []MaterialConfig{}
@@ -49,6 +57,54 @@ func ConfigFactory(yaml *simpleyaml.Yaml, colors map[string]color.Color) ([]Mate
 				}
 				cartoonConfig.Name = name
 				configs = append(configs, cartoonConfig)
+			case "phong", "blinn":
+				phongConfig := &PhongConfig{Blinn: t == "blinn"}
+				err := phongConfig.FromYaml(conf, colors)
+				if err != nil {
+					return nil, err
+				}
+				phongConfig.Name = name
+				configs = append(configs, phongConfig)
+			case "pbr":
+				pbrConfig := &PBRConfig{}
+				err := pbrConfig.FromYaml(conf, colors)
+				if err != nil {
+					return nil, err
+				}
+				pbrConfig.Name = name
+				configs = append(configs, pbrConfig)
+			case "hair":
+				hairConfig := &HairConfig{}
+				err := hairConfig.FromYaml(conf, colors)
+				if err != nil {
+					return nil, err
+				}
+				hairConfig.Name = name
+				configs = append(configs, hairConfig)
+			case "mix":
+				mixConfig := &MixConfig{}
+				err := mixConfig.FromYaml(conf, colors)
+				if err != nil {
+					return nil, err
+				}
+				mixConfig.Name = name
+				configs = append(configs, mixConfig)
+			case "layered":
+				layeredConfig := &LayeredConfig{}
+				err := layeredConfig.FromYaml(conf, colors)
+				if err != nil {
+					return nil, err
+				}
+				layeredConfig.Name = name
+				configs = append(configs, layeredConfig)
+			case "thinfilm":
+				thinFilmConfig := &ThinFilmConfig{}
+				err := thinFilmConfig.FromYaml(conf, colors)
+				if err != nil {
+					return nil, err
+				}
+				thinFilmConfig.Name = name
+				configs = append(configs, thinFilmConfig)
 			}
 		}
 	}
@@ -56,17 +112,73 @@ func ConfigFactory(yaml *simpleyaml.Yaml, colors map[string]color.Color) ([]Mate
 	return configs, nil
 }
 
-// Factory will make shapes according to type
+// Factory will make materials according to type, materials named by a parent
+// config are built before it
 func Factory(configs []MaterialConfig) (map[string]Material, error) {
-	materials := map[string]Material{}
+	named := map[string]MaterialConfig{}
 	for _, config := range configs {
-		var material Material
+		named[config.GetName()] = config
+	}
+
+	materials := map[string]Material{}
+	building := map[string]bool{}
+	var build func(config MaterialConfig) (Material, error)
+	build = func(config MaterialConfig) (Material, error) {
+		name := config.GetName()
+		if material, ok := materials[name]; ok {
+			return material, nil
+		}
+		if building[name] {
+			return nil, errors.New(fmt.Sprintf("material %s references itself.", name))
+		}
+		building[name] = true
+		defer delete(building, name)
+
+		if parent, ok := config.(parentConfig); ok {
+			children := []Material{}
+			for _, childName := range parent.ChildNames() {
+				child, ok := named[childName]
+				if !ok {
+					return nil, errors.New(fmt.Sprintf("material %s does not exist in scene.", childName))
+				}
+				material, err := build(child)
+				if err != nil {
+					return nil, err
+				}
+				children = append(children, material)
+			}
+			err := parent.SetChildren(children)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		material, err := config.NewMaterial()
 		if err != nil {
 			return nil, err
 		}
-		materials[config.GetName()] = material
+		materials[name] = material
+		return material, nil
+	}
+
+	for _, config := range configs {
+		_, err := build(config)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return materials, nil
 }
+
+// floatFromYaml reads a number written with or without a decimal point
+func floatFromYaml(config *simpleyaml.Yaml) (float64, error) {
+	if f, err := config.Float(); err == nil {
+		return f, nil
+	}
+	i, err := config.Int()
+	if err != nil {
+		return 0, err
+	}
+	return float64(i), nil
+}
@@ -1,6 +1,7 @@
 package material
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/chrispotter/trace/internal/color"
@@ -97,3 +98,91 @@ func TestMaterialFactory(t *testing.T) {
 		})
 	}
 }
+
+func TestMaterialFactoryReferences(t *testing.T) {
+	colors := map[string]color.Color{
+		"color1": &color.ColorValue{},
+		"color2": &color.ColorValue{},
+	}
+	var tests = []struct {
+		Description string
+		Bytes       []byte
+		ExpectedErr error
+	}{
+		{
+			Description: "Test materials are built before the materials naming them",
+			Bytes: []byte(`
+    blend:
+      type: mix
+      materials: [coated, lambert1]
+      factor: 0.5
+    coated:
+      type: layered
+      base: lambert1
+    lambert1:
+      type: lambert
+      color: [color1, color2]
+`),
+		},
+		{
+			Description: "Test thin film is built after its base",
+			Bytes: []byte(`
+    blend:
+      type: mix
+      materials: [coated, lambert1]
+      factor: 0.5
+    coated:
+      type: thinfilm
+      base: lambert1
+      thickness: 400
+    lambert1:
+      type: lambert
+      color: [color1, color2]
+`),
+		},
+		{
+			Description: "Test missing material returns error",
+			Bytes: []byte(`
+    coated:
+      type: layered
+      base: paint
+`),
+			ExpectedErr: errors.New("material paint does not exist in scene."),
+		},
+		{
+			Description: "Test material naming itself returns error",
+			Bytes: []byte(`
+    coated:
+      type: layered
+      base: coated
+`),
+			ExpectedErr: errors.New("material coated references itself."),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			configs, err := ConfigFactory(yaml, colors)
+			require.NoError(t, err)
+			materials, err := Factory(configs)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, materials, 3)
+			blend := materials["blend"].(*Mix)
+			assert.Same(t, materials["coated"], blend.A)
+			assert.Same(t, materials["lambert1"], blend.B)
+			switch coated := materials["coated"].(type) {
+			case *Layered:
+				assert.Same(t, materials["lambert1"], coated.Base)
+			case *ThinFilm:
+				assert.Same(t, materials["lambert1"], coated.Base)
+			default:
+				t.Errorf("coated is a %T", coated)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package material
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// HairConfig defines a Kajiya-Kay hair material for the MaterialFactory
+type HairConfig struct {
+	Name      string
+	Colors    []color.Color
+	Shininess float64
+}
+
+func (hc *HairConfig) GetName() string {
+	return hc.Name
+}
+
+// NewMaterial generates a Material from the config object
+// satisfies the MaterialConfig interface  (1/2)
+func (hc *HairConfig) NewMaterial() (Material, error) {
+	return &Hair{
+		Name:      hc.Name,
+		Ambient:   hc.Colors[0],
+		Diffuse:   hc.Colors[1],
+		Specular:  hc.Colors[2],
+		Shininess: hc.Shininess,
+	}, nil
+}
+
+// FromYaml generates Config from input yaml
+// satisfies the interface MaterialConfig (2/2)
+func (hc *HairConfig) FromYaml(config *simpleyaml.Yaml, colors map[string]color.Color) error {
+	hc.Shininess = 40.0
+	if config.Get("shininess").IsFound() {
+		shininess, err := floatFromYaml(config.Get("shininess"))
+		if err != nil {
+			return errors.New("hair shininess is not a number")
+		}
+		if shininess <= 0 {
+			return errors.New("hair shininess must be positive")
+		}
+		hc.Shininess = shininess
+	}
+
+	configColors, err := config.Get("color").Array()
+	if err != nil || len(configColors) != 3 {
+		return errors.New("not enough colors in hair config")
+	}
+	// color[0] is ambient
+	// color[1] is diffuse
+	// color[2] is specular
+	for _, colorName := range configColors {
+		name, _ := colorName.(string)
+		c, ok := colors[name]
+		if !ok {
+			return errors.New(fmt.Sprintf("color %v does not exist in scene.", colorName))
+		}
+		hc.Colors = append(hc.Colors, c)
+	}
+
+	return nil
+}
+
+// Hair lights a strand by the Kajiya-Kay model, the diffuse term follows the
+// sine between the strand and the light and the specular term peaks on the
+// cone of directions the light is mirrored into around the strand, the
+// strand runs along the Tangent of the ShadingContext
+type Hair struct {
+	Name                       string
+	Ambient, Diffuse, Specular color.Color
+	Shininess                  float64
+}
+
+// BSDF satisfies the Material interface
+func (h *Hair) BSDF(ctx *ShadingContext) BSDF {
+	return &hairBSDF{hair: h, ctx: ctx}
+}
+
+// hairBSDF is a Hair at one point along a strand
+type hairBSDF struct {
+	hair *Hair
+	ctx  *ShadingContext
+}
+
+// Ambient satisfies the Ambient interface
+func (b *hairBSDF) Ambient() vmath.Vector3d {
+	return colorAt(b.hair.Ambient, b.ctx)
+}
+
+func (b *hairBSDF) Evaluate(in vmath.Vector3d, out vmath.Vector3d) vmath.Vector3d {
+	h := b.hair
+	tangent := b.ctx.Tangent
+	tangent.Normalize()
+	angle, cam := tangent.Dot(in), tangent.Dot(out)
+	sinLight := math.Sqrt(math.Max(1-angle*angle, 0))
+	sinCam := math.Sqrt(math.Max(1-cam*cam, 0))
+	specular := math.Pow(math.Max(sinLight*sinCam-angle*cam, 0), h.Shininess)
+
+	return toon(colorAt(h.Diffuse, b.ctx).SMultiply(sinLight).
+		Add(colorAt(h.Specular, b.ctx).SMultiply(specular)))
+}
+
+// Sample picks any direction around the strand as likely as any other since
+// strands are lit from every side
+func (b *hairBSDF) Sample(out vmath.Vector3d, u1 float64, u2 float64) (vmath.Vector3d, vmath.Vector3d, float64) {
+	z := 1 - 2*u1
+	r, phi := math.Sqrt(math.Max(1-z*z, 0)), 2*math.Pi*u2
+	in := vmath.Vector3d{X: r * math.Cos(phi), Y: r * math.Sin(phi), Z: z}
+	return weigh(b, in, out, b.Pdf(in, out))
+}
+
+func (b *hairBSDF) Pdf(in vmath.Vector3d, out vmath.Vector3d) float64 {
+	return 1 / (4 * math.Pi)
+}
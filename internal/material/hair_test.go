@@ -0,0 +1,140 @@
+package material
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHairConfigFromYaml(t *testing.T) {
+	colors := map[string]color.Color{
+		"one":   &color.ColorValue{Name: "one"},
+		"two":   &color.ColorValue{Name: "two"},
+		"three": &color.ColorValue{Name: "three"},
+	}
+	var tests = []struct {
+		Description string
+		Expected    *HairConfig
+		Config      []byte
+		ExpectedErr error
+	}{
+		{
+			Description: "Test default shininess",
+			Expected: &HairConfig{
+				Colors:    []color.Color{colors["one"], colors["two"], colors["three"]},
+				Shininess: 40.0,
+			},
+			Config: []byte(`
+    color: [one, two, three]
+`),
+		},
+		{
+			Description: "Test integer shininess",
+			Expected: &HairConfig{
+				Colors:    []color.Color{colors["one"], colors["two"], colors["three"]},
+				Shininess: 80.0,
+			},
+			Config: []byte(`
+    shininess: 80
+    color: [one, two, three]
+`),
+		},
+		{
+			Description: "Test missing specular color returns error",
+			Config: []byte(`
+    color: [one, two]
+`),
+			ExpectedErr: errors.New("not enough colors in hair config"),
+		},
+		{
+			Description: "Test unknown color returns error",
+			Config: []byte(`
+    color: [one, two, four]
+`),
+			ExpectedErr: errors.New("color four does not exist in scene."),
+		},
+		{
+			Description: "Test zero shininess returns error",
+			Config: []byte(`
+    shininess: 0
+    color: [one, two, three]
+`),
+			ExpectedErr: errors.New("hair shininess must be positive"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Config)
+			require.NoError(t, err)
+			config := &HairConfig{}
+			err = config.FromYaml(yaml, colors)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, config)
+		})
+	}
+}
+
+func TestHairEvaluate(t *testing.T) {
+	hair := &Hair{
+		Ambient:   &color.ColorValue{Color: vmath.Vector3d{X: 10.0, Y: 10.0, Z: 10.0}},
+		Diffuse:   &color.ColorValue{Color: vmath.Vector3d{X: 100.0, Y: 0.0, Z: 0.0}},
+		Specular:  &color.ColorValue{Color: vmath.Vector3d{X: 0.0, Y: 100.0, Z: 0.0}},
+		Shininess: 10.0,
+	}
+	// the strand runs along z
+	bsdf := hair.BSDF(&ShadingContext{Tangent: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}})
+	half := math.Sqrt(0.5)
+
+	var tests = []struct {
+		Description string
+		In, Out     vmath.Vector3d
+		Expected    vmath.Vector3d
+	}{
+		{
+			Description: "Test light along the strand is not reflected",
+			In:          direction(0),
+			Out:         direction(90),
+			Expected:    vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0},
+		},
+		{
+			Description: "Test light across the strand seen from across is lit without highlight",
+			In:          direction(90),
+			Out:         direction(90),
+			Expected:    vmath.Vector3d{X: 100.0, Y: 100.0, Z: 0.0},
+		},
+		{
+			Description: "Test camera on the mirrored cone sees the full highlight",
+			In:          direction(45),
+			Out:         direction(135),
+			Expected:    vmath.Vector3d{X: 100.0 * half, Y: 100.0, Z: 0.0},
+		},
+		{
+			Description: "Test camera off the mirrored cone sees no highlight",
+			In:          direction(45),
+			Out:         direction(45),
+			Expected:    vmath.Vector3d{X: 100.0 * half, Y: 0.0, Z: 0.0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			c := litColor(bsdf, test.In, test.Out)
+			assert.InDelta(t, test.Expected.X, c.X, 1e-9)
+			assert.InDelta(t, test.Expected.Y, c.Y, 1e-9)
+			assert.InDelta(t, test.Expected.Z, c.Z, 1e-9)
+		})
+	}
+
+	assert.Equal(t, vmath.Vector3d{X: 10.0, Y: 10.0, Z: 10.0}, bsdf.(Ambient).Ambient())
+}
@@ -8,13 +8,14 @@ import (
 	"github.com/smallfish/simpleyaml"
 
 	"github.com/chrispotter/trace/internal/color"
-	"github.com/chrispotter/trace/internal/lights"
+	vmath "github.com/chrispotter/trace/internal/math"
 )
 
 // LambertConfig defines a sphere for the ShapeFactory
 type LambertConfig struct {
 	Name   string
 	Colors []color.Color
+	Maps   *SurfaceMaps
 }
 
 func (lc *LambertConfig) GetName() string {
@@ -29,6 +30,7 @@ func (lc *LambertConfig) NewMaterial() (Material, error) {
 		Ambient: lc.Colors[0],
 		Diffuse: lc.Colors[1],
 		SH:      1.0,
+		Maps:    lc.Maps,
 	}, nil
 }
 
@@ -57,21 +59,46 @@ func (lc *LambertConfig) FromYaml(config *simpleyaml.Yaml, colors map[string]col
 		return errors.New("not enough colors in lambert config")
 	}
 
+	maps, err := mapsFromYaml(config)
+	if err != nil {
+		return err
+	}
+	lc.Maps = maps
+
 	return nil
 }
 
 // Lambert is a default flat shader with a Ambient and Diffuse color
 type Lambert struct {
-	Name                                             string
-	Ambient, Diffuse                                 color.Color
-	LightIndex, DistanceLightHit, U, V, N, SH        float64
-	Reflect, Iridesent, Refract, Glossy, Transparent bool
+	Name                                  string
+	Ambient, Diffuse                      color.Color
+	LightIndex, DistanceLightHit, N, SH   float64
+	Reflect, Refract, Glossy, Transparent bool
+	Maps                                  *SurfaceMaps
+}
+
+// BSDF satisfies the Material interface
+func (l *Lambert) BSDF(ctx *ShadingContext) BSDF {
+	return &lambertBSDF{lambert: l, ctx: ctx}
+}
+
+// GetMaps satisfies the Mapped interface
+func (l *Lambert) GetMaps() *SurfaceMaps {
+	return l.Maps
 }
 
-func (l *Lambert) ReturnColor(angle float64, cam float64, ref float64, light lights.Light) color.Color {
+// lambertBSDF steps from Diffuse to Ambient across a narrow band around the
+// edge of the light
+type lambertBSDF struct {
+	lambert *Lambert
+	ctx     *ShadingContext
+}
+
+func (b *lambertBSDF) Evaluate(in vmath.Vector3d, out vmath.Vector3d) vmath.Vector3d {
+	l := b.lambert
 	beta := 1.0
 
-	c := ((angle + 1.0) / 2.0)
+	c := ((b.ctx.Normal.Dot(in) + 1.0) / 2.0)
 	if c > .55 {
 		c = .55
 	}
@@ -91,9 +118,16 @@ func (l *Lambert) ReturnColor(angle float64, cam float64, ref float64, light lig
 	ca := math.Pow(e, beta)
 
 	//(l.diffuse.ReturnColor(u, v)*(1.0-ca) + l.ambient.ReturnColor(u, v)*ca) * sh
-	matColor := l.Diffuse.GetColor(l.U, l.V).SMultiply(1.0 - ca).Add(l.Ambient.GetColor(l.U, l.V).SMultiply(ca)).SMultiply(l.SH)
+	matColor := colorAt(l.Diffuse, b.ctx).SMultiply(1.0 - ca).Add(colorAt(l.Ambient, b.ctx).SMultiply(ca)).SMultiply(l.SH)
 
-	matColor = matColor.Add(light.GetColor().GetColor(0, 0))
+	return toon(matColor)
+}
+
+func (b *lambertBSDF) Sample(out vmath.Vector3d, u1 float64, u2 float64) (vmath.Vector3d, vmath.Vector3d, float64) {
+	in := cosineSample(b.ctx.Normal, u1, u2)
+	return weigh(b, in, out, cosinePdf(b.ctx.Normal, in))
+}
 
-	return color.NewColorValue(matColor)
+func (b *lambertBSDF) Pdf(in vmath.Vector3d, out vmath.Vector3d) float64 {
+	return cosinePdf(b.ctx.Normal, in)
 }
@@ -2,10 +2,10 @@ package material
 
 import (
 	"errors"
+	"math"
 	"testing"
 
 	"github.com/chrispotter/trace/internal/color"
-	"github.com/chrispotter/trace/internal/lights"
 	vmath "github.com/chrispotter/trace/internal/math"
 	"github.com/smallfish/simpleyaml"
 	"github.com/stretchr/testify/assert"
@@ -158,7 +158,7 @@ func TestLambertConfigNewMaterial(t *testing.T) {
 	}
 }
 
-func TestLambertReturnColor(t *testing.T) {
+func TestLambertEvaluate(t *testing.T) {
 	var tests = []struct {
 		Description string
 		Material    *Lambert
@@ -225,8 +225,12 @@ func TestLambertReturnColor(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.Description, func(t *testing.T) {
-			color := test.Material.ReturnColor(test.Angle, 0, 0, &lights.PointLight{})
-			assert.Equal(t, test.Expected, color)
+			ctx := &ShadingContext{Normal: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}}
+			in := direction(math.Acos(test.Angle) * 180 / math.Pi)
+			color := litColor(test.Material.BSDF(ctx), in, ctx.Normal)
+			assert.InDelta(t, test.Expected.X, color.X, 1e-9)
+			assert.InDelta(t, test.Expected.Y, color.Y, 1e-9)
+			assert.InDelta(t, test.Expected.Z, color.Z, 1e-9)
 		})
 	}
 }
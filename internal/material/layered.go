@@ -0,0 +1,183 @@
+package material
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// LayeredConfig defines a clear coat over another material for the
+// MaterialFactory
+type LayeredConfig struct {
+	Name      string
+	BaseName  string
+	IOR       float64
+	Roughness float64
+	Base      Material
+	Maps      *SurfaceMaps
+}
+
+func (lc *LayeredConfig) GetName() string {
+	return lc.Name
+}
+
+// ChildNames is the material under the coat
+func (lc *LayeredConfig) ChildNames() []string {
+	return []string{lc.BaseName}
+}
+
+// SetChildren hands the built material of ChildNames to the config
+func (lc *LayeredConfig) SetChildren(materials []Material) error {
+	if len(materials) != 1 {
+		return errors.New(fmt.Sprintf("layered %s needs one base", lc.Name))
+	}
+	lc.Base = materials[0]
+	return nil
+}
+
+// NewMaterial generates a Material from the config object
+// satisfies the MaterialConfig interface  (1/2)
+func (lc *LayeredConfig) NewMaterial() (Material, error) {
+	if lc.Base == nil {
+		return nil, errors.New(fmt.Sprintf("layered %s needs one base", lc.Name))
+	}
+	return &Layered{
+		Name:      lc.Name,
+		Base:      lc.Base,
+		IOR:       lc.IOR,
+		Roughness: lc.Roughness,
+		Maps:      lc.Maps,
+	}, nil
+}
+
+// FromYaml generates Config from input yaml, base is required and names the
+// material under the coat, ior and roughness of the coat default to 1.5 and 0
+// satisfies the interface MaterialConfig (2/2)
+func (lc *LayeredConfig) FromYaml(config *simpleyaml.Yaml, colors map[string]color.Color) error {
+	base, err := config.Get("base").String()
+	if err != nil {
+		return errors.New("layered requires a base material")
+	}
+	lc.BaseName = base
+
+	lc.IOR = 1.5
+	if config.Get("ior").IsFound() {
+		lc.IOR, err = floatFromYaml(config.Get("ior"))
+		if err != nil || lc.IOR < 1 {
+			return errors.New("layered ior must be 1 or more")
+		}
+	}
+	if config.Get("roughness").IsFound() {
+		lc.Roughness, err = floatFromYaml(config.Get("roughness"))
+		if err != nil || lc.Roughness < 0 || lc.Roughness > 1 {
+			return errors.New("layered roughness must be from 0 to 1")
+		}
+	}
+
+	maps, err := mapsFromYaml(config)
+	if err != nil {
+		return err
+	}
+	lc.Maps = maps
+
+	return nil
+}
+
+// Layered is a dielectric coat of IOR over Base, the coat reflects like the
+// specular layer of a PBR of Roughness and Base only gets the light the coat
+// lets through on the way in and out
+type Layered struct {
+	Name      string
+	Base      Material
+	IOR       float64
+	Roughness float64
+	Maps      *SurfaceMaps
+}
+
+// BSDF satisfies the Material interface
+func (l *Layered) BSDF(ctx *ShadingContext) BSDF {
+	f0 := math.Pow((l.IOR-1)/(l.IOR+1), 2)
+	chance := math.Min(math.Max(schlick(ctx.Normal.Dot(ctx.Out), l.IOR), 0.25), 0.75)
+	return &layeredBSDF{
+		base: l.Base.BSDF(ctx),
+		coat: pbrLobes{
+			f0:             vmath.Vector3d{X: f0, Y: f0, Z: f0},
+			alpha:          math.Max(l.Roughness*l.Roughness, 1e-3),
+			specularChance: 1.0,
+		},
+		ior:    l.IOR,
+		chance: chance,
+		n:      ctx.Normal,
+	}
+}
+
+// GetMaps satisfies the Mapped interface, the coat follows the maps of Base
+// when it has none of its own
+func (l *Layered) GetMaps() *SurfaceMaps {
+	if l.Maps != nil {
+		return l.Maps
+	}
+	if mapped, ok := l.Base.(Mapped); ok {
+		return mapped.GetMaps()
+	}
+	return nil
+}
+
+// layeredBSDF is a Layered at one point of a surface with normal n, chance
+// is how often Sample picks the coat
+type layeredBSDF struct {
+	base   BSDF
+	coat   pbrLobes
+	ior    float64
+	chance float64
+	n      vmath.Vector3d
+}
+
+// through is the share of light that gets through the coat to the base and
+// back out
+func (b *layeredBSDF) through(in vmath.Vector3d, out vmath.Vector3d) float64 {
+	return (1 - schlick(b.n.Dot(in), b.ior)) * (1 - schlick(b.n.Dot(out), b.ior))
+}
+
+// Ambient satisfies the Ambient interface
+func (b *layeredBSDF) Ambient() vmath.Vector3d {
+	return ambientOf(b.base).SMultiply(b.through(b.n, b.n))
+}
+
+func (b *layeredBSDF) Evaluate(in vmath.Vector3d, out vmath.Vector3d) vmath.Vector3d {
+	c := b.base.Evaluate(in, out).SMultiply(b.through(in, out))
+	nl, nv := b.n.Dot(in), b.n.Dot(out)
+	if nl <= 0 || nv <= 0 {
+		return c
+	}
+	h := in.Add(out)
+	h.Normalize()
+	return c.Add(b.coat.eval(nl, nv, b.n.Dot(h), out.Dot(h)).SMultiply(nl))
+}
+
+// Sample picks the coat by its GGX normals or the base by its own Sample
+func (b *layeredBSDF) Sample(out vmath.Vector3d, u1 float64, u2 float64) (vmath.Vector3d, vmath.Vector3d, float64) {
+	var in vmath.Vector3d
+	if u1 < b.chance {
+		in = ggxSample(b.n, b.coat.alpha, out, u1/b.chance, u2)
+	} else {
+		in, _, _ = b.base.Sample(out, (u1-b.chance)/(1-b.chance), u2)
+	}
+	return weigh(b, in, out, b.Pdf(in, out))
+}
+
+func (b *layeredBSDF) Pdf(in vmath.Vector3d, out vmath.Vector3d) float64 {
+	pdf := (1 - b.chance) * b.base.Pdf(in, out)
+	nl, nv := b.n.Dot(in), b.n.Dot(out)
+	if nl <= 0 || nv <= 0 {
+		return pdf
+	}
+	h := in.Add(out)
+	h.Normalize()
+	return pdf + b.chance*b.coat.pdf(nl, b.n.Dot(h), out.Dot(h))
+}
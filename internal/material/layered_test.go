@@ -0,0 +1,104 @@
+package material
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayeredConfigFromYaml(t *testing.T) {
+	var tests = []struct {
+		Description string
+		Expected    *LayeredConfig
+		Config      []byte
+		ExpectedErr error
+	}{
+		{
+			Description: "Test default coat",
+			Expected:    &LayeredConfig{BaseName: "paint", IOR: 1.5},
+			Config: []byte(`
+    base: paint
+`),
+		},
+		{
+			Description: "Test rough coat",
+			Expected:    &LayeredConfig{BaseName: "paint", IOR: 2.0, Roughness: 0.3},
+			Config: []byte(`
+    base: paint
+    ior: 2
+    roughness: 0.3
+`),
+		},
+		{
+			Description: "Test missing base returns error",
+			Config: []byte(`
+    ior: 1.5
+`),
+			ExpectedErr: errors.New("layered requires a base material"),
+		},
+		{
+			Description: "Test ior below 1 returns error",
+			Config: []byte(`
+    base: paint
+    ior: 0.5
+`),
+			ExpectedErr: errors.New("layered ior must be 1 or more"),
+		},
+		{
+			Description: "Test roughness past 1 returns error",
+			Config: []byte(`
+    base: paint
+    roughness: 1.5
+`),
+			ExpectedErr: errors.New("layered roughness must be from 0 to 1"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Config)
+			require.NoError(t, err)
+			config := &LayeredConfig{}
+			err = config.FromYaml(yaml, map[string]color.Color{})
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, config)
+		})
+	}
+}
+
+func TestLayeredEvaluate(t *testing.T) {
+	layered := &Layered{
+		Base: &Phong{
+			Ambient:   &color.ColorValue{Color: vmath.Vector3d{X: 20.0, Y: 0.0, Z: 0.0}},
+			Diffuse:   &color.ColorValue{Color: vmath.Vector3d{X: 100.0, Y: 0.0, Z: 0.0}},
+			Specular:  &color.ColorValue{Color: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}},
+			Shininess: 2.0,
+		},
+		IOR: 1.5,
+	}
+	ctx := &ShadingContext{Normal: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}}
+	bsdf := layered.BSDF(ctx)
+
+	// away from the mirror direction the coat only dims the base by what it
+	// reflects on the way in and out, the tail of its highlight is tiny
+	c := litColor(bsdf, direction(0), direction(60))
+	assert.InDelta(t, 100.0*0.96*(1-(0.04+0.96*math.Pow(0.5, 5))), c.X, 1e-3)
+	assert.InDelta(t, 0.0, c.Y, 1e-3)
+	assert.InDelta(t, 20.0*0.96*0.96, bsdf.(Ambient).Ambient().X, 1e-9)
+
+	// on the mirror direction the smooth coat reflects a white highlight
+	c = litColor(bsdf, direction(45), direction(-45))
+	assert.Greater(t, c.Y, 255.0)
+	assert.InDelta(t, c.Y, c.Z, 1e-9)
+	assert.Greater(t, c.X, c.Y)
+}
@@ -0,0 +1,114 @@
+package material
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// Mapped is a Material that bends the normal of the surface it is on before
+// the surface is lit, shapes look for it to apply normal and bump maps
+type Mapped interface {
+	Material
+	GetMaps() *SurfaceMaps
+}
+
+// SurfaceMaps are the normal and bump maps of a material, both are tiled
+// across the uv of the surface and either can be nil
+type SurfaceMaps struct {
+	// NormalMap holds tangent space normals as rgb, red along the direction
+	// u increases, green along v and blue out of the surface
+	NormalMap *color.ImageValue
+	// BumpMap holds grayscale heights, BumpScale is how far a height of 1
+	// moves the surface in uv units
+	BumpMap   *color.ImageValue
+	BumpScale float64
+}
+
+// mapsFromYaml reads normal_map, bump_map and bump_scale from a material
+// config, nil is returned when the material has neither map
+func mapsFromYaml(config *simpleyaml.Yaml) (*SurfaceMaps, error) {
+	maps := &SurfaceMaps{BumpScale: 1.0}
+	if path, err := config.Get("normal_map").String(); err == nil {
+		maps.NormalMap, err = color.LoadImageValue(path)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("normal map %s: %s", path, err.Error()))
+		}
+	}
+	if path, err := config.Get("bump_map").String(); err == nil {
+		maps.BumpMap, err = color.LoadImageValue(path)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("bump map %s: %s", path, err.Error()))
+		}
+	}
+	if config.Get("bump_scale").IsFound() {
+		scale, err := floatFromYaml(config.Get("bump_scale"))
+		if err != nil {
+			return nil, errors.New("bump scale is not a number")
+		}
+		maps.BumpScale = scale
+	}
+	if maps.NormalMap == nil && maps.BumpMap == nil {
+		return nil, nil
+	}
+	return maps, nil
+}
+
+// Perturb bends the normal n at uv, tangent and bitangent are the directions
+// u and v increase in across the surface and need not be unit length or
+// perpendicular to n, the maps are averaged over the footprint of duvdx and
+// duvdy so they do not sparkle far away
+func (s *SurfaceMaps) Perturb(n vmath.Vector3d, tangent vmath.Vector3d, bitangent vmath.Vector3d, uv vmath.Vector2d, duvdx vmath.Vector2d, duvdy vmath.Vector2d) vmath.Vector3d {
+	t, b := tangentFrame(n, tangent, bitangent)
+
+	if s.NormalMap != nil {
+		m := s.NormalMap.GetSolidColor(&color.Point{UV: uv, UVDx: duvdx, UVDy: duvdy}).SMultiply(2.0 / 255.0).Subtract(vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0})
+		n = t.SMultiply(m.X).Add(b.SMultiply(m.Y)).Add(n.SMultiply(m.Z))
+		n.Normalize()
+		t, b = tangentFrame(n, t, b)
+	}
+
+	if s.BumpMap != nil {
+		// the slope of the heights across one texel either side of uv
+		height := func(u float64, v float64) float64 {
+			return s.BumpMap.Height(vmath.Vector2d{X: u, Y: v}, duvdx, duvdy)
+		}
+		// the slope is taken across the footprint when it is wider than a
+		// texel
+		width := math.Max(duvdx.Norm(), duvdy.Norm())
+		du := math.Max(1.0/float64(s.BumpMap.Pyramid[0].Width), width)
+		dv := math.Max(1.0/float64(s.BumpMap.Pyramid[0].Height), width)
+		dhdu := (height(uv.X+du, uv.Y) - height(uv.X-du, uv.Y)) / (2 * du)
+		dhdv := (height(uv.X, uv.Y+dv) - height(uv.X, uv.Y-dv)) / (2 * dv)
+		n = n.Subtract(t.SMultiply(s.BumpScale * dhdu)).Subtract(b.SMultiply(s.BumpScale * dhdv))
+		n.Normalize()
+	}
+
+	return n
+}
+
+// tangentFrame makes tangent and bitangent unit length and perpendicular to
+// n and each other, keeping the side of n bitangent was on
+func tangentFrame(n vmath.Vector3d, tangent vmath.Vector3d, bitangent vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	t := tangent.Subtract(n.SMultiply(n.Dot(tangent)))
+	if t.Normsqr() < 1e-18 {
+		t = bitangent.Cross(n)
+	}
+	if t.Normsqr() < 1e-18 {
+		t = n.Cross(vmath.Vector3d{X: 1.0, Y: 0.0, Z: 0.0})
+		if t.Normsqr() < 1e-18 {
+			t = n.Cross(vmath.Vector3d{X: 0.0, Y: 1.0, Z: 0.0})
+		}
+	}
+	t.Normalize()
+	b := n.Cross(t)
+	if b.Dot(bitangent) < 0 {
+		b = b.UNegate()
+	}
+	return t, b
+}
@@ -0,0 +1,111 @@
+package material
+
+import (
+	"image"
+	stdcolor "image/color"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// flatImage is a w by h image of a single color
+func flatImage(w int, h int, c stdcolor.Color) *image.RGBA64 {
+	img := image.NewRGBA64(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestSurfaceMapsPerturb(t *testing.T) {
+	n := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}
+	tangent := vmath.Vector3d{X: 2.0, Y: 0.0, Z: 0.0}
+	bitangent := vmath.Vector3d{X: 0.0, Y: 3.0, Z: 0.0}
+	half := math.Sqrt(0.5)
+
+	// a ramp rising along u by a height of 1 across the image
+	ramp := image.NewGray16(image.Rect(0, 0, 16, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 16; x++ {
+			ramp.SetGray16(x, y, stdcolor.Gray16{Y: uint16(x * 4096)})
+		}
+	}
+	// normals leaning along u on the left and against it on the right
+	leaning := flatImage(2, 1, stdcolor.RGBA64{R: 0xffff, G: 0x8000, B: 0xffff, A: 0xffff})
+	leaning.Set(1, 0, stdcolor.RGBA64{R: 0x0000, G: 0x8000, B: 0xffff, A: 0xffff})
+
+	var tests = []struct {
+		Description string
+		Maps        *SurfaceMaps
+		UV          vmath.Vector2d
+		Footprint   vmath.Vector2d
+		Expected    vmath.Vector3d
+	}{
+		{
+			Description: "Test flat normal map keeps the normal",
+			Maps:        &SurfaceMaps{NormalMap: color.NewImageValue(flatImage(1, 1, stdcolor.RGBA64{R: 0x8000, G: 0x8000, B: 0xffff, A: 0xffff}))},
+			Expected:    n,
+		},
+		{
+			Description: "Test normal map leaning along u",
+			Maps:        &SurfaceMaps{NormalMap: color.NewImageValue(flatImage(1, 1, stdcolor.RGBA64{R: 0xffff, G: 0x8000, B: 0xffff, A: 0xffff}))},
+			Expected:    vmath.Vector3d{X: half, Y: 0.0, Z: half},
+		},
+		{
+			Description: "Test bump map slope tilts away from the rise",
+			Maps:        &SurfaceMaps{BumpMap: color.NewImageValue(ramp), BumpScale: 1.0},
+			UV:          vmath.Vector2d{X: 0.5, Y: 0.5},
+			Expected:    vmath.Vector3d{X: -half, Y: 0.0, Z: half},
+		},
+		{
+			Description: "Test normal map read by a pixel smaller than a texel",
+			Maps:        &SurfaceMaps{NormalMap: color.NewImageValue(leaning)},
+			UV:          vmath.Vector2d{X: 0.25, Y: 0.5},
+			Footprint:   vmath.Vector2d{X: 0.01, Y: 0.0},
+			Expected:    vmath.Vector3d{X: half, Y: 0.0, Z: half},
+		},
+		{
+			Description: "Test normal map read by a pixel wider than the image is averaged flat",
+			Maps:        &SurfaceMaps{NormalMap: color.NewImageValue(leaning)},
+			UV:          vmath.Vector2d{X: 0.25, Y: 0.5},
+			Footprint:   vmath.Vector2d{X: 4.0, Y: 0.0},
+			Expected:    n,
+		},
+		{
+			Description: "Test bump map read by a pixel wider than the image is flat",
+			Maps:        &SurfaceMaps{BumpMap: color.NewImageValue(ramp), BumpScale: 1.0},
+			UV:          vmath.Vector2d{X: 0.5, Y: 0.5},
+			Footprint:   vmath.Vector2d{X: 32.0, Y: 0.0},
+			Expected:    n,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			p := test.Maps.Perturb(n, tangent, bitangent, test.UV, test.Footprint, vmath.Vector2d{X: -test.Footprint.Y, Y: test.Footprint.X})
+			assert.InDelta(t, test.Expected.X, p.X, 1e-3)
+			assert.InDelta(t, test.Expected.Y, p.Y, 1e-3)
+			assert.InDelta(t, test.Expected.Z, p.Z, 1e-3)
+		})
+	}
+}
+
+func TestTangentFrame(t *testing.T) {
+	n := vmath.Vector3d{X: 0.0, Y: 1.0, Z: 0.0}
+	// tangent leaning out of the surface and a bitangent on the far side
+	tangent, bitangent := tangentFrame(n, vmath.Vector3d{X: 1.0, Y: 1.0, Z: 0.0}, vmath.Vector3d{X: 0.0, Y: 0.0, Z: -5.0})
+	assert.InDelta(t, 1.0, tangent.X, 1e-12)
+	assert.InDelta(t, -1.0, bitangent.Z, 1e-12)
+
+	// without a frame any two directions across the surface do
+	tangent, bitangent = tangentFrame(n, vmath.Vector3d{}, vmath.Vector3d{})
+	assert.InDelta(t, 0.0, tangent.Dot(n), 1e-12)
+	assert.InDelta(t, 0.0, bitangent.Dot(n), 1e-12)
+	assert.InDelta(t, 1.0, tangent.Norm(), 1e-12)
+}
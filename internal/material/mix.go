@@ -0,0 +1,164 @@
+package material
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// MixConfig defines a blend of two other materials for the MaterialFactory
+type MixConfig struct {
+	Name      string
+	Names     []string
+	Factor    Param
+	IOR       float64
+	Materials []Material
+	Maps      *SurfaceMaps
+}
+
+func (mc *MixConfig) GetName() string {
+	return mc.Name
+}
+
+// ChildNames are the two materials blended
+func (mc *MixConfig) ChildNames() []string {
+	return mc.Names
+}
+
+// SetChildren hands the built materials of ChildNames to the config
+func (mc *MixConfig) SetChildren(materials []Material) error {
+	if len(materials) != 2 {
+		return errors.New(fmt.Sprintf("mix %s needs two materials", mc.Name))
+	}
+	mc.Materials = materials
+	return nil
+}
+
+// NewMaterial generates a Material from the config object
+// satisfies the MaterialConfig interface  (1/2)
+func (mc *MixConfig) NewMaterial() (Material, error) {
+	if len(mc.Materials) != 2 {
+		return nil, errors.New(fmt.Sprintf("mix %s needs two materials", mc.Name))
+	}
+	return &Mix{
+		Name:   mc.Name,
+		A:      mc.Materials[0],
+		B:      mc.Materials[1],
+		Factor: mc.Factor,
+		IOR:    mc.IOR,
+		Maps:   mc.Maps,
+	}, nil
+}
+
+// FromYaml generates Config from input yaml, materials names the two
+// materials blended and the share of the second is either a factor that is
+// a number, a texture or a color of the scene used as a mask, 0.5 when
+// missing, or the fresnel reflectance of a dielectric of the index of
+// refraction given
+// satisfies the interface MaterialConfig (2/2)
+func (mc *MixConfig) FromYaml(config *simpleyaml.Yaml, colors map[string]color.Color) error {
+	names, err := config.Get("materials").Array()
+	if err != nil || len(names) != 2 {
+		return errors.New("mix requires two materials")
+	}
+	for _, name := range names {
+		n, ok := name.(string)
+		if !ok {
+			return errors.New("mix requires two materials")
+		}
+		mc.Names = append(mc.Names, n)
+	}
+
+	if config.Get("factor").IsFound() && config.Get("fresnel").IsFound() {
+		return errors.New("mix takes a factor or a fresnel, not both")
+	}
+	mc.Factor = constantParam(0.5)
+	if config.Get("factor").IsFound() {
+		mc.Factor, err = paramFromYaml(config, "mix", "factor", colors, false)
+		if err != nil {
+			return err
+		}
+	}
+	if config.Get("fresnel").IsFound() {
+		mc.IOR, err = floatFromYaml(config.Get("fresnel"))
+		if err != nil || mc.IOR < 1 {
+			return errors.New("mix fresnel must be an index of refraction of 1 or more")
+		}
+	}
+
+	maps, err := mapsFromYaml(config)
+	if err != nil {
+		return err
+	}
+	mc.Maps = maps
+
+	return nil
+}
+
+// Mix blends material A into material B by Factor, or by how much a
+// dielectric of IOR reflects towards the camera when IOR is set
+type Mix struct {
+	Name   string
+	A, B   Material
+	Factor Param
+	// IOR picks the share of B by fresnel instead of Factor when it is not 0
+	IOR  float64
+	Maps *SurfaceMaps
+}
+
+// BSDF satisfies the Material interface
+func (m *Mix) BSDF(ctx *ShadingContext) BSDF {
+	w := m.Factor.Scalar(ctx)
+	if m.IOR != 0 {
+		w = schlick(ctx.Normal.Dot(ctx.Out), m.IOR)
+	}
+	return &mixBSDF{a: m.A.BSDF(ctx), b: m.B.BSDF(ctx), w: w}
+}
+
+// GetMaps satisfies the Mapped interface, the blend follows the maps of A,
+// or of B when A has none, when it has none of its own
+func (m *Mix) GetMaps() *SurfaceMaps {
+	if m.Maps != nil {
+		return m.Maps
+	}
+	for _, child := range []Material{m.A, m.B} {
+		if mapped, ok := child.(Mapped); ok && mapped.GetMaps() != nil {
+			return mapped.GetMaps()
+		}
+	}
+	return nil
+}
+
+// mixBSDF is w of b and the rest of a
+type mixBSDF struct {
+	a, b BSDF
+	w    float64
+}
+
+// Ambient satisfies the Ambient interface
+func (b *mixBSDF) Ambient() vmath.Vector3d {
+	return ambientOf(b.a).SMultiply(1 - b.w).Add(ambientOf(b.b).SMultiply(b.w))
+}
+
+func (b *mixBSDF) Evaluate(in vmath.Vector3d, out vmath.Vector3d) vmath.Vector3d {
+	return b.a.Evaluate(in, out).SMultiply(1 - b.w).Add(b.b.Evaluate(in, out).SMultiply(b.w))
+}
+
+// Sample picks which of the two to sample by their share
+func (b *mixBSDF) Sample(out vmath.Vector3d, u1 float64, u2 float64) (vmath.Vector3d, vmath.Vector3d, float64) {
+	var in vmath.Vector3d
+	if u1 < b.w {
+		in, _, _ = b.b.Sample(out, u1/b.w, u2)
+	} else {
+		in, _, _ = b.a.Sample(out, (u1-b.w)/(1-b.w), u2)
+	}
+	return weigh(b, in, out, b.Pdf(in, out))
+}
+
+func (b *mixBSDF) Pdf(in vmath.Vector3d, out vmath.Vector3d) float64 {
+	return b.a.Pdf(in, out)*(1-b.w) + b.b.Pdf(in, out)*b.w
+}
@@ -0,0 +1,201 @@
+package material
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMixConfigFromYaml(t *testing.T) {
+	var tests = []struct {
+		Description string
+		Expected    *MixConfig
+		Config      []byte
+		ExpectedErr error
+	}{
+		{
+			Description: "Test default factor",
+			Expected: &MixConfig{
+				Names:  []string{"one", "two"},
+				Factor: constantParam(0.5),
+			},
+			Config: []byte(`
+    materials: [one, two]
+`),
+		},
+		{
+			Description: "Test constant factor",
+			Expected: &MixConfig{
+				Names:  []string{"one", "two"},
+				Factor: constantParam(0.25),
+			},
+			Config: []byte(`
+    materials: [one, two]
+    factor: 0.25
+`),
+		},
+		{
+			Description: "Test fresnel",
+			Expected: &MixConfig{
+				Names:  []string{"one", "two"},
+				Factor: constantParam(0.5),
+				IOR:    1.5,
+			},
+			Config: []byte(`
+    materials: [one, two]
+    fresnel: 1.5
+`),
+		},
+		{
+			Description: "Test one material returns error",
+			Config: []byte(`
+    materials: [one]
+`),
+			ExpectedErr: errors.New("mix requires two materials"),
+		},
+		{
+			Description: "Test factor and fresnel returns error",
+			Config: []byte(`
+    materials: [one, two]
+    factor: 0.25
+    fresnel: 1.5
+`),
+			ExpectedErr: errors.New("mix takes a factor or a fresnel, not both"),
+		},
+		{
+			Description: "Test factor past 1 returns error",
+			Config: []byte(`
+    materials: [one, two]
+    factor: 2
+`),
+			ExpectedErr: errors.New("mix factor must be from 0 to 1"),
+		},
+		{
+			Description: "Test fresnel below 1 returns error",
+			Config: []byte(`
+    materials: [one, two]
+    fresnel: 0.5
+`),
+			ExpectedErr: errors.New("mix fresnel must be an index of refraction of 1 or more"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Config)
+			require.NoError(t, err)
+			config := &MixConfig{}
+			err = config.FromYaml(yaml, map[string]color.Color{})
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, config)
+		})
+	}
+}
+
+func TestMixEvaluate(t *testing.T) {
+	red := &Phong{
+		Ambient:   &color.ColorValue{Color: vmath.Vector3d{X: 20.0, Y: 0.0, Z: 0.0}},
+		Diffuse:   &color.ColorValue{Color: vmath.Vector3d{X: 100.0, Y: 0.0, Z: 0.0}},
+		Specular:  &color.ColorValue{Color: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}},
+		Shininess: 2.0,
+	}
+	green := &Phong{
+		Ambient:   &color.ColorValue{Color: vmath.Vector3d{X: 0.0, Y: 20.0, Z: 0.0}},
+		Diffuse:   &color.ColorValue{Color: vmath.Vector3d{X: 0.0, Y: 100.0, Z: 0.0}},
+		Specular:  &color.ColorValue{Color: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}},
+		Shininess: 2.0,
+	}
+	var tests = []struct {
+		Description string
+		Mix         *Mix
+		Out         vmath.Vector3d
+		// Expected is the share of green
+		Expected float64
+	}{
+		{
+			Description: "Test factor of 0 is all of the first material",
+			Mix:         &Mix{A: red, B: green, Factor: constantParam(0.0)},
+			Out:         direction(0),
+			Expected:    0.0,
+		},
+		{
+			Description: "Test factor blends the two",
+			Mix:         &Mix{A: red, B: green, Factor: constantParam(0.25)},
+			Out:         direction(0),
+			Expected:    0.25,
+		},
+		{
+			Description: "Test fresnel head on is mostly the first material",
+			Mix:         &Mix{A: red, B: green, IOR: 1.5},
+			Out:         direction(0),
+			Expected:    0.04,
+		},
+		{
+			Description: "Test fresnel at a grazing angle is mostly the second material",
+			Mix:         &Mix{A: red, B: green, IOR: 1.5},
+			Out:         direction(85),
+			Expected:    0.04 + 0.96*math.Pow(1-math.Cos(85*math.Pi/180), 5),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			ctx := &ShadingContext{Normal: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}, Out: test.Out}
+			bsdf := test.Mix.BSDF(ctx)
+			c := litColor(bsdf, direction(0), test.Out)
+			assert.InDelta(t, 100.0*(1-test.Expected), c.X, 1e-9)
+			assert.InDelta(t, 100.0*test.Expected, c.Y, 1e-9)
+			ambient := bsdf.(Ambient).Ambient()
+			assert.InDelta(t, 20.0*(1-test.Expected), ambient.X, 1e-9)
+			assert.InDelta(t, 20.0*test.Expected, ambient.Y, 1e-9)
+		})
+	}
+}
+
+func TestMixGetMaps(t *testing.T) {
+	own := &SurfaceMaps{BumpScale: 1.0}
+	ofA := &SurfaceMaps{BumpScale: 2.0}
+	ofB := &SurfaceMaps{BumpScale: 3.0}
+	var tests = []struct {
+		Description string
+		Mix         *Mix
+		Expected    *SurfaceMaps
+	}{
+		{
+			Description: "Test own maps come first",
+			Mix:         &Mix{A: &Phong{Maps: ofA}, B: &Phong{Maps: ofB}, Maps: own},
+			Expected:    own,
+		},
+		{
+			Description: "Test maps of A are followed",
+			Mix:         &Mix{A: &Phong{Maps: ofA}, B: &Phong{Maps: ofB}},
+			Expected:    ofA,
+		},
+		{
+			Description: "Test maps of B are followed when A has none",
+			Mix:         &Mix{A: &Phong{}, B: &Phong{Maps: ofB}},
+			Expected:    ofB,
+		},
+		{
+			Description: "Test no maps",
+			Mix:         &Mix{A: &Phong{}, B: &Phong{}},
+			Expected:    nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			assert.True(t, test.Mix.GetMaps() == test.Expected)
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package material
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// Param is a material parameter that is either a constant Value, read from
+// Image by uv or read from a procedural Source color of the scene, every
+// channel is from 0 to 1
+type Param struct {
+	Value  vmath.Vector3d
+	Image  *color.ImageValue
+	Source color.Color
+	// Channel picks the red, green or blue of Image for a single number by 0,
+	// 1 or 2, the brightness is used when it is -1
+	Channel int
+}
+
+// Color returns the parameter at the point of the surface ctx is of, Image
+// is averaged over the footprint of the pixel
+func (p Param) Color(ctx *ShadingContext) vmath.Vector3d {
+	switch {
+	case p.Image != nil:
+		return colorAt(p.Image, ctx).Divide(255.0)
+	case p.Source != nil:
+		return colorAt(p.Source, ctx).Divide(255.0)
+	}
+	return p.Value
+}
+
+// Scalar returns the parameter at the point of the surface ctx is of as a
+// single number
+func (p Param) Scalar(ctx *ShadingContext) float64 {
+	if p.Image == nil && p.Source == nil {
+		return p.Value.X
+	}
+	c := p.Color(ctx)
+	switch p.Channel {
+	case 0:
+		return c.X
+	case 1:
+		return c.Y
+	case 2:
+		return c.Z
+	}
+	return (c.X + c.Y + c.Z) / 3
+}
+
+// constantParam is a Param of the number v
+func constantParam(v float64) Param {
+	return Param{Value: vmath.Vector3d{X: v, Y: v, Z: v}, Channel: -1}
+}
+
+// paramFromYaml reads a texture block with a path to an image and an optional
+// channel of r, g or b, a scene color name, or when not isColor a number from
+// 0 to 1, procedural colors are read at every hit and plain ones once, errors
+// are prefixed by the kind of material being read
+func paramFromYaml(config *simpleyaml.Yaml, kind string, key string, colors map[string]color.Color, isColor bool) (Param, error) {
+	value := config.Get(key)
+	if value.Get("texture").IsFound() {
+		path, err := value.Get("texture").String()
+		if err != nil {
+			return Param{}, errors.New(fmt.Sprintf("%s %s texture is not a path", kind, key))
+		}
+		image, err := color.LoadImageValue(path)
+		if err != nil {
+			return Param{}, errors.New(fmt.Sprintf("%s %s texture %s: %s", kind, key, path, err.Error()))
+		}
+		p := Param{Image: image, Channel: -1}
+		if value.Get("channel").IsFound() {
+			channel, _ := value.Get("channel").String()
+			switch channel {
+			case "r":
+				p.Channel = 0
+			case "g":
+				p.Channel = 1
+			case "b":
+				p.Channel = 2
+			default:
+				return Param{}, errors.New(fmt.Sprintf("%s %s channel must be r, g or b", kind, key))
+			}
+		}
+		return p, nil
+	}
+
+	if name, err := value.String(); err == nil {
+		c, ok := colors[name]
+		if !ok {
+			return Param{}, errors.New(fmt.Sprintf("color %s does not exist in scene.", name))
+		}
+		if _, ok := c.(*color.ColorValue); ok {
+			return Param{Value: c.GetColor(0, 0).Divide(255.0), Channel: -1}, nil
+		}
+		return Param{Source: c, Channel: -1}, nil
+	}
+	if isColor {
+		return Param{}, errors.New(fmt.Sprintf("%s %s requires a color or a texture", kind, key))
+	}
+
+	v, err := floatFromYaml(value)
+	if err != nil {
+		return Param{}, errors.New(fmt.Sprintf("%s %s is not a number", kind, key))
+	}
+	if v < 0 || v > 1 {
+		return Param{}, errors.New(fmt.Sprintf("%s %s must be from 0 to 1", kind, key))
+	}
+	return constantParam(v), nil
+}
+
+// PBRConfig defines a metallic roughness material for the MaterialFactory
+type PBRConfig struct {
+	Name                                     string
+	BaseColor, Metallic, Roughness, Specular Param
+	Maps                                     *SurfaceMaps
+}
+
+func (pc *PBRConfig) GetName() string {
+	return pc.Name
+}
+
+// NewMaterial generates a Material from the config object
+// satisfies the MaterialConfig interface  (1/2)
+func (pc *PBRConfig) NewMaterial() (Material, error) {
+	return &PBR{
+		Name:      pc.Name,
+		BaseColor: pc.BaseColor,
+		Metallic:  pc.Metallic,
+		Roughness: pc.Roughness,
+		Specular:  pc.Specular,
+		Maps:      pc.Maps,
+	}, nil
+}
+
+// FromYaml generates Config from input yaml, base_color is required and
+// metallic, roughness and specular default to 0, 0.5 and 0.5
+// satisfies the interface MaterialConfig (2/2)
+func (pc *PBRConfig) FromYaml(config *simpleyaml.Yaml, colors map[string]color.Color) error {
+	if !config.Get("base_color").IsFound() {
+		return errors.New("pbr requires a base_color")
+	}
+	var err error
+	pc.BaseColor, err = paramFromYaml(config, "pbr", "base_color", colors, true)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range []struct {
+		key   string
+		param *Param
+		value float64
+	}{
+		{"metallic", &pc.Metallic, 0.0},
+		{"roughness", &pc.Roughness, 0.5},
+		{"specular", &pc.Specular, 0.5},
+	} {
+		*field.param = constantParam(field.value)
+		if config.Get(field.key).IsFound() {
+			*field.param, err = paramFromYaml(config, "pbr", field.key, colors, false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	maps, err := mapsFromYaml(config)
+	if err != nil {
+		return err
+	}
+	pc.Maps = maps
+
+	return nil
+}
+
+// PBR is the metallic roughness model of glTF and Disney, a diffuse base
+// under a GGX microfacet specular layer shadowed by Smith, metals have no
+// diffuse and tint their reflections by BaseColor, Specular scales the
+// reflectance of everything else up to 8% head on
+type PBR struct {
+	Name                                     string
+	BaseColor, Metallic, Roughness, Specular Param
+	Maps                                     *SurfaceMaps
+}
+
+// BSDF satisfies the Material interface, the parameters are read at the
+// point of ctx
+func (p *PBR) BSDF(ctx *ShadingContext) BSDF {
+	return &pbrBSDF{lobes: p.lobes(ctx), n: ctx.Normal}
+}
+
+// GetMaps satisfies the Mapped interface
+func (p *PBR) GetMaps() *SurfaceMaps {
+	return p.Maps
+}
+
+// pbrBSDF is a PBR read at one point of a surface with normal n
+type pbrBSDF struct {
+	lobes pbrLobes
+	n     vmath.Vector3d
+}
+
+func (b *pbrBSDF) Evaluate(in vmath.Vector3d, out vmath.Vector3d) vmath.Vector3d {
+	nl, nv := b.n.Dot(in), b.n.Dot(out)
+	if nl <= 0 || nv <= 0 {
+		return vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	}
+	h := in.Add(out)
+	h.Normalize()
+	return b.lobes.eval(nl, nv, b.n.Dot(h), out.Dot(h)).SMultiply(nl)
+}
+
+// Sample importance samples the GGX normals for the specular layer and the
+// cosine for the diffuse one
+func (b *pbrBSDF) Sample(out vmath.Vector3d, u1 float64, u2 float64) (vmath.Vector3d, vmath.Vector3d, float64) {
+	s := b.lobes
+	var in vmath.Vector3d
+	if u1 < s.specularChance {
+		in = ggxSample(b.n, s.alpha, out, u1/s.specularChance, u2)
+	} else {
+		in = cosineSample(b.n, (u1-s.specularChance)/(1-s.specularChance), u2)
+	}
+	return weigh(b, in, out, b.Pdf(in, out))
+}
+
+// ggxSample reflects out about a normal picked from the GGX distribution of
+// roughness alpha around n
+func ggxSample(n vmath.Vector3d, alpha float64, out vmath.Vector3d, u1 float64, u2 float64) vmath.Vector3d {
+	t, bt := tangentFrame(n, vmath.Vector3d{}, vmath.Vector3d{})
+	a2 := alpha * alpha
+	cos := math.Sqrt((1 - u2) / (1 + (a2-1)*u2))
+	sin := math.Sqrt(math.Max(1-cos*cos, 0))
+	phi := 2 * math.Pi * u1
+	h := t.SMultiply(sin * math.Cos(phi)).Add(bt.SMultiply(sin * math.Sin(phi))).Add(n.SMultiply(cos))
+	in := h.SMultiply(2 * out.Dot(h)).Subtract(out)
+	in.Normalize()
+	return in
+}
+
+func (b *pbrBSDF) Pdf(in vmath.Vector3d, out vmath.Vector3d) float64 {
+	nl, nv := b.n.Dot(in), b.n.Dot(out)
+	if nl <= 0 || nv <= 0 {
+		return 0.0
+	}
+	h := in.Add(out)
+	h.Normalize()
+	return b.lobes.pdf(nl, b.n.Dot(h), out.Dot(h))
+}
+
+// pbrLobes are the parameters of a PBR read at one point
+type pbrLobes struct {
+	diffuse, f0    vmath.Vector3d
+	alpha          float64
+	specularChance float64
+}
+
+// lobes reads the parameters of p at the point of the surface ctx is of
+func (p *PBR) lobes(ctx *ShadingContext) pbrLobes {
+	base := p.BaseColor.Color(ctx)
+	metallic := math.Min(math.Max(p.Metallic.Scalar(ctx), 0), 1)
+	roughness := math.Min(math.Max(p.Roughness.Scalar(ctx), 0), 1)
+	dielectric := 0.08 * p.Specular.Scalar(ctx)
+
+	return pbrLobes{
+		diffuse: base.SMultiply(1 - metallic),
+		f0: vmath.Vector3d{X: dielectric, Y: dielectric, Z: dielectric}.SMultiply(1 - metallic).
+			Add(base.SMultiply(metallic)),
+		// a perfect mirror has no distribution to sample, so the
+		// smoothest surface is only very nearly one
+		alpha:          math.Max(roughness*roughness, 1e-3),
+		specularChance: 0.5 + 0.5*metallic,
+	}
+}
+
+// eval is the BSDF of the cosines between the normal, the light, the camera
+// and the half way vector, the diffuse base only gets the light the specular
+// layer does not reflect so the two never add up to more than came in
+func (s pbrLobes) eval(nl float64, nv float64, nh float64, vh float64) vmath.Vector3d {
+	schlick := math.Pow(1-vh, 5)
+	fresnel := s.f0.Add(vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}.Subtract(s.f0).SMultiply(schlick))
+
+	specular := fresnel.SMultiply(ggx(s.alpha, nl, nv, nh))
+	diffuse := s.diffuse.Compt(vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}.Subtract(fresnel)).SMultiply(1 / math.Pi)
+	return diffuse.Add(specular)
+}
+
+// ggx is the GGX microfacet specular of roughness alpha shadowed by Smith
+// before the fresnel, of the cosines between the normal, the light, the
+// camera and the half way vector
+func ggx(alpha float64, nl float64, nv float64, nh float64) float64 {
+	a2 := alpha * alpha
+	d := nh*nh*(a2-1) + 1
+	distribution := a2 / (math.Pi * d * d)
+	smith := func(x float64) float64 {
+		return 2 * x / (x + math.Sqrt(a2+(1-a2)*x*x))
+	}
+	return distribution * smith(nl) * smith(nv) / (4 * nl * nv)
+}
+
+// pdf is the chance Sample picks the light direction of the cosines
+func (s pbrLobes) pdf(nl float64, nh float64, vh float64) float64 {
+	a2 := s.alpha * s.alpha
+	d := nh*nh*(a2-1) + 1
+	distribution := a2 / (math.Pi * d * d)
+	return s.specularChance*distribution*nh/(4*vh) + (1-s.specularChance)*nl/math.Pi
+}
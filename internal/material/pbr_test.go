@@ -0,0 +1,260 @@
+package material
+
+import (
+	"errors"
+	"image"
+	stdcolor "image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPBRConfigFromYaml(t *testing.T) {
+	colors := map[string]color.Color{
+		"white": &color.ColorValue{Color: vmath.Vector3d{X: 255.0, Y: 255.0, Z: 255.0}},
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, stdcolor.RGBA{R: 0, G: 255, B: 0, A: 255})
+	path := filepath.Join(t.TempDir(), "green.png")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(file, img))
+	require.NoError(t, file.Close())
+
+	var tests = []struct {
+		Description string
+		Config      string
+		// Expected is base color, metallic, roughness and specular at any uv
+		Expected    [4]vmath.Vector3d
+		ExpectedErr error
+	}{
+		{
+			Description: "Test defaults",
+			Config: `
+    base_color: white
+`,
+			Expected: [4]vmath.Vector3d{
+				{X: 1.0, Y: 1.0, Z: 1.0}, {}, {X: 0.5, Y: 0.5, Z: 0.5}, {X: 0.5, Y: 0.5, Z: 0.5},
+			},
+		},
+		{
+			Description: "Test constants and textures",
+			Config: `
+    base_color:
+      texture: ` + path + `
+    metallic: 1
+    roughness:
+      texture: ` + path + `
+      channel: g
+    specular:
+      texture: ` + path + `
+`,
+			Expected: [4]vmath.Vector3d{
+				{X: 0.0, Y: 1.0, Z: 0.0}, {X: 1.0, Y: 1.0, Z: 1.0}, {X: 1.0, Y: 1.0, Z: 1.0}, {X: 1.0 / 3, Y: 1.0 / 3, Z: 1.0 / 3},
+			},
+		},
+		{
+			Description: "Test missing base color returns error",
+			Config: `
+    metallic: 1
+`,
+			ExpectedErr: errors.New("pbr requires a base_color"),
+		},
+		{
+			Description: "Test unknown color returns error",
+			Config: `
+    base_color: black
+`,
+			ExpectedErr: errors.New("color black does not exist in scene."),
+		},
+		{
+			Description: "Test roughness past 1 returns error",
+			Config: `
+    base_color: white
+    roughness: 1.5
+`,
+			ExpectedErr: errors.New("pbr roughness must be from 0 to 1"),
+		},
+		{
+			Description: "Test unknown channel returns error",
+			Config: `
+    base_color: white
+    metallic:
+      texture: ` + path + `
+      channel: a
+`,
+			ExpectedErr: errors.New("pbr metallic channel must be r, g or b"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml([]byte(test.Config))
+			require.NoError(t, err)
+			config := &PBRConfig{}
+			err = config.FromYaml(yaml, colors)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+
+			ctx := &ShadingContext{UV: vmath.Vector2d{X: 0.3, Y: 0.6}}
+			assert.Equal(t, test.Expected[0], config.BaseColor.Color(ctx))
+			for index, param := range []Param{config.Metallic, config.Roughness, config.Specular} {
+				assert.InDelta(t, test.Expected[index+1].X, param.Scalar(ctx), 1e-9)
+			}
+		})
+	}
+}
+
+func TestPBREvaluate(t *testing.T) {
+	var tests = []struct {
+		Description string
+		PBR         *PBR
+		In          vmath.Vector3d
+		Expected    vmath.Vector3d
+	}{
+		{
+			Description: "Test diffuse without reflectance facing the light is its color",
+			PBR: &PBR{
+				BaseColor: Param{Value: vmath.Vector3d{X: 0.8, Y: 0.4, Z: 0.2}},
+				Metallic:  constantParam(0.0),
+				Roughness: constantParam(1.0),
+				Specular:  constantParam(0.0),
+			},
+			In:       direction(0),
+			Expected: vmath.Vector3d{X: 204.0, Y: 102.0, Z: 51.0},
+		},
+		{
+			Description: "Test light behind the surface is black",
+			PBR: &PBR{
+				BaseColor: Param{Value: vmath.Vector3d{X: 0.8, Y: 0.4, Z: 0.2}},
+				Metallic:  constantParam(0.0),
+				Roughness: constantParam(0.5),
+				Specular:  constantParam(0.5),
+			},
+			In:       direction(120),
+			Expected: vmath.Vector3d{},
+		},
+		{
+			Description: "Test rough metal has no diffuse and is tinted by its color",
+			PBR: &PBR{
+				BaseColor: Param{Value: vmath.Vector3d{X: 1.0, Y: 0.5, Z: 0.0}},
+				Metallic:  constantParam(1.0),
+				Roughness: constantParam(1.0),
+				Specular:  constantParam(0.5),
+			},
+			In: direction(0),
+			// a2 = 1 so D = 1/pi and G = 1 head on, F is the base color
+			Expected: vmath.Vector3d{X: 255.0 / 4, Y: 255.0 / 8, Z: 0.0},
+		},
+	}
+
+	ctx := &ShadingContext{Normal: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}}
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			c := litColor(test.PBR.BSDF(ctx), test.In, direction(0))
+			assert.InDelta(t, test.Expected.X, c.X, 1e-9)
+			assert.InDelta(t, test.Expected.Y, c.Y, 1e-9)
+			assert.InDelta(t, test.Expected.Z, c.Z, 1e-9)
+		})
+	}
+}
+
+func TestPBRSample(t *testing.T) {
+	ctx := &ShadingContext{Normal: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}}
+	out := vmath.Vector3d{X: 0.6, Y: 0.0, Z: 0.8}
+
+	var tests = []struct {
+		Description         string
+		Metallic, Roughness float64
+		// Albedo is the least and most of the light reflected
+		Albedo [2]float64
+	}{
+		{
+			Description: "Test rough plastic",
+			Metallic:    0.0,
+			Roughness:   0.8,
+			Albedo:      [2]float64{0.85, 1.0},
+		},
+		{
+			Description: "Test smooth plastic",
+			Metallic:    0.0,
+			Roughness:   0.2,
+			Albedo:      [2]float64{0.9, 1.0},
+		},
+		{
+			Description: "Test polished metal",
+			Metallic:    1.0,
+			Roughness:   0.1,
+			Albedo:      [2]float64{0.95, 1.0},
+		},
+		{
+			Description: "Test rough metal loses light to shadowing",
+			Metallic:    1.0,
+			Roughness:   1.0,
+			// D is 1 over pi and the albedo is G1(0.8) 2 (1 - ln 2) / 4 0.8
+			Albedo: [2]float64{0.34, 0.342},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			bsdf := (&PBR{
+				BaseColor: constantParam(1.0),
+				Metallic:  constantParam(test.Metallic),
+				Roughness: constantParam(test.Roughness),
+				Specular:  constantParam(0.5),
+			}).BSDF(ctx)
+
+			// stratified over the unit square, the mean weight is the light
+			// reflected of a white furnace
+			const steps = 128
+			albedo := 0.0
+			for i := 0; i < steps; i++ {
+				for j := 0; j < steps; j++ {
+					u1, u2 := (float64(i)+0.5)/steps, (float64(j)+0.5)/steps
+					_, weight, _ := bsdf.Sample(out, u1, u2)
+					albedo += weight.X / (steps * steps)
+				}
+			}
+			assert.GreaterOrEqual(t, albedo, test.Albedo[0])
+			assert.LessOrEqual(t, albedo, test.Albedo[1])
+		})
+	}
+}
+
+func TestPBRTexture(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, stdcolor.RGBA{R: 255, G: 255, B: 255, A: 255})
+	img.Set(1, 0, stdcolor.RGBA{R: 0, G: 0, B: 0, A: 255})
+	pbr := &PBR{
+		BaseColor: Param{Image: color.NewImageValue(img), Channel: -1},
+		Metallic:  constantParam(0.0),
+		Roughness: constantParam(1.0),
+		Specular:  constantParam(0.0),
+	}
+	n := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}
+
+	left := litColor(pbr.BSDF(&ShadingContext{Normal: n, UV: vmath.Vector2d{X: 0.25, Y: 0.5}}), n, n)
+	right := litColor(pbr.BSDF(&ShadingContext{Normal: n, UV: vmath.Vector2d{X: 0.75, Y: 0.5}}), n, n)
+	assert.InDelta(t, 255.0, left.X, 1e-9)
+	assert.InDelta(t, 0.0, right.X, 1e-9)
+
+	// a pixel wider than the image sees it averaged
+	far := litColor(pbr.BSDF(&ShadingContext{
+		Normal: n,
+		UV:     vmath.Vector2d{X: 0.25, Y: 0.5},
+		UVDx:   vmath.Vector2d{X: 4.0, Y: 0.0},
+		UVDy:   vmath.Vector2d{X: 0.0, Y: 4.0},
+	}), n, n)
+	assert.InDelta(t, 127.5, far.X, 1e-9)
+}
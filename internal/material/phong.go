@@ -0,0 +1,151 @@
+package material
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// PhongConfig defines a phong or blinn material for the MaterialFactory
+type PhongConfig struct {
+	Name      string
+	Blinn     bool
+	Colors    []color.Color
+	Shininess float64
+	Maps      *SurfaceMaps
+}
+
+func (pc *PhongConfig) GetName() string {
+	return pc.Name
+}
+
+// NewMaterial generates a Material from the config object
+// satisfies the MaterialConfig interface  (1/2)
+func (pc *PhongConfig) NewMaterial() (Material, error) {
+	return &Phong{
+		Name:      pc.Name,
+		Blinn:     pc.Blinn,
+		Ambient:   pc.Colors[0],
+		Diffuse:   pc.Colors[1],
+		Specular:  pc.Colors[2],
+		Shininess: pc.Shininess,
+		Maps:      pc.Maps,
+	}, nil
+}
+
+// FromYaml generates Config from input yaml
+// satisfies the interface MaterialConfig (2/2)
+func (pc *PhongConfig) FromYaml(config *simpleyaml.Yaml, colors map[string]color.Color) error {
+	pc.Shininess = 32.0
+	if config.Get("shininess").IsFound() {
+		shininess, err := floatFromYaml(config.Get("shininess"))
+		if err != nil {
+			return errors.New("phong shininess is not a number")
+		}
+		if shininess <= 0 {
+			return errors.New("phong shininess must be positive")
+		}
+		pc.Shininess = shininess
+	}
+
+	configColors, err := config.Get("color").Array()
+	if err != nil || len(configColors) != 3 {
+		return errors.New("not enough colors in phong config")
+	}
+	// color[0] is ambient
+	// color[1] is diffuse
+	// color[2] is specular
+	for _, colorName := range configColors {
+		name, _ := colorName.(string)
+		c, ok := colors[name]
+		if !ok {
+			return errors.New(fmt.Sprintf("color %v does not exist in scene.", colorName))
+		}
+		pc.Colors = append(pc.Colors, c)
+	}
+
+	maps, err := mapsFromYaml(config)
+	if err != nil {
+		return err
+	}
+	pc.Maps = maps
+
+	return nil
+}
+
+// Phong lights a surface with an Ambient color, a Diffuse color following
+// the cosine to the light and a Specular highlight raised to Shininess, the
+// highlight is of the mirrored light and the camera or with Blinn of the
+// normal and the half way vector between the light and the camera
+type Phong struct {
+	Name                       string
+	Blinn                      bool
+	Ambient, Diffuse, Specular color.Color
+	Shininess                  float64
+	Maps                       *SurfaceMaps
+}
+
+// BSDF satisfies the Material interface
+func (p *Phong) BSDF(ctx *ShadingContext) BSDF {
+	return &phongBSDF{phong: p, ctx: ctx}
+}
+
+// GetMaps satisfies the Mapped interface
+func (p *Phong) GetMaps() *SurfaceMaps {
+	return p.Maps
+}
+
+// phongBSDF is a Phong at one point of a surface
+type phongBSDF struct {
+	phong *Phong
+	ctx   *ShadingContext
+}
+
+// Ambient satisfies the Ambient interface
+func (b *phongBSDF) Ambient() vmath.Vector3d {
+	return colorAt(b.phong.Ambient, b.ctx)
+}
+
+func (b *phongBSDF) Evaluate(in vmath.Vector3d, out vmath.Vector3d) vmath.Vector3d {
+	p, n := b.phong, b.ctx.Normal
+	angle := n.Dot(in)
+	if angle <= 0 {
+		return vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	}
+
+	matColor := colorAt(p.Diffuse, b.ctx).SMultiply(angle)
+	if n.Dot(out) > 0 {
+		matColor = matColor.Add(colorAt(p.Specular, b.ctx).SMultiply(b.highlight(in, out)))
+	}
+	return toon(matColor)
+}
+
+// highlight is the strength of the specular term
+func (b *phongBSDF) highlight(in vmath.Vector3d, out vmath.Vector3d) float64 {
+	n := b.ctx.Normal
+	if !b.phong.Blinn {
+		// the light mirrored about the normal
+		reflected := n.SMultiply(2 * n.Dot(in)).Subtract(in)
+		return math.Pow(math.Max(reflected.Dot(out), 0), b.phong.Shininess)
+	}
+	half := in.Add(out)
+	if half.Normsqr() < 1e-18 {
+		return 0.0
+	}
+	half.Normalize()
+	return math.Pow(math.Max(n.Dot(half), 0), b.phong.Shininess)
+}
+
+func (b *phongBSDF) Sample(out vmath.Vector3d, u1 float64, u2 float64) (vmath.Vector3d, vmath.Vector3d, float64) {
+	in := cosineSample(b.ctx.Normal, u1, u2)
+	return weigh(b, in, out, cosinePdf(b.ctx.Normal, in))
+}
+
+func (b *phongBSDF) Pdf(in vmath.Vector3d, out vmath.Vector3d) float64 {
+	return cosinePdf(b.ctx.Normal, in)
+}
@@ -0,0 +1,148 @@
+package material
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhongConfigFromYaml(t *testing.T) {
+	colors := map[string]color.Color{
+		"one":   &color.ColorValue{Name: "one"},
+		"two":   &color.ColorValue{Name: "two"},
+		"three": &color.ColorValue{Name: "three"},
+	}
+	var tests = []struct {
+		Description string
+		Expected    *PhongConfig
+		Config      []byte
+		ExpectedErr error
+	}{
+		{
+			Description: "Test default shininess",
+			Expected: &PhongConfig{
+				Colors:    []color.Color{colors["one"], colors["two"], colors["three"]},
+				Shininess: 32.0,
+			},
+			Config: []byte(`
+    color: [one, two, three]
+`),
+		},
+		{
+			Description: "Test float shininess",
+			Expected: &PhongConfig{
+				Colors:    []color.Color{colors["one"], colors["two"], colors["three"]},
+				Shininess: 12.5,
+			},
+			Config: []byte(`
+    shininess: 12.5
+    color: [one, two, three]
+`),
+		},
+		{
+			Description: "Test missing specular color returns error",
+			Config: []byte(`
+    color: [one, two]
+`),
+			ExpectedErr: errors.New("not enough colors in phong config"),
+		},
+		{
+			Description: "Test shininess that is not a number returns error",
+			Config: []byte(`
+    shininess: shiny
+    color: [one, two, three]
+`),
+			ExpectedErr: errors.New("phong shininess is not a number"),
+		},
+		{
+			Description: "Test negative shininess returns error",
+			Config: []byte(`
+    shininess: -1
+    color: [one, two, three]
+`),
+			ExpectedErr: errors.New("phong shininess must be positive"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Config)
+			require.NoError(t, err)
+			config := &PhongConfig{}
+			err = config.FromYaml(yaml, colors)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, config)
+		})
+	}
+}
+
+func TestPhongEvaluate(t *testing.T) {
+	var tests = []struct {
+		Description string
+		In, Out     vmath.Vector3d
+		// Expected is the green specular part, Phong then Blinn
+		Expected [2]float64
+		Diffuse  float64
+	}{
+		{
+			Description: "Test light behind the surface is not reflected",
+			In:          direction(120),
+			Out:         direction(0),
+			Expected:    [2]float64{0.0, 0.0},
+			Diffuse:     0.0,
+		},
+		{
+			Description: "Test light and camera along the normal see the full highlight",
+			In:          direction(0),
+			Out:         direction(0),
+			Expected:    [2]float64{100.0, 100.0},
+			Diffuse:     100.0,
+		},
+		{
+			Description: "Test camera on the mirrored light sees the full highlight",
+			In:          direction(45),
+			Out:         direction(-45),
+			Expected:    [2]float64{100.0, 100.0},
+			Diffuse:     100.0 * math.Sqrt(0.5),
+		},
+		{
+			Description: "Test camera 60 degrees from the mirrored light",
+			In:          direction(0),
+			Out:         direction(60),
+			// blinn is of the 30 degrees from the normal to the half way vector
+			Expected: [2]float64{25.0, 75.0},
+			Diffuse:  100.0,
+		},
+	}
+
+	ctx := &ShadingContext{Normal: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}}
+	for _, test := range tests {
+		for index, blinn := range []bool{false, true} {
+			phong := &Phong{
+				Blinn:     blinn,
+				Ambient:   &color.ColorValue{Color: vmath.Vector3d{X: 10.0, Y: 10.0, Z: 10.0}},
+				Diffuse:   &color.ColorValue{Color: vmath.Vector3d{X: 100.0, Y: 0.0, Z: 0.0}},
+				Specular:  &color.ColorValue{Color: vmath.Vector3d{X: 0.0, Y: 100.0, Z: 0.0}},
+				Shininess: 2.0,
+			}
+			t.Run(test.Description, func(t *testing.T) {
+				bsdf := phong.BSDF(ctx)
+				c := litColor(bsdf, test.In, test.Out)
+				assert.InDelta(t, test.Diffuse, c.X, 1e-9)
+				assert.InDelta(t, test.Expected[index], c.Y, 1e-9)
+				assert.InDelta(t, 0.0, c.Z, 1e-9)
+				assert.Equal(t, vmath.Vector3d{X: 10.0, Y: 10.0, Z: 10.0}, bsdf.(Ambient).Ambient())
+			})
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package material
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// ThinFilmConfig defines a film like soap or oil over another material for
+// the MaterialFactory
+type ThinFilmConfig struct {
+	Name     string
+	BaseName string
+	// Thickness is read from 0 to 1 between MinThickness and MaxThickness
+	// in nanometres, a constant thickness is a range of one
+	Thickness                  Param
+	MinThickness, MaxThickness float64
+	IOR, BaseIOR               float64
+	Roughness                  float64
+	Base                       Material
+	Maps                       *SurfaceMaps
+}
+
+func (tc *ThinFilmConfig) GetName() string {
+	return tc.Name
+}
+
+// ChildNames is the material under the film
+func (tc *ThinFilmConfig) ChildNames() []string {
+	return []string{tc.BaseName}
+}
+
+// SetChildren hands the built material of ChildNames to the config
+func (tc *ThinFilmConfig) SetChildren(materials []Material) error {
+	if len(materials) != 1 {
+		return errors.New(fmt.Sprintf("thinfilm %s needs one base", tc.Name))
+	}
+	tc.Base = materials[0]
+	return nil
+}
+
+// NewMaterial generates a Material from the config object
+// satisfies the MaterialConfig interface  (1/2)
+func (tc *ThinFilmConfig) NewMaterial() (Material, error) {
+	if tc.Base == nil {
+		return nil, errors.New(fmt.Sprintf("thinfilm %s needs one base", tc.Name))
+	}
+	return &ThinFilm{
+		Name:         tc.Name,
+		Base:         tc.Base,
+		Thickness:    tc.Thickness,
+		MinThickness: tc.MinThickness,
+		MaxThickness: tc.MaxThickness,
+		IOR:          tc.IOR,
+		BaseIOR:      tc.BaseIOR,
+		Roughness:    tc.Roughness,
+		Maps:         tc.Maps,
+	}, nil
+}
+
+// FromYaml generates Config from input yaml, base is required and names the
+// material under the film, thickness is required and is a number of
+// nanometres or a texture or color read between the nanometres of
+// thickness_range which defaults to 100 and 900, ior of the film defaults to
+// 1.33 like soap, base_ior under it to 1 like the air inside a bubble and
+// roughness to 0
+// satisfies the interface MaterialConfig (2/2)
+func (tc *ThinFilmConfig) FromYaml(config *simpleyaml.Yaml, colors map[string]color.Color) error {
+	base, err := config.Get("base").String()
+	if err != nil {
+		return errors.New("thinfilm requires a base material")
+	}
+	tc.BaseName = base
+
+	if !config.Get("thickness").IsFound() {
+		return errors.New("thinfilm requires a thickness")
+	}
+	if thickness, err := floatFromYaml(config.Get("thickness")); err == nil {
+		if thickness < 0 {
+			return errors.New("thinfilm thickness must be 0 or more")
+		}
+		tc.Thickness = constantParam(0)
+		tc.MinThickness, tc.MaxThickness = thickness, thickness
+	} else {
+		tc.Thickness, err = paramFromYaml(config, "thinfilm", "thickness", colors, true)
+		if err != nil {
+			return err
+		}
+		tc.MinThickness, tc.MaxThickness = 100, 900
+		if config.Get("thickness_range").IsFound() {
+			values, err := config.Get("thickness_range").Array()
+			if err != nil || len(values) != 2 {
+				return errors.New("thinfilm thickness_range must be a min and max")
+			}
+			tc.MinThickness, err = floatFromYaml(config.Get("thickness_range").GetIndex(0))
+			if err != nil {
+				return errors.New("thinfilm thickness_range must be a min and max")
+			}
+			tc.MaxThickness, err = floatFromYaml(config.Get("thickness_range").GetIndex(1))
+			if err != nil || tc.MinThickness < 0 || tc.MaxThickness < tc.MinThickness {
+				return errors.New("thinfilm thickness_range must be a min and max")
+			}
+		}
+	}
+
+	tc.IOR = 1.33
+	if config.Get("ior").IsFound() {
+		tc.IOR, err = floatFromYaml(config.Get("ior"))
+		if err != nil || tc.IOR < 1 {
+			return errors.New("thinfilm ior must be 1 or more")
+		}
+	}
+	tc.BaseIOR = 1.0
+	if config.Get("base_ior").IsFound() {
+		tc.BaseIOR, err = floatFromYaml(config.Get("base_ior"))
+		if err != nil || tc.BaseIOR < 1 {
+			return errors.New("thinfilm base_ior must be 1 or more")
+		}
+	}
+	if config.Get("roughness").IsFound() {
+		tc.Roughness, err = floatFromYaml(config.Get("roughness"))
+		if err != nil || tc.Roughness < 0 || tc.Roughness > 1 {
+			return errors.New("thinfilm roughness must be from 0 to 1")
+		}
+	}
+
+	maps, err := mapsFromYaml(config)
+	if err != nil {
+		return err
+	}
+	tc.Maps = maps
+
+	return nil
+}
+
+// ThinFilm is a film of IOR a few hundred nanometres thick over Base, light
+// reflected off the top and the bottom of the film interferes so how much
+// is reflected depends on the wavelength, which colors soap bubbles and oil
+// slicks, the film reflects like the specular layer of a PBR of Roughness
+// and Base only gets the light the film lets through on the way in and out
+type ThinFilm struct {
+	Name                       string
+	Base                       Material
+	Thickness                  Param
+	MinThickness, MaxThickness float64
+	// IOR is of the film and BaseIOR of what is under it
+	IOR, BaseIOR float64
+	Roughness    float64
+	Maps         *SurfaceMaps
+}
+
+// BSDF satisfies the Material interface, the thickness is read at the point
+// of ctx
+func (tf *ThinFilm) BSDF(ctx *ShadingContext) BSDF {
+	t := math.Min(math.Max(tf.Thickness.Scalar(ctx), 0), 1)
+	b := &thinFilmBSDF{
+		base:      tf.Base.BSDF(ctx),
+		alpha:     math.Max(tf.Roughness*tf.Roughness, 1e-3),
+		thickness: tf.MinThickness + t*(tf.MaxThickness-tf.MinThickness),
+		ior:       tf.IOR,
+		baseIOR:   tf.BaseIOR,
+		n:         ctx.Normal,
+	}
+	r := b.reflectance(ctx.Normal.Dot(ctx.Out))
+	b.chance = math.Min(math.Max((r.X+r.Y+r.Z)/3, 0.25), 0.75)
+	return b
+}
+
+// GetMaps satisfies the Mapped interface, the film follows the maps of Base
+// when it has none of its own
+func (tf *ThinFilm) GetMaps() *SurfaceMaps {
+	if tf.Maps != nil {
+		return tf.Maps
+	}
+	if mapped, ok := tf.Base.(Mapped); ok {
+		return mapped.GetMaps()
+	}
+	return nil
+}
+
+// thinFilmBSDF is a ThinFilm at one point of a surface with normal n, chance
+// is how often Sample picks the film
+type thinFilmBSDF struct {
+	base                    BSDF
+	alpha                   float64
+	thickness, ior, baseIOR float64
+	chance                  float64
+	n                       vmath.Vector3d
+}
+
+// reflectance is the share of red, green and blue light the film reflects
+// at the cosine to its normal
+func (b *thinFilmBSDF) reflectance(cos float64) vmath.Vector3d {
+	return filmReflectance(cos, b.thickness, b.ior, b.baseIOR)
+}
+
+// through is the share of light that gets through the film to the base and
+// back out
+func (b *thinFilmBSDF) through(in vmath.Vector3d, out vmath.Vector3d) vmath.Vector3d {
+	one := vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}
+	return one.Subtract(b.reflectance(b.n.Dot(in))).Compt(one.Subtract(b.reflectance(b.n.Dot(out))))
+}
+
+// Ambient satisfies the Ambient interface
+func (b *thinFilmBSDF) Ambient() vmath.Vector3d {
+	return ambientOf(b.base).Compt(b.through(b.n, b.n))
+}
+
+func (b *thinFilmBSDF) Evaluate(in vmath.Vector3d, out vmath.Vector3d) vmath.Vector3d {
+	c := b.base.Evaluate(in, out).Compt(b.through(in, out))
+	nl, nv := b.n.Dot(in), b.n.Dot(out)
+	if nl <= 0 || nv <= 0 {
+		return c
+	}
+	h := in.Add(out)
+	h.Normalize()
+	return c.Add(b.reflectance(out.Dot(h)).SMultiply(ggx(b.alpha, nl, nv, b.n.Dot(h)) * nl))
+}
+
+// Sample picks the film by its GGX normals or the base by its own Sample
+func (b *thinFilmBSDF) Sample(out vmath.Vector3d, u1 float64, u2 float64) (vmath.Vector3d, vmath.Vector3d, float64) {
+	var in vmath.Vector3d
+	if u1 < b.chance {
+		in = ggxSample(b.n, b.alpha, out, u1/b.chance, u2)
+	} else {
+		in, _, _ = b.base.Sample(out, (u1-b.chance)/(1-b.chance), u2)
+	}
+	return weigh(b, in, out, b.Pdf(in, out))
+}
+
+func (b *thinFilmBSDF) Pdf(in vmath.Vector3d, out vmath.Vector3d) float64 {
+	pdf := (1 - b.chance) * b.base.Pdf(in, out)
+	nl, nv := b.n.Dot(in), b.n.Dot(out)
+	if nl <= 0 || nv <= 0 {
+		return pdf
+	}
+	h := in.Add(out)
+	h.Normalize()
+	film := pbrLobes{alpha: b.alpha, specularChance: 1.0}
+	return pdf + b.chance*film.pdf(nl, b.n.Dot(h), out.Dot(h))
+}
+
+// filmReflectance is the share of red, green and blue light reflected at
+// the cosine to the normal by a film thickness nanometres thick of index of
+// refraction ior over a base of baseIOR, the reflectance of every wavelength
+// of the visible spectrum is weighed by how much it adds to each of red,
+// green and blue
+func filmReflectance(cos float64, thickness float64, ior float64, baseIOR float64) vmath.Vector3d {
+	cos1 := math.Min(math.Max(cos, 0), 1)
+	sin1 := math.Sqrt(1 - cos1*cos1)
+	// snell gives the angle inside the film and under it, the base is never
+	// less dense than air so light always gets through
+	cos2 := math.Sqrt(1 - math.Pow(sin1/ior, 2))
+	cos3 := math.Sqrt(1 - math.Pow(sin1/baseIOR, 2))
+
+	// the fresnel amplitudes of the top and bottom of the film for s and p
+	// polarized light
+	fresnel := func(n1 float64, c1 float64, n2 float64, c2 float64) (float64, float64) {
+		return (n1*c1 - n2*c2) / (n1*c1 + n2*c2), (n2*c1 - n1*c2) / (n2*c1 + n1*c2)
+	}
+	s12, p12 := fresnel(1, cos1, ior, cos2)
+	s23, p23 := fresnel(ior, cos2, baseIOR, cos3)
+
+	// airy is the reflectance of the film with the amplitudes r12 and r23
+	// for light out of phase by delta between the two reflections
+	airy := func(r12 float64, r23 float64, delta float64) float64 {
+		cross := 2 * r12 * r23 * math.Cos(delta)
+		return (r12*r12 + r23*r23 + cross) / (1 + r12*r12*r23*r23 + cross)
+	}
+
+	rgb := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	for _, sample := range spectrum {
+		delta := 4 * math.Pi * ior * thickness * cos2 / sample.wavelength
+		r := (airy(s12, s23, delta) + airy(p12, p23, delta)) / 2
+		rgb = rgb.Add(sample.rgb.SMultiply(r))
+	}
+	return vmath.Vector3d{
+		X: math.Min(math.Max(rgb.X, 0), 1),
+		Y: math.Min(math.Max(rgb.Y, 0), 1),
+		Z: math.Min(math.Max(rgb.Z, 0), 1),
+	}
+}
+
+// spectralSample is how much light of a wavelength in nanometres adds to
+// red, green and blue
+type spectralSample struct {
+	wavelength float64
+	rgb        vmath.Vector3d
+}
+
+// spectrum is the visible wavelengths every 10 nanometres, a reflectance of
+// 1 at every wavelength adds up to white
+var spectrum = newSpectrum(380, 780, 10)
+
+// newSpectrum weighs the wavelengths from first to last by the CIE color
+// matching functions in the fit of Wyman, Sloan and Shirley turned into
+// linear sRGB, each channel is scaled to add up to 1
+func newSpectrum(first float64, last float64, step float64) []spectralSample {
+	lobe := func(wavelength float64, mean float64, below float64, above float64) float64 {
+		width := above
+		if wavelength < mean {
+			width = below
+		}
+		return math.Exp(-0.5 * math.Pow((wavelength-mean)/width, 2))
+	}
+
+	samples := []spectralSample{}
+	total := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	for wavelength := first; wavelength <= last; wavelength += step {
+		x := 1.056*lobe(wavelength, 599.8, 37.9, 31.0) + 0.362*lobe(wavelength, 442.0, 16.0, 26.7) - 0.065*lobe(wavelength, 501.1, 20.4, 26.2)
+		y := 0.821*lobe(wavelength, 568.8, 46.9, 40.5) + 0.286*lobe(wavelength, 530.9, 16.3, 31.1)
+		z := 1.217*lobe(wavelength, 437.0, 11.8, 36.0) + 0.681*lobe(wavelength, 459.0, 26.0, 13.8)
+		rgb := vmath.Vector3d{
+			X: 3.2406*x - 1.5372*y - 0.4986*z,
+			Y: -0.9689*x + 1.8758*y + 0.0415*z,
+			Z: 0.0557*x - 0.2040*y + 1.0570*z,
+		}
+		samples = append(samples, spectralSample{wavelength: wavelength, rgb: rgb})
+		total = total.Add(rgb)
+	}
+	for index := range samples {
+		samples[index].rgb = vmath.Vector3d{
+			X: samples[index].rgb.X / total.X,
+			Y: samples[index].rgb.Y / total.Y,
+			Z: samples[index].rgb.Z / total.Z,
+		}
+	}
+	return samples
+}
@@ -0,0 +1,175 @@
+package material
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/chrispotter/trace/internal/color"
+	vmath "github.com/chrispotter/trace/internal/math"
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThinFilmConfigFromYaml(t *testing.T) {
+	colors := map[string]color.Color{
+		"oil": &color.Procedural{Pattern: "noise"},
+	}
+	var tests = []struct {
+		Description string
+		Expected    *ThinFilmConfig
+		Config      []byte
+		ExpectedErr error
+	}{
+		{
+			Description: "Test default soap film",
+			Expected: &ThinFilmConfig{
+				BaseName:     "paint",
+				Thickness:    constantParam(0),
+				MinThickness: 400,
+				MaxThickness: 400,
+				IOR:          1.33,
+				BaseIOR:      1.0,
+			},
+			Config: []byte(`
+    base: paint
+    thickness: 400
+`),
+		},
+		{
+			Description: "Test textured oil film",
+			Expected: &ThinFilmConfig{
+				BaseName:     "paint",
+				Thickness:    Param{Source: colors["oil"], Channel: -1},
+				MinThickness: 200,
+				MaxThickness: 700.5,
+				IOR:          1.5,
+				BaseIOR:      1.33,
+				Roughness:    0.3,
+			},
+			Config: []byte(`
+    base: paint
+    thickness: oil
+    thickness_range: [200, 700.5]
+    ior: 1.5
+    base_ior: 1.33
+    roughness: 0.3
+`),
+		},
+		{
+			Description: "Test missing base returns error",
+			Config: []byte(`
+    thickness: 400
+`),
+			ExpectedErr: errors.New("thinfilm requires a base material"),
+		},
+		{
+			Description: "Test missing thickness returns error",
+			Config: []byte(`
+    base: paint
+`),
+			ExpectedErr: errors.New("thinfilm requires a thickness"),
+		},
+		{
+			Description: "Test negative thickness returns error",
+			Config: []byte(`
+    base: paint
+    thickness: -1
+`),
+			ExpectedErr: errors.New("thinfilm thickness must be 0 or more"),
+		},
+		{
+			Description: "Test backwards thickness_range returns error",
+			Config: []byte(`
+    base: paint
+    thickness: oil
+    thickness_range: [700, 200]
+`),
+			ExpectedErr: errors.New("thinfilm thickness_range must be a min and max"),
+		},
+		{
+			Description: "Test base_ior below 1 returns error",
+			Config: []byte(`
+    base: paint
+    thickness: 400
+    base_ior: 0.5
+`),
+			ExpectedErr: errors.New("thinfilm base_ior must be 1 or more"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Config)
+			require.NoError(t, err)
+			config := &ThinFilmConfig{}
+			err = config.FromYaml(yaml, colors)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, config)
+		})
+	}
+}
+
+func TestFilmReflectance(t *testing.T) {
+	// a film as dense as its base is only the one surface, every wavelength
+	// reflects the same
+	single := math.Pow(0.5/2.5, 2)
+	r := filmReflectance(1, 300, 1.5, 1.5)
+	assert.InDelta(t, single, r.X, 1e-6)
+	assert.InDelta(t, single, r.Y, 1e-6)
+	assert.InDelta(t, single, r.Z, 1e-6)
+
+	// a soap film too thin to put any wavelength out of phase is black
+	assert.Equal(t, vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}, filmReflectance(1, 0, 1.33, 1.0))
+
+	// a quarter of a green wavelength thick the two reflections of green
+	// cancel out, coating glass reflects less than bare glass
+	bare := math.Pow(0.5625/2.5625, 2)
+	coated := filmReflectance(1, 550/(4*1.25), 1.25, 1.5625)
+	assert.InDelta(t, 0.0, coated.Y, 1e-3)
+	assert.Less(t, coated.X, bare)
+	assert.Less(t, coated.Z, bare)
+
+	// the color changes with the thickness and the angle
+	assert.NotEqual(t, filmReflectance(1, 300, 1.33, 1.0), filmReflectance(1, 500, 1.33, 1.0))
+	assert.NotEqual(t, filmReflectance(1, 300, 1.33, 1.0), filmReflectance(0.5, 300, 1.33, 1.0))
+	// a 200nm soap film is magenta head on
+	magenta := filmReflectance(1, 200, 1.33, 1.0)
+	assert.Greater(t, magenta.X, magenta.Y)
+	assert.Greater(t, magenta.Z, magenta.Y)
+}
+
+func TestThinFilmEvaluate(t *testing.T) {
+	film := &ThinFilm{
+		Base: &Phong{
+			Ambient:   &color.ColorValue{Color: vmath.Vector3d{X: 20.0, Y: 20.0, Z: 20.0}},
+			Diffuse:   &color.ColorValue{Color: vmath.Vector3d{X: 100.0, Y: 100.0, Z: 100.0}},
+			Specular:  &color.ColorValue{Color: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}},
+			Shininess: 2.0,
+		},
+		Thickness:    constantParam(0.5),
+		MinThickness: 100,
+		MaxThickness: 300,
+		IOR:          1.33,
+		BaseIOR:      1.0,
+	}
+	ctx := &ShadingContext{Normal: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}}
+	bsdf := film.BSDF(ctx)
+	magenta := filmReflectance(1, 200, 1.33, 1.0)
+
+	// the base only gets the colors the film does not reflect
+	ambient := bsdf.(Ambient).Ambient()
+	assert.InDelta(t, 20.0*math.Pow(1-magenta.X, 2), ambient.X, 1e-9)
+	assert.InDelta(t, 20.0*math.Pow(1-magenta.Y, 2), ambient.Y, 1e-9)
+	assert.Greater(t, ambient.Y, ambient.X)
+
+	// on the mirror direction the smooth film reflects a colored highlight
+	c := litColor(bsdf, direction(0.01), direction(-0.01))
+	assert.Greater(t, c.X, c.Y)
+	assert.Greater(t, c.Z, c.Y)
+}
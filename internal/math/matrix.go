@@ -0,0 +1,230 @@
+package math
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Matrix4 is a row major 4x4 matrix, points are column vectors multiplied on
+// the right so A.Multiply(B) applies B first and then A
+type Matrix4 [4][4]float64
+
+// Identity4 returns the identity Matrix4
+func Identity4() Matrix4 {
+	return Matrix4{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1},
+	}
+}
+
+// Translate returns a Matrix4 moving points by v
+func Translate(v Vector3d) Matrix4 {
+	return Matrix4{
+		{1, 0, 0, v.X},
+		{0, 1, 0, v.Y},
+		{0, 0, 1, v.Z},
+		{0, 0, 0, 1},
+	}
+}
+
+// Scale returns a Matrix4 scaling each axis by the matching component of v
+func Scale(v Vector3d) Matrix4 {
+	return Matrix4{
+		{v.X, 0, 0, 0},
+		{0, v.Y, 0, 0},
+		{0, 0, v.Z, 0},
+		{0, 0, 0, 1},
+	}
+}
+
+// RotateAxis returns a Matrix4 rotating angle degrees around axis, the same
+// rotation as Vector3d.Rotate
+func RotateAxis(axis Vector3d, angle float64) Matrix4 {
+	m := Identity4()
+	for column, basis := range []Vector3d{{X: 1}, {Y: 1}, {Z: 1}} {
+		r := basis.Rotate(axis, angle)
+		m[0][column], m[1][column], m[2][column] = r.X, r.Y, r.Z
+	}
+	return m
+}
+
+// Euler returns a Matrix4 rotating by angles in degrees around the X, Y and
+// then Z axis
+func Euler(angles Vector3d) Matrix4 {
+	return RotateAxis(Vector3d{Z: 1}, angles.Z).
+		Multiply(RotateAxis(Vector3d{Y: 1}, angles.Y)).
+		Multiply(RotateAxis(Vector3d{X: 1}, angles.X))
+}
+
+// Multiply composes two Matrix4, the result applies m2 and then m
+func (m Matrix4) Multiply(m2 Matrix4) Matrix4 {
+	result := Matrix4{}
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			for index := 0; index < 4; index++ {
+				result[row][column] += m[row][index] * m2[index][column]
+			}
+		}
+	}
+	return result
+}
+
+// Transpose of a Matrix4
+func (m Matrix4) Transpose() Matrix4 {
+	result := Matrix4{}
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			result[row][column] = m[column][row]
+		}
+	}
+	return result
+}
+
+// Inverse of a Matrix4 by Gauss-Jordan elimination with partial pivoting,
+// errors if the matrix is singular
+func (m Matrix4) Inverse() (Matrix4, error) {
+	a := m
+	result := Identity4()
+	for column := 0; column < 4; column++ {
+		pivot := column
+		for row := column + 1; row < 4; row++ {
+			if math.Abs(a[row][column]) > math.Abs(a[pivot][column]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][column]) < 1e-12 {
+			return Matrix4{}, errors.New("matrix is not invertible")
+		}
+		a[column], a[pivot] = a[pivot], a[column]
+		result[column], result[pivot] = result[pivot], result[column]
+
+		scale := a[column][column]
+		for index := 0; index < 4; index++ {
+			a[column][index] /= scale
+			result[column][index] /= scale
+		}
+		for row := 0; row < 4; row++ {
+			if row == column {
+				continue
+			}
+			factor := a[row][column]
+			for index := 0; index < 4; index++ {
+				a[row][index] -= factor * a[column][index]
+				result[row][index] -= factor * result[column][index]
+			}
+		}
+	}
+	return result, nil
+}
+
+// MultiplyVector4 applies the Matrix4 to v
+func (m Matrix4) MultiplyVector4(v Vector4d) Vector4d {
+	return Vector4d{
+		X: m[0][0]*v.X + m[0][1]*v.Y + m[0][2]*v.Z + m[0][3]*v.W,
+		Y: m[1][0]*v.X + m[1][1]*v.Y + m[1][2]*v.Z + m[1][3]*v.W,
+		Z: m[2][0]*v.X + m[2][1]*v.Y + m[2][2]*v.Z + m[2][3]*v.W,
+		W: m[3][0]*v.X + m[3][1]*v.Y + m[3][2]*v.Z + m[3][3]*v.W,
+	}
+}
+
+// MultiplyPoint applies the Matrix4 to p including its translation
+func (m Matrix4) MultiplyPoint(p Vector3d) Vector3d {
+	v := m.MultiplyVector4(Vector4d{X: p.X, Y: p.Y, Z: p.Z, W: 1})
+	if v.W != 1 && v.W != 0 {
+		return Vector3d{X: v.X / v.W, Y: v.Y / v.W, Z: v.Z / v.W}
+	}
+	return Vector3d{X: v.X, Y: v.Y, Z: v.Z}
+}
+
+// MultiplyDirection applies the Matrix4 to d ignoring its translation
+func (m Matrix4) MultiplyDirection(d Vector3d) Vector3d {
+	return Vector3d{
+		X: m[0][0]*d.X + m[0][1]*d.Y + m[0][2]*d.Z,
+		Y: m[1][0]*d.X + m[1][1]*d.Y + m[1][2]*d.Z,
+		Z: m[2][0]*d.X + m[2][1]*d.Y + m[2][2]*d.Z,
+	}
+}
+
+// MultiplyRay moves both the origin and direction of ray and its
+// differentials, the direction is left unnormalized so ratios along the ray
+// are the same before and after
+func (m Matrix4) MultiplyRay(ray *Ray) *Ray {
+	moved := &Ray{
+		Origin:    m.MultiplyPoint(ray.Origin),
+		Direction: m.MultiplyDirection(ray.Direction),
+	}
+	if d := ray.Differentials; d != nil {
+		moved.Differentials = &RayDifferentials{
+			XOrigin:    m.MultiplyPoint(d.XOrigin),
+			XDirection: m.MultiplyDirection(d.XDirection),
+			YOrigin:    m.MultiplyPoint(d.YOrigin),
+			YDirection: m.MultiplyDirection(d.YDirection),
+		}
+	}
+	return moved
+}
+
+// Equals between two Matrix4
+func (m Matrix4) Equals(m2 Matrix4) bool {
+	return m == m2
+}
+
+// String representation of Matrix4
+func (m Matrix4) String() string {
+	return fmt.Sprintf("{%v, %v, %v, %v}", m[0], m[1], m[2], m[3])
+}
+
+// Quaternion is a rotation W + Xi + Yj + Zk
+type Quaternion struct {
+	W, X, Y, Z float64
+}
+
+// QuaternionFromAxisAngle returns the Quaternion rotating angle degrees
+// around axis
+func QuaternionFromAxisAngle(axis Vector3d, angle float64) Quaternion {
+	if axis.IsZero() {
+		return Quaternion{W: 1}
+	}
+	axis = axis.Divide(axis.Norm())
+	half := angle * math.Pi / 360
+	s := math.Sin(half)
+	return Quaternion{W: math.Cos(half), X: axis.X * s, Y: axis.Y * s, Z: axis.Z * s}
+}
+
+// Multiply composes two Quaternion, the result rotates by q2 and then q
+func (q Quaternion) Multiply(q2 Quaternion) Quaternion {
+	return Quaternion{
+		W: q.W*q2.W - q.X*q2.X - q.Y*q2.Y - q.Z*q2.Z,
+		X: q.W*q2.X + q.X*q2.W + q.Y*q2.Z - q.Z*q2.Y,
+		Y: q.W*q2.Y - q.X*q2.Z + q.Y*q2.W + q.Z*q2.X,
+		Z: q.W*q2.Z + q.X*q2.Y - q.Y*q2.X + q.Z*q2.W,
+	}
+}
+
+// Norm computes a quaternions magnitude
+func (q Quaternion) Norm() float64 {
+	return math.Sqrt(q.W*q.W + q.X*q.X + q.Y*q.Y + q.Z*q.Z)
+}
+
+// Normalize a Quaternion
+func (q *Quaternion) Normalize() error {
+	n := q.Norm()
+	if n == 0 {
+		return errors.New("attempting to take the norm of a zero quaternion")
+	}
+	q.W, q.X, q.Y, q.Z = q.W/n, q.X/n, q.Y/n, q.Z/n
+	return nil
+}
+
+// Matrix4 returns the rotation of a unit Quaternion as a Matrix4
+func (q Quaternion) Matrix4() Matrix4 {
+	return Matrix4{
+		{1 - 2*(q.Y*q.Y+q.Z*q.Z), 2 * (q.X*q.Y - q.W*q.Z), 2 * (q.X*q.Z + q.W*q.Y), 0},
+		{2 * (q.X*q.Y + q.W*q.Z), 1 - 2*(q.X*q.X+q.Z*q.Z), 2 * (q.Y*q.Z - q.W*q.X), 0},
+		{2 * (q.X*q.Z - q.W*q.Y), 2 * (q.Y*q.Z + q.W*q.X), 1 - 2*(q.X*q.X+q.Y*q.Y), 0},
+		{0, 0, 0, 1},
+	}
+}
@@ -0,0 +1,171 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func assertVector3dInDelta(t *testing.T, expected Vector3d, result Vector3d) {
+	assert.InDelta(t, expected.X, result.X, 1e-12)
+	assert.InDelta(t, expected.Y, result.Y, 1e-12)
+	assert.InDelta(t, expected.Z, result.Z, 1e-12)
+}
+
+func TestMatrix4MultiplyPoint(t *testing.T) {
+	var tests = []struct {
+		Description string
+		Expected    Vector3d
+		Matrix      Matrix4
+		Point       Vector3d
+	}{
+		{
+			Description: "identity",
+			Expected:    Vector3d{X: 1, Y: 2, Z: 3},
+			Matrix:      Identity4(),
+			Point:       Vector3d{X: 1, Y: 2, Z: 3},
+		},
+		{
+			Description: "translate",
+			Expected:    Vector3d{X: 2, Y: 0, Z: 6},
+			Matrix:      Translate(Vector3d{X: 1, Y: -2, Z: 3}),
+			Point:       Vector3d{X: 1, Y: 2, Z: 3},
+		},
+		{
+			Description: "scale then translate",
+			Expected:    Vector3d{X: 3, Y: 4, Z: 12},
+			Matrix:      Translate(Vector3d{X: 1, Y: 0, Z: 3}).Multiply(Scale(Vector3d{X: 2, Y: 2, Z: 3})),
+			Point:       Vector3d{X: 1, Y: 2, Z: 3},
+		},
+		{
+			Description: "euler matches box rotation order",
+			Expected: Vector3d{X: 1, Y: 2, Z: 3}.
+				Rotate(Vector3d{X: 1}, 30).
+				Rotate(Vector3d{Y: 1}, 45).
+				Rotate(Vector3d{Z: 1}, 60),
+			Matrix: Euler(Vector3d{X: 30, Y: 45, Z: 60}),
+			Point:  Vector3d{X: 1, Y: 2, Z: 3},
+		},
+		{
+			Description: "quaternion matches axis angle",
+			Expected:    Vector3d{X: 1, Y: 2, Z: 3}.Rotate(Vector3d{X: 1, Y: 1, Z: 0}, 70),
+			Matrix:      QuaternionFromAxisAngle(Vector3d{X: 1, Y: 1, Z: 0}, 70).Matrix4(),
+			Point:       Vector3d{X: 1, Y: 2, Z: 3},
+		},
+		{
+			Description: "composed quaternions",
+			Expected:    Vector3d{X: 0, Y: 0, Z: -1},
+			Matrix: QuaternionFromAxisAngle(Vector3d{X: 1}, -90).
+				Multiply(QuaternionFromAxisAngle(Vector3d{Z: 1}, 90)).Matrix4(),
+			Point: Vector3d{X: 1, Y: 0, Z: 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			assertVector3dInDelta(t, test.Expected, test.Matrix.MultiplyPoint(test.Point))
+		})
+	}
+}
+
+func TestMatrix4MultiplyDirection(t *testing.T) {
+	m := Translate(Vector3d{X: 5, Y: 5, Z: 5}).Multiply(Scale(Vector3d{X: 2, Y: 1, Z: 1}))
+	assertVector3dInDelta(t, Vector3d{X: 2, Y: 1, Z: 0}, m.MultiplyDirection(Vector3d{X: 1, Y: 1, Z: 0}))
+
+	ray := m.MultiplyRay(&Ray{Origin: Vector3d{}, Direction: Vector3d{X: 1}})
+	assertVector3dInDelta(t, Vector3d{X: 5, Y: 5, Z: 5}, ray.Origin)
+	assertVector3dInDelta(t, Vector3d{X: 2}, ray.Direction)
+	assert.Nil(t, ray.Differentials)
+
+	ray = m.MultiplyRay(&Ray{
+		Origin:    Vector3d{},
+		Direction: Vector3d{X: 1},
+		Differentials: &RayDifferentials{
+			XOrigin:    Vector3d{Y: 1},
+			XDirection: Vector3d{X: 1},
+			YOrigin:    Vector3d{},
+			YDirection: Vector3d{X: 1, Y: 1},
+		},
+	})
+	assertVector3dInDelta(t, Vector3d{X: 5, Y: 6, Z: 5}, ray.Differentials.XOrigin)
+	assertVector3dInDelta(t, Vector3d{X: 2}, ray.Differentials.XDirection)
+	assertVector3dInDelta(t, Vector3d{X: 5, Y: 5, Z: 5}, ray.Differentials.YOrigin)
+	assertVector3dInDelta(t, Vector3d{X: 2, Y: 1}, ray.Differentials.YDirection)
+}
+
+func TestMatrix4Inverse(t *testing.T) {
+	var tests = []struct {
+		Description string
+		Matrix      Matrix4
+		ExpectedErr bool
+	}{
+		{
+			Description: "identity",
+			Matrix:      Identity4(),
+		},
+		{
+			Description: "affine",
+			Matrix: Translate(Vector3d{X: 1, Y: 2, Z: 3}).
+				Multiply(Euler(Vector3d{X: 10, Y: 20, Z: 30})).
+				Multiply(Scale(Vector3d{X: 2, Y: 0.5, Z: 4})),
+		},
+		{
+			Description: "needs pivoting",
+			Matrix: Matrix4{
+				{0, 1, 0, 0},
+				{1, 0, 0, 0},
+				{0, 0, 0, 1},
+				{0, 0, 1, 0},
+			},
+		},
+		{
+			Description: "singular",
+			Matrix:      Scale(Vector3d{X: 1, Y: 0, Z: 1}),
+			ExpectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			inverse, err := test.Matrix.Inverse()
+			if test.ExpectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			product := test.Matrix.Multiply(inverse)
+			identity := Identity4()
+			for row := 0; row < 4; row++ {
+				for column := 0; column < 4; column++ {
+					assert.InDelta(t, identity[row][column], product[row][column], 1e-12)
+				}
+			}
+		})
+	}
+}
+
+func TestMatrix4Transpose(t *testing.T) {
+	m := Matrix4{
+		{1, 2, 3, 4},
+		{5, 6, 7, 8},
+		{9, 10, 11, 12},
+		{13, 14, 15, 16},
+	}
+	assert.Equal(t, Matrix4{
+		{1, 5, 9, 13},
+		{2, 6, 10, 14},
+		{3, 7, 11, 15},
+		{4, 8, 12, 16},
+	}, m.Transpose())
+	assert.True(t, m.Transpose().Transpose().Equals(m))
+}
+
+func TestQuaternionNormalize(t *testing.T) {
+	q := Quaternion{W: 2}
+	require.NoError(t, q.Normalize())
+	assert.Equal(t, Quaternion{W: 1}, q)
+
+	zero := Quaternion{}
+	assert.Error(t, zero.Normalize())
+}
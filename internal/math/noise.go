@@ -0,0 +1,131 @@
+package math
+
+import (
+	"math"
+	"math/rand"
+)
+
+// permutation is the shuffled lattice hash of Perlin noise, doubled so
+// lookups of a hash plus one stay in range, it is seeded so noise is the same
+// on every run
+var permutation = func() [512]int {
+	var p [512]int
+	for index, value := range rand.New(rand.NewSource(0)).Perm(256) {
+		p[index] = value
+		p[index+256] = value
+	}
+	return p
+}()
+
+// Perlin returns improved Perlin noise at p, it is 0 on every integer lattice
+// point and stays roughly between -1 and 1
+func Perlin(p Vector3d) float64 {
+	fx, fy, fz := math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z)
+	x, y, z := int(fx)&255, int(fy)&255, int(fz)&255
+	px, py, pz := p.X-fx, p.Y-fy, p.Z-fz
+	u, v, w := fade(px), fade(py), fade(pz)
+
+	a := permutation[x] + y
+	aa, ab := permutation[a]+z, permutation[a+1]+z
+	b := permutation[x+1] + y
+	ba, bb := permutation[b]+z, permutation[b+1]+z
+
+	return lerp(w,
+		lerp(v,
+			lerp(u, gradient(permutation[aa], px, py, pz), gradient(permutation[ba], px-1, py, pz)),
+			lerp(u, gradient(permutation[ab], px, py-1, pz), gradient(permutation[bb], px-1, py-1, pz))),
+		lerp(v,
+			lerp(u, gradient(permutation[aa+1], px, py, pz-1), gradient(permutation[ba+1], px-1, py, pz-1)),
+			lerp(u, gradient(permutation[ab+1], px, py-1, pz-1), gradient(permutation[bb+1], px-1, py-1, pz-1))))
+}
+
+// FBM sums octaves of Perlin noise, each lacunarity times the frequency and
+// gain times the amplitude of the last, the sum is scaled back to roughly
+// between -1 and 1
+func FBM(p Vector3d, octaves int, lacunarity float64, gain float64) float64 {
+	sum, amplitude, total := 0.0, 1.0, 0.0
+	for octave := 0; octave < octaves; octave++ {
+		sum += amplitude * Perlin(p)
+		total += amplitude
+		amplitude *= gain
+		p = p.SMultiply(lacunarity)
+	}
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
+
+// Turbulence sums the absolute value of octaves of Perlin noise the way FBM
+// sums them, folding the noise into sharp creases, it is roughly between 0
+// and 1
+func Turbulence(p Vector3d, octaves int, lacunarity float64, gain float64) float64 {
+	sum, amplitude, total := 0.0, 1.0, 0.0
+	for octave := 0; octave < octaves; octave++ {
+		sum += amplitude * math.Abs(Perlin(p))
+		total += amplitude
+		amplitude *= gain
+		p = p.SMultiply(lacunarity)
+	}
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
+
+// Worley returns the distance from p to the nearest of the feature points
+// scattered one to every unit cell, cellular noise that is 0 on a feature
+// point and rarely more than 1
+func Worley(p Vector3d) float64 {
+	fx, fy, fz := math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z)
+	nearest := math.Inf(1)
+	for dx := -1.0; dx <= 1; dx++ {
+		for dy := -1.0; dy <= 1; dy++ {
+			for dz := -1.0; dz <= 1; dz++ {
+				x, y, z := fx+dx, fy+dy, fz+dz
+				hx := permutation[permutation[permutation[int(x)&255]+int(y)&255]+int(z)&255]
+				hy := permutation[hx]
+				hz := permutation[hy]
+				feature := Vector3d{
+					X: x + float64(hx)/255,
+					Y: y + float64(hy)/255,
+					Z: z + float64(hz)/255,
+				}
+				nearest = math.Min(nearest, feature.Subtract(p).Norm())
+			}
+		}
+	}
+	return nearest
+}
+
+// fade is the quintic easing curve between lattice points
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func lerp(t float64, a float64, b float64) float64 {
+	return a + t*(b-a)
+}
+
+// gradient dots x, y, z with one of 12 edge directions of a cube picked by
+// hash
+func gradient(hash int, x float64, y float64, z float64) float64 {
+	h := hash & 15
+	u := y
+	if h < 8 {
+		u = x
+	}
+	v := z
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
@@ -0,0 +1,73 @@
+package math
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPerlin(t *testing.T) {
+	// zero on the lattice, including negative and wrapped cells
+	for _, p := range []Vector3d{
+		{X: 0, Y: 0, Z: 0},
+		{X: 3, Y: -7, Z: 12},
+		{X: 300, Y: 1, Z: -256},
+	} {
+		assert.Equal(t, 0.0, Perlin(p), p.String())
+	}
+
+	low, high, changes := math.Inf(1), math.Inf(-1), 0
+	last := Perlin(Vector3d{X: 0.05, Y: 0.1, Z: 0.2})
+	for index := 1; index < 2000; index++ {
+		f := float64(index)
+		n := Perlin(Vector3d{X: f*0.173 + 0.05, Y: f*0.071 + 0.1, Z: f*0.029 + 0.2})
+		low, high = math.Min(low, n), math.Max(high, n)
+		if math.Abs(n-last) > 1e-9 {
+			changes++
+		}
+		last = n
+	}
+	assert.GreaterOrEqual(t, low, -1.1)
+	assert.LessOrEqual(t, high, 1.1)
+	assert.Less(t, low, -0.3)
+	assert.Greater(t, high, 0.3)
+	assert.Greater(t, changes, 1900)
+
+	// the same point always gives the same noise and nearby points are close
+	p := Vector3d{X: 1.3, Y: 2.7, Z: -0.4}
+	assert.Equal(t, Perlin(p), Perlin(p))
+	assert.InDelta(t, Perlin(p), Perlin(p.Add(Vector3d{X: 1e-6})), 1e-4)
+}
+
+func TestFBM(t *testing.T) {
+	p := Vector3d{X: 1.3, Y: 2.7, Z: -0.4}
+	assert.Equal(t, Perlin(p), FBM(p, 1, 2.0, 0.5))
+	assert.InDelta(t, (Perlin(p)+0.5*Perlin(p.SMultiply(2)))/1.5, FBM(p, 2, 2.0, 0.5), 1e-12)
+	assert.Equal(t, 0.0, FBM(p, 0, 2.0, 0.5))
+}
+
+func TestTurbulence(t *testing.T) {
+	p := Vector3d{X: 1.3, Y: 2.7, Z: -0.4}
+	assert.Equal(t, math.Abs(Perlin(p)), Turbulence(p, 1, 2.0, 0.5))
+	assert.InDelta(t, (math.Abs(Perlin(p))+0.5*math.Abs(Perlin(p.SMultiply(2))))/1.5, Turbulence(p, 2, 2.0, 0.5), 1e-12)
+	assert.Equal(t, 0.0, Turbulence(p, 0, 2.0, 0.5))
+}
+
+func TestWorley(t *testing.T) {
+	low, high := math.Inf(1), math.Inf(-1)
+	for index := 0; index < 2000; index++ {
+		f := float64(index)
+		p := Vector3d{X: f*0.173 - 40, Y: f*0.071 + 0.1, Z: f*0.029 - 7}
+		n := Worley(p)
+		low, high = math.Min(low, n), math.Max(high, n)
+
+		// the distance to the nearest feature point changes no faster than
+		// the point moves
+		assert.LessOrEqual(t, math.Abs(Worley(p.Add(Vector3d{X: 0.01}))-n), 0.01+1e-12)
+	}
+	assert.GreaterOrEqual(t, low, 0.0)
+	assert.Less(t, low, 0.1)
+	assert.Greater(t, high, 0.5)
+	assert.LessOrEqual(t, high, math.Sqrt(3))
+}
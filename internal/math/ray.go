@@ -1,6 +1,91 @@
 package math
 
+import "math"
+
 // Ray is a vector with an origin and a direction
 type Ray struct {
 	Origin, Direction Vector3d
+	// Differentials are the rays through the neighbouring pixels, nil when
+	// the ray was not cast from a camera
+	Differentials *RayDifferentials
+}
+
+// RayDifferentials are the rays cast through the next pixel across and the
+// next pixel down from the one a Ray was cast through, textures are filtered
+// over the area between where they land
+type RayDifferentials struct {
+	XOrigin, XDirection Vector3d
+	YOrigin, YDirection Vector3d
+}
+
+// DifferentialHits returns where the differentials of the ray cross the
+// plane through p with normal n, false when the ray has no differentials or
+// they run along the plane
+func (r *Ray) DifferentialHits(p Vector3d, n Vector3d) (Vector3d, Vector3d, bool) {
+	d := r.Differentials
+	if d == nil {
+		return Vector3d{}, Vector3d{}, false
+	}
+	dx, dy := n.Dot(d.XDirection), n.Dot(d.YDirection)
+	if math.Abs(dx) < 1e-12 || math.Abs(dy) < 1e-12 {
+		return Vector3d{}, Vector3d{}, false
+	}
+	px := d.XOrigin.Add(d.XDirection.SMultiply(n.Dot(p.Subtract(d.XOrigin)) / dx))
+	py := d.YOrigin.Add(d.YDirection.SMultiply(n.Dot(p.Subtract(d.YOrigin)) / dy))
+	return px, py, true
+}
+
+// Reflect returns the ray mirrored about the normal n at p where it hit a
+// surface, the differentials are mirrored where they cross the plane of the
+// surface so the footprint keeps growing after the bounce
+func (r *Ray) Reflect(p Vector3d, n Vector3d) *Ray {
+	reflect := func(d Vector3d) Vector3d {
+		return d.Subtract(n.SMultiply(2 * n.Dot(d)))
+	}
+	reflected := &Ray{Origin: p, Direction: reflect(r.Direction)}
+	if px, py, ok := r.DifferentialHits(p, n); ok {
+		reflected.Differentials = &RayDifferentials{
+			XOrigin:    px,
+			XDirection: reflect(r.Differentials.XDirection),
+			YOrigin:    py,
+			YDirection: reflect(r.Differentials.YDirection),
+		}
+	}
+	return reflected
+}
+
+// Refract returns the ray bent through the surface with normal n at p,
+// eta is the index of refraction the ray leaves over the one it enters and
+// false is returned when all of the light is reflected instead
+func (r *Ray) Refract(p Vector3d, n Vector3d, eta float64) (*Ray, bool) {
+	if n.Dot(r.Direction) > 0 {
+		n = n.UNegate()
+	}
+	refract := func(d Vector3d) (Vector3d, bool) {
+		d.Normalize()
+		cosi := -n.Dot(d)
+		k := 1 - eta*eta*(1-cosi*cosi)
+		if k < 0 {
+			return Vector3d{}, false
+		}
+		return d.SMultiply(eta).Add(n.SMultiply(eta*cosi - math.Sqrt(k))), true
+	}
+	direction, ok := refract(r.Direction)
+	if !ok {
+		return nil, false
+	}
+	refracted := &Ray{Origin: p, Direction: direction}
+	if px, py, ok := r.DifferentialHits(p, n); ok {
+		dx, okx := refract(r.Differentials.XDirection)
+		dy, oky := refract(r.Differentials.YDirection)
+		if okx && oky {
+			refracted.Differentials = &RayDifferentials{
+				XOrigin:    px,
+				XDirection: dx,
+				YOrigin:    py,
+				YDirection: dy,
+			}
+		}
+	}
+	return refracted, true
 }
@@ -0,0 +1,93 @@
+package math
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// downRay looks down the y axis from y = 1 with differentials a tenth to
+// the side in x and z
+func downRay() *Ray {
+	return &Ray{
+		Origin:    Vector3d{Y: 1},
+		Direction: Vector3d{Y: -1},
+		Differentials: &RayDifferentials{
+			XOrigin:    Vector3d{Y: 1},
+			XDirection: Vector3d{X: 0.1, Y: -1},
+			YOrigin:    Vector3d{Y: 1},
+			YDirection: Vector3d{Y: -1, Z: 0.1},
+		},
+	}
+}
+
+func TestRayDifferentialHits(t *testing.T) {
+	px, py, ok := downRay().DifferentialHits(Vector3d{}, Vector3d{Y: 1})
+	require.True(t, ok)
+	assertVector3dInDelta(t, Vector3d{X: 0.1}, px)
+	assertVector3dInDelta(t, Vector3d{Z: 0.1}, py)
+
+	_, _, ok = (&Ray{Direction: Vector3d{Y: -1}}).DifferentialHits(Vector3d{}, Vector3d{Y: 1})
+	assert.False(t, ok, "a ray without differentials")
+
+	_, _, ok = downRay().DifferentialHits(Vector3d{}, Vector3d{X: 1})
+	assert.False(t, ok, "differentials along the plane")
+}
+
+func TestRayReflect(t *testing.T) {
+	reflected := downRay().Reflect(Vector3d{}, Vector3d{Y: 1})
+	assertVector3dInDelta(t, Vector3d{}, reflected.Origin)
+	assertVector3dInDelta(t, Vector3d{Y: 1}, reflected.Direction)
+	require.NotNil(t, reflected.Differentials)
+	assertVector3dInDelta(t, Vector3d{X: 0.1}, reflected.Differentials.XOrigin)
+	assertVector3dInDelta(t, Vector3d{X: 0.1, Y: 1}, reflected.Differentials.XDirection)
+
+	// the footprint keeps growing at the same rate after the bounce
+	px, _, ok := reflected.DifferentialHits(Vector3d{Y: 1}, Vector3d{Y: 1})
+	require.True(t, ok)
+	assertVector3dInDelta(t, Vector3d{X: 0.2, Y: 1}, px)
+}
+
+func TestRayRefract(t *testing.T) {
+	tests := []struct {
+		Description string
+		Eta         float64
+		Ray         *Ray
+		Expected    Vector3d
+		ExpectedOk  bool
+	}{
+		{
+			Description: "Test the same index passes straight through",
+			Eta:         1.0,
+			Ray:         downRay(),
+			Expected:    Vector3d{Y: -1},
+			ExpectedOk:  true,
+		},
+		{
+			Description: "Test entering glass bends towards the normal",
+			Eta:         1.0 / 1.5,
+			Ray:         &Ray{Direction: Vector3d{X: math.Sqrt2 / 2, Y: -math.Sqrt2 / 2}},
+			Expected:    Vector3d{X: math.Sqrt2 / 3, Y: -math.Sqrt(1 - 2.0/9)},
+			ExpectedOk:  true,
+		},
+		{
+			Description: "Test leaving glass at a grazing angle reflects",
+			Eta:         1.5,
+			Ray:         &Ray{Direction: Vector3d{X: 0.9, Y: -math.Sqrt(1 - 0.81)}},
+			ExpectedOk:  false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			refracted, ok := test.Ray.Refract(Vector3d{}, Vector3d{Y: 1}, test.Eta)
+			require.Equal(t, test.ExpectedOk, ok)
+			if !ok {
+				return
+			}
+			assertVector3dInDelta(t, test.Expected, refracted.Direction)
+			assert.Equal(t, test.Ray.Differentials != nil, refracted.Differentials != nil)
+		})
+	}
+}
@@ -31,3 +31,177 @@ func Pythag(a float64, b float64) float64 {
 		return 0
 	}
 }
+
+// SolveQuadratic returns the real roots of a*x^2 + b*x + c in ascending
+// order, a double root is returned once
+func SolveQuadratic(a float64, b float64, c float64) []float64 {
+	return polynomialRoots([]float64{a, b, c})
+}
+
+// SolveCubic returns the real roots of a*x^3 + b*x^2 + c*x + d in ascending
+// order
+func SolveCubic(a float64, b float64, c float64, d float64) []float64 {
+	return polynomialRoots([]float64{a, b, c, d})
+}
+
+// SolveQuartic returns the real roots of a*x^4 + b*x^3 + c*x^2 + d*x + e in
+// ascending order. Roots are bracketed between the turning points of the
+// polynomial and refined with safeguarded Newton steps, so a ray grazing a
+// surface (a double root) is still found instead of being lost to a slightly
+// negative discriminant like closed form solutions do
+func SolveQuartic(a float64, b float64, c float64, d float64, e float64) []float64 {
+	return polynomialRoots([]float64{a, b, c, d, e})
+}
+
+// SolvePolynomial returns the real roots of the polynomial with coeffs given
+// from the highest power down in ascending order, it is how SolveQuartic
+// works for any degree
+func SolvePolynomial(coeffs ...float64) []float64 {
+	return polynomialRoots(coeffs)
+}
+
+// rootEpsilon is the relative tolerance used to accept a root
+const rootEpsilon = 1e-10
+
+// polynomialRoots returns the real roots of the polynomial with coeffs given
+// from the highest power down
+func polynomialRoots(coeffs []float64) []float64 {
+	// drop vanishing leading terms so a degenerate quartic is solved as the
+	// lower order polynomial it really is
+	scale := 0.0
+	for _, c := range coeffs {
+		scale = math.Max(scale, math.Abs(c))
+	}
+	if scale == 0 {
+		return nil
+	}
+	for len(coeffs) > 1 && math.Abs(coeffs[0]) <= scale*1e-14 {
+		coeffs = coeffs[1:]
+	}
+
+	switch len(coeffs) {
+	case 1:
+		return nil
+	case 2:
+		return []float64{-coeffs[1] / coeffs[0]}
+	case 3:
+		return quadraticRoots(coeffs[0], coeffs[1], coeffs[2])
+	}
+
+	// the polynomial is monotonic between the roots of its derivative, so
+	// each of those intervals holds at most one root
+	derivative := make([]float64, len(coeffs)-1)
+	degree := len(coeffs) - 1
+	for index := range derivative {
+		derivative[index] = coeffs[index] * float64(degree-index)
+	}
+	turns := polynomialRoots(derivative)
+
+	// Cauchy's bound, every root lies inside +/- bound
+	bound := 0.0
+	for _, c := range coeffs[1:] {
+		bound = math.Max(bound, math.Abs(c/coeffs[0]))
+	}
+	bound++
+
+	points := append([]float64{-bound}, turns...)
+	points = append(points, bound)
+
+	roots := []float64{}
+	for index := 0; index < len(points)-1; index++ {
+		lo, hi := points[index], points[index+1]
+		flo, fhi := evaluatePolynomial(coeffs, lo), evaluatePolynomial(coeffs, hi)
+		if index > 0 && isPolynomialRoot(coeffs, lo, flo) {
+			// touching zero at a turning point is a double root
+			roots = appendRoot(roots, lo)
+			continue
+		}
+		if (flo < 0) != (fhi < 0) && !isPolynomialRoot(coeffs, hi, fhi) {
+			roots = appendRoot(roots, refineRoot(coeffs, derivative, lo, hi, flo))
+		}
+	}
+	return roots
+}
+
+// quadraticRoots solves a*x^2 + b*x + c without the cancellation of the
+// schoolbook formula
+func quadraticRoots(a float64, b float64, c float64) []float64 {
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		// treat a tiny negative discriminant as the double root it nearly is
+		if disc < -rootEpsilon*(b*b+math.Abs(4*a*c)) {
+			return nil
+		}
+		disc = 0
+	}
+	if disc == 0 {
+		return []float64{-b / (2 * a)}
+	}
+
+	q := -0.5 * (b + math.Copysign(math.Sqrt(disc), b))
+	x1, x2 := q/a, c/q
+	if q == 0 {
+		x2 = -x1
+	}
+	if x1 > x2 {
+		x1, x2 = x2, x1
+	}
+	return []float64{x1, x2}
+}
+
+// evaluatePolynomial with Horner's method
+func evaluatePolynomial(coeffs []float64, x float64) float64 {
+	result := 0.0
+	for _, c := range coeffs {
+		result = result*x + c
+	}
+	return result
+}
+
+// isPolynomialRoot checks fx against the size of the terms summed to make it
+func isPolynomialRoot(coeffs []float64, x float64, fx float64) bool {
+	magnitude := 0.0
+	ax := math.Abs(x)
+	for _, c := range coeffs {
+		magnitude = magnitude*ax + math.Abs(c)
+	}
+	return math.Abs(fx) <= rootEpsilon*magnitude
+}
+
+// refineRoot finds the root bracketed by lo and hi, Newton steps are used
+// while they stay inside the bracket and bisection otherwise
+func refineRoot(coeffs []float64, derivative []float64, lo float64, hi float64, flo float64) float64 {
+	x := (lo + hi) / 2
+	for iteration := 0; iteration < 100; iteration++ {
+		fx := evaluatePolynomial(coeffs, x)
+		if fx == 0 {
+			return x
+		}
+		if (fx < 0) == (flo < 0) {
+			lo, flo = x, fx
+		} else {
+			hi = x
+		}
+		if hi-lo <= 1e-15*math.Max(1, math.Abs(x)) {
+			break
+		}
+
+		next := x - fx/evaluatePolynomial(derivative, x)
+		if next <= lo || next >= hi || math.IsNaN(next) {
+			next = (lo + hi) / 2
+		}
+		if math.Abs(next-x) <= 1e-15*math.Max(1, math.Abs(x)) {
+			return next
+		}
+		x = next
+	}
+	return x
+}
+
+// appendRoot adds x unless it repeats the last root found
+func appendRoot(roots []float64, x float64) []float64 {
+	if len(roots) > 0 && math.Abs(roots[len(roots)-1]-x) <= 1e-9*math.Max(1, math.Abs(x)) {
+		return roots
+	}
+	return append(roots, x)
+}
@@ -0,0 +1,165 @@
+package math
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPythag(t *testing.T) {
+	assert.Equal(t, 5.0, Pythag(3, 4))
+	assert.Equal(t, 5.0, Pythag(-4, 3))
+	assert.Equal(t, 0.0, Pythag(0, 0))
+	assert.InEpsilon(t, 5e200, Pythag(3e200, 4e200), 1e-15)
+}
+
+func TestSolveQuadratic(t *testing.T) {
+	var tests = []struct {
+		Description string
+		Coeffs      [3]float64
+		Expected    []float64
+	}{
+		{
+			Description: "two roots",
+			Coeffs:      [3]float64{1, -3, 2},
+			Expected:    []float64{1, 2},
+		},
+		{
+			Description: "double root",
+			Coeffs:      [3]float64{1, -2, 1},
+			Expected:    []float64{1},
+		},
+		{
+			Description: "no real roots",
+			Coeffs:      [3]float64{1, 0, 1},
+			Expected:    nil,
+		},
+		{
+			Description: "cancellation prone roots",
+			Coeffs:      [3]float64{1, -1e8, 1},
+			Expected:    []float64{1e-8, 1e8},
+		},
+		{
+			Description: "linear",
+			Coeffs:      [3]float64{0, 2, -4},
+			Expected:    []float64{2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			roots := SolveQuadratic(test.Coeffs[0], test.Coeffs[1], test.Coeffs[2])
+			assertRoots(t, test.Expected, roots)
+		})
+	}
+}
+
+func TestSolveCubic(t *testing.T) {
+	// (x-1)(x-2)(x-3)
+	assertRoots(t, []float64{1, 2, 3}, SolveCubic(1, -6, 11, -6))
+	// x^3 has a triple root at 0
+	assertRoots(t, []float64{0}, SolveCubic(1, 0, 0, 0))
+	// (x+2)(x^2+1)
+	assertRoots(t, []float64{-2}, SolveCubic(1, 2, 1, 2))
+}
+
+func TestSolveQuartic(t *testing.T) {
+	var tests = []struct {
+		Description string
+		Coeffs      [5]float64
+		Expected    []float64
+	}{
+		{
+			Description: "four simple roots (x-1)(x-2)(x-3)(x-4)",
+			Coeffs:      [5]float64{1, -10, 35, -50, 24},
+			Expected:    []float64{1, 2, 3, 4},
+		},
+		{
+			Description: "double root (x-1)^2(x-3)(x+2)",
+			Coeffs:      [5]float64{1, -3, -3, 11, -6},
+			Expected:    []float64{-2, 1, 3},
+		},
+		{
+			Description: "two double roots (x^2-4)^2",
+			Coeffs:      [5]float64{1, 0, -8, 0, 16},
+			Expected:    []float64{-2, 2},
+		},
+		{
+			Description: "no real roots x^4+1",
+			Coeffs:      [5]float64{1, 0, 0, 0, 1},
+			Expected:    nil,
+		},
+		{
+			Description: "two real roots (x^2-1)(x^2+1)",
+			Coeffs:      [5]float64{1, 0, 0, 0, -1},
+			Expected:    []float64{-1, 1},
+		},
+		{
+			Description: "scaled coefficients 1e-6(x-0.5)(x-7)(x-8)(x-100)",
+			Coeffs:      [5]float64{1e-6, -115.5e-6, 1613.5e-6, -6378e-6, 2800e-6},
+			Expected:    []float64{0.5, 7, 8, 100},
+		},
+		{
+			Description: "degenerate to cubic",
+			Coeffs:      [5]float64{0, 1, -6, 11, -6},
+			Expected:    []float64{1, 2, 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			c := test.Coeffs
+			roots := SolveQuartic(c[0], c[1], c[2], c[3], c[4])
+			assertRoots(t, test.Expected, roots)
+		})
+	}
+}
+
+func TestSolvePolynomial(t *testing.T) {
+	// (x-1)(x-2)(x-3)(x-4)(x-5)(x-6)
+	assertRoots(t, []float64{1, 2, 3, 4, 5, 6}, SolvePolynomial(1, -21, 175, -735, 1624, -1764, 720))
+	// (1 - x^2)^3 - 0.125 crosses where x^2 = 0.5
+	assertRoots(t, []float64{-math.Sqrt(0.5), math.Sqrt(0.5)}, SolvePolynomial(-1, 0, 3, 0, -3, 0, 0.875))
+	// (x^2+1)^3
+	assertRoots(t, nil, SolvePolynomial(1, 0, 3, 0, 3, 0, 1))
+}
+
+// TestSolveQuarticGrazing intersects rays with a torus of major radius 2 and
+// minor radius 1 lying in the xy plane, a ray along y = 3 touches the outside
+// of the tube in exactly one point
+func TestSolveQuarticGrazing(t *testing.T) {
+	torus := func(oy float64) []float64 {
+		// ray from (-10, oy, 0) along +x
+		R, r := 2.0, 1.0
+		ox, dx := -10.0, 1.0
+		k := ox*ox + oy*oy + R*R - r*r
+		od := ox * dx
+		a := dx * dx * dx * dx
+		b := 4 * dx * dx * od
+		c := 2*dx*dx*k + 4*od*od - 4*R*R*dx*dx
+		d := 4*k*od - 8*R*R*ox*dx
+		e := k*k - 4*R*R*(ox*ox+oy*oy)
+		return SolveQuartic(a, b, c, d, e)
+	}
+
+	// tangent to the top of the tube
+	assertRoots(t, []float64{10}, torus(3))
+	// just inside the tangent there are two nearby crossings
+	roots := torus(3 - 1e-6)
+	require.Len(t, roots, 2)
+	assert.InDelta(t, 10, roots[0], 1e-2)
+	assert.InDelta(t, 10, roots[1], 1e-2)
+	// just outside the tangent misses
+	assert.Empty(t, torus(3+1e-4))
+	// tangent to the inside of the hole touches twice and crosses the tube
+	assertRoots(t, []float64{10 - math.Sqrt(8), 10, 10 + math.Sqrt(8)}, torus(1))
+}
+
+func assertRoots(t *testing.T, expected []float64, roots []float64) {
+	require.Len(t, roots, len(expected), "roots %v", roots)
+	for index := range expected {
+		assert.InDelta(t, expected[index], roots[index], 1e-6)
+	}
+}
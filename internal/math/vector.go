@@ -158,6 +158,20 @@ func (v Vector3d) Cross(vec Vector3d) Vector3d {
 	}
 }
 
+// Rotate Vector3d by angle degrees around axis
+func (v Vector3d) Rotate(axis Vector3d, angle float64) Vector3d {
+	if axis.IsZero() {
+		return v
+	}
+	axis = axis.Divide(axis.Norm())
+	cosVal := math.Cos(angle * math.Pi / 180)
+	sinVal := math.Sin(angle * math.Pi / 180)
+
+	return v.SMultiply(cosVal).
+		Add(axis.Cross(v).SMultiply(sinVal)).
+		Add(axis.SMultiply(axis.Dot(v) * (1 - cosVal)))
+}
+
 // Divide Vector3d by a scalar
 func (v Vector3d) Divide(s float64) Vector3d {
 	return Vector3d{
@@ -355,3 +355,43 @@ func TestNormalize(t *testing.T) {
 		})
 	}
 }
+
+func TestVector3dRotate(t *testing.T) {
+	var tests = []struct {
+		Description string
+		Expected    Vector3d
+		Vector      Vector3d
+		Axis        Vector3d
+		Angle       float64
+	}{
+		{
+			Description: "X around Z 90 degrees",
+			Expected:    Vector3d{X: 0, Y: 1, Z: 0},
+			Vector:      Vector3d{X: 1, Y: 0, Z: 0},
+			Axis:        Vector3d{X: 0, Y: 0, Z: 1},
+			Angle:       90,
+		},
+		{
+			Description: "unnormalized axis",
+			Expected:    Vector3d{X: 0, Y: 0, Z: -1},
+			Vector:      Vector3d{X: 0, Y: 1, Z: 0},
+			Axis:        Vector3d{X: 5, Y: 0, Z: 0},
+			Angle:       -90,
+		},
+		{
+			Description: "zero axis leaves vector",
+			Expected:    Vector3d{X: 1, Y: 2, Z: 3},
+			Vector:      Vector3d{X: 1, Y: 2, Z: 3},
+			Angle:       45,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			result := test.Vector.Rotate(test.Axis, test.Angle)
+			assert.InDelta(t, test.Expected.X, result.X, 1e-12)
+			assert.InDelta(t, test.Expected.Y, result.Y, 1e-12)
+			assert.InDelta(t, test.Expected.Z, result.Z, 1e-12)
+		})
+	}
+}
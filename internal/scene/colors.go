@@ -27,7 +27,12 @@ func (s *Scene) colors(y *simpleyaml.Yaml) error {
 		colorConfigs = append(colorConfigs, colorConfig)
 	}
 
-	colors, err := color.ColorFactory(colorConfigs)
+	projectors := map[string]color.Projector{}
+	for _, camera := range s.Cameras {
+		projectors[camera.Name] = camera
+	}
+
+	colors, err := color.ColorFactory(colorConfigs, projectors)
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package scene
 
 import (
 	"github.com/chrispotter/trace/internal/common"
+	vmath "github.com/chrispotter/trace/internal/math"
 	"github.com/chrispotter/trace/internal/shapes"
 	"github.com/smallfish/simpleyaml"
 )
@@ -12,7 +13,12 @@ func (s *Scene) shapes(y *simpleyaml.Yaml) error {
 	}
 
 	yml := y.Get("shapes")
-	shapeConfigs, err := shapes.ShapesConfigFactory(yml, s.Materials)
+	cameras := map[string]vmath.Vector3d{}
+	for _, camera := range s.Cameras {
+		cameras[camera.Name] = camera.P
+	}
+
+	shapeConfigs, err := shapes.ShapesConfigFactory(yml, s.Materials, cameras)
 	if err != nil {
 		return err
 	}
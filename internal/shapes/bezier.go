@@ -0,0 +1,385 @@
+package shapes
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// BezierConfig defines a set of bicubic Bezier patches for the ShapeFactory
+type BezierConfig struct {
+	Name    string
+	File    string
+	Patches []BezierPatch
+	// Divisions is the number of rows and columns of quads each patch is
+	// tessellated into to find the hit that is then refined on the patch
+	Divisions int
+	Material  material.Material
+}
+
+func (bc *BezierConfig) GetName() string {
+	return bc.Name
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (bc *BezierConfig) NewShape() (common.Traceable, error) {
+	patches := bc.Patches
+	if bc.File != "" {
+		loaded, err := loadBPT(bc.File)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("bezier %s: %s", bc.Name, err.Error()))
+		}
+		patches = append(loaded, patches...)
+	}
+	bezier, err := NewBezier(patches, bc.Divisions)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("bezier %s: %s", bc.Name, err.Error()))
+	}
+	bezier.Name = bc.Name
+	bezier.Material = bc.Material
+	return bezier, nil
+}
+
+// FromYaml generates Config from input yaml, file is a patch file in the bpt
+// format of the Utah teapot and patches a list of 16 control points per patch
+// satisfies the interface ShapesConfig (2/2)
+func (bc *BezierConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	if file, err := config.Get("file").String(); err == nil {
+		bc.File = file
+	}
+	if config.Get("patches").IsFound() {
+		count, err := config.Get("patches").GetArraySize()
+		if err != nil {
+			return errors.New("bezier patches must be a list")
+		}
+		for index := 0; index < count; index++ {
+			points := config.Get("patches").GetIndex(index)
+			size, err := points.GetArraySize()
+			if err != nil || size != 16 {
+				return errors.New(fmt.Sprintf("bezier patch %d requires 16 points", index))
+			}
+			patch := BezierPatch{}
+			for point := range patch {
+				v, err := vector3dFromYaml(points.GetIndex(point))
+				if err != nil {
+					return errors.New(fmt.Sprintf("bezier patch %d point %d: %s", index, point, err.Error()))
+				}
+				patch[point] = v
+			}
+			bc.Patches = append(bc.Patches, patch)
+		}
+	}
+	if bc.File == "" && len(bc.Patches) == 0 {
+		return errors.New("bezier requires a file or patches")
+	}
+
+	bc.Divisions = 8
+	if config.Get("divisions").IsFound() {
+		divisions, err := config.Get("divisions").Int()
+		if err != nil || divisions <= 0 {
+			return errors.New("bezier divisions must be positive")
+		}
+		bc.Divisions = divisions
+	}
+
+	m, err := materialFromYaml(config, materials)
+	if err != nil {
+		return err
+	}
+	bc.Material = m
+
+	return nil
+}
+
+// loadBPT reads the patches of the bpt file at path, which starts with the
+// number of patches followed by the degree of each patch in u and v and its
+// control points
+func loadBPT(path string) ([]BezierPatch, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readBPT(file)
+}
+
+// readBPT reads a bpt file from r, see loadBPT
+func readBPT(r io.Reader) ([]BezierPatch, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	next := func() (float64, error) {
+		if !scanner.Scan() {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return strconv.ParseFloat(scanner.Text(), 64)
+	}
+
+	count, err := next()
+	if err != nil {
+		return nil, errors.New("bpt requires the number of patches")
+	}
+	patches := make([]BezierPatch, int(count))
+	for index := range patches {
+		u, err := next()
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("bpt ended after %d of %d patches", index, len(patches)))
+		}
+		v, err := next()
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("bpt ended after %d of %d patches", index, len(patches)))
+		}
+		if u != 3 || v != 3 {
+			return nil, errors.New(fmt.Sprintf("bpt patch %d is degree %v by %v, only bicubic patches are supported", index, u, v))
+		}
+		for point := range patches[index] {
+			var xyz [3]float64
+			for axis := range xyz {
+				xyz[axis], err = next()
+				if err != nil {
+					return nil, errors.New(fmt.Sprintf("bpt ended after %d of %d patches", index, len(patches)))
+				}
+			}
+			patches[index][point] = vmath.Vector3d{X: xyz[0], Y: xyz[1], Z: xyz[2]}
+		}
+	}
+	return patches, nil
+}
+
+// BezierPatch is the 4 by 4 control points of a bicubic Bezier patch, row by
+// row, u runs along a row and v down the rows
+type BezierPatch [16]vmath.Vector3d
+
+// bernstein returns the cubic Bernstein polynomials at t and their
+// derivatives
+func bernstein(t float64) ([4]float64, [4]float64) {
+	s := 1 - t
+	return [4]float64{s * s * s, 3 * t * s * s, 3 * t * t * s, t * t * t},
+		[4]float64{-3 * s * s, 3*s*s - 6*t*s, 6*t*s - 3*t*t, 3 * t * t}
+}
+
+// evaluate returns the point of the patch at u, v and its derivatives along
+// u and v
+func (p *BezierPatch) evaluate(u float64, v float64) (vmath.Vector3d, vmath.Vector3d, vmath.Vector3d) {
+	bu, du := bernstein(u)
+	bv, dv := bernstein(v)
+	point := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	pu := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	pv := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			control := p[row*4+column]
+			point = point.Add(control.SMultiply(bu[column] * bv[row]))
+			pu = pu.Add(control.SMultiply(du[column] * bv[row]))
+			pv = pv.Add(control.SMultiply(bu[column] * dv[row]))
+		}
+	}
+	return point, pu, pv
+}
+
+// normal returns the normal of the patch at u, v, where the patch collapses
+// to a point like the top of the teapot lid the normal is taken a little
+// towards the middle of the patch instead
+func (p *BezierPatch) normal(u float64, v float64) (vmath.Vector3d, bool) {
+	_, pu, pv := p.evaluate(u, v)
+	norm := pu.Cross(pv)
+	if norm.Normalize() == nil {
+		return norm, true
+	}
+	_, pu, pv = p.evaluate(u+(0.5-u)*1e-3, v+(0.5-v)*1e-3)
+	norm = pu.Cross(pv)
+	return norm, norm.Normalize() == nil
+}
+
+// newton moves uv to where ray crosses the patch by Newton's method on the
+// two planes that meet along the line of ray, it returns the ratio along ray
+// of that point and false if it did not converge inside the patch
+func (p *BezierPatch) newton(ray *vmath.Ray, uv vmath.Vector2d) (float64, vmath.Vector2d, bool) {
+	d := ray.Direction
+	n1 := vmath.Vector3d{X: 0.0, Y: d.Z, Z: -d.Y}
+	if math.Abs(d.X) > math.Abs(d.Y) && math.Abs(d.X) > math.Abs(d.Z) {
+		n1 = vmath.Vector3d{X: d.Y, Y: -d.X, Z: 0.0}
+	}
+	n2 := d.Cross(n1)
+	if n1.Normalize() != nil || n2.Normalize() != nil {
+		return 0, uv, false
+	}
+	o1, o2 := n1.Dot(ray.Origin), n2.Dot(ray.Origin)
+
+	u, v := uv.X, uv.Y
+	for step := 0; step < 8; step++ {
+		point, pu, pv := p.evaluate(u, v)
+		f1, f2 := n1.Dot(point)-o1, n2.Dot(point)-o2
+		if math.Abs(f1)+math.Abs(f2) < 1e-10 {
+			break
+		}
+		j11, j12 := n1.Dot(pu), n1.Dot(pv)
+		j21, j22 := n2.Dot(pu), n2.Dot(pv)
+		det := j11*j22 - j12*j21
+		if det == 0 {
+			return 0, uv, false
+		}
+		u -= (j22*f1 - j12*f2) / det
+		v -= (j11*f2 - j21*f1) / det
+	}
+
+	if u < -1e-6 || u > 1+1e-6 || v < -1e-6 || v > 1+1e-6 {
+		return 0, uv, false
+	}
+	point, _, _ := p.evaluate(u, v)
+	if math.Abs(n1.Dot(point)-o1)+math.Abs(n2.Dot(point)-o2) > 1e-6 {
+		return 0, uv, false
+	}
+	t := point.Subtract(ray.Origin).Dot(d) / d.Dot(d)
+	return t, vmath.Vector2d{X: u, Y: v}, t > 1e-9
+}
+
+// invert returns the parameters of the point of the patch nearest point by
+// Newton's method from uv, which is expected to be close to them
+func (p *BezierPatch) invert(point vmath.Vector3d, uv vmath.Vector2d) vmath.Vector2d {
+	u, v := uv.X, uv.Y
+	for step := 0; step < 4; step++ {
+		at, pu, pv := p.evaluate(u, v)
+		r := point.Subtract(at)
+		// least squares of pu·du + pv·dv = r
+		a11, a12, a22 := pu.Dot(pu), pu.Dot(pv), pv.Dot(pv)
+		b1, b2 := pu.Dot(r), pv.Dot(r)
+		det := a11*a22 - a12*a12
+		if det == 0 {
+			break
+		}
+		u += (a22*b1 - a12*b2) / det
+		v += (a11*b2 - a12*b1) / det
+	}
+	return vmath.Vector2d{X: u, Y: v}
+}
+
+// Bezier is a set of bicubic Bezier patches, each tessellated into a Mesh
+// that finds roughly where a ray hits before the hit is moved onto the patch
+type Bezier struct {
+	*Mesh
+	Patches []BezierPatch
+
+	// the patch every triangle was cut from and the patch and parameters of
+	// the last hit
+	trianglePatch []int
+	hitPatch      int
+	hitUV         vmath.Vector2d
+}
+
+// NewBezier tessellates every patch into divisions by divisions quads
+func NewBezier(patches []BezierPatch, divisions int) (*Bezier, error) {
+	if len(patches) == 0 {
+		return nil, errors.New("bezier requires 1 or more patches")
+	}
+	if divisions <= 0 {
+		return nil, errors.New("bezier divisions must be positive")
+	}
+
+	vertices, normals := []vmath.Vector3d{}, []vmath.Vector3d{}
+	triangles, uvs := [][3]int{}, [][3]vmath.Vector2d{}
+	trianglePatch := []int{}
+	for index := range patches {
+		patch := &patches[index]
+		start := len(vertices)
+		for row := 0; row <= divisions; row++ {
+			for column := 0; column <= divisions; column++ {
+				u, v := float64(column)/float64(divisions), float64(row)/float64(divisions)
+				point, _, _ := patch.evaluate(u, v)
+				norm, _ := patch.normal(u, v)
+				vertices = append(vertices, point)
+				normals = append(normals, norm)
+			}
+		}
+		vertex := func(row int, column int) (int, vmath.Vector2d) {
+			return start + row*(divisions+1) + column,
+				vmath.Vector2d{X: float64(column) / float64(divisions), Y: float64(row) / float64(divisions)}
+		}
+		for row := 0; row < divisions; row++ {
+			for column := 0; column < divisions; column++ {
+				i00, uv00 := vertex(row, column)
+				i01, uv01 := vertex(row, column+1)
+				i10, uv10 := vertex(row+1, column)
+				i11, uv11 := vertex(row+1, column+1)
+				triangles = append(triangles, [3]int{i00, i01, i11}, [3]int{i00, i11, i10})
+				uvs = append(uvs, [3]vmath.Vector2d{uv00, uv01, uv11}, [3]vmath.Vector2d{uv00, uv11, uv10})
+				trianglePatch = append(trianglePatch, index, index)
+			}
+		}
+	}
+
+	mesh, err := NewMesh(vertices, triangles)
+	if err != nil {
+		return nil, err
+	}
+	mesh.Normals = normals
+	mesh.UVs = uvs
+	return &Bezier{
+		Mesh:          mesh,
+		Patches:       patches,
+		trianglePatch: trianglePatch,
+	}, nil
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene, the hit on the tessellation is the
+// starting guess for Newton's method on its patch, if that fails the hit on
+// the tessellation is kept
+func (b *Bezier) Intersect(ray *vmath.Ray) bool {
+	if !b.Mesh.Intersect(ray) {
+		return false
+	}
+	b.hitPatch = b.trianglePatch[b.hitTriangle]
+	b.hitUV = b.Mesh.CalculateUV(b.PlaceHit)
+
+	t, uv, ok := b.Patches[b.hitPatch].newton(ray, b.hitUV)
+	if ok {
+		b.hitUV = uv
+		b.intersectionRatio = t
+		b.PlaceHit = ray.Origin.Add(ray.Direction.SMultiply(t))
+	}
+	return true
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (b *Bezier) GetType() string {
+	return "bezier"
+}
+
+// CalculateNorm returns the normal of the patch at the last hit, hit is
+// expected to be that hit
+func (b *Bezier) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	if norm, ok := b.Patches[b.hitPatch].normal(b.hitUV.X, b.hitUV.Y); ok {
+		return norm
+	}
+	return b.Mesh.CalculateNorm(hit)
+}
+
+// CalculateUV returns the parameters of the patch of the last hit at hit,
+// which is expected to be near that hit
+func (b *Bezier) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	return b.Patches[b.hitPatch].invert(hit, b.hitUV)
+}
+
+// CalculateFrame returns the derivatives of the patch at the last hit
+func (b *Bezier) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	_, pu, pv := b.Patches[b.hitPatch].evaluate(b.hitUV.X, b.hitUV.Y)
+	return pu, pv
+}
+
+func (b *Bezier) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(b, b.PlaceHit, 0), ray, objs)
+}
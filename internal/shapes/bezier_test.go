@@ -0,0 +1,204 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// domePatch is a patch over x and z from 0 to 3 raised in the middle
+func domePatch() BezierPatch {
+	patch := BezierPatch{}
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			y := 0.0
+			if row > 0 && row < 3 && column > 0 && column < 3 {
+				y = 2.0
+			}
+			patch[row*4+column] = vmath.Vector3d{X: float64(column), Y: y, Z: float64(row)}
+		}
+	}
+	return patch
+}
+
+func TestBezierPatchEvaluate(t *testing.T) {
+	patch := domePatch()
+	point, pu, pv := patch.evaluate(0.5, 0.5)
+	// the middle of the dome is 9/16 of the way up to its inner points
+	assert.InDelta(t, 1.5, point.X, 1e-9)
+	assert.InDelta(t, 2*0.75*0.75, point.Y, 1e-9)
+	assert.InDelta(t, 1.5, point.Z, 1e-9)
+	assert.InDelta(t, 0, pu.Y, 1e-9)
+	assert.InDelta(t, 0, pv.Y, 1e-9)
+
+	norm, ok := patch.normal(0.5, 0.5)
+	require.True(t, ok)
+	assert.InDelta(t, -1, norm.Y, 1e-9)
+}
+
+func TestBezierIntersect(t *testing.T) {
+	bezier, err := NewBezier([]BezierPatch{domePatch()}, 2)
+	require.NoError(t, err)
+
+	tests := []struct {
+		Description string
+		Origin      vmath.Vector3d
+		Expected    bool
+		U, V        float64
+	}{
+		{
+			Description: "middle",
+			Origin:      vmath.Vector3d{X: 1.5, Y: 10, Z: 1.5},
+			Expected:    true,
+			U:           0.5,
+			V:           0.5,
+		},
+		{
+			Description: "off center where the tessellation is far off",
+			Origin:      vmath.Vector3d{X: 0.6, Y: 10, Z: 1.1},
+			Expected:    true,
+		},
+		{
+			Description: "outside",
+			Origin:      vmath.Vector3d{X: 3.5, Y: 10, Z: 1.5},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			ray := &vmath.Ray{Origin: test.Origin, Direction: vmath.Vector3d{Y: -1}}
+			require.Equal(t, test.Expected, bezier.Intersect(ray))
+			if !test.Expected {
+				return
+			}
+			// the hit is on the patch, not just on its tessellation
+			uv := bezier.CalculateUV(bezier.PlaceHit)
+			point, pu, pv := bezier.Patches[0].evaluate(uv.X, uv.Y)
+			assert.InDelta(t, test.Origin.X, point.X, 1e-9)
+			assert.InDelta(t, test.Origin.Z, point.Z, 1e-9)
+			assert.InDelta(t, test.Origin.Y-point.Y, bezier.GetIntersectionRatio(), 1e-9)
+			if test.U != 0 {
+				assert.InDelta(t, test.U, uv.X, 1e-9)
+				assert.InDelta(t, test.V, uv.Y, 1e-9)
+			}
+
+			expected := pu.Cross(pv)
+			expected.Normalize()
+			norm := bezier.CalculateNorm(bezier.PlaceHit)
+			assert.InDelta(t, expected.X, norm.X, 1e-9)
+			assert.InDelta(t, expected.Y, norm.Y, 1e-9)
+			assert.InDelta(t, expected.Z, norm.Z, 1e-9)
+		})
+	}
+}
+
+func TestBezierFootprint(t *testing.T) {
+	// a flat patch with evenly spaced control points has u along x and v
+	// along z, a third of the patch per unit
+	patch := BezierPatch{}
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			patch[row*4+column] = vmath.Vector3d{X: float64(column), Y: 0.0, Z: float64(row)}
+		}
+	}
+	bezier, err := NewBezier([]BezierPatch{patch}, 2)
+	require.NoError(t, err)
+
+	ray := &vmath.Ray{
+		Origin:    vmath.Vector3d{X: 1.4, Y: 2.0, Z: 1.3},
+		Direction: vmath.Vector3d{Y: -1.0},
+		Differentials: &vmath.RayDifferentials{
+			XOrigin:    vmath.Vector3d{X: 1.4, Y: 2.0, Z: 1.3},
+			XDirection: vmath.Vector3d{X: 0.1, Y: -1.0},
+			YOrigin:    vmath.Vector3d{X: 1.4, Y: 2.0, Z: 1.3},
+			YDirection: vmath.Vector3d{Y: -1.0, Z: 0.05},
+		},
+	}
+	require.True(t, bezier.Intersect(ray))
+	h := newSurfaceHit(bezier, bezier.PlaceHit, 0)
+	assert.InDelta(t, 1.4/3, h.uv.X, 1e-9)
+	assert.InDelta(t, 1.3/3, h.uv.Y, 1e-9)
+
+	px, py := h.footprint(ray)
+	dx, dy := h.uvChange(px), h.uvChange(py)
+	assert.InDelta(t, 0.2/3, dx.X, 1e-6)
+	assert.InDelta(t, 0.0, dx.Y, 1e-6)
+	assert.InDelta(t, 0.0, dy.X, 1e-6)
+	assert.InDelta(t, 0.1/3, dy.Y, 1e-6)
+}
+
+func TestBezierPatchCollapsedNormal(t *testing.T) {
+	// a patch whose first row is a single point, like the top of a lid
+	patch := domePatch()
+	for column := 0; column < 4; column++ {
+		patch[column] = vmath.Vector3d{X: 1.5, Y: 0, Z: 0}
+	}
+	norm, ok := patch.normal(0.3, 0)
+	require.True(t, ok)
+	assert.InDelta(t, 1, norm.Norm(), 1e-9)
+	assert.False(t, math.IsNaN(norm.X))
+}
+
+func TestReadBPT(t *testing.T) {
+	patch := domePatch()
+	lines := []string{"1", "3 3"}
+	for _, point := range patch {
+		lines = append(lines, fmt.Sprintf("%v %v %v", point.X, point.Y, point.Z))
+	}
+
+	patches, err := readBPT(strings.NewReader(strings.Join(lines, "\n")))
+	require.NoError(t, err)
+	assert.Equal(t, []BezierPatch{patch}, patches)
+
+	_, err = readBPT(strings.NewReader("2\n3 3\n0 0 0\n"))
+	assert.Equal(t, errors.New("bpt ended after 0 of 2 patches"), err)
+	_, err = readBPT(strings.NewReader("1\n2 3\n"))
+	assert.Equal(t, errors.New("bpt patch 0 is degree 2 by 3, only bicubic patches are supported"), err)
+}
+
+func TestBezierConfigFromYaml(t *testing.T) {
+	tests := []struct {
+		Description   string
+		Yaml          string
+		ExpectedError error
+	}{
+		{
+			Description: "file",
+			Yaml:        "file: teapot.bpt\ndivisions: 4\n",
+		},
+		{
+			Description:   "nothing to render",
+			Yaml:          "divisions: 4\n",
+			ExpectedError: errors.New("bezier requires a file or patches"),
+		},
+		{
+			Description:   "short patch",
+			Yaml:          "patches:\n  - [[0, 0, 0], [1, 0, 0]]\n",
+			ExpectedError: errors.New("bezier patch 0 requires 16 points"),
+		},
+		{
+			Description:   "zero divisions",
+			Yaml:          "file: teapot.bpt\ndivisions: 0\n",
+			ExpectedError: errors.New("bezier divisions must be positive"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml([]byte(test.Yaml))
+			require.NoError(t, err)
+			config := &BezierConfig{}
+			err = config.FromYaml(yaml, map[string]material.Material{})
+			assert.Equal(t, test.ExpectedError, err)
+		})
+	}
+}
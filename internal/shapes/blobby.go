@@ -0,0 +1,397 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// BlobbyConfig defines a blobby surface for the ShapeFactory
+type BlobbyConfig struct {
+	Name      string
+	Threshold float64
+	Sources   []BlobSource
+	Material  material.Material
+}
+
+func (bc *BlobbyConfig) GetName() string {
+	return bc.Name
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (bc *BlobbyConfig) NewShape() (common.Traceable, error) {
+	if bc.Threshold <= 0 {
+		return nil, errors.New("blobby threshold must be positive")
+	}
+	if len(bc.Sources) == 0 {
+		return nil, errors.New(fmt.Sprintf("blobby %s needs at least 1 source.", bc.Name))
+	}
+	blobby := NewBlobby(bc.Sources, bc.Threshold)
+	blobby.Name = bc.Name
+	blobby.Material = bc.Material
+	return blobby, nil
+}
+
+// FromYaml generates Config from input yaml, every source is either a point
+// with a position or a segment from a to b and has a radius of influence and
+// a weight which defaults to 1 and subtracts from the field when negative
+// satisfies the interface ShapesConfig (2/2)
+func (bc *BlobbyConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	bc.Threshold = 0.5
+	if config.Get("threshold").IsFound() {
+		threshold, err := floatFromYaml(config.Get("threshold"))
+		if err != nil {
+			return errors.New("blobby threshold: " + err.Error())
+		}
+		bc.Threshold = threshold
+	}
+
+	count, err := config.Get("sources").GetArraySize()
+	if err != nil || count == 0 {
+		return errors.New("blobby requires 1 or more sources")
+	}
+	for index := 0; index < count; index++ {
+		source, err := blobSourceFromYaml(config.Get("sources").GetIndex(index))
+		if err != nil {
+			return errors.New(fmt.Sprintf("blobby source %d: %s", index, err.Error()))
+		}
+		bc.Sources = append(bc.Sources, source)
+	}
+
+	m, err := materialFromYaml(config, materials)
+	if err != nil {
+		return err
+	}
+	bc.Material = m
+
+	return nil
+}
+
+func blobSourceFromYaml(config *simpleyaml.Yaml) (BlobSource, error) {
+	source := BlobSource{Weight: 1.0}
+	if config.Get("position").IsFound() {
+		position, err := vector3dFromYaml(config.Get("position"))
+		if err != nil {
+			return source, errors.New("position: " + err.Error())
+		}
+		source.A, source.B = position, position
+	} else {
+		a, err := vector3dFromYaml(config.Get("a"))
+		if err != nil {
+			return source, errors.New("requires a position or a and b")
+		}
+		b, err := vector3dFromYaml(config.Get("b"))
+		if err != nil {
+			return source, errors.New("requires a position or a and b")
+		}
+		source.A, source.B = a, b
+	}
+
+	radius, err := floatFromYaml(config.Get("radius"))
+	if err != nil || radius <= 0 {
+		return source, errors.New("radius must be positive")
+	}
+	source.Radius = radius
+	if config.Get("weight").IsFound() {
+		weight, err := floatFromYaml(config.Get("weight"))
+		if err != nil {
+			return source, errors.New("weight: " + err.Error())
+		}
+		source.Weight = weight
+	}
+
+	return source, nil
+}
+
+// BlobSource adds Weight*(1 - d^2/Radius^2)^3 to the field of a blobby, d is
+// the distance to the segment from A to B which is a point when they match
+type BlobSource struct {
+	A, B   vmath.Vector3d
+	Radius float64
+	Weight float64
+}
+
+// closest returns the point of the source nearest p
+func (bs *BlobSource) closest(p vmath.Vector3d) vmath.Vector3d {
+	ab := bs.B.Subtract(bs.A)
+	length := ab.Dot(ab)
+	if length == 0 {
+		return bs.A
+	}
+	s := math.Min(math.Max(p.Subtract(bs.A).Dot(ab)/length, 0), 1)
+	return bs.A.Add(ab.SMultiply(s))
+}
+
+// blobPiece is a span of a ray inside the radius of a source where the
+// squared distance to the source is a quadratic in the ratio, measured to the
+// point Center or when Axis is set the line through Center along Axis
+type blobPiece struct {
+	In, Out      float64
+	Center, Axis vmath.Vector3d
+	Source       *BlobSource
+}
+
+// distanceQuadratic returns A, B and C of the squared distance of the ray at
+// ratio t to the piece, A*t^2 + B*t + C
+func (bp *blobPiece) distanceQuadratic(origin vmath.Vector3d, direction vmath.Vector3d) (float64, float64, float64) {
+	w := origin.Subtract(bp.Center)
+	d := direction
+	if !bp.Axis.IsZero() {
+		w = w.Subtract(bp.Axis.SMultiply(w.Dot(bp.Axis)))
+		d = d.Subtract(bp.Axis.SMultiply(d.Dot(bp.Axis)))
+	}
+	return d.Dot(d), 2 * w.Dot(d), w.Dot(w)
+}
+
+// pieces splits the line of ray where the nearest point of the source moves
+// between its ends and its middle and keeps the parts within Radius
+func (bs *BlobSource) pieces(ray *vmath.Ray) []blobPiece {
+	candidates := []blobPiece{{In: math.Inf(-1), Out: math.Inf(1), Center: bs.A}}
+	ab := bs.B.Subtract(bs.A)
+	if length := ab.Norm(); length > 0 {
+		axis := ab.Divide(length)
+		o := ray.Origin.Subtract(bs.A).Dot(axis)
+		d := ray.Direction.Dot(axis)
+		middle := blobPiece{Center: bs.A, Axis: axis}
+		switch {
+		case d == 0 && o <= 0:
+			// parallel to the segment and beyond A, the first candidate
+		case d == 0 && o >= length:
+			candidates = []blobPiece{{In: math.Inf(-1), Out: math.Inf(1), Center: bs.B}}
+		case d == 0:
+			middle.In, middle.Out = math.Inf(-1), math.Inf(1)
+			candidates = []blobPiece{middle}
+		default:
+			// ratios where the ray passes the planes through each end
+			tA, tB := -o/d, (length-o)/d
+			first, last := bs.A, bs.B
+			if tA > tB {
+				tA, tB = tB, tA
+				first, last = bs.B, bs.A
+			}
+			middle.In, middle.Out = tA, tB
+			candidates = []blobPiece{
+				{In: math.Inf(-1), Out: tA, Center: first},
+				middle,
+				{In: tB, Out: math.Inf(1), Center: last},
+			}
+		}
+	}
+
+	pieces := []blobPiece{}
+	for _, piece := range candidates {
+		a, b, c := piece.distanceQuadratic(ray.Origin, ray.Direction)
+		roots := vmath.SolveQuadratic(a, b, c-bs.Radius*bs.Radius)
+		if len(roots) < 2 {
+			continue
+		}
+		piece.In = math.Max(piece.In, roots[0])
+		piece.Out = math.Min(piece.Out, roots[1])
+		if piece.In < piece.Out {
+			piece.Source = bs
+			pieces = append(pieces, piece)
+		}
+	}
+	return pieces
+}
+
+// Blobby is the surface where the summed field of Sources equals Threshold,
+// the inside is where the field is above it
+type Blobby struct {
+	Name              string
+	Sources           []BlobSource
+	Threshold         float64
+	PlaceHit          vmath.Vector3d
+	intersectionRatio float64
+
+	Material material.Material
+}
+
+// NewBlobby makes a blobby from sources
+func NewBlobby(sources []BlobSource, threshold float64) *Blobby {
+	return &Blobby{
+		Sources:   sources,
+		Threshold: threshold,
+	}
+}
+
+// Field returns the sum of every source at p
+func (b *Blobby) Field(p vmath.Vector3d) float64 {
+	f := 0.0
+	for index := range b.Sources {
+		source := &b.Sources[index]
+		d := p.Subtract(source.closest(p))
+		u := 1 - d.Dot(d)/(source.Radius*source.Radius)
+		if u > 0 {
+			f += source.Weight * u * u * u
+		}
+	}
+	return f
+}
+
+// roots returns every ratio along ray where the field crosses Threshold in
+// ascending order. Along the ray each source is a polynomial of degree 6
+// between the ratios it starts and stops having influence, so the line is cut
+// at each of those ratios and the sum of the sources inside each span solved
+// exactly
+func (b *Blobby) roots(ray *vmath.Ray) []float64 {
+	pieces := []blobPiece{}
+	events := []float64{}
+	for index := range b.Sources {
+		for _, piece := range b.Sources[index].pieces(ray) {
+			pieces = append(pieces, piece)
+			events = append(events, piece.In, piece.Out)
+		}
+	}
+	sort.Float64s(events)
+
+	roots := []float64{}
+	for index := 0; index < len(events)-1; index++ {
+		start, end := events[index], events[index+1]
+		if end-start <= 0 {
+			continue
+		}
+		// solve from the start of the span to keep the polynomial well
+		// conditioned far from the origin of the ray
+		origin := ray.Origin.Add(ray.Direction.SMultiply(start))
+		middle := (start + end) / 2
+		poly := make([]float64, 7)
+		for _, piece := range pieces {
+			if piece.In > middle || piece.Out < middle {
+				continue
+			}
+			r2 := piece.Source.Radius * piece.Source.Radius
+			qa, qb, qc := piece.distanceQuadratic(origin, ray.Direction)
+			// (1 - q/r^2)^3 with q the squared distance
+			u := []float64{-qa / r2, -qb / r2, 1 - qc/r2}
+			cube := multiplyPolynomial(u, multiplyPolynomial(u, u))
+			for power, c := range cube {
+				poly[power] += piece.Source.Weight * c
+			}
+		}
+		poly[6] -= b.Threshold
+
+		for _, root := range vmath.SolvePolynomial(poly...) {
+			if root < 0 || root > end-start {
+				continue
+			}
+			t := start + root
+			if len(roots) > 0 && t-roots[len(roots)-1] < 1e-9 {
+				// the same crossing found at the end of the last span
+				continue
+			}
+			roots = append(roots, t)
+		}
+	}
+	return roots
+}
+
+// multiplyPolynomial multiplies two polynomials given from the highest power
+// down
+func multiplyPolynomial(a []float64, b []float64) []float64 {
+	result := make([]float64, len(a)+len(b)-1)
+	for i, x := range a {
+		for j, y := range b {
+			result[i+j] += x * y
+		}
+	}
+	return result
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene
+func (b *Blobby) Intersect(ray *vmath.Ray) bool {
+	for _, root := range b.roots(ray) {
+		if root > 0 {
+			b.intersectionRatio = root
+			b.PlaceHit = ray.Origin.Add(ray.Direction.SMultiply(root))
+			return true
+		}
+	}
+	return false
+}
+
+// Intervals satisfies the Solid interface
+func (b *Blobby) Intervals(ray *vmath.Ray) []Interval {
+	roots := b.roots(ray)
+	intervals := []Interval{}
+	for index := 0; index < len(roots)-1; index++ {
+		// the field only touching the threshold does not enter the blobby
+		middle := ray.Origin.Add(ray.Direction.SMultiply((roots[index] + roots[index+1]) / 2))
+		if b.Field(middle) <= b.Threshold {
+			continue
+		}
+		intervals = append(intervals, Interval{
+			In:  Crossing{T: roots[index], Solid: b},
+			Out: Crossing{T: roots[index+1], Solid: b},
+		})
+		index++
+	}
+	return intervals
+}
+
+// GetPosition satisfies requirements for Object
+// interface for a scene, the position is the middle of the sources
+func (b *Blobby) GetPosition() vmath.Vector3d {
+	p := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	for _, source := range b.Sources {
+		p = p.Add(source.A.Add(source.B).SMultiply(0.5))
+	}
+	return p.Divide(float64(len(b.Sources)))
+}
+
+func (b *Blobby) GetName() string {
+	return b.Name
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (b *Blobby) GetType() string {
+	return "blobby"
+}
+
+// GetIntersectionRatio
+func (b *Blobby) GetIntersectionRatio() float64 {
+	return b.intersectionRatio
+}
+
+// CalculateNorm is the negative gradient of the field, the field falls away
+// from each source so it adds 6*w*(1 - d^2/r^2)^2/r^2 * (hit - closest)
+func (b *Blobby) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	grad := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	for index := range b.Sources {
+		source := &b.Sources[index]
+		d := hit.Subtract(source.closest(hit))
+		r2 := source.Radius * source.Radius
+		u := 1 - d.Dot(d)/r2
+		if u > 0 {
+			grad = grad.Add(d.SMultiply(6.0 * source.Weight * u * u / r2))
+		}
+	}
+	grad.Normalize()
+
+	return grad
+}
+
+// CalculateUV wraps uv around the middle of the sources like a sphere
+func (b *Blobby) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	return sphericalUV(hit.Subtract(b.GetPosition()))
+}
+
+// GetMaterial returns the material the blobby is shaded with
+func (b *Blobby) GetMaterial() material.Material {
+	return b.Material
+}
+
+func (b *Blobby) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(b, b.PlaceHit, 0), ray, objs)
+}
@@ -0,0 +1,208 @@
+package shapes
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestBlobbyIntersect(t *testing.T) {
+	// with radius 2 and threshold 1/8 a single source reaches sqrt(2)
+	point := func(p vmath.Vector3d, weight float64) BlobSource {
+		return BlobSource{A: p, B: p, Radius: 2, Weight: weight}
+	}
+
+	tests := []struct {
+		Description   string
+		Blobby        *Blobby
+		Ray           *vmath.Ray
+		Expected      bool
+		ExpectedRatio float64
+		ExpectedNorm  vmath.Vector3d
+		Intervals     int
+	}{
+		{
+			Description: "single point is a sphere",
+			Blobby:      NewBlobby([]BlobSource{point(vmath.Vector3d{}, 1)}, 0.125),
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{Z: 10},
+				Direction: vmath.Vector3d{Z: -1},
+			},
+			Expected:      true,
+			ExpectedRatio: 10 - math.Sqrt2,
+			ExpectedNorm:  vmath.Vector3d{Z: 1},
+			Intervals:     1,
+		},
+		{
+			Description: "far away origin",
+			Blobby:      NewBlobby([]BlobSource{point(vmath.Vector3d{}, 1)}, 0.125),
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{Z: 1e5},
+				Direction: vmath.Vector3d{Z: -1},
+			},
+			Expected:      true,
+			ExpectedRatio: 1e5 - math.Sqrt2,
+			ExpectedNorm:  vmath.Vector3d{Z: 1},
+			Intervals:     1,
+		},
+		{
+			Description: "two points bridge the gap between them",
+			Blobby: NewBlobby([]BlobSource{
+				point(vmath.Vector3d{X: -1.5}, 1),
+				point(vmath.Vector3d{X: 1.5}, 1),
+			}, 0.125),
+			// alone each source would stop at 1.414 from its center
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{Z: 10},
+				Direction: vmath.Vector3d{Z: -1},
+			},
+			Expected:     true,
+			ExpectedNorm: vmath.Vector3d{Z: 1},
+			Intervals:    1,
+		},
+		{
+			Description: "segment is a capsule",
+			Blobby: NewBlobby([]BlobSource{
+				{A: vmath.Vector3d{X: -3}, B: vmath.Vector3d{X: 3}, Radius: 2, Weight: 1},
+			}, 0.125),
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: 1, Y: 10},
+				Direction: vmath.Vector3d{Y: -2},
+			},
+			Expected:      true,
+			ExpectedRatio: (10 - math.Sqrt2) / 2,
+			ExpectedNorm:  vmath.Vector3d{Y: 1},
+			Intervals:     1,
+		},
+		{
+			Description: "segment end cap",
+			Blobby: NewBlobby([]BlobSource{
+				{A: vmath.Vector3d{X: -3}, B: vmath.Vector3d{X: 3}, Radius: 2, Weight: 1},
+			}, 0.125),
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: 10},
+				Direction: vmath.Vector3d{X: -1},
+			},
+			Expected:      true,
+			ExpectedRatio: 7 - math.Sqrt2,
+			ExpectedNorm:  vmath.Vector3d{X: 1},
+			Intervals:     1,
+		},
+		{
+			Description: "negative weight hollows the middle",
+			Blobby: NewBlobby([]BlobSource{
+				{A: vmath.Vector3d{}, B: vmath.Vector3d{}, Radius: 4, Weight: 1},
+				point(vmath.Vector3d{}, -1),
+			}, 0.125),
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{},
+				Direction: vmath.Vector3d{X: 1},
+			},
+			Expected:  true,
+			Intervals: 2,
+		},
+		{
+			Description: "miss",
+			Blobby:      NewBlobby([]BlobSource{point(vmath.Vector3d{}, 1)}, 0.125),
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{Y: 1.5, Z: 10},
+				Direction: vmath.Vector3d{Z: -1},
+			},
+			Expected:  false,
+			Intervals: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			assert.Equal(t, test.Expected, test.Blobby.Intersect(test.Ray))
+			assert.Len(t, test.Blobby.Intervals(test.Ray), test.Intervals)
+			if !test.Expected {
+				return
+			}
+			assert.InDelta(t, test.Blobby.Threshold, test.Blobby.Field(test.Blobby.PlaceHit), 1e-9)
+			if test.ExpectedRatio != 0 {
+				assert.InDelta(t, test.ExpectedRatio, test.Blobby.GetIntersectionRatio(), 1e-9)
+			}
+			if !test.ExpectedNorm.IsZero() {
+				norm := test.Blobby.CalculateNorm(test.Blobby.PlaceHit)
+				assert.InDelta(t, test.ExpectedNorm.X, norm.X, 1e-9)
+				assert.InDelta(t, test.ExpectedNorm.Y, norm.Y, 1e-9)
+				assert.InDelta(t, test.ExpectedNorm.Z, norm.Z, 1e-9)
+			}
+		})
+	}
+}
+
+func TestBlobbyConfigFromYaml(t *testing.T) {
+	tests := []struct {
+		Description string
+		Bytes       []byte
+		Expected    []BlobSource
+		ExpectedErr error
+	}{
+		{
+			Description: "point and segment",
+			Bytes: []byte(`
+threshold: 0.3
+sources:
+  - position: [1, 0, 0]
+    radius: 2
+  - a: [0, 0, 0]
+    b: [0, 1, 0]
+    radius: 1.5
+    weight: -0.5
+`),
+			Expected: []BlobSource{
+				{A: vmath.Vector3d{X: 1}, B: vmath.Vector3d{X: 1}, Radius: 2, Weight: 1},
+				{A: vmath.Vector3d{}, B: vmath.Vector3d{Y: 1}, Radius: 1.5, Weight: -0.5},
+			},
+		},
+		{
+			Description: "no sources",
+			Bytes: []byte(`
+threshold: 0.3
+`),
+			ExpectedErr: errors.New("blobby requires 1 or more sources"),
+		},
+		{
+			Description: "no radius",
+			Bytes: []byte(`
+sources:
+  - position: [1, 0, 0]
+`),
+			ExpectedErr: errors.New("blobby source 0: radius must be positive"),
+		},
+		{
+			Description: "half a segment",
+			Bytes: []byte(`
+sources:
+  - a: [1, 0, 0]
+    radius: 1
+`),
+			ExpectedErr: errors.New("blobby source 0: requires a position or a and b"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			config := &BlobbyConfig{}
+			err = config.FromYaml(yaml, map[string]material.Material{})
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, config.Sources)
+		})
+	}
+}
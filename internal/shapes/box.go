@@ -0,0 +1,261 @@
+package shapes
+
+import (
+	"errors"
+	"math"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// BoxConfig defines a box for the ShapeFactory
+type BoxConfig struct {
+	Name     string
+	Position vmath.Vector3d
+	Size     vmath.Vector3d
+	// Rotation in degrees around the X, Y and then Z axis
+	Rotation vmath.Vector3d
+	Material material.Material
+}
+
+func (bc *BoxConfig) GetName() string {
+	return bc.Name
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (bc *BoxConfig) NewShape() (common.Traceable, error) {
+	if bc.Size.X <= 0 || bc.Size.Y <= 0 || bc.Size.Z <= 0 {
+		return nil, errors.New("box size must be positive on every axis")
+	}
+	box := NewBox(bc.Position, bc.Size, bc.Rotation)
+	box.Name = bc.Name
+	box.Material = bc.Material
+	return box, nil
+}
+
+// FromYaml generates Config from input yaml, a box is either a min and max
+// corner or a center position and size
+// satisfies the interface ShapesConfig (2/2)
+func (bc *BoxConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	if config.Get("min").IsFound() || config.Get("max").IsFound() {
+		min, err := vector3dFromYaml(config.Get("min"))
+		if err != nil {
+			return errors.New("box min: " + err.Error())
+		}
+		max, err := vector3dFromYaml(config.Get("max"))
+		if err != nil {
+			return errors.New("box max: " + err.Error())
+		}
+		bc.Position = min.Add(max).SMultiply(0.5)
+		bc.Size = vmath.Vector3d{
+			X: math.Abs(max.X - min.X),
+			Y: math.Abs(max.Y - min.Y),
+			Z: math.Abs(max.Z - min.Z),
+		}
+	} else {
+		if config.Get("position").IsFound() {
+			position, err := vector3dFromYaml(config.Get("position"))
+			if err != nil {
+				return errors.New("box position: " + err.Error())
+			}
+			bc.Position = position
+		}
+		if config.Get("size").IsFound() {
+			size, err := vector3dFromYaml(config.Get("size"))
+			if err != nil {
+				return errors.New("box size: " + err.Error())
+			}
+			bc.Size = size
+		}
+	}
+	if config.Get("rotation").IsFound() {
+		rotation, err := vector3dFromYaml(config.Get("rotation"))
+		if err != nil {
+			return errors.New("box rotation: " + err.Error())
+		}
+		bc.Rotation = rotation
+	}
+
+	m, err := materialFromYaml(config, materials)
+	if err != nil {
+		return err
+	}
+	bc.Material = m
+
+	return nil
+}
+
+// Box is an oriented box centered on P, axis holds the local frame of the box
+// and s the half size along each axis
+type Box struct {
+	Name              string
+	axis              []vmath.Vector3d
+	s                 []float64
+	PlaceHit          vmath.Vector3d
+	P                 vmath.Vector3d
+	intersectionRatio float64
+
+	Material material.Material
+}
+
+// NewBox makes a box centered on pos, rotation is in degrees around the X, Y
+// and then Z axis
+func NewBox(pos vmath.Vector3d, size vmath.Vector3d, rotation vmath.Vector3d) *Box {
+	axis := []vmath.Vector3d{
+		{X: 1.0, Y: 0.0, Z: 0.0},
+		{X: 0.0, Y: 1.0, Z: 0.0},
+		{X: 0.0, Y: 0.0, Z: 1.0},
+	}
+	for index := range axis {
+		axis[index] = axis[index].
+			Rotate(vmath.Vector3d{X: 1.0, Y: 0.0, Z: 0.0}, rotation.X).
+			Rotate(vmath.Vector3d{X: 0.0, Y: 1.0, Z: 0.0}, rotation.Y).
+			Rotate(vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}, rotation.Z)
+	}
+
+	return &Box{
+		P:    pos,
+		axis: axis,
+		s:    []float64{size.X / 2.0, size.Y / 2.0, size.Z / 2.0},
+	}
+}
+
+// slabs clips the ray against the three pairs of planes bounding the box and
+// returns the entry and exit ratio, ok is false if the line of the ray misses
+// the box
+func (b *Box) slabs(ray *vmath.Ray) (float64, float64, bool) {
+	tNear, tFar := math.Inf(-1), math.Inf(1)
+	offset := ray.Origin.Subtract(b.P)
+	for index, axis := range b.axis {
+		o := axis.Dot(offset)
+		d := axis.Dot(ray.Direction)
+		if d == 0 {
+			// parallel to this slab, so the origin has to be between the planes
+			if math.Abs(o) > b.s[index] {
+				return 0, 0, false
+			}
+			continue
+		}
+
+		t1 := (-b.s[index] - o) / d
+		t2 := (b.s[index] - o) / d
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tNear = math.Max(tNear, t1)
+		tFar = math.Min(tFar, t2)
+		if tNear > tFar {
+			return 0, 0, false
+		}
+	}
+
+	return tNear, tFar, true
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene
+func (b *Box) Intersect(ray *vmath.Ray) bool {
+	tNear, tFar, ok := b.slabs(ray)
+	if !ok || tFar < 0 {
+		return false
+	}
+
+	// when the ray starts inside the box the exit is the visible side
+	b.intersectionRatio = tNear
+	if tNear < 0 {
+		b.intersectionRatio = tFar
+	}
+	b.PlaceHit = ray.Origin.Add(ray.Direction.SMultiply(b.intersectionRatio))
+	return true
+}
+
+// Intervals satisfies the Solid interface
+func (b *Box) Intervals(ray *vmath.Ray) []Interval {
+	tNear, tFar, ok := b.slabs(ray)
+	if !ok {
+		return nil
+	}
+	return []Interval{{
+		In:  Crossing{T: tNear, Solid: b},
+		Out: Crossing{T: tFar, Solid: b},
+	}}
+}
+
+// GetPosition satisfies requirements for Object
+// interface for a scene
+func (b *Box) GetPosition() vmath.Vector3d {
+	return b.P
+}
+
+func (b *Box) GetName() string {
+	return b.Name
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (b *Box) GetType() string {
+	return "box"
+}
+
+// GetIntersectionRatio
+func (b *Box) GetIntersectionRatio() float64 {
+	return b.intersectionRatio
+}
+
+// face returns the index of the axis whose face contains hit and which side of
+// the box it is on
+func (b *Box) face(hit vmath.Vector3d) (int, float64) {
+	local := hit.Subtract(b.P)
+	face, side, best := 0, 1.0, -1.0
+	for index, axis := range b.axis {
+		d := axis.Dot(local) / b.s[index]
+		if math.Abs(d) > best {
+			face = index
+			best = math.Abs(d)
+			side = math.Copysign(1.0, d)
+		}
+	}
+	return face, side
+}
+
+// CalculateNorm returns the outward normal of the face containing hit
+func (b *Box) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	face, side := b.face(hit)
+	return b.axis[face].SMultiply(side)
+}
+
+// CalculateUV maps each face of the box onto the unit square
+func (b *Box) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	face, side := b.face(hit)
+	local := hit.Subtract(b.P)
+	// the two axes spanning the face, ordered so u runs the same way around
+	// the box for opposite faces
+	uAxis, vAxis := (face+1)%3, (face+2)%3
+	u := b.axis[uAxis].Dot(local) / b.s[uAxis] * side
+	v := b.axis[vAxis].Dot(local) / b.s[vAxis]
+	return vmath.Vector2d{
+		X: (u + 1.0) / 2.0,
+		Y: (v + 1.0) / 2.0,
+	}
+}
+
+// CalculateFrame returns the axes spanning the face containing hit in the
+// directions u and v increase in
+func (b *Box) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	face, side := b.face(hit)
+	return b.axis[(face+1)%3].SMultiply(side), b.axis[(face+2)%3]
+}
+
+// GetMaterial returns the material the box is shaded with
+func (b *Box) GetMaterial() material.Material {
+	return b.Material
+}
+
+func (b *Box) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(b, b.PlaceHit, 0), ray, objs)
+}
@@ -0,0 +1,166 @@
+package shapes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestBoxConfigFromYaml(t *testing.T) {
+	tests := []struct {
+		Description string
+		Expected    *BoxConfig
+		ExpectedErr error
+		Bytes       []byte
+	}{
+		{
+			Description: "Test Empty Map creates no Config",
+			Expected:    &BoxConfig{},
+			Bytes:       []byte(``),
+		},
+		{
+			Description: "min and max corners",
+			Expected: &BoxConfig{
+				Position: vmath.Vector3d{X: 0.0, Y: -1.0, Z: 0.0},
+				Size:     vmath.Vector3d{X: 10.0, Y: 1.0, Z: 4.0},
+			},
+			Bytes: []byte(`
+    min: [-5.0, -1.5, -2.0]
+    max: [5.0, -0.5, 2.0]
+`),
+		},
+		{
+			Description: "center, size and rotation",
+			Expected: &BoxConfig{
+				Position: vmath.Vector3d{X: 1.0, Y: 2.0, Z: 3.0},
+				Size:     vmath.Vector3d{X: 2.0, Y: 2.0, Z: 2.0},
+				Rotation: vmath.Vector3d{X: 0.0, Y: 45.0, Z: 0.0},
+			},
+			Bytes: []byte(`
+    position: [1.0, 2.0, 3.0]
+    size: [2, 2, 2]
+    rotation: [0, 45, 0]
+`),
+		},
+		{
+			Description: "min without max",
+			ExpectedErr: errors.New("box max: type assertion to []interface{} failed"),
+			Bytes: []byte(`
+    min: [-5.0, -1.5, -2.0]
+`),
+		},
+		{
+			Description: "missing material",
+			ExpectedErr: errors.New("material shiny does not exist in scene."),
+			Bytes: []byte(`
+    size: [2, 2, 2]
+    material: shiny
+`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			boxConfig := &BoxConfig{}
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			err = boxConfig.FromYaml(yaml, map[string]material.Material{})
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, boxConfig)
+		})
+	}
+}
+
+func TestBoxIntersect(t *testing.T) {
+	tests := []struct {
+		Description   string
+		Box           *Box
+		Expected      bool
+		ExpectedRatio float64
+		ExpectedNorm  vmath.Vector3d
+		ExpectedUV    vmath.Vector2d
+		Ray           *vmath.Ray
+	}{
+		{
+			Description:   "Test Simple Hit",
+			Box:           NewBox(vmath.Vector3d{}, vmath.Vector3d{X: 2, Y: 2, Z: 2}, vmath.Vector3d{}),
+			Expected:      true,
+			ExpectedRatio: 9.0,
+			ExpectedNorm:  vmath.Vector3d{X: 0, Y: 0, Z: 1},
+			ExpectedUV:    vmath.Vector2d{X: 0.5, Y: 0.5},
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: 0, Y: 0, Z: 10},
+				Direction: vmath.Vector3d{X: 0, Y: 0, Z: -1},
+			},
+		},
+		{
+			Description: "Test Simple Miss",
+			Box:         NewBox(vmath.Vector3d{}, vmath.Vector3d{X: 2, Y: 2, Z: 2}, vmath.Vector3d{}),
+			Expected:    false,
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: 0, Y: 0, Z: 10},
+				Direction: vmath.Vector3d{X: 0, Y: 0, Z: 1},
+			},
+		},
+		{
+			Description: "Test parallel Miss",
+			Box:         NewBox(vmath.Vector3d{}, vmath.Vector3d{X: 2, Y: 2, Z: 2}, vmath.Vector3d{}),
+			Expected:    false,
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: 0, Y: 3, Z: 10},
+				Direction: vmath.Vector3d{X: 0, Y: 0, Z: -1},
+			},
+		},
+		{
+			Description:   "Test inside hits far side",
+			Box:           NewBox(vmath.Vector3d{}, vmath.Vector3d{X: 2, Y: 4, Z: 2}, vmath.Vector3d{}),
+			Expected:      true,
+			ExpectedRatio: 2.0,
+			ExpectedNorm:  vmath.Vector3d{X: 0, Y: 1, Z: 0},
+			ExpectedUV:    vmath.Vector2d{X: 0.5, Y: 0.5},
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: 0, Y: 0, Z: 0},
+				Direction: vmath.Vector3d{X: 0, Y: 1, Z: 0},
+			},
+		},
+		{
+			Description:   "Test rotated box hits edge on",
+			Box:           NewBox(vmath.Vector3d{}, vmath.Vector3d{X: 2, Y: 2, Z: 2}, vmath.Vector3d{X: 0, Y: 90, Z: 0}),
+			Expected:      true,
+			ExpectedRatio: 4.0,
+			ExpectedNorm:  vmath.Vector3d{X: -1, Y: 0, Z: 0},
+			ExpectedUV:    vmath.Vector2d{X: 0.5, Y: 0.5},
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: -5, Y: 0, Z: 0},
+				Direction: vmath.Vector3d{X: 1, Y: 0, Z: 0},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			hit := test.Box.Intersect(test.Ray)
+			assert.Equal(t, test.Expected, hit)
+			if test.Expected {
+				assert.InDelta(t, test.ExpectedRatio, test.Box.GetIntersectionRatio(), 1e-9)
+				norm := test.Box.CalculateNorm(test.Box.PlaceHit)
+				assert.InDelta(t, test.ExpectedNorm.X, norm.X, 1e-9)
+				assert.InDelta(t, test.ExpectedNorm.Y, norm.Y, 1e-9)
+				assert.InDelta(t, test.ExpectedNorm.Z, norm.Z, 1e-9)
+				uv := test.Box.CalculateUV(test.Box.PlaceHit)
+				assert.InDelta(t, test.ExpectedUV.X, uv.X, 1e-9)
+				assert.InDelta(t, test.ExpectedUV.Y, uv.Y, 1e-9)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package shapes
+
+import (
+	"math"
+	"sort"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// bvhLeafSize is the most primitives a node of a bvh holds before it is split
+const bvhLeafSize = 4
+
+// aabb is an axis aligned box from Min to Max
+type aabb struct {
+	Min, Max vmath.Vector3d
+}
+
+// emptyBox contains nothing, growing it by any point makes a box of that point
+func emptyBox() aabb {
+	inf := math.Inf(1)
+	return aabb{
+		Min: vmath.Vector3d{X: inf, Y: inf, Z: inf},
+		Max: vmath.Vector3d{X: -inf, Y: -inf, Z: -inf},
+	}
+}
+
+// grow returns the box around b and p
+func (b aabb) grow(p vmath.Vector3d) aabb {
+	return aabb{
+		Min: vmath.Vector3d{X: math.Min(b.Min.X, p.X), Y: math.Min(b.Min.Y, p.Y), Z: math.Min(b.Min.Z, p.Z)},
+		Max: vmath.Vector3d{X: math.Max(b.Max.X, p.X), Y: math.Max(b.Max.Y, p.Y), Z: math.Max(b.Max.Z, p.Z)},
+	}
+}
+
+// union returns the box around b and o
+func (b aabb) union(o aabb) aabb {
+	return b.grow(o.Min).grow(o.Max)
+}
+
+// center is the middle of the box
+func (b aabb) center() vmath.Vector3d {
+	return b.Min.Add(b.Max).SMultiply(0.5)
+}
+
+// hit reports if ray passes through the box anywhere between its origin and
+// tMax
+func (b aabb) hit(ray *vmath.Ray, tMax float64) bool {
+	min := [3]float64{b.Min.X, b.Min.Y, b.Min.Z}
+	max := [3]float64{b.Max.X, b.Max.Y, b.Max.Z}
+	origin := [3]float64{ray.Origin.X, ray.Origin.Y, ray.Origin.Z}
+	direction := [3]float64{ray.Direction.X, ray.Direction.Y, ray.Direction.Z}
+
+	tNear, tFar := 0.0, tMax
+	for axis := range min {
+		if direction[axis] == 0 {
+			if origin[axis] < min[axis] || origin[axis] > max[axis] {
+				return false
+			}
+			continue
+		}
+		t1 := (min[axis] - origin[axis]) / direction[axis]
+		t2 := (max[axis] - origin[axis]) / direction[axis]
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tNear = math.Max(tNear, t1)
+		tFar = math.Min(tFar, t2)
+		if tNear > tFar {
+			return false
+		}
+	}
+	return true
+}
+
+// bvhNode is a box around either two child nodes or, when Count is above
+// zero, Count primitives starting at Start in the order of the bvh
+type bvhNode struct {
+	Box         aabb
+	Left, Right int
+	Start       int
+	Count       int
+}
+
+// bvh is a bounding volume hierarchy over primitives known only by their
+// boxes, the first node is the root
+type bvh struct {
+	nodes []bvhNode
+	order []int
+}
+
+// newBVH builds a bvh over boxes by splitting every node at the median of the
+// centers of its boxes along its longest axis
+func newBVH(boxes []aabb) *bvh {
+	b := &bvh{order: make([]int, len(boxes))}
+	for index := range b.order {
+		b.order[index] = index
+	}
+	if len(boxes) > 0 {
+		b.build(boxes, 0, len(boxes))
+	}
+	return b
+}
+
+// build adds the node over order[start:end] and its children, returning its
+// index
+func (b *bvh) build(boxes []aabb, start int, end int) int {
+	box, centers := emptyBox(), emptyBox()
+	for _, index := range b.order[start:end] {
+		box = box.union(boxes[index])
+		centers = centers.grow(boxes[index].center())
+	}
+
+	node := len(b.nodes)
+	b.nodes = append(b.nodes, bvhNode{Box: box, Start: start, Count: end - start})
+	if end-start <= bvhLeafSize {
+		return node
+	}
+
+	extent := centers.Max.Subtract(centers.Min)
+	axis := func(v vmath.Vector3d) float64 { return v.X }
+	if extent.Y > extent.X && extent.Y >= extent.Z {
+		axis = func(v vmath.Vector3d) float64 { return v.Y }
+	} else if extent.Z > extent.X && extent.Z > extent.Y {
+		axis = func(v vmath.Vector3d) float64 { return v.Z }
+	}
+	primitives := b.order[start:end]
+	sort.Slice(primitives, func(i, j int) bool {
+		return axis(boxes[primitives[i]].center()) < axis(boxes[primitives[j]].center())
+	})
+
+	middle := (start + end) / 2
+	left := b.build(boxes, start, middle)
+	right := b.build(boxes, middle, end)
+	b.nodes[node].Left, b.nodes[node].Right, b.nodes[node].Count = left, right, 0
+	return node
+}
+
+// intersect walks the nodes ray passes through and calls test on every
+// primitive in them, test returns the ratio along ray of its hit, the nearest
+// primitive hit and its ratio are returned
+func (b *bvh) intersect(ray *vmath.Ray, test func(index int) (float64, bool)) (int, float64, bool) {
+	nearest, hit, found := math.Inf(1), -1, false
+	if len(b.nodes) == 0 {
+		return hit, nearest, found
+	}
+
+	stack := []int{0}
+	for len(stack) > 0 {
+		node := &b.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		if !node.Box.hit(ray, nearest) {
+			continue
+		}
+		if node.Count == 0 {
+			stack = append(stack, node.Left, node.Right)
+			continue
+		}
+		for _, index := range b.order[node.Start : node.Start+node.Count] {
+			if t, ok := test(index); ok && t < nearest {
+				nearest, hit, found = t, index, true
+			}
+		}
+	}
+	return hit, nearest, found
+}
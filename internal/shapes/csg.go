@@ -0,0 +1,298 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// CSG operations combining the children of a CSG
+const (
+	CSGUnion        = "union"
+	CSGIntersection = "intersection"
+	CSGDifference   = "difference"
+)
+
+// CSGConfig defines a boolean combination of other shapes in the scene for the
+// ShapeFactory
+type CSGConfig struct {
+	Name      string
+	Operation string
+	Shapes    []string
+	Material  material.Material
+
+	children []Solid
+}
+
+func (cc *CSGConfig) GetName() string {
+	return cc.Name
+}
+
+// ChildNames satisfies the parentConfig interface
+func (cc *CSGConfig) ChildNames() []string {
+	return cc.Shapes
+}
+
+// SetChildren satisfies the parentConfig interface, every child has to be a
+// closed Solid for the inside of it to be known
+func (cc *CSGConfig) SetChildren(children []common.Traceable) error {
+	cc.children = []Solid{}
+	for index, child := range children {
+		solid, ok := child.(Solid)
+		if !ok {
+			return errors.New(fmt.Sprintf("shape %s can not be used in csg %s, it is not a closed solid.", cc.Shapes[index], cc.Name))
+		}
+		cc.children = append(cc.children, solid)
+	}
+	return nil
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (cc *CSGConfig) NewShape() (common.Traceable, error) {
+	if len(cc.children) < 2 {
+		return nil, errors.New(fmt.Sprintf("csg %s needs at least 2 shapes.", cc.Name))
+	}
+	return &CSG{
+		Name:      cc.Name,
+		Operation: cc.Operation,
+		Children:  cc.children,
+		Material:  cc.Material,
+	}, nil
+}
+
+// FromYaml generates Config from input yaml, shapes names the shapes to
+// combine in order so a difference removes every later shape from the first
+// satisfies the interface ShapesConfig (2/2)
+func (cc *CSGConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	operation, err := config.Get("operation").String()
+	if err != nil {
+		return errors.New("csg requires an operation")
+	}
+	switch operation {
+	case CSGUnion, CSGIntersection, CSGDifference:
+		cc.Operation = operation
+	default:
+		return errors.New(fmt.Sprintf("csg operation %s is not one of union, intersection or difference.", operation))
+	}
+
+	names, err := config.Get("shapes").Array()
+	if err != nil || len(names) < 2 {
+		return errors.New("csg requires 2 or more shapes")
+	}
+	for _, name := range names {
+		n, ok := name.(string)
+		if !ok {
+			return errors.New(fmt.Sprintf("csg shape %v is not a name", name))
+		}
+		cc.Shapes = append(cc.Shapes, n)
+	}
+
+	m, err := materialFromYaml(config, materials)
+	if err != nil {
+		return err
+	}
+	cc.Material = m
+
+	return nil
+}
+
+// CSG is the union, intersection or difference of its Children, when
+// Material is set it is used instead of the material of each child
+type CSG struct {
+	Name              string
+	Operation         string
+	Children          []Solid
+	PlaceHit          vmath.Vector3d
+	intersectionRatio float64
+	// hit is the crossing of the last successful Intersect
+	hit Crossing
+
+	Material material.Material
+}
+
+// Intervals satisfies the Solid interface, the crossings returned belong to
+// the primitive children so nested CSG keeps the surface that was hit
+func (c *CSG) Intervals(ray *vmath.Ray) []Interval {
+	intervals := c.Children[0].Intervals(ray)
+	for _, child := range c.Children[1:] {
+		switch c.Operation {
+		case CSGUnion:
+			intervals = unionIntervals(intervals, child.Intervals(ray))
+		case CSGIntersection:
+			intervals = intersectIntervals(intervals, child.Intervals(ray))
+		case CSGDifference:
+			intervals = differenceIntervals(intervals, child.Intervals(ray))
+		}
+	}
+	return intervals
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene
+func (c *CSG) Intersect(ray *vmath.Ray) bool {
+	for _, interval := range c.Intervals(ray) {
+		crossing := interval.In
+		if crossing.T <= 0 {
+			// started inside, so the way out is the visible surface
+			crossing = interval.Out
+		}
+		if crossing.T > 0 {
+			c.hit = crossing
+			c.intersectionRatio = crossing.T
+			c.PlaceHit = ray.Origin.Add(ray.Direction.SMultiply(crossing.T))
+			return true
+		}
+	}
+	return false
+}
+
+// GetPosition satisfies requirements for Object
+// interface for a scene
+func (c *CSG) GetPosition() vmath.Vector3d {
+	if object, ok := c.Children[0].(common.Object); ok {
+		return object.GetPosition()
+	}
+	return vmath.Vector3d{}
+}
+
+func (c *CSG) GetName() string {
+	return c.Name
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (c *CSG) GetType() string {
+	return "csg"
+}
+
+// GetIntersectionRatio
+func (c *CSG) GetIntersectionRatio() float64 {
+	return c.intersectionRatio
+}
+
+// CalculateNorm returns the normal of the child surface found by the last
+// Intersect, reversed where the surface is the wall of a subtracted shape
+func (c *CSG) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	norm := c.hit.Solid.CalculateNorm(hit)
+	if c.hit.Flip {
+		return norm.UNegate()
+	}
+	return norm
+}
+
+// CalculateFrame returns the frame of the child surface found by the last
+// Intersect
+func (c *CSG) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	if framed, ok := c.hit.Solid.(Framed); ok {
+		return framed.CalculateFrame(hit)
+	}
+	return vmath.Vector3d{}, vmath.Vector3d{}
+}
+
+// CalculateUV returns the UV of the child surface found by the last Intersect
+func (c *CSG) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	if uv, ok := c.hit.Solid.(interface {
+		CalculateUV(vmath.Vector3d) vmath.Vector2d
+	}); ok {
+		return uv.CalculateUV(hit)
+	}
+	return vmath.Vector2d{}
+}
+
+// GetMaterial returns the CSG material if set, otherwise the material of the
+// child hit by the last Intersect
+func (c *CSG) GetMaterial() material.Material {
+	if c.Material != nil || c.hit.Solid == nil {
+		return c.Material
+	}
+	return c.hit.Solid.GetMaterial()
+}
+
+func (c *CSG) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(c, c.PlaceHit, 0), ray, objs)
+}
+
+// unionIntervals merges two sorted interval lists into the spans inside either
+func unionIntervals(a []Interval, b []Interval) []Interval {
+	merged := append(append([]Interval{}, a...), b...)
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].In.T < merged[j].In.T
+	})
+
+	result := []Interval{}
+	for _, interval := range merged {
+		last := len(result) - 1
+		if last >= 0 && interval.In.T <= result[last].Out.T {
+			if interval.Out.T > result[last].Out.T {
+				result[last].Out = interval.Out
+			}
+			continue
+		}
+		result = append(result, interval)
+	}
+	return result
+}
+
+// intersectIntervals returns the spans inside both sorted interval lists
+func intersectIntervals(a []Interval, b []Interval) []Interval {
+	result := []Interval{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		in, out := a[i].In, a[i].Out
+		if b[j].In.T > in.T {
+			in = b[j].In
+		}
+		if b[j].Out.T < out.T {
+			out = b[j].Out
+		}
+		if in.T < out.T {
+			result = append(result, Interval{In: in, Out: out})
+		}
+		if a[i].Out.T < b[j].Out.T {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+// differenceIntervals returns the spans inside a and outside b, the walls left
+// by b face into the hole so their crossings are flipped
+func differenceIntervals(a []Interval, b []Interval) []Interval {
+	result := []Interval{}
+	for _, interval := range a {
+		in := interval.In
+		remaining := true
+		for _, cut := range b {
+			if cut.Out.T <= in.T || cut.In.T >= interval.Out.T {
+				continue
+			}
+			if cut.In.T > in.T {
+				result = append(result, Interval{In: in, Out: flipCrossing(cut.In)})
+			}
+			if cut.Out.T >= interval.Out.T {
+				remaining = false
+				break
+			}
+			in = flipCrossing(cut.Out)
+		}
+		if remaining {
+			result = append(result, Interval{In: in, Out: interval.Out})
+		}
+	}
+	return result
+}
+
+func flipCrossing(c Crossing) Crossing {
+	c.Flip = !c.Flip
+	return c
+}
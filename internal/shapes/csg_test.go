@@ -0,0 +1,249 @@
+package shapes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestCSGIntersect(t *testing.T) {
+	// two unit spheres overlapping between x = -0.5 and x = 0.5
+	left := func() Solid { return NewSphere(vmath.Vector3d{X: -0.5}, 1.0) }
+	right := func() Solid { return NewSphere(vmath.Vector3d{X: 0.5}, 1.0) }
+	alongX := &vmath.Ray{
+		Origin:    vmath.Vector3d{X: -10},
+		Direction: vmath.Vector3d{X: 1},
+	}
+
+	tests := []struct {
+		Description   string
+		CSG           *CSG
+		Ray           *vmath.Ray
+		Expected      bool
+		ExpectedRatio float64
+		ExpectedNorm  vmath.Vector3d
+		Intervals     [][2]float64
+	}{
+		{
+			Description:   "union spans both spheres",
+			CSG:           &CSG{Operation: CSGUnion, Children: []Solid{left(), right()}},
+			Ray:           alongX,
+			Expected:      true,
+			ExpectedRatio: 8.5,
+			ExpectedNorm:  vmath.Vector3d{X: -1},
+			Intervals:     [][2]float64{{8.5, 11.5}},
+		},
+		{
+			Description:   "intersection is the lens in the middle",
+			CSG:           &CSG{Operation: CSGIntersection, Children: []Solid{left(), right()}},
+			Ray:           alongX,
+			Expected:      true,
+			ExpectedRatio: 9.5,
+			ExpectedNorm:  vmath.Vector3d{X: -1},
+			Intervals:     [][2]float64{{9.5, 10.5}},
+		},
+		{
+			Description:   "difference leaves a crescent",
+			CSG:           &CSG{Operation: CSGDifference, Children: []Solid{right(), left()}},
+			Ray:           alongX,
+			Expected:      true,
+			ExpectedRatio: 10.5,
+			// the wall of the bite faces away from the removed sphere
+			ExpectedNorm: vmath.Vector3d{X: -1},
+			Intervals:    [][2]float64{{10.5, 11.5}},
+		},
+		{
+			Description: "hollow ball off center",
+			CSG: &CSG{Operation: CSGDifference, Children: []Solid{
+				NewSphere(vmath.Vector3d{}, 1.0),
+				NewSphere(vmath.Vector3d{}, 0.5),
+			}},
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{Y: 0.75, Z: -10},
+				Direction: vmath.Vector3d{Z: 1},
+			},
+			Expected:      true,
+			ExpectedRatio: 10 - 0.6614378277661477,
+			Intervals:     [][2]float64{{10 - 0.6614378277661477, 10 + 0.6614378277661477}},
+		},
+		{
+			Description: "nested difference of a union",
+			CSG: &CSG{Operation: CSGDifference, Children: []Solid{
+				&CSG{Operation: CSGUnion, Children: []Solid{left(), right()}},
+				NewBox(vmath.Vector3d{}, vmath.Vector3d{X: 1, Y: 4, Z: 4}, vmath.Vector3d{}),
+			}},
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{}, Direction: vmath.Vector3d{X: 1}},
+			Expected:      true,
+			ExpectedRatio: 0.5,
+			// looking out of the cut the wall faces back at the ray
+			ExpectedNorm: vmath.Vector3d{X: -1},
+			Intervals:    [][2]float64{{-1.5, -0.5}, {0.5, 1.5}},
+		},
+		{
+			Description: "ray starting inside hits the way out",
+			CSG:         &CSG{Operation: CSGUnion, Children: []Solid{left(), right()}},
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{},
+				Direction: vmath.Vector3d{X: 1},
+			},
+			Expected:      true,
+			ExpectedRatio: 1.5,
+			ExpectedNorm:  vmath.Vector3d{X: 1},
+			Intervals:     [][2]float64{{-1.5, 1.5}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			intervals := test.CSG.Intervals(test.Ray)
+			require.Len(t, intervals, len(test.Intervals))
+			for index, interval := range intervals {
+				assert.InDelta(t, test.Intervals[index][0], interval.In.T, 1e-9)
+				assert.InDelta(t, test.Intervals[index][1], interval.Out.T, 1e-9)
+			}
+
+			assert.Equal(t, test.Expected, test.CSG.Intersect(test.Ray))
+			if test.Expected {
+				assert.InDelta(t, test.ExpectedRatio, test.CSG.GetIntersectionRatio(), 1e-9)
+				if !test.ExpectedNorm.IsZero() {
+					norm := test.CSG.CalculateNorm(test.CSG.PlaceHit)
+					assert.InDelta(t, test.ExpectedNorm.X, norm.X, 1e-9)
+					assert.InDelta(t, test.ExpectedNorm.Y, norm.Y, 1e-9)
+					assert.InDelta(t, test.ExpectedNorm.Z, norm.Z, 1e-9)
+				}
+			}
+		})
+	}
+}
+
+func TestCSGShapesFactory(t *testing.T) {
+	tests := []struct {
+		Description   string
+		Bytes         []byte
+		ExpectedTypes []string
+		ExpectedErr   error
+	}{
+		{
+			Description: "children are only rendered through the csg",
+			Bytes: []byte(`
+ball:
+  type: sphere
+  radius: 1.0
+block:
+  type: box
+  size: [1.5, 1.5, 1.5]
+part:
+  type: csg
+  operation: intersection
+  shapes: [ball, block]
+`),
+			ExpectedTypes: []string{"csg"},
+		},
+		{
+			Description: "nested csg",
+			Bytes: []byte(`
+ball:
+  type: sphere
+  radius: 1.0
+block:
+  type: box
+  size: [1.5, 1.5, 1.5]
+ring:
+  type: torus
+  major_radius: 2.0
+  minor_radius: 0.5
+part:
+  type: csg
+  operation: intersection
+  shapes: [ball, block]
+assembly:
+  type: csg
+  operation: union
+  shapes: [part, ring]
+`),
+			ExpectedTypes: []string{"csg"},
+		},
+		{
+			Description: "missing child",
+			Bytes: []byte(`
+ball:
+  type: sphere
+  radius: 1.0
+part:
+  type: csg
+  operation: union
+  shapes: [ball, cube]
+`),
+			ExpectedErr: errors.New("shape cube does not exist in scene."),
+		},
+		{
+			Description: "child referencing itself",
+			Bytes: []byte(`
+ball:
+  type: sphere
+  radius: 1.0
+part:
+  type: csg
+  operation: union
+  shapes: [ball, part]
+`),
+			ExpectedErr: errors.New("shape part references itself."),
+		},
+		{
+			Description: "open child",
+			Bytes: []byte(`
+ball:
+  type: sphere
+  radius: 1.0
+lid:
+  type: disk
+  normal: [0, 1, 0]
+  radius: 1.0
+part:
+  type: csg
+  operation: union
+  shapes: [ball, lid]
+`),
+			ExpectedErr: errors.New("shape lid can not be used in csg part, it is not a closed solid."),
+		},
+		{
+			Description: "unknown operation",
+			Bytes: []byte(`
+part:
+  type: csg
+  operation: xor
+  shapes: [ball, lid]
+`),
+			ExpectedErr: errors.New("csg operation xor is not one of union, intersection or difference."),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			configs, err := ShapesConfigFactory(yaml, map[string]material.Material{}, nil)
+			var traceables []common.Traceable
+			if err == nil {
+				traceables, err = ShapesFactory(configs)
+			}
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			types := []string{}
+			for _, traceable := range traceables {
+				types = append(types, traceable.(common.Object).GetType())
+			}
+			assert.Equal(t, test.ExpectedTypes, types)
+		})
+	}
+}
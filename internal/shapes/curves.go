@@ -0,0 +1,493 @@
+package shapes
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// Bases the control points of a strand can be given in
+const (
+	CurveBezier  = "bezier"
+	CurveBSpline = "bspline"
+)
+
+// Modes a strand can be intersected as
+const (
+	// CurveRibbon is a flat strip always facing the ray
+	CurveRibbon = "ribbon"
+	// CurveTube is a round tube
+	CurveTube = "tube"
+)
+
+// CurvePoint is a control point of a strand and the width of the strand there
+type CurvePoint struct {
+	P     vmath.Vector3d
+	Width float64
+}
+
+// CurvesConfig defines a set of cubic strands like hair or grass for the
+// ShapeFactory
+type CurvesConfig struct {
+	Name     string
+	File     string
+	Strands  [][]CurvePoint
+	Basis    string
+	Mode     string
+	Material material.Material
+}
+
+func (cc *CurvesConfig) GetName() string {
+	return cc.Name
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (cc *CurvesConfig) NewShape() (common.Traceable, error) {
+	strands := cc.Strands
+	if cc.File != "" {
+		loaded, err := loadStrands(cc.File)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("curves %s: %s", cc.Name, err.Error()))
+		}
+		strands = append(loaded, strands...)
+	}
+	curves, err := NewCurves(strands, cc.Basis, cc.Mode)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("curves %s: %s", cc.Name, err.Error()))
+	}
+	curves.Name = cc.Name
+	curves.Material = cc.Material
+	return curves, nil
+}
+
+// FromYaml generates Config from input yaml, strands are lists of control
+// points of x, y, z and width and file holds more of them, one control point
+// per line with a blank line between strands
+// satisfies the interface ShapesConfig (2/2)
+func (cc *CurvesConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	cc.Basis = CurveBezier
+	if basis, err := config.Get("basis").String(); err == nil {
+		cc.Basis = basis
+	}
+	cc.Mode = CurveRibbon
+	if mode, err := config.Get("mode").String(); err == nil {
+		cc.Mode = mode
+	}
+	if file, err := config.Get("file").String(); err == nil {
+		cc.File = file
+	}
+
+	if config.Get("strands").IsFound() {
+		count, err := config.Get("strands").GetArraySize()
+		if err != nil {
+			return errors.New("curves strands must be a list")
+		}
+		for index := 0; index < count; index++ {
+			points := config.Get("strands").GetIndex(index)
+			size, err := points.GetArraySize()
+			if err != nil {
+				return errors.New(fmt.Sprintf("curves strand %d must be a list", index))
+			}
+			strand := []CurvePoint{}
+			for point := 0; point < size; point++ {
+				values, err := floatsFromYaml(points.GetIndex(point))
+				if err != nil || len(values) != 4 {
+					return errors.New(fmt.Sprintf("curves strand %d point %d requires x, y, z and width", index, point))
+				}
+				strand = append(strand, CurvePoint{
+					P:     vmath.Vector3d{X: values[0], Y: values[1], Z: values[2]},
+					Width: values[3],
+				})
+			}
+			cc.Strands = append(cc.Strands, strand)
+		}
+	}
+	if cc.File == "" && len(cc.Strands) == 0 {
+		return errors.New("curves requires a file or strands")
+	}
+
+	m, err := materialFromYaml(config, materials)
+	if err != nil {
+		return err
+	}
+	cc.Material = m
+
+	return nil
+}
+
+// loadStrands reads the strands of the file at path, see readStrands
+func loadStrands(path string) ([][]CurvePoint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readStrands(file)
+}
+
+// readStrands reads a control point of x, y, z and width from every line of
+// r, blank lines end a strand and lines starting with # are skipped
+func readStrands(r io.Reader) ([][]CurvePoint, error) {
+	strands := [][]CurvePoint{}
+	strand := []CurvePoint{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+		if text == "" {
+			if len(strand) > 0 {
+				strands = append(strands, strand)
+				strand = []CurvePoint{}
+			}
+			continue
+		}
+		values, err := parseFloats(strings.Fields(text), 4)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("curves line %d: %s", line, err.Error()))
+		}
+		strand = append(strand, CurvePoint{
+			P:     vmath.Vector3d{X: values[0], Y: values[1], Z: values[2]},
+			Width: values[3],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(strand) > 0 {
+		strands = append(strands, strand)
+	}
+	return strands, nil
+}
+
+// bezierSegments splits strand into the control points of its cubic Bezier
+// segments, a uniform B-spline is converted to the Bezier segments tracing
+// the same curve
+func bezierSegments(strand []CurvePoint, basis string) ([][4]CurvePoint, error) {
+	segments := [][4]CurvePoint{}
+	switch basis {
+	case CurveBezier:
+		if len(strand) < 4 || (len(strand)-1)%3 != 0 {
+			return nil, errors.New("needs 3n+1 points for a bezier basis")
+		}
+		for start := 0; start+3 < len(strand); start += 3 {
+			segments = append(segments, [4]CurvePoint{strand[start], strand[start+1], strand[start+2], strand[start+3]})
+		}
+	case CurveBSpline:
+		if len(strand) < 4 {
+			return nil, errors.New("needs at least 4 points for a bspline basis")
+		}
+		mix := func(a, b, c CurvePoint, wa, wb, wc float64) CurvePoint {
+			return CurvePoint{
+				P:     a.P.SMultiply(wa).Add(b.P.SMultiply(wb)).Add(c.P.SMultiply(wc)),
+				Width: a.Width*wa + b.Width*wb + c.Width*wc,
+			}
+		}
+		for start := 0; start+3 < len(strand); start++ {
+			p0, p1, p2, p3 := strand[start], strand[start+1], strand[start+2], strand[start+3]
+			segments = append(segments, [4]CurvePoint{
+				mix(p0, p1, p2, 1.0/6.0, 4.0/6.0, 1.0/6.0),
+				mix(p1, p2, p2, 2.0/3.0, 1.0/3.0, 0),
+				mix(p1, p2, p2, 1.0/3.0, 2.0/3.0, 0),
+				mix(p1, p2, p3, 1.0/6.0, 4.0/6.0, 1.0/6.0),
+			})
+		}
+	default:
+		return nil, errors.New(fmt.Sprintf("basis %s is not supported", basis))
+	}
+	return segments, nil
+}
+
+// evaluateSegment returns the point, width and tangent of a Bezier segment
+// at t
+func evaluateSegment(segment [4]CurvePoint, t float64) (vmath.Vector3d, float64, vmath.Vector3d) {
+	b, d := bernstein(t)
+	point := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	tangent := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	width := 0.0
+	for index, control := range segment {
+		point = point.Add(control.P.SMultiply(b[index]))
+		tangent = tangent.Add(control.P.SMultiply(d[index]))
+		width += control.Width * b[index]
+	}
+	return point, width, tangent
+}
+
+// curvePiece is a straight part of a strand between two points of it, V0 and
+// V1 are how far along the strand the ends are
+type curvePiece struct {
+	A, B               vmath.Vector3d
+	WidthA, WidthB     float64
+	TangentA, TangentB vmath.Vector3d
+	V0, V1             float64
+	Strand             int
+}
+
+// Curves is a set of strands, each split into straight pieces short enough
+// to follow the curve to within a small part of its width and found through
+// a bvh
+type Curves struct {
+	Name              string
+	Mode              string
+	Strands           int
+	PlaceHit          vmath.Vector3d
+	intersectionRatio float64
+
+	pieces []curvePiece
+	bvh    *bvh
+	// the shading of the last hit, strands are too thin to find it again
+	// from the hit alone
+	hitNorm, hitTangent vmath.Vector3d
+	hitUV               vmath.Vector2d
+
+	Material material.Material
+}
+
+// NewCurves splits every strand in basis into pieces that are intersected as
+// mode
+func NewCurves(strands [][]CurvePoint, basis string, mode string) (*Curves, error) {
+	if len(strands) == 0 {
+		return nil, errors.New("curves requires 1 or more strands")
+	}
+	if mode != CurveRibbon && mode != CurveTube {
+		return nil, errors.New(fmt.Sprintf("curves mode %s is not supported", mode))
+	}
+
+	c := &Curves{Mode: mode, Strands: len(strands)}
+	boxes := []aabb{}
+	for index, strand := range strands {
+		for _, point := range strand {
+			if point.Width < 0 {
+				return nil, errors.New(fmt.Sprintf("curves strand %d width can not be negative", index))
+			}
+		}
+		segments, err := bezierSegments(strand, basis)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("curves strand %d %s", index, err.Error()))
+		}
+		for number, segment := range segments {
+			count := segmentPieces(segment)
+			for piece := 0; piece < count; piece++ {
+				t0, t1 := float64(piece)/float64(count), float64(piece+1)/float64(count)
+				a, wa, ta := evaluateSegment(segment, t0)
+				b, wb, tb := evaluateSegment(segment, t1)
+				ta.Normalize()
+				tb.Normalize()
+				c.pieces = append(c.pieces, curvePiece{
+					A: a, B: b,
+					WidthA: wa, WidthB: wb,
+					TangentA: ta, TangentB: tb,
+					V0:     (float64(number) + t0) / float64(len(segments)),
+					V1:     (float64(number) + t1) / float64(len(segments)),
+					Strand: index,
+				})
+				radius := math.Max(wa, wb) / 2
+				pad := vmath.Vector3d{X: radius, Y: radius, Z: radius}
+				boxes = append(boxes, emptyBox().
+					grow(a.Subtract(pad)).grow(a.Add(pad)).
+					grow(b.Subtract(pad)).grow(b.Add(pad)))
+			}
+		}
+	}
+	c.bvh = newBVH(boxes)
+	return c, nil
+}
+
+// segmentPieces is how many straight pieces segment is split into so they
+// stay within a twentieth of its narrowest width of the curve, using the
+// bound on the distance of a cubic from its chords by its second differences
+func segmentPieces(segment [4]CurvePoint) int {
+	bend := 0.0
+	width := math.Inf(1)
+	for index := range segment {
+		width = math.Min(width, segment[index].Width)
+		if index < 2 {
+			second := segment[index].P.Subtract(segment[index+1].P.SMultiply(2)).Add(segment[index+2].P)
+			bend = math.Max(bend, second.Norm())
+		}
+	}
+	tolerance := math.Max(width*0.05, 1e-4)
+	pieces := int(math.Ceil(math.Sqrt(0.75 * bend / tolerance)))
+	return int(math.Min(math.Max(float64(pieces), 1), 64))
+}
+
+// closest returns the ratio along ray and the fraction along the piece of the
+// points where they pass closest to each other
+func (p *curvePiece) closest(ray *vmath.Ray) (float64, float64) {
+	axis := p.B.Subtract(p.A)
+	offset := ray.Origin.Subtract(p.A)
+	a, b, e := ray.Direction.Dot(ray.Direction), ray.Direction.Dot(axis), axis.Dot(axis)
+	c, f := ray.Direction.Dot(offset), axis.Dot(offset)
+
+	s := 0.0
+	if denom := a*e - b*b; denom > 1e-12*a*e {
+		s = math.Min(math.Max((a*f-b*c)/denom, 0), 1)
+	}
+	t := (b*s - c) / a
+	if e > 0 {
+		s = math.Min(math.Max((b*t+f)/e, 0), 1)
+		t = (b*s - c) / a
+	}
+	return t, s
+}
+
+// intersectRibbon finds where ray crosses the strip of the piece facing it
+func (p *curvePiece) intersectRibbon(ray *vmath.Ray) (float64, float64, bool) {
+	t, s := p.closest(ray)
+	if t <= 1e-9 {
+		return 0, 0, false
+	}
+	axisPoint := p.A.Add(p.B.Subtract(p.A).SMultiply(s))
+	distance := ray.Origin.Add(ray.Direction.SMultiply(t)).Subtract(axisPoint).Norm()
+	width := p.WidthA + (p.WidthB-p.WidthA)*s
+	return t, s, distance <= width/2
+}
+
+// intersectTube finds where ray enters the round tube around the piece, the
+// tube has the width of the piece where the ray passes closest to it and the
+// ends are rounded by spheres so bends have no gaps
+func (p *curvePiece) intersectTube(ray *vmath.Ray) (float64, float64, bool) {
+	_, near := p.closest(ray)
+	radius := (p.WidthA + (p.WidthB-p.WidthA)*near) / 2
+	nearest, fraction, found := math.Inf(1), 0.0, false
+
+	axis := p.B.Subtract(p.A)
+	length := axis.Norm()
+	if length > 0 {
+		direction := axis.Divide(length)
+		offset := ray.Origin.Subtract(p.A)
+		d := ray.Direction.Subtract(direction.SMultiply(ray.Direction.Dot(direction)))
+		o := offset.Subtract(direction.SMultiply(offset.Dot(direction)))
+		for _, t := range vmath.SolveQuadratic(d.Dot(d), 2*o.Dot(d), o.Dot(o)-radius*radius) {
+			s := ray.Origin.Add(ray.Direction.SMultiply(t)).Subtract(p.A).Dot(direction) / length
+			if t > 1e-9 && s >= 0 && s <= 1 {
+				nearest, fraction, found = t, s, true
+				break
+			}
+		}
+	}
+	for end, center := range []vmath.Vector3d{p.A, p.B} {
+		r := p.WidthA / 2
+		if end == 1 {
+			r = p.WidthB / 2
+		}
+		offset := ray.Origin.Subtract(center)
+		a := ray.Direction.Dot(ray.Direction)
+		for _, t := range vmath.SolveQuadratic(a, 2*offset.Dot(ray.Direction), offset.Dot(offset)-r*r) {
+			if t > 1e-9 {
+				if t < nearest {
+					nearest, fraction, found = t, float64(end), true
+				}
+				break
+			}
+		}
+	}
+	return nearest, fraction, found
+}
+
+// intersectPiece intersects ray with a piece as a ribbon or a tube by Mode
+func (c *Curves) intersectPiece(ray *vmath.Ray, index int) (float64, float64, bool) {
+	if c.Mode == CurveTube {
+		return c.pieces[index].intersectTube(ray)
+	}
+	return c.pieces[index].intersectRibbon(ray)
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene
+func (c *Curves) Intersect(ray *vmath.Ray) bool {
+	index, t, ok := c.bvh.intersect(ray, func(index int) (float64, bool) {
+		t, _, hit := c.intersectPiece(ray, index)
+		return t, hit
+	})
+	if !ok {
+		return false
+	}
+
+	piece := &c.pieces[index]
+	_, s, _ := c.intersectPiece(ray, index)
+	c.intersectionRatio = t
+	c.PlaceHit = ray.Origin.Add(ray.Direction.SMultiply(t))
+	c.hitTangent = piece.TangentA.SMultiply(1 - s).Add(piece.TangentB.SMultiply(s))
+	c.hitTangent.Normalize()
+	c.hitUV = vmath.Vector2d{
+		X: (float64(piece.Strand) + 0.5) / float64(c.Strands),
+		Y: piece.V0 + (piece.V1-piece.V0)*s,
+	}
+
+	// a ribbon faces back along the ray and a tube points away from its axis,
+	// either way without the part along the strand
+	c.hitNorm = ray.Direction.UNegate()
+	if c.Mode == CurveTube {
+		c.hitNorm = c.PlaceHit.Subtract(piece.A.Add(piece.B.Subtract(piece.A).SMultiply(s)))
+	}
+	c.hitNorm = c.hitNorm.Subtract(c.hitTangent.SMultiply(c.hitNorm.Dot(c.hitTangent)))
+	if c.hitNorm.Normalize() != nil {
+		c.hitNorm = ray.Direction.UNegate()
+		c.hitNorm.Normalize()
+	}
+	return true
+}
+
+// GetPosition satisfies requirements for Object
+// interface for a scene
+func (c *Curves) GetPosition() vmath.Vector3d {
+	return c.bvh.nodes[0].Box.center()
+}
+
+func (c *Curves) GetName() string {
+	return c.Name
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (c *Curves) GetType() string {
+	return "curves"
+}
+
+// GetIntersectionRatio
+func (c *Curves) GetIntersectionRatio() float64 {
+	return c.intersectionRatio
+}
+
+// CalculateNorm returns the normal at the last hit, hit is expected to be
+// that hit
+func (c *Curves) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	return c.hitNorm
+}
+
+// CalculateFrame returns the direction along the strand at the last hit,
+// which hair is shaded about, and the direction across it
+func (c *Curves) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	return c.hitTangent, c.hitNorm.Cross(c.hitTangent)
+}
+
+// CalculateUV returns where across the strands and how far along its strand
+// the last hit is
+func (c *Curves) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	return c.hitUV
+}
+
+// GetMaterial returns the material the curves are shaded with
+func (c *Curves) GetMaterial() material.Material {
+	return c.Material
+}
+
+// ReturnColor shades the last hit
+func (c *Curves) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(c, c.PlaceHit, 0), ray, objs)
+}
@@ -0,0 +1,281 @@
+package shapes
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/lights"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestReadStrands(t *testing.T) {
+	strands, err := readStrands(strings.NewReader(`
+# two strands
+0 0 0 0.1
+0 1 0 0.1
+
+1 0 0 0.2
+1 1 0 0.1
+1 2 0 0.0
+`))
+	require.NoError(t, err)
+	assert.Equal(t, [][]CurvePoint{
+		{
+			{P: vmath.Vector3d{X: 0, Y: 0, Z: 0}, Width: 0.1},
+			{P: vmath.Vector3d{X: 0, Y: 1, Z: 0}, Width: 0.1},
+		},
+		{
+			{P: vmath.Vector3d{X: 1, Y: 0, Z: 0}, Width: 0.2},
+			{P: vmath.Vector3d{X: 1, Y: 1, Z: 0}, Width: 0.1},
+			{P: vmath.Vector3d{X: 1, Y: 2, Z: 0}, Width: 0.0},
+		},
+	}, strands)
+
+	_, err = readStrands(strings.NewReader("0 0 0\n"))
+	assert.Equal(t, errors.New("curves line 1: requires 4 values"), err)
+}
+
+func TestBezierSegments(t *testing.T) {
+	// evenly spaced points along y stay on y in both bases
+	line := []CurvePoint{}
+	for index := 0; index < 7; index++ {
+		line = append(line, CurvePoint{P: vmath.Vector3d{Y: float64(index)}, Width: 1})
+	}
+
+	segments, err := bezierSegments(line, CurveBezier)
+	require.NoError(t, err)
+	assert.Len(t, segments, 2)
+
+	segments, err = bezierSegments(line, CurveBSpline)
+	require.NoError(t, err)
+	require.Len(t, segments, 4)
+	// a uniform B-spline of evenly spaced points starts one point in
+	assert.Equal(t, vmath.Vector3d{Y: 1}, segments[0][0].P)
+	assert.InDelta(t, 5, segments[3][3].P.Y, 1e-9)
+	assert.InDelta(t, 1, segments[1][2].Width, 1e-9)
+	for _, segment := range segments {
+		for _, point := range segment {
+			assert.Equal(t, 0.0, point.P.X)
+		}
+	}
+
+	_, err = bezierSegments(line[:5], CurveBezier)
+	assert.Equal(t, errors.New("needs 3n+1 points for a bezier basis"), err)
+	_, err = bezierSegments(line[:3], CurveBSpline)
+	assert.Equal(t, errors.New("needs at least 4 points for a bspline basis"), err)
+	_, err = bezierSegments(line, "linear")
+	assert.Equal(t, errors.New("basis linear is not supported"), err)
+}
+
+func TestCurvesIntersect(t *testing.T) {
+	// a strand bending from straight up at the origin towards +x
+	strand := []CurvePoint{
+		{P: vmath.Vector3d{X: 0, Y: 0, Z: 0}, Width: 0.4},
+		{P: vmath.Vector3d{X: 0, Y: 1, Z: 0}, Width: 0.4},
+		{P: vmath.Vector3d{X: 0, Y: 2, Z: 0}, Width: 0.4},
+		{P: vmath.Vector3d{X: 1, Y: 3, Z: 0}, Width: 0.4},
+	}
+
+	tests := []struct {
+		Description   string
+		Mode          string
+		Ray           *vmath.Ray
+		Expected      bool
+		ExpectedRatio float64
+		ExpectedNorm  vmath.Vector3d
+	}{
+		{
+			Description:   "ribbon faces the ray",
+			Mode:          CurveRibbon,
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{X: 0.1, Y: 0.2, Z: 5}, Direction: vmath.Vector3d{Z: -1}},
+			Expected:      true,
+			ExpectedRatio: 5,
+			ExpectedNorm:  vmath.Vector3d{Z: 1},
+		},
+		{
+			Description: "ribbon missed beside",
+			Mode:        CurveRibbon,
+			Ray:         &vmath.Ray{Origin: vmath.Vector3d{X: 0.3, Y: 0.2, Z: 5}, Direction: vmath.Vector3d{Z: -1}},
+		},
+		{
+			Description:   "tube hit in the middle",
+			Mode:          CurveTube,
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{X: 0, Y: 0.2, Z: 5}, Direction: vmath.Vector3d{Z: -1}},
+			Expected:      true,
+			ExpectedRatio: 4.8,
+			ExpectedNorm:  vmath.Vector3d{Z: 1},
+		},
+		{
+			Description:   "tube hit on its side",
+			Mode:          CurveTube,
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{X: 5, Y: 0.2, Z: 0}, Direction: vmath.Vector3d{X: -2}},
+			Expected:      true,
+			ExpectedRatio: 2.4,
+			ExpectedNorm:  vmath.Vector3d{X: 1},
+		},
+		{
+			Description: "tube missed above its end",
+			Mode:        CurveTube,
+			Ray:         &vmath.Ray{Origin: vmath.Vector3d{X: 1, Y: 3.5, Z: 5}, Direction: vmath.Vector3d{Z: -1}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			curves, err := NewCurves([][]CurvePoint{strand}, CurveBezier, test.Mode)
+			require.NoError(t, err)
+			require.Equal(t, test.Expected, curves.Intersect(test.Ray))
+			if !test.Expected {
+				return
+			}
+			assert.InDelta(t, test.ExpectedRatio, curves.GetIntersectionRatio(), 1e-3)
+			norm := curves.CalculateNorm(curves.PlaceHit)
+			assert.InDelta(t, test.ExpectedNorm.X, norm.X, 2e-2)
+			assert.InDelta(t, test.ExpectedNorm.Y, norm.Y, 2e-2)
+			assert.InDelta(t, test.ExpectedNorm.Z, norm.Z, 2e-2)
+
+			// near the root the strand still runs straight up
+			tangent, _ := curves.CalculateFrame(curves.PlaceHit)
+			assert.InDelta(t, 1, tangent.Y, 1e-2)
+			uv := curves.CalculateUV(curves.PlaceHit)
+			assert.InDelta(t, 0.5, uv.X, 1e-9)
+			assert.InDelta(t, 0.07, uv.Y, 0.03)
+		})
+	}
+}
+
+func TestCurvesReturnColorPlaced(t *testing.T) {
+	newStrand := func() *Curves {
+		curves, err := NewCurves([][]CurvePoint{{
+			{P: vmath.Vector3d{X: 0, Y: 0, Z: 0}, Width: 0.4},
+			{P: vmath.Vector3d{X: 0, Y: 1, Z: 0}, Width: 0.4},
+			{P: vmath.Vector3d{X: 0, Y: 2, Z: 0}, Width: 0.4},
+			{P: vmath.Vector3d{X: 0, Y: 3, Z: 0}, Width: 0.4},
+		}}, CurveBezier, CurveTube)
+		require.NoError(t, err)
+		curves.Material = &material.Hair{
+			Ambient:   &color.ColorValue{Color: vmath.Vector3d{}},
+			Diffuse:   &color.ColorValue{Color: vmath.Vector3d{X: 100.0, Y: 100.0, Z: 100.0}},
+			Specular:  &color.ColorValue{Color: vmath.Vector3d{X: 100.0, Y: 100.0, Z: 100.0}},
+			Shininess: 10.0,
+		}
+		return curves
+	}
+	// the light comes down along the strand at a slant, a strand shaded
+	// without its tangent would get the whole of its specular
+	objs := &common.RenderableObjects{Lights: []lights.Light{&lights.DirectionalLight{
+		V:         vmath.Vector3d{Y: -1.0, Z: -1.0},
+		Intensity: 1.0,
+		Color:     &color.ColorValue{Color: vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}},
+	}}}
+	ray := &vmath.Ray{Origin: vmath.Vector3d{Y: 1.5, Z: 5}, Direction: vmath.Vector3d{Z: -1}}
+
+	bare := newStrand()
+	require.True(t, bare.Intersect(ray))
+	expected := bare.ReturnColor(ray, objs).GetColor(0, 0)
+
+	transformed, err := NewTransformed(newStrand(), vmath.Translate(vmath.Vector3d{X: 2}))
+	require.NoError(t, err)
+	for _, test := range []struct {
+		Description string
+		Shape       Surface
+		Ray         *vmath.Ray
+	}{
+		{
+			Description: "strand in a group",
+			Shape:       &Group{Children: []Surface{newStrand()}},
+			Ray:         ray,
+		},
+		{
+			Description: "moved strand",
+			Shape:       transformed,
+			Ray:         &vmath.Ray{Origin: vmath.Vector3d{X: 2, Y: 1.5, Z: 5}, Direction: vmath.Vector3d{Z: -1}},
+		},
+	} {
+		t.Run(test.Description, func(t *testing.T) {
+			require.True(t, test.Shape.Intersect(test.Ray))
+			hit := test.Ray.Origin.Add(test.Ray.Direction.SMultiply(test.Shape.GetIntersectionRatio()))
+			tangent, _ := test.Shape.(Framed).CalculateFrame(hit)
+			assert.InDelta(t, 1, math.Abs(tangent.Y), 1e-2)
+			c := test.Shape.ReturnColor(test.Ray, objs).GetColor(0, 0)
+			assert.InDelta(t, expected.X, c.X, 1e-6)
+			assert.InDelta(t, expected.Y, c.Y, 1e-6)
+			assert.InDelta(t, expected.Z, c.Z, 1e-6)
+		})
+	}
+}
+
+func TestNewCurvesErrors(t *testing.T) {
+	strand := []CurvePoint{{Width: 1}, {Width: 1}, {Width: 1}, {Width: -1}}
+	_, err := NewCurves(nil, CurveBezier, CurveRibbon)
+	assert.Equal(t, errors.New("curves requires 1 or more strands"), err)
+	_, err = NewCurves([][]CurvePoint{strand}, CurveBezier, "flat")
+	assert.Equal(t, errors.New("curves mode flat is not supported"), err)
+	_, err = NewCurves([][]CurvePoint{strand}, CurveBezier, CurveTube)
+	assert.Equal(t, errors.New("curves strand 0 width can not be negative"), err)
+	_, err = NewCurves([][]CurvePoint{strand[:3]}, CurveBezier, CurveTube)
+	assert.Equal(t, errors.New("curves strand 0 needs 3n+1 points for a bezier basis"), err)
+}
+
+func TestCurvesConfigFromYaml(t *testing.T) {
+	tests := []struct {
+		Description   string
+		Yaml          string
+		Expected      *CurvesConfig
+		ExpectedError error
+	}{
+		{
+			Description: "strands with defaults",
+			Yaml:        "strands:\n  - [[0, 0, 0, 1], [0, 1, 0, 1], [0, 2, 0, 1], [0, 3, 0, 0.5]]\n",
+			Expected: &CurvesConfig{
+				Basis: CurveBezier,
+				Mode:  CurveRibbon,
+				Strands: [][]CurvePoint{{
+					{P: vmath.Vector3d{X: 0, Y: 0, Z: 0}, Width: 1},
+					{P: vmath.Vector3d{X: 0, Y: 1, Z: 0}, Width: 1},
+					{P: vmath.Vector3d{X: 0, Y: 2, Z: 0}, Width: 1},
+					{P: vmath.Vector3d{X: 0, Y: 3, Z: 0}, Width: 0.5},
+				}},
+			},
+		},
+		{
+			Description: "file as bspline tubes",
+			Yaml:        "file: hair.txt\nbasis: bspline\nmode: tube\n",
+			Expected:    &CurvesConfig{File: "hair.txt", Basis: CurveBSpline, Mode: CurveTube},
+		},
+		{
+			Description:   "nothing to render",
+			Yaml:          "mode: tube\n",
+			ExpectedError: errors.New("curves requires a file or strands"),
+		},
+		{
+			Description:   "point without width",
+			Yaml:          "strands:\n  - [[0, 0, 0]]\n",
+			ExpectedError: errors.New("curves strand 0 point 0 requires x, y, z and width"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml([]byte(test.Yaml))
+			require.NoError(t, err)
+			config := &CurvesConfig{}
+			err = config.FromYaml(yaml, map[string]material.Material{})
+			if test.ExpectedError != nil {
+				assert.Equal(t, test.ExpectedError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, config)
+		})
+	}
+}
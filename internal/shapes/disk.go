@@ -0,0 +1,139 @@
+package shapes
+
+import (
+	"errors"
+	"math"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// DiskConfig defines a disk for the ShapeFactory
+type DiskConfig struct {
+	Name        string
+	Position    vmath.Vector3d
+	Normal      vmath.Vector3d
+	Radius      float64
+	InnerRadius float64
+	Material    material.Material
+}
+
+func (dc *DiskConfig) GetName() string {
+	return dc.Name
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (dc *DiskConfig) NewShape() (common.Traceable, error) {
+	if dc.Normal.IsZero() {
+		return nil, errors.New("disk normal can not be zero")
+	}
+	if dc.InnerRadius < 0 || dc.InnerRadius >= dc.Radius {
+		return nil, errors.New("disk inner_radius must be between 0 and radius")
+	}
+	disk := NewDisk(dc.Position, dc.Normal, dc.Radius, dc.InnerRadius)
+	disk.Name = dc.Name
+	disk.Material = dc.Material
+	return disk, nil
+}
+
+// FromYaml generates Config from input yaml
+// satisfies the interface ShapesConfig (2/2)
+func (dc *DiskConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	if config.Get("position").IsFound() {
+		position, err := vector3dFromYaml(config.Get("position"))
+		if err != nil {
+			return errors.New("disk position: " + err.Error())
+		}
+		dc.Position = position
+	}
+	if config.Get("normal").IsFound() {
+		normal, err := vector3dFromYaml(config.Get("normal"))
+		if err != nil {
+			return errors.New("disk normal: " + err.Error())
+		}
+		dc.Normal = normal
+	}
+	if radius, err := config.Get("radius").Float(); err == nil {
+		dc.Radius = radius
+	}
+	if innerRadius, err := config.Get("inner_radius").Float(); err == nil {
+		dc.InnerRadius = innerRadius
+	}
+
+	m, err := materialFromYaml(config, materials)
+	if err != nil {
+		return err
+	}
+	dc.Material = m
+
+	return nil
+}
+
+// Disk is a Plane bounded by radius around P, an InnerRadius above zero cuts a
+// hole in the middle to make an annulus
+type Disk struct {
+	*Plane
+	Radius, InnerRadius float64
+}
+
+// NewDisk makes a disk centered on pos facing normal
+func NewDisk(pos vmath.Vector3d, normal vmath.Vector3d, radius float64, innerRadius float64) *Disk {
+	return &Disk{
+		Plane:       NewPlane(pos, normal),
+		Radius:      radius,
+		InnerRadius: innerRadius,
+	}
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene
+func (d *Disk) Intersect(ray *vmath.Ray) bool {
+	if !d.Plane.Intersect(ray) {
+		return false
+	}
+
+	distance := d.PlaceHit.Subtract(d.P).Norm()
+	return distance <= d.Radius && distance >= d.InnerRadius
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (d *Disk) GetType() string {
+	return "disk"
+}
+
+// CalculateNorm returns the normal the disk was built with
+func (d *Disk) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	return d.axis[2]
+}
+
+// CalculateUV maps the angle around the disk to u and the distance from the
+// inner to the outer radius to v
+func (d *Disk) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	local := hit.Subtract(d.P)
+	phi := math.Atan2(d.axis[1].Dot(local), d.axis[0].Dot(local))
+	if phi < 0 {
+		phi += 2 * math.Pi
+	}
+	return vmath.Vector2d{
+		X: phi / (2 * math.Pi),
+		Y: (local.Norm() - d.InnerRadius) / (d.Radius - d.InnerRadius),
+	}
+}
+
+// CalculateFrame returns the direction around the disk and out from its
+// center, the directions u and v increase in
+func (d *Disk) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	local := hit.Subtract(d.P)
+	x, y := d.axis[0].Dot(local), d.axis[1].Dot(local)
+	return d.axis[1].SMultiply(x).Subtract(d.axis[0].SMultiply(y)), local
+}
+
+func (d *Disk) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(d, d.PlaceHit, 0), ray, objs)
+}
@@ -0,0 +1,101 @@
+package shapes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestDiskConfigNewShape(t *testing.T) {
+	tests := []struct {
+		Description string
+		Bytes       []byte
+		ExpectedErr error
+	}{
+		{
+			Description: "annulus",
+			Bytes: []byte(`
+    position: [0.0, 0.0, 0.0]
+    normal: [0.0, 1.0, 0.0]
+    radius: 2.0
+    inner_radius: 1.0
+`),
+		},
+		{
+			Description: "missing normal",
+			Bytes: []byte(`
+    radius: 2.0
+`),
+			ExpectedErr: errors.New("disk normal can not be zero"),
+		},
+		{
+			Description: "inner radius larger than radius",
+			Bytes: []byte(`
+    normal: [0.0, 1.0, 0.0]
+    radius: 2.0
+    inner_radius: 3.0
+`),
+			ExpectedErr: errors.New("disk inner_radius must be between 0 and radius"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			config := &DiskConfig{}
+			require.NoError(t, config.FromYaml(yaml, map[string]material.Material{}))
+			_, err = config.NewShape()
+			assert.Equal(t, test.ExpectedErr, err)
+		})
+	}
+}
+
+func TestDiskIntersect(t *testing.T) {
+	tests := []struct {
+		Description   string
+		Expected      bool
+		ExpectedRatio float64
+		ExpectedUV    vmath.Vector2d
+		Origin        vmath.Vector3d
+	}{
+		{
+			Description:   "hits ring",
+			Expected:      true,
+			ExpectedRatio: 5.0,
+			ExpectedUV:    vmath.Vector2d{X: 0.0, Y: 0.5},
+			Origin:        vmath.Vector3d{X: 0.0, Y: 1.5, Z: 5.0},
+		},
+		{
+			Description: "misses through the hole",
+			Expected:    false,
+			Origin:      vmath.Vector3d{X: 0.0, Y: 0.5, Z: 5.0},
+		},
+		{
+			Description: "misses outside radius",
+			Expected:    false,
+			Origin:      vmath.Vector3d{X: 0.0, Y: 2.5, Z: 5.0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			disk := NewDisk(vmath.Vector3d{}, vmath.Vector3d{X: 0, Y: 0, Z: 1}, 2.0, 1.0)
+			ray := &vmath.Ray{Origin: test.Origin, Direction: vmath.Vector3d{X: 0, Y: 0, Z: -1}}
+			assert.Equal(t, test.Expected, disk.Intersect(ray))
+			if test.Expected {
+				assert.InDelta(t, test.ExpectedRatio, disk.GetIntersectionRatio(), 1e-9)
+				assert.Equal(t, vmath.Vector3d{X: 0, Y: 0, Z: 1}, disk.CalculateNorm(disk.PlaceHit))
+				uv := disk.CalculateUV(disk.PlaceHit)
+				assert.InDelta(t, test.ExpectedUV.Y, uv.Y, 1e-9)
+				assert.True(t, uv.X >= 0 && uv.X < 1)
+			}
+		})
+	}
+}
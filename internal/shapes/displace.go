@@ -0,0 +1,508 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// maxDisplaceLevel caps how many times the triangles of a displaced shape
+// are split, every level is four times the triangles of the last
+const maxDisplaceLevel = 6
+
+// DisplacedConfig defines another shape in the scene tessellated and moved
+// along its normals by a height map for the ShapeFactory
+type DisplacedConfig struct {
+	Name      string
+	Shape     string
+	HeightMap string
+	// Scale is how far a height of 1 moves the surface
+	Scale float64
+	// Resolution is how many rows and columns of the uv of an analytic shape
+	// are made into triangles before they are split
+	Resolution int
+	// Subdivision is how many times every triangle is split in four, unless
+	// CameraName is set
+	Subdivision int
+	// CameraName names the scene camera at Camera, when set every triangle
+	// is split instead until none of its edges is longer than EdgeLength for
+	// every unit the edge is away from Camera
+	CameraName string
+	Camera     *vmath.Vector3d
+	EdgeLength float64
+	Material   material.Material
+
+	child Surface
+}
+
+func (dc *DisplacedConfig) GetName() string {
+	return dc.Name
+}
+
+// ChildNames satisfies the parentConfig interface
+func (dc *DisplacedConfig) ChildNames() []string {
+	return []string{dc.Shape}
+}
+
+// SetChildren satisfies the parentConfig interface
+func (dc *DisplacedConfig) SetChildren(children []common.Traceable) error {
+	surface, ok := children[0].(Surface)
+	if !ok {
+		return errors.New(fmt.Sprintf("shape %s can not be displaced by %s.", dc.Shape, dc.Name))
+	}
+	dc.child = surface
+	return nil
+}
+
+// NewShape generates a Shape from the config object, the result is a Mesh
+// of the displaced triangles
+// satisfies the interface ShapesConfig (1/2)
+func (dc *DisplacedConfig) NewShape() (common.Traceable, error) {
+	heights, err := color.LoadImageValue(dc.HeightMap)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("displaced %s: %s", dc.Name, err.Error()))
+	}
+	patches, err := tessellate(dc.child, dc.Resolution)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("displaced %s: shape %s %s", dc.Name, dc.Shape, err.Error()))
+	}
+
+	if dc.Camera != nil {
+		patches = refinePatches(patches, *dc.Camera, dc.EdgeLength)
+	}
+	for level := 0; dc.Camera == nil && level < dc.Subdivision; level++ {
+		patches = splitPatches(patches)
+	}
+
+	mesh, err := displace(patches, heights, dc.Scale)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("displaced %s: %s", dc.Name, err.Error()))
+	}
+	mesh.Name = dc.Name
+	mesh.Material = dc.Material
+	if mesh.Material == nil {
+		mesh.Material = dc.child.GetMaterial()
+	}
+	return mesh, nil
+}
+
+// FromYaml generates Config from input yaml, shape names the shape to
+// displace by the grayscale image height_map, material when set replaces the
+// material of the shape, camera names the scene camera the tessellation
+// follows and is found by ShapesConfigFactory
+// satisfies the interface ShapesConfig (2/2)
+func (dc *DisplacedConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	shape, err := config.Get("shape").String()
+	if err != nil {
+		return errors.New("displaced requires a shape")
+	}
+	dc.Shape = shape
+
+	dc.HeightMap, err = config.Get("height_map").String()
+	if err != nil {
+		return errors.New("displaced requires a height_map")
+	}
+
+	dc.Scale = 1.0
+	if config.Get("scale").IsFound() {
+		dc.Scale, err = floatFromYaml(config.Get("scale"))
+		if err != nil {
+			return errors.New("displaced scale is not a number")
+		}
+	}
+
+	dc.Resolution = 32
+	if config.Get("resolution").IsFound() {
+		dc.Resolution, err = config.Get("resolution").Int()
+		if err != nil || dc.Resolution < 1 {
+			return errors.New("displaced resolution must be 1 or more")
+		}
+	}
+
+	if config.Get("subdivision").IsFound() {
+		dc.Subdivision, err = config.Get("subdivision").Int()
+		if err != nil || dc.Subdivision < 0 || dc.Subdivision > maxDisplaceLevel {
+			return errors.New(fmt.Sprintf("displaced subdivision must be from 0 to %d", maxDisplaceLevel))
+		}
+	}
+
+	if config.Get("camera").IsFound() {
+		dc.CameraName, err = config.Get("camera").String()
+		if err != nil {
+			return errors.New("displaced camera must name a camera of the scene")
+		}
+		dc.EdgeLength = 0.01
+		if config.Get("edge_length").IsFound() {
+			dc.EdgeLength, err = floatFromYaml(config.Get("edge_length"))
+			if err != nil || dc.EdgeLength <= 0 {
+				return errors.New("displaced edge_length must be a positive number")
+			}
+		}
+	}
+
+	m, err := materialFromYaml(config, materials)
+	if err != nil {
+		return err
+	}
+	dc.Material = m
+
+	return nil
+}
+
+// surfacePoint is a point on a surface before it is displaced
+type surfacePoint struct {
+	P, N vmath.Vector3d
+	UV   vmath.Vector2d
+}
+
+// surfacePatch is a triangle of the parameters of a surface, at places any
+// parameters on the surface so split triangles stay on it
+type surfacePatch struct {
+	Corners [3]vmath.Vector2d
+	at      func(param vmath.Vector2d) surfacePoint
+}
+
+// tessellate covers surface in patches, analytic shapes are split into
+// resolution rows and columns of their uv
+func tessellate(surface Surface, resolution int) ([]surfacePatch, error) {
+	switch s := surface.(type) {
+	case *Sphere:
+		return gridPatches(resolution, func(uv vmath.Vector2d) surfacePoint {
+			phi, theta := (uv.X-0.5)*2*math.Pi, (uv.Y-0.5)*math.Pi
+			p := s.P.Add(s.axis[0].SMultiply(s.s[0] * math.Cos(theta) * math.Sin(phi))).
+				Add(s.axis[1].SMultiply(s.s[1] * math.Sin(theta))).
+				Add(s.axis[2].SMultiply(s.s[2] * math.Cos(theta) * math.Cos(phi)))
+			return surfacePoint{P: p, N: s.CalculateNorm(p), UV: uv}
+		}), nil
+	case *Torus:
+		return gridPatches(resolution, func(uv vmath.Vector2d) surfacePoint {
+			alpha, beta := uv.X*2*math.Pi-math.Pi, uv.Y*2*math.Pi-math.Pi
+			ring := s.MajorRadius + s.MinorRadius*math.Cos(beta)
+			p := s.P.Add(s.axis[0].SMultiply(ring * math.Cos(alpha))).
+				Add(s.axis[1].SMultiply(ring * math.Sin(alpha))).
+				Add(s.axis[2].SMultiply(s.MinorRadius * math.Sin(beta)))
+			return surfacePoint{P: p, N: s.CalculateNorm(p), UV: uv}
+		}), nil
+	case *Disk:
+		return gridPatches(resolution, func(uv vmath.Vector2d) surfacePoint {
+			phi, radius := uv.X*2*math.Pi, s.InnerRadius+uv.Y*(s.Radius-s.InnerRadius)
+			p := s.P.Add(s.axis[0].SMultiply(radius * math.Cos(phi))).
+				Add(s.axis[1].SMultiply(radius * math.Sin(phi)))
+			return surfacePoint{P: p, N: s.axis[2], UV: uv}
+		}), nil
+	case *Rectangle:
+		return gridPatches(resolution, func(uv vmath.Vector2d) surfacePoint {
+			p := s.P.Add(s.Edge1.SMultiply(uv.X)).Add(s.Edge2.SMultiply(uv.Y))
+			return surfacePoint{P: p, N: s.axis[2], UV: uv}
+		}), nil
+	case *Box:
+		patches := []surfacePatch{}
+		for face := 0; face < 3; face++ {
+			for _, side := range []float64{-1.0, 1.0} {
+				face, side := face, side
+				uAxis, vAxis := (face+1)%3, (face+2)%3
+				patches = append(patches, gridPatches(resolution, func(uv vmath.Vector2d) surfacePoint {
+					p := s.P.Add(s.axis[face].SMultiply(side * s.s[face])).
+						Add(s.axis[uAxis].SMultiply((2*uv.X - 1) * s.s[uAxis] * side)).
+						Add(s.axis[vAxis].SMultiply((2*uv.Y - 1) * s.s[vAxis]))
+					return surfacePoint{P: p, N: s.axis[face].SMultiply(side), UV: uv}
+				})...)
+			}
+		}
+		return patches, nil
+	case *Triangle:
+		return trianglePatches(s.Vertices, [3]vmath.Vector3d{s.axis[2], s.axis[2], s.axis[2]}, s.UVs), nil
+	case *Bezier:
+		patches := []surfacePatch{}
+		for index := range s.Patches {
+			patch := &s.Patches[index]
+			patches = append(patches, gridPatches(resolution, func(uv vmath.Vector2d) surfacePoint {
+				p, _, _ := patch.evaluate(uv.X, uv.Y)
+				norm, _ := patch.normal(uv.X, uv.Y)
+				return surfacePoint{P: p, N: norm, UV: uv}
+			})...)
+		}
+		return patches, nil
+	case *Mesh:
+		if len(s.UVs) != len(s.Triangles) {
+			return nil, errors.New("has no uvs to displace by")
+		}
+		patches := []surfacePatch{}
+		for index, triangle := range s.Triangles {
+			v0, v1, v2 := s.corners(index)
+			flat := v1.Subtract(v0).Cross(v2.Subtract(v0))
+			flat.Normalize()
+			normals := [3]vmath.Vector3d{flat, flat, flat}
+			if len(s.Normals) == len(s.Vertices) {
+				normals = [3]vmath.Vector3d{s.Normals[triangle[0]], s.Normals[triangle[1]], s.Normals[triangle[2]]}
+			}
+			patches = append(patches, trianglePatches([3]vmath.Vector3d{v0, v1, v2}, normals, s.UVs[index])...)
+		}
+		return patches, nil
+	case *TransformedSolid:
+		return tessellate(s.Transformed, resolution)
+	case *Transformed:
+		patches, err := tessellate(s.Shape, resolution)
+		if err != nil {
+			return nil, err
+		}
+		for index := range patches {
+			at := patches[index].at
+			patches[index].at = func(param vmath.Vector2d) surfacePoint {
+				point := at(param)
+				point.P = s.toWorld.MultiplyPoint(point.P)
+				point.N = s.toWorldNormal.MultiplyDirection(point.N)
+				point.N.Normalize()
+				return point
+			}
+		}
+		return patches, nil
+	}
+	return nil, errors.New("has no surface to tessellate")
+}
+
+// gridPatches splits the unit square of uv into resolution rows and columns
+// of two triangles each on the surface at
+func gridPatches(resolution int, at func(uv vmath.Vector2d) surfacePoint) []surfacePatch {
+	patches := []surfacePatch{}
+	step := 1.0 / float64(resolution)
+	for row := 0; row < resolution; row++ {
+		for column := 0; column < resolution; column++ {
+			u0, v0 := float64(column)*step, float64(row)*step
+			u1, v1 := u0+step, v0+step
+			patches = append(patches,
+				surfacePatch{Corners: [3]vmath.Vector2d{{X: u0, Y: v0}, {X: u1, Y: v0}, {X: u1, Y: v1}}, at: at},
+				surfacePatch{Corners: [3]vmath.Vector2d{{X: u0, Y: v0}, {X: u1, Y: v1}, {X: u0, Y: v1}}, at: at},
+			)
+		}
+	}
+	return patches
+}
+
+// trianglePatches is the flat triangle of vertices as a patch, its
+// parameters are the weights of the second and third vertex
+func trianglePatches(vertices [3]vmath.Vector3d, normals [3]vmath.Vector3d, uvs [3]vmath.Vector2d) []surfacePatch {
+	at := func(param vmath.Vector2d) surfacePoint {
+		w := 1 - param.X - param.Y
+		norm := normals[0].SMultiply(w).Add(normals[1].SMultiply(param.X)).Add(normals[2].SMultiply(param.Y))
+		norm.Normalize()
+		return surfacePoint{
+			P:  vertices[0].SMultiply(w).Add(vertices[1].SMultiply(param.X)).Add(vertices[2].SMultiply(param.Y)),
+			N:  norm,
+			UV: uvs[0].SMultiply(w).Add(uvs[1].SMultiply(param.X)).Add(uvs[2].SMultiply(param.Y)),
+		}
+	}
+	return []surfacePatch{{
+		Corners: [3]vmath.Vector2d{{X: 0.0, Y: 0.0}, {X: 1.0, Y: 0.0}, {X: 0.0, Y: 1.0}},
+		at:      at,
+	}}
+}
+
+// splitPatches splits every patch into four at the middle of its edges
+func splitPatches(patches []surfacePatch) []surfacePatch {
+	split := make([]surfacePatch, 0, 4*len(patches))
+	for _, patch := range patches {
+		c := patch.Corners
+		m01 := c[0].Add(c[1]).SMultiply(0.5)
+		m12 := c[1].Add(c[2]).SMultiply(0.5)
+		m20 := c[2].Add(c[0]).SMultiply(0.5)
+		split = append(split,
+			surfacePatch{Corners: [3]vmath.Vector2d{c[0], m01, m20}, at: patch.at},
+			surfacePatch{Corners: [3]vmath.Vector2d{m01, c[1], m12}, at: patch.at},
+			surfacePatch{Corners: [3]vmath.Vector2d{m20, m12, c[2]}, at: patch.at},
+			surfacePatch{Corners: [3]vmath.Vector2d{m01, m12, m20}, at: patch.at},
+		)
+	}
+	return split
+}
+
+// refinePatches splits every patch until none of its edges is longer than
+// edgeLength for every unit the middle of the edge is from camera, or than
+// the longest edge of patches split maxDisplaceLevel times, near patches end
+// up finer than far ones
+func refinePatches(patches []surfacePatch, camera vmath.Vector3d, edgeLength float64) []surfacePatch {
+	longest := 0.0
+	for _, patch := range patches {
+		points := patch.points()
+		for index := range points {
+			longest = math.Max(longest, points[index].Subtract(points[(index+1)%3]).Norm())
+		}
+	}
+	shortest := longest / math.Pow(2, maxDisplaceLevel)
+
+	refined := []surfacePatch{}
+	for _, patch := range patches {
+		refined = append(refined, refinePatch(patch, camera, edgeLength, shortest, 0)...)
+	}
+	return refined
+}
+
+// points are the corners of the patch on its surface
+func (patch surfacePatch) points() [3]vmath.Vector3d {
+	var points [3]vmath.Vector3d
+	for index, corner := range patch.Corners {
+		points[index] = patch.at(corner).P
+	}
+	return points
+}
+
+// refinePatch splits the edges of patch that are too long for refinePatches
+// and then the patches it is split into, whether an edge is split only
+// depends on where its ends are so the patches either side of it split it
+// the same way and do not crack apart
+func refinePatch(patch surfacePatch, camera vmath.Vector3d, edgeLength float64, shortest float64, depth int) []surfacePatch {
+	points := patch.points()
+	split := [3]bool{}
+	count := 0
+	for index := range points {
+		a, b := points[index], points[(index+1)%3]
+		length := a.Subtract(b).Norm()
+		middle := a.Add(b).SMultiply(0.5)
+		// the depth only stops a patch that never gets shorter edges
+		split[index] = depth < 2*maxDisplaceLevel && length > shortest && length > edgeLength*middle.Subtract(camera).Norm()
+		if split[index] {
+			count++
+		}
+	}
+
+	c := patch.Corners
+	corner := func(index int) vmath.Vector2d {
+		return c[index%3]
+	}
+	middle := func(index int) vmath.Vector2d {
+		return corner(index).Add(corner(index + 1)).SMultiply(0.5)
+	}
+	var parts [][3]vmath.Vector2d
+	switch count {
+	case 0:
+		return []surfacePatch{patch}
+	case 1:
+		// the edge from index to index+1 is split
+		index := 0
+		for !split[index] {
+			index++
+		}
+		parts = [][3]vmath.Vector2d{
+			{corner(index), middle(index), corner(index + 2)},
+			{middle(index), corner(index + 1), corner(index + 2)},
+		}
+	case 2:
+		// every edge but the one from index to index+1 is split
+		index := 0
+		for split[index] {
+			index++
+		}
+		parts = [][3]vmath.Vector2d{
+			{middle(index + 1), corner(index + 2), middle(index + 2)},
+			{corner(index), corner(index + 1), middle(index + 1)},
+			{corner(index), middle(index + 1), middle(index + 2)},
+		}
+	default:
+		parts = [][3]vmath.Vector2d{
+			{c[0], middle(0), middle(2)},
+			{middle(0), c[1], middle(1)},
+			{middle(2), middle(1), c[2]},
+			{middle(0), middle(1), middle(2)},
+		}
+	}
+
+	refined := []surfacePatch{}
+	for _, corners := range parts {
+		refined = append(refined, refinePatch(surfacePatch{Corners: corners, at: patch.at}, camera, edgeLength, shortest, depth+1)...)
+	}
+	return refined
+}
+
+// weldKey is a position rounded so corners shared by patches, and seams
+// where the uv of a shape wraps around, become a single vertex
+type weldKey [3]int64
+
+func newWeldKey(p vmath.Vector3d) weldKey {
+	return weldKey{int64(math.Round(p.X * 1e9)), int64(math.Round(p.Y * 1e9)), int64(math.Round(p.Z * 1e9))}
+}
+
+// displace moves every corner of patches along the surface normal by the
+// height at its uv times scale and makes a Mesh of the result, corners
+// shared by patches are welded first so the surface does not tear, the
+// heights are averaged over the longest uv edge around each corner so a
+// coarse mesh does not alias a detailed image
+func displace(patches []surfacePatch, heights *color.ImageValue, scale float64) (*Mesh, error) {
+	index := map[weldKey]int{}
+	points := []surfacePoint{}
+	normalSums := []vmath.Vector3d{}
+	footprints := []float64{}
+	triangles := make([][3]int, len(patches))
+	uvs := make([][3]vmath.Vector2d, len(patches))
+	for face, patch := range patches {
+		var corners [3]surfacePoint
+		for corner, param := range patch.Corners {
+			corners[corner] = patch.at(param)
+		}
+		for corner, point := range corners {
+			key := newWeldKey(point.P)
+			vertex, ok := index[key]
+			if !ok {
+				vertex = len(points)
+				index[key] = vertex
+				points = append(points, point)
+				normalSums = append(normalSums, vmath.Vector3d{})
+				footprints = append(footprints, 0.0)
+			}
+			normalSums[vertex] = normalSums[vertex].Add(point.N)
+			// the uvs of one patch are read together so a seam where the
+			// uv wraps around is not taken for a long edge
+			for _, other := range corners {
+				footprints[vertex] = math.Max(footprints[vertex], other.UV.Subtract(point.UV).Norm())
+			}
+			triangles[face][corner] = vertex
+			uvs[face][corner] = point.UV
+		}
+	}
+
+	vertices := make([]vmath.Vector3d, len(points))
+	for vertex, point := range points {
+		norm := normalSums[vertex]
+		if norm.Normalize() != nil {
+			norm = point.N
+		}
+		width := footprints[vertex]
+		height := heights.Height(point.UV, vmath.Vector2d{X: width, Y: 0.0}, vmath.Vector2d{X: 0.0, Y: width})
+		vertices[vertex] = point.P.Add(norm.SMultiply(scale * height))
+	}
+
+	// smooth normals of the displaced surface, each face is weighted by its
+	// area and faced the same way as the surface it came from
+	normals := make([]vmath.Vector3d, len(vertices))
+	for _, triangle := range triangles {
+		v0, v1, v2 := vertices[triangle[0]], vertices[triangle[1]], vertices[triangle[2]]
+		face := v1.Subtract(v0).Cross(v2.Subtract(v0))
+		side := normalSums[triangle[0]].Add(normalSums[triangle[1]]).Add(normalSums[triangle[2]])
+		if face.Dot(side) < 0 {
+			face = face.UNegate()
+		}
+		for _, vertex := range triangle {
+			normals[vertex] = normals[vertex].Add(face)
+		}
+	}
+	for vertex := range normals {
+		if normals[vertex].Normalize() != nil {
+			normals[vertex] = normalSums[vertex]
+			normals[vertex].Normalize()
+		}
+	}
+
+	mesh, err := NewMesh(vertices, triangles)
+	if err != nil {
+		return nil, err
+	}
+	mesh.Normals = normals
+	mesh.UVs = uvs
+	return mesh, nil
+}
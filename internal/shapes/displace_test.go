@@ -0,0 +1,298 @@
+package shapes
+
+import (
+	"errors"
+	"image"
+	stdcolor "image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// edgeCounts returns how many triangles of mesh share each edge
+func edgeCounts(mesh *Mesh) map[[2]int]int {
+	counts := map[[2]int]int{}
+	for _, triangle := range mesh.Triangles {
+		for index := range triangle {
+			a, b := triangle[index], triangle[(index+1)%3]
+			if a == b {
+				continue
+			}
+			if a > b {
+				a, b = b, a
+			}
+			counts[[2]int{a, b}]++
+		}
+	}
+	return counts
+}
+
+func TestDisplace(t *testing.T) {
+	flat := image.NewGray(image.Rect(0, 0, 1, 1))
+	flat.Set(0, 0, stdcolor.White)
+	white := color.NewImageValue(flat)
+
+	t.Run("sphere grows by the height", func(t *testing.T) {
+		patches, err := tessellate(NewSphere(vmath.Vector3d{X: 1.0}, 1.0), 8)
+		require.NoError(t, err)
+		mesh, err := displace(splitPatches(patches), white, 0.5)
+		require.NoError(t, err)
+
+		for index, vertex := range mesh.Vertices {
+			offset := vertex.Subtract(vmath.Vector3d{X: 1.0})
+			assert.InDelta(t, 1.5, offset.Norm(), 1e-9)
+			assert.Greater(t, mesh.Normals[index].Dot(offset), 0.0)
+		}
+	})
+
+	t.Run("box stays closed", func(t *testing.T) {
+		patches, err := tessellate(NewBox(vmath.Vector3d{}, vmath.Vector3d{X: 2.0, Y: 2.0, Z: 2.0}, vmath.Vector3d{}), 2)
+		require.NoError(t, err)
+		mesh, err := displace(patches, white, 0.1)
+		require.NoError(t, err)
+
+		// 6 faces of 3 by 3 vertices sharing the edges and corners
+		assert.Equal(t, 26, len(mesh.Vertices))
+		for edge, count := range edgeCounts(mesh) {
+			assert.Equal(t, 2, count, "edge %v", edge)
+		}
+		// the middle of each face moves straight out and corners along the
+		// average of the three faces
+		moved := map[float64]int{}
+		for _, vertex := range mesh.Vertices {
+			furthest := math.Max(math.Abs(vertex.X), math.Max(math.Abs(vertex.Y), math.Abs(vertex.Z)))
+			moved[math.Round(furthest*1e9)/1e9]++
+		}
+		assert.Equal(t, map[float64]int{
+			1.1: 6,
+			math.Round((1.0+0.1/math.Sqrt(2))*1e9) / 1e9: 12,
+			math.Round((1.0+0.1/math.Sqrt(3))*1e9) / 1e9: 8,
+		}, moved)
+	})
+
+	t.Run("transformed shapes are tessellated in place", func(t *testing.T) {
+		rectangle := NewRectangle(vmath.Vector3d{}, vmath.Vector3d{X: 1.0}, vmath.Vector3d{Y: 1.0})
+		moved, err := NewTransformed(rectangle, vmath.Translate(vmath.Vector3d{Z: -3.0}))
+		require.NoError(t, err)
+		patches, err := tessellate(moved, 1)
+		require.NoError(t, err)
+		mesh, err := displace(patches, white, 1.0)
+		require.NoError(t, err)
+		for _, vertex := range mesh.Vertices {
+			assert.InDelta(t, -2.0, vertex.Z, 1e-9)
+		}
+	})
+
+	t.Run("coarse mesh averages fine heights", func(t *testing.T) {
+		// black but for the four corner pixels, which are all the corners
+		// of the rectangle land on
+		dots := image.NewGray(image.Rect(0, 0, 16, 16))
+		for _, corner := range [][2]int{{0, 0}, {15, 0}, {0, 15}, {15, 15}} {
+			dots.Set(corner[0], corner[1], stdcolor.White)
+		}
+		patches, err := tessellate(NewRectangle(vmath.Vector3d{}, vmath.Vector3d{X: 1.0}, vmath.Vector3d{Y: 1.0}), 1)
+		require.NoError(t, err)
+		mesh, err := displace(patches, color.NewImageValue(dots), 1.0)
+		require.NoError(t, err)
+		for _, vertex := range mesh.Vertices {
+			assert.InDelta(t, 4.0/256.0, math.Abs(vertex.Z), 1e-3)
+		}
+	})
+
+	t.Run("mesh without uvs", func(t *testing.T) {
+		mesh, err := NewMesh([]vmath.Vector3d{{}, {X: 1.0}, {Y: 1.0}}, [][3]int{{0, 1, 2}})
+		require.NoError(t, err)
+		_, err = tessellate(mesh, 1)
+		assert.Equal(t, errors.New("has no uvs to displace by"), err)
+	})
+}
+
+func TestRefinePatches(t *testing.T) {
+	// a unit square whose longest edge is its diagonal
+	patches, err := tessellate(NewRectangle(vmath.Vector3d{}, vmath.Vector3d{X: 1.0}, vmath.Vector3d{Y: 1.0}), 1)
+	require.NoError(t, err)
+
+	far := refinePatches(patches, vmath.Vector3d{Z: 1000.0}, 0.01)
+	assert.Len(t, far, 2)
+
+	centered := refinePatches(patches, vmath.Vector3d{X: 0.5, Y: 0.5, Z: 10.0}, 0.01)
+	assert.Greater(t, len(centered), len(far))
+	assert.LessOrEqual(t, len(centered), 2*int(math.Pow(4, maxDisplaceLevel)))
+
+	// a camera over a corner refines the patches near it more than the rest
+	corner := refinePatches(patches, vmath.Vector3d{Z: 0.5}, 0.05)
+	near, away := 0, 0
+	for _, patch := range corner {
+		c := patch.Corners[0].Add(patch.Corners[1]).Add(patch.Corners[2]).SMultiply(1.0 / 3.0)
+		if c.X+c.Y < 1.0 {
+			near++
+		} else {
+			away++
+		}
+	}
+	assert.Greater(t, near, 2*away)
+
+	// every edge inside the square is split the same way by both patches
+	// along it so there are no cracks
+	edges := map[[2]vmath.Vector2d]int{}
+	for _, patch := range corner {
+		for index := range patch.Corners {
+			a, b := patch.Corners[index], patch.Corners[(index+1)%3]
+			if a.X > b.X || (a.X == b.X && a.Y > b.Y) {
+				a, b = b, a
+			}
+			edges[[2]vmath.Vector2d{a, b}]++
+		}
+	}
+	for edge, count := range edges {
+		a, b := edge[0], edge[1]
+		onBorder := (a.X == b.X && (a.X == 0 || a.X == 1)) || (a.Y == b.Y && (a.Y == 0 || a.Y == 1))
+		if !onBorder {
+			assert.Equal(t, 2, count, "edge %v", edge)
+		}
+	}
+}
+
+func TestDisplacedShapesFactory(t *testing.T) {
+	img := image.NewGray16(image.Rect(0, 0, 2, 2))
+	img.SetGray16(0, 0, stdcolor.Gray16{Y: math.MaxUint16})
+	path := filepath.Join(t.TempDir(), "heights.png")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(file, img))
+	require.NoError(t, file.Close())
+
+	cameras := map[string]vmath.Vector3d{"camera1": {Z: 10.0}}
+
+	tests := []struct {
+		Description string
+		Bytes       string
+		Triangles   int
+		ExpectedErr error
+	}{
+		{
+			Description: "subdivided sphere",
+			Bytes: `
+ball:
+  type: sphere
+  radius: 1.0
+rock:
+  type: displaced
+  shape: ball
+  height_map: ` + path + `
+  scale: 0.2
+  resolution: 4
+  subdivision: 1
+`,
+			Triangles: 4 * 4 * 2 * 4,
+		},
+		{
+			Description: "adaptive rectangle",
+			Bytes: `
+card:
+  type: rectangle
+  corner: [0.0, 0.0, 0.0]
+  edge1: [1.0, 0.0, 0.0]
+  edge2: [0.0, 1.0, 0.0]
+rough:
+  type: displaced
+  shape: card
+  height_map: ` + path + `
+  resolution: 1
+  camera: camera1
+  edge_length: 0.02
+`,
+			Triangles: 2 * 4 * 4 * 4,
+		},
+		{
+			Description: "adaptive rectangle without its camera",
+			Bytes: `
+card:
+  type: rectangle
+  corner: [0.0, 0.0, 0.0]
+  edge1: [1.0, 0.0, 0.0]
+  edge2: [0.0, 1.0, 0.0]
+rough:
+  type: displaced
+  shape: card
+  height_map: ` + path + `
+  camera: camera2
+`,
+			ExpectedErr: errors.New("camera camera2 does not exist in scene."),
+		},
+		{
+			Description: "shape without a surface",
+			Bytes: `
+floor:
+  type: plane
+  normal: [0.0, 1.0, 0.0]
+rough:
+  type: displaced
+  shape: floor
+  height_map: ` + path + `
+`,
+			ExpectedErr: errors.New("displaced rough: shape floor has no surface to tessellate"),
+		},
+		{
+			Description: "missing height map",
+			Bytes: `
+ball:
+  type: sphere
+  radius: 1.0
+rock:
+  type: displaced
+  shape: ball
+`,
+			ExpectedErr: errors.New("displaced requires a height_map"),
+		},
+		{
+			Description: "subdivision too deep",
+			Bytes: `
+ball:
+  type: sphere
+  radius: 1.0
+rock:
+  type: displaced
+  shape: ball
+  height_map: ` + path + `
+  subdivision: 7
+`,
+			ExpectedErr: errors.New("displaced subdivision must be from 0 to 6"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml([]byte(test.Bytes))
+			require.NoError(t, err)
+			configs, err := ShapesConfigFactory(yaml, map[string]material.Material{}, cameras)
+			if err == nil {
+				var shapes []interface{}
+				traceables, buildErr := ShapesFactory(configs)
+				err = buildErr
+				for _, traceable := range traceables {
+					shapes = append(shapes, traceable)
+				}
+				if err == nil {
+					require.Len(t, shapes, 1)
+					assert.Equal(t, test.Triangles, len(shapes[0].(*Mesh).Triangles))
+				}
+			}
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
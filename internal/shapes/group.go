@@ -0,0 +1,159 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// GroupConfig collects other shapes in the scene into one shape for the
+// ShapeFactory, a transform on the group moves every shape in it
+type GroupConfig struct {
+	Name   string
+	Shapes []string
+
+	children []Surface
+}
+
+func (gc *GroupConfig) GetName() string {
+	return gc.Name
+}
+
+// ChildNames satisfies the parentConfig interface
+func (gc *GroupConfig) ChildNames() []string {
+	return gc.Shapes
+}
+
+// SetChildren satisfies the parentConfig interface
+func (gc *GroupConfig) SetChildren(children []common.Traceable) error {
+	gc.children = []Surface{}
+	for index, child := range children {
+		surface, ok := child.(Surface)
+		if !ok {
+			return errors.New(fmt.Sprintf("shape %s can not be used in group %s.", gc.Shapes[index], gc.Name))
+		}
+		gc.children = append(gc.children, surface)
+	}
+	return nil
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (gc *GroupConfig) NewShape() (common.Traceable, error) {
+	if len(gc.children) == 0 {
+		return nil, errors.New(fmt.Sprintf("group %s needs at least 1 shape.", gc.Name))
+	}
+	return &Group{
+		Name:     gc.Name,
+		Children: gc.children,
+	}, nil
+}
+
+// FromYaml generates Config from input yaml
+// satisfies the interface ShapesConfig (2/2)
+func (gc *GroupConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	names, err := config.Get("shapes").Array()
+	if err != nil || len(names) == 0 {
+		return errors.New("group requires 1 or more shapes")
+	}
+	for _, name := range names {
+		n, ok := name.(string)
+		if !ok {
+			return errors.New(fmt.Sprintf("group shape %v is not a name", name))
+		}
+		gc.Shapes = append(gc.Shapes, n)
+	}
+
+	return nil
+}
+
+// Group is the nearest hit of any of its Children, the Children may be shared
+// with instances of the group so the hit is saved as soon as it is found
+type Group struct {
+	Name     string
+	Children []Surface
+	surfaceHit
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene
+func (g *Group) Intersect(ray *vmath.Ray) bool {
+	var nearest Surface
+	ratio := 0.0
+	for _, child := range g.Children {
+		if !child.Intersect(ray) {
+			continue
+		}
+		if nearest == nil || child.GetIntersectionRatio() < ratio {
+			nearest = child
+			ratio = child.GetIntersectionRatio()
+			// saved now as a later child may be this one again
+			g.surfaceHit = newSurfaceHit(child, ray.Origin.Add(ray.Direction.SMultiply(ratio)), ratio)
+		}
+	}
+	return nearest != nil
+}
+
+// GetPosition satisfies requirements for Object
+// interface for a scene, a group is positioned by its transform
+func (g *Group) GetPosition() vmath.Vector3d {
+	return vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+}
+
+func (g *Group) GetName() string {
+	return g.Name
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (g *Group) GetType() string {
+	return "group"
+}
+
+// GetIntersectionRatio
+func (g *Group) GetIntersectionRatio() float64 {
+	return g.intersectionRatio
+}
+
+// CalculateNorm returns the normal of the child hit by the last Intersect
+func (g *Group) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	return g.norm
+}
+
+// CalculateUV returns the UV of the child hit by the last Intersect
+func (g *Group) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	return g.uv
+}
+
+// CalculateFrame returns the directions u and v increase in across the child
+// hit by the last Intersect
+func (g *Group) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	return g.tangent, g.bitangent
+}
+
+// ObjectPoint returns the hit of the child found by the last Intersect in
+// its own space
+func (g *Group) ObjectPoint(hit vmath.Vector3d) vmath.Vector3d {
+	return g.object
+}
+
+// ObjectNormal returns the normal of the child found by the last Intersect in
+// its own space
+func (g *Group) ObjectNormal(hit vmath.Vector3d) vmath.Vector3d {
+	return g.objectNorm
+}
+
+// GetMaterial returns the material of the child hit by the last Intersect
+func (g *Group) GetMaterial() material.Material {
+	return g.material
+}
+
+func (g *Group) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(g.surfaceHit, ray, objs)
+}
@@ -0,0 +1,234 @@
+package shapes
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestGroupIntersect(t *testing.T) {
+	group := &Group{Children: []Surface{
+		NewSphere(vmath.Vector3d{Z: -5}, 1.0),
+		NewSphere(vmath.Vector3d{Z: 5}, 1.0),
+		NewBox(vmath.Vector3d{X: 5}, vmath.Vector3d{X: 2, Y: 2, Z: 2}, vmath.Vector3d{}),
+	}}
+
+	tests := []struct {
+		Description   string
+		Ray           *vmath.Ray
+		Expected      bool
+		ExpectedRatio float64
+		ExpectedNorm  vmath.Vector3d
+	}{
+		{
+			Description:   "nearest of two children",
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{Z: 10}, Direction: vmath.Vector3d{Z: -1}},
+			Expected:      true,
+			ExpectedRatio: 4,
+			ExpectedNorm:  vmath.Vector3d{Z: 1},
+		},
+		{
+			Description:   "nearest of two children from the other side",
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{Z: -10}, Direction: vmath.Vector3d{Z: 1}},
+			Expected:      true,
+			ExpectedRatio: 4,
+			ExpectedNorm:  vmath.Vector3d{Z: -1},
+		},
+		{
+			Description:   "box child",
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{X: 5, Y: 10}, Direction: vmath.Vector3d{Y: -1}},
+			Expected:      true,
+			ExpectedRatio: 9,
+			ExpectedNorm:  vmath.Vector3d{Y: 1},
+		},
+		{
+			Description: "miss",
+			Ray:         &vmath.Ray{Origin: vmath.Vector3d{Y: 10}, Direction: vmath.Vector3d{Y: 1}},
+			Expected:    false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			assert.Equal(t, test.Expected, group.Intersect(test.Ray))
+			if test.Expected {
+				assert.InDelta(t, test.ExpectedRatio, group.GetIntersectionRatio(), 1e-9)
+				norm := group.CalculateNorm(group.PlaceHit)
+				assert.InDelta(t, test.ExpectedNorm.X, norm.X, 1e-9)
+				assert.InDelta(t, test.ExpectedNorm.Y, norm.Y, 1e-9)
+				assert.InDelta(t, test.ExpectedNorm.Z, norm.Z, 1e-9)
+			}
+		})
+	}
+}
+
+func TestInstanceSharedGeometry(t *testing.T) {
+	red := &material.Lambert{}
+	blue := &material.Lambert{}
+	ball := NewSphere(vmath.Vector3d{}, 1.0)
+	ball.Material = red
+
+	place := func(position vmath.Vector3d, m material.Material) *Transformed {
+		transformed, err := NewTransformed(&Instance{Shape: ball, Material: m}, vmath.Translate(position))
+		require.NoError(t, err)
+		return transformed
+	}
+	left := place(vmath.Vector3d{X: -3}, nil)
+	right := place(vmath.Vector3d{X: 3}, blue)
+
+	// both instances are hit before either is shaded, as when tracing a scene
+	require.True(t, left.Intersect(&vmath.Ray{Origin: vmath.Vector3d{X: -3, Y: 10}, Direction: vmath.Vector3d{Y: -1}}))
+	require.True(t, right.Intersect(&vmath.Ray{Origin: vmath.Vector3d{X: 10}, Direction: vmath.Vector3d{X: -1}}))
+
+	assert.InDelta(t, 9, left.GetIntersectionRatio(), 1e-9)
+	assert.Equal(t, vmath.Vector3d{X: 0, Y: 1, Z: 0}, left.CalculateNorm(left.PlaceHit))
+	assert.True(t, left.GetMaterial() == red)
+
+	assert.InDelta(t, 6, right.GetIntersectionRatio(), 1e-9)
+	assert.Equal(t, vmath.Vector3d{X: 1, Y: 0, Z: 0}, right.CalculateNorm(right.PlaceHit))
+	assert.True(t, right.GetMaterial() == blue)
+}
+
+func TestGroupShapesFactory(t *testing.T) {
+	tests := []struct {
+		Description   string
+		Bytes         []byte
+		ExpectedNames []string
+		ExpectedErr   error
+		// ExpectedErrContains is part of an error whose shape name depends
+		// on the order the shapes are built in
+		ExpectedErrContains string
+	}{
+		{
+			Description: "instances share a group",
+			Bytes: []byte(`
+trunk:
+  type: box
+  size: [0.5, 2, 0.5]
+crown:
+  type: sphere
+  radius: 1.0
+  position: [0.0, 2.0, 0.0]
+tree:
+  type: group
+  shapes: [trunk, crown]
+tree1:
+  type: instance
+  shape: tree
+  transform:
+    translate: [-3, 0, 0]
+tree2:
+  type: instance
+  shape: tree
+  transform:
+    translate: [3, 0, 0]
+    scale: 1.5
+`),
+			ExpectedNames: []string{"tree1", "tree2"},
+		},
+		{
+			Description: "groups of groups",
+			Bytes: []byte(`
+ball:
+  type: sphere
+  radius: 1.0
+pair:
+  type: group
+  shapes: [ball]
+  transform:
+    translate: [1, 0, 0]
+scene:
+  type: group
+  shapes: [pair, ball]
+`),
+			ExpectedNames: []string{"scene"},
+		},
+		{
+			Description: "instance of a missing shape",
+			Bytes: []byte(`
+tree1:
+  type: instance
+  shape: tree
+`),
+			ExpectedErr: errors.New("shape tree does not exist in scene."),
+		},
+		{
+			Description: "group containing itself",
+			Bytes: []byte(`
+pair:
+  type: group
+  shapes: [pair]
+`),
+			ExpectedErrContains: "references itself",
+		},
+		{
+			Description: "group containing an instance of itself",
+			Bytes: []byte(`
+ball:
+  type: sphere
+  radius: 1.0
+pair:
+  type: group
+  shapes: [ball, copy]
+copy:
+  type: instance
+  shape: pair
+`),
+			ExpectedErrContains: "references itself",
+		},
+		{
+			Description: "instance without a shape",
+			Bytes: []byte(`
+tree1:
+  type: instance
+`),
+			ExpectedErr: errors.New("instance requires a shape"),
+		},
+		{
+			Description: "instance with a missing material",
+			Bytes: []byte(`
+tree1:
+  type: instance
+  shape: tree
+  material: bark
+`),
+			ExpectedErr: errors.New("material bark does not exist in scene."),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			configs, err := ShapesConfigFactory(yaml, map[string]material.Material{}, nil)
+			var traceables []common.Traceable
+			if err == nil {
+				traceables, err = ShapesFactory(configs)
+			}
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			if test.ExpectedErrContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.ExpectedErrContains)
+				return
+			}
+			require.NoError(t, err)
+			names := []string{}
+			for _, traceable := range traceables {
+				names = append(names, traceable.(common.Object).GetName())
+			}
+			sort.Strings(names)
+			assert.Equal(t, test.ExpectedNames, names)
+		})
+	}
+}
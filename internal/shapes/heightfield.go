@@ -0,0 +1,386 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	stdcolor "image/color"
+	_ "image/png"
+	"math"
+	"os"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// HeightfieldConfig defines terrain from a grayscale image for the
+// ShapeFactory
+type HeightfieldConfig struct {
+	Name     string
+	File     string
+	Position vmath.Vector3d
+	Size     vmath.Vector3d
+	Material material.Material
+}
+
+func (hc *HeightfieldConfig) GetName() string {
+	return hc.Name
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (hc *HeightfieldConfig) NewShape() (common.Traceable, error) {
+	if hc.Size.X <= 0 || hc.Size.Z <= 0 {
+		return nil, errors.New("heightfield size must be positive along x and z")
+	}
+	heights, err := loadHeights(hc.File)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("heightfield %s: %s", hc.Name, err.Error()))
+	}
+	heightfield, err := NewHeightfield(heights, hc.Position, hc.Size)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("heightfield %s: %s", hc.Name, err.Error()))
+	}
+	heightfield.Name = hc.Name
+	heightfield.Material = hc.Material
+	return heightfield, nil
+}
+
+// FromYaml generates Config from input yaml, file is a grayscale png spread
+// over size along x and z from position with white raised by size along y
+// satisfies the interface ShapesConfig (2/2)
+func (hc *HeightfieldConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	file, err := config.Get("file").String()
+	if err != nil {
+		return errors.New("heightfield requires a file")
+	}
+	hc.File = file
+
+	if config.Get("position").IsFound() {
+		position, err := vector3dFromYaml(config.Get("position"))
+		if err != nil {
+			return errors.New("heightfield position: " + err.Error())
+		}
+		hc.Position = position
+	}
+	size, err := vector3dFromYaml(config.Get("size"))
+	if err != nil {
+		return errors.New("heightfield size: " + err.Error())
+	}
+	hc.Size = size
+
+	m, err := materialFromYaml(config, materials)
+	if err != nil {
+		return err
+	}
+	hc.Material = m
+
+	return nil
+}
+
+// loadHeights reads every pixel of the image at path as a height from 0 to 1,
+// 16 bit grayscale images keep their full precision
+func loadHeights(path string) ([][]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	heights := make([][]float64, bounds.Dy())
+	for z := range heights {
+		heights[z] = make([]float64, bounds.Dx())
+		for x := range heights[z] {
+			gray := stdcolor.Gray16Model.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+z)).(stdcolor.Gray16)
+			heights[z][x] = float64(gray.Y) / math.MaxUint16
+		}
+	}
+	return heights, nil
+}
+
+// Heightfield is a grid of heights spread over a rectangle, each cell between
+// four heights is split into two triangles along its diagonal
+type Heightfield struct {
+	Name string
+	// Heights is indexed by z and then x, scaled by Size.Y
+	Heights           [][]float64
+	P                 vmath.Vector3d
+	Size              vmath.Vector3d
+	PlaceHit          vmath.Vector3d
+	intersectionRatio float64
+
+	// normals at each height for smooth shading and the lowest and highest
+	// point of each cell to skip cells the ray passes over
+	normals   [][]vmath.Vector3d
+	min, max  [][]float64
+	low, high float64
+
+	Material material.Material
+}
+
+// NewHeightfield spreads heights over size along x and z starting at pos,
+// heights has at least 2 rows of 2
+func NewHeightfield(heights [][]float64, pos vmath.Vector3d, size vmath.Vector3d) (*Heightfield, error) {
+	if len(heights) < 2 || len(heights[0]) < 2 {
+		return nil, errors.New("heightfield needs at least 2 by 2 heights")
+	}
+	for _, row := range heights {
+		if len(row) != len(heights[0]) {
+			return nil, errors.New("heightfield rows must all be the same length")
+		}
+	}
+
+	h := &Heightfield{
+		Heights: heights,
+		P:       pos,
+		Size:    size,
+	}
+	h.build()
+	return h, nil
+}
+
+// columns and rows of heights, there is one less cell in each direction
+func (h *Heightfield) columns() int {
+	return len(h.Heights[0])
+}
+
+func (h *Heightfield) rows() int {
+	return len(h.Heights)
+}
+
+// cellSize is the size of a cell along x and z
+func (h *Heightfield) cellSize() (float64, float64) {
+	return h.Size.X / float64(h.columns()-1), h.Size.Z / float64(h.rows()-1)
+}
+
+// vertex returns the point of the height at column x and row z
+func (h *Heightfield) vertex(x int, z int) vmath.Vector3d {
+	cx, cz := h.cellSize()
+	return vmath.Vector3d{
+		X: h.P.X + float64(x)*cx,
+		Y: h.P.Y + h.Heights[z][x]*h.Size.Y,
+		Z: h.P.Z + float64(z)*cz,
+	}
+}
+
+// build works out the normals at every height by central differences and the
+// height range of every cell
+func (h *Heightfield) build() {
+	cx, cz := h.cellSize()
+	h.normals = make([][]vmath.Vector3d, h.rows())
+	for z := range h.normals {
+		h.normals[z] = make([]vmath.Vector3d, h.columns())
+		for x := range h.normals[z] {
+			x0, x1 := int(math.Max(float64(x-1), 0)), int(math.Min(float64(x+1), float64(h.columns()-1)))
+			z0, z1 := int(math.Max(float64(z-1), 0)), int(math.Min(float64(z+1), float64(h.rows()-1)))
+			dx := (h.Heights[z][x1] - h.Heights[z][x0]) * h.Size.Y / (float64(x1-x0) * cx)
+			dz := (h.Heights[z1][x] - h.Heights[z0][x]) * h.Size.Y / (float64(z1-z0) * cz)
+			norm := vmath.Vector3d{X: -dx, Y: 1.0, Z: -dz}
+			norm.Normalize()
+			h.normals[z][x] = norm
+		}
+	}
+
+	h.low, h.high = math.Inf(1), math.Inf(-1)
+	h.min = make([][]float64, h.rows()-1)
+	h.max = make([][]float64, h.rows()-1)
+	for z := range h.min {
+		h.min[z] = make([]float64, h.columns()-1)
+		h.max[z] = make([]float64, h.columns()-1)
+		for x := range h.min[z] {
+			corners := []float64{h.Heights[z][x], h.Heights[z][x+1], h.Heights[z+1][x], h.Heights[z+1][x+1]}
+			h.min[z][x], h.max[z][x] = corners[0], corners[0]
+			for _, c := range corners[1:] {
+				h.min[z][x] = math.Min(h.min[z][x], c)
+				h.max[z][x] = math.Max(h.max[z][x], c)
+			}
+			h.min[z][x] = h.P.Y + h.min[z][x]*h.Size.Y
+			h.max[z][x] = h.P.Y + h.max[z][x]*h.Size.Y
+			h.low = math.Min(h.low, h.min[z][x])
+			h.high = math.Max(h.high, h.max[z][x])
+		}
+	}
+}
+
+// bounds clips ray to the box around the heightfield
+func (h *Heightfield) bounds(ray *vmath.Ray) (float64, float64, bool) {
+	min := []float64{h.P.X, h.low, h.P.Z}
+	max := []float64{h.P.X + h.Size.X, h.high, h.P.Z + h.Size.Z}
+	origin := []float64{ray.Origin.X, ray.Origin.Y, ray.Origin.Z}
+	direction := []float64{ray.Direction.X, ray.Direction.Y, ray.Direction.Z}
+
+	tNear, tFar := 0.0, math.Inf(1)
+	for axis := range min {
+		if direction[axis] == 0 {
+			if origin[axis] < min[axis] || origin[axis] > max[axis] {
+				return 0, 0, false
+			}
+			continue
+		}
+		t1 := (min[axis] - origin[axis]) / direction[axis]
+		t2 := (max[axis] - origin[axis]) / direction[axis]
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tNear = math.Max(tNear, t1)
+		tFar = math.Min(tFar, t2)
+		if tNear > tFar {
+			return 0, 0, false
+		}
+	}
+	return tNear, tFar, true
+}
+
+// Intersect walks the cells under the ray from where it enters the heightfield
+// to where it leaves, testing the two triangles of each cell it passes
+// through close enough to hit
+func (h *Heightfield) Intersect(ray *vmath.Ray) bool {
+	tNear, tFar, ok := h.bounds(ray)
+	if !ok {
+		return false
+	}
+
+	cx, cz := h.cellSize()
+	// position and direction of the ray in cells
+	start := ray.Origin.Add(ray.Direction.SMultiply(tNear))
+	gx, gz := (start.X-h.P.X)/cx, (start.Z-h.P.Z)/cz
+	dx, dz := ray.Direction.X/cx, ray.Direction.Z/cz
+	x := int(math.Min(math.Max(math.Floor(gx), 0), float64(h.columns()-2)))
+	z := int(math.Min(math.Max(math.Floor(gz), 0), float64(h.rows()-2)))
+
+	stepX, nextX, deltaX := 0, math.Inf(1), math.Inf(1)
+	if dx > 0 {
+		stepX, nextX, deltaX = 1, tNear+(float64(x+1)-gx)/dx, 1/dx
+	} else if dx < 0 {
+		stepX, nextX, deltaX = -1, tNear+(float64(x)-gx)/dx, -1/dx
+	}
+	stepZ, nextZ, deltaZ := 0, math.Inf(1), math.Inf(1)
+	if dz > 0 {
+		stepZ, nextZ, deltaZ = 1, tNear+(float64(z+1)-gz)/dz, 1/dz
+	} else if dz < 0 {
+		stepZ, nextZ, deltaZ = -1, tNear+(float64(z)-gz)/dz, -1/dz
+	}
+
+	t := tNear
+	for t <= tFar && x >= 0 && x < h.columns()-1 && z >= 0 && z < h.rows()-1 {
+		tExit := math.Min(math.Min(nextX, nextZ), tFar)
+		// the ray only reaches the cell when it is within the cell heights
+		// somewhere between entering and leaving it
+		y0 := ray.Origin.Y + ray.Direction.Y*t
+		y1 := ray.Origin.Y + ray.Direction.Y*tExit
+		if math.Min(y0, y1) <= h.max[z][x] && math.Max(y0, y1) >= h.min[z][x] {
+			if hit, ok := h.intersectCell(ray, x, z); ok {
+				h.intersectionRatio = hit
+				h.PlaceHit = ray.Origin.Add(ray.Direction.SMultiply(hit))
+				return true
+			}
+		}
+
+		if nextX < nextZ {
+			x += stepX
+			t = nextX
+			nextX += deltaX
+		} else {
+			z += stepZ
+			t = nextZ
+			nextZ += deltaZ
+		}
+	}
+	return false
+}
+
+// intersectCell returns the nearest hit in front of the ray origin with the
+// two triangles of a cell
+func (h *Heightfield) intersectCell(ray *vmath.Ray, x int, z int) (float64, bool) {
+	v00, v10 := h.vertex(x, z), h.vertex(x+1, z)
+	v01, v11 := h.vertex(x, z+1), h.vertex(x+1, z+1)
+	nearest, found := math.Inf(1), false
+	for _, triangle := range [][3]vmath.Vector3d{{v00, v10, v11}, {v00, v11, v01}} {
+		t, _, _, ok := intersectTriangle(ray, triangle[0], triangle[1], triangle[2])
+		if ok && t > 1e-9 && t < nearest {
+			nearest, found = t, true
+		}
+	}
+	return nearest, found
+}
+
+// cell returns the cell under hit and how far across it hit is along x and z
+func (h *Heightfield) cell(hit vmath.Vector3d) (int, int, float64, float64) {
+	cx, cz := h.cellSize()
+	gx, gz := (hit.X-h.P.X)/cx, (hit.Z-h.P.Z)/cz
+	x := int(math.Min(math.Max(math.Floor(gx), 0), float64(h.columns()-2)))
+	z := int(math.Min(math.Max(math.Floor(gz), 0), float64(h.rows()-2)))
+	return x, z, gx - float64(x), gz - float64(z)
+}
+
+// GetPosition satisfies requirements for Object
+// interface for a scene
+func (h *Heightfield) GetPosition() vmath.Vector3d {
+	return h.P
+}
+
+func (h *Heightfield) GetName() string {
+	return h.Name
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (h *Heightfield) GetType() string {
+	return "heightfield"
+}
+
+// GetIntersectionRatio
+func (h *Heightfield) GetIntersectionRatio() float64 {
+	return h.intersectionRatio
+}
+
+// CalculateNorm interpolates the normals at the corners of the triangle under
+// hit so the terrain shades smoothly
+func (h *Heightfield) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	x, z, fx, fz := h.cell(hit)
+	var norm vmath.Vector3d
+	if fx >= fz {
+		norm = h.normals[z][x].SMultiply(1 - fx).
+			Add(h.normals[z][x+1].SMultiply(fx - fz)).
+			Add(h.normals[z+1][x+1].SMultiply(fz))
+	} else {
+		norm = h.normals[z][x].SMultiply(1 - fz).
+			Add(h.normals[z+1][x+1].SMultiply(fx)).
+			Add(h.normals[z+1][x].SMultiply(fz - fx))
+	}
+	norm.Normalize()
+
+	return norm
+}
+
+// CalculateUV maps the rectangle the heightfield covers onto the unit square
+func (h *Heightfield) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	return vmath.Vector2d{
+		X: math.Min(math.Max((hit.X-h.P.X)/h.Size.X, 0), 1),
+		Y: math.Min(math.Max((hit.Z-h.P.Z)/h.Size.Z, 0), 1),
+	}
+}
+
+// CalculateFrame returns the x and z axes the UVs increase along
+func (h *Heightfield) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	return vmath.Vector3d{X: 1.0, Y: 0.0, Z: 0.0}, vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0}
+}
+
+// GetMaterial returns the material the heightfield is shaded with
+func (h *Heightfield) GetMaterial() material.Material {
+	return h.Material
+}
+
+func (h *Heightfield) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(h, h.PlaceHit, 0), ray, objs)
+}
@@ -0,0 +1,144 @@
+package shapes
+
+import (
+	"errors"
+	"image"
+	stdcolor "image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestHeightfieldIntersect(t *testing.T) {
+	// a 4 by 4 cell field over x and z from 0 to 4 with one peak at (2, 2)
+	peak := [][]float64{
+		{0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0},
+		{0, 0, 1, 0, 0},
+		{0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0},
+	}
+	field, err := NewHeightfield(peak, vmath.Vector3d{}, vmath.Vector3d{X: 4, Y: 2, Z: 4})
+	require.NoError(t, err)
+
+	tests := []struct {
+		Description   string
+		Ray           *vmath.Ray
+		Expected      bool
+		ExpectedRatio float64
+		ExpectedNorm  vmath.Vector3d
+		ExpectedUV    vmath.Vector2d
+	}{
+		{
+			Description:   "straight down on the peak",
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{X: 2, Y: 10, Z: 2}, Direction: vmath.Vector3d{Y: -1}},
+			Expected:      true,
+			ExpectedRatio: 8,
+			ExpectedNorm:  vmath.Vector3d{Y: 1},
+			ExpectedUV:    vmath.Vector2d{X: 0.5, Y: 0.5},
+		},
+		{
+			Description:   "straight down on flat ground",
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{X: 0.5, Y: 10, Z: 3.5}, Direction: vmath.Vector3d{Y: -2}},
+			Expected:      true,
+			ExpectedRatio: 5,
+			ExpectedNorm:  vmath.Vector3d{Y: 1},
+			ExpectedUV:    vmath.Vector2d{X: 0.125, Y: 0.875},
+		},
+		{
+			Description:   "along the ground into the side of the peak",
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{X: -10, Y: 1, Z: 2}, Direction: vmath.Vector3d{X: 1}},
+			Expected:      true,
+			ExpectedRatio: 11.5,
+			ExpectedUV:    vmath.Vector2d{X: 0.375, Y: 0.5},
+		},
+		{
+			Description: "over the top",
+			Ray:         &vmath.Ray{Origin: vmath.Vector3d{X: -10, Y: 2.5, Z: 2}, Direction: vmath.Vector3d{X: 1}},
+			Expected:    false,
+		},
+		{
+			Description: "outside the rectangle",
+			Ray:         &vmath.Ray{Origin: vmath.Vector3d{X: 5, Y: 10, Z: 2}, Direction: vmath.Vector3d{Y: -1}},
+			Expected:    false,
+		},
+		{
+			Description: "diagonal from above",
+			Ray:         &vmath.Ray{Origin: vmath.Vector3d{X: -1, Y: 5, Z: -1}, Direction: vmath.Vector3d{X: 1, Y: -1, Z: 1}},
+			Expected:    true,
+			// reaches the peak exactly at (2, 2, 2)
+			ExpectedRatio: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			assert.Equal(t, test.Expected, field.Intersect(test.Ray))
+			if !test.Expected {
+				return
+			}
+			assert.InDelta(t, test.ExpectedRatio, field.GetIntersectionRatio(), 1e-9)
+			if !test.ExpectedNorm.IsZero() {
+				norm := field.CalculateNorm(field.PlaceHit)
+				assert.InDelta(t, test.ExpectedNorm.X, norm.X, 1e-9)
+				assert.InDelta(t, test.ExpectedNorm.Y, norm.Y, 1e-9)
+				assert.InDelta(t, test.ExpectedNorm.Z, norm.Z, 1e-9)
+			}
+			if test.ExpectedUV != (vmath.Vector2d{}) {
+				uv := field.CalculateUV(field.PlaceHit)
+				assert.InDelta(t, test.ExpectedUV.X, uv.X, 1e-9)
+				assert.InDelta(t, test.ExpectedUV.Y, uv.Y, 1e-9)
+			}
+		})
+	}
+}
+
+func TestHeightfieldSmoothNormal(t *testing.T) {
+	// a ramp rising 1 over every cell along x
+	ramp := [][]float64{
+		{0, 1, 2},
+		{0, 1, 2},
+	}
+	field, err := NewHeightfield(ramp, vmath.Vector3d{}, vmath.Vector3d{X: 2, Y: 1, Z: 1})
+	require.NoError(t, err)
+	norm := field.CalculateNorm(vmath.Vector3d{X: 1.5, Y: 1.5, Z: 0.25})
+	assert.InDelta(t, -math.Sqrt(0.5), norm.X, 1e-9)
+	assert.InDelta(t, math.Sqrt(0.5), norm.Y, 1e-9)
+	assert.InDelta(t, 0, norm.Z, 1e-9)
+}
+
+func TestLoadHeights(t *testing.T) {
+	img := image.NewGray16(image.Rect(0, 0, 3, 2))
+	img.SetGray16(0, 0, stdcolor.Gray16{Y: 0})
+	img.SetGray16(1, 0, stdcolor.Gray16{Y: 1})
+	img.SetGray16(2, 1, stdcolor.Gray16{Y: math.MaxUint16})
+	path := filepath.Join(t.TempDir(), "heights.png")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(file, img))
+	require.NoError(t, file.Close())
+
+	heights, err := loadHeights(path)
+	require.NoError(t, err)
+	assert.Equal(t, [][]float64{
+		{0, 1.0 / math.MaxUint16, 0},
+		{0, 0, 1},
+	}, heights)
+
+	_, err = loadHeights(filepath.Join(t.TempDir(), "missing.png"))
+	assert.Error(t, err)
+}
+
+func TestNewHeightfieldErrors(t *testing.T) {
+	_, err := NewHeightfield([][]float64{{0, 1}}, vmath.Vector3d{}, vmath.Vector3d{X: 1, Y: 1, Z: 1})
+	assert.Equal(t, errors.New("heightfield needs at least 2 by 2 heights"), err)
+	_, err = NewHeightfield([][]float64{{0, 1}, {0}}, vmath.Vector3d{}, vmath.Vector3d{X: 1, Y: 1, Z: 1})
+	assert.Equal(t, errors.New("heightfield rows must all be the same length"), err)
+}
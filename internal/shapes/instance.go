@@ -0,0 +1,162 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// InstanceConfig defines another copy of a shape in the scene for the
+// ShapeFactory, the geometry of the shape is shared with every instance of it
+// so only the transform and material of the instance cost memory
+type InstanceConfig struct {
+	Name     string
+	Shape    string
+	Material material.Material
+
+	child Surface
+}
+
+func (ic *InstanceConfig) GetName() string {
+	return ic.Name
+}
+
+// ChildNames satisfies the parentConfig interface
+func (ic *InstanceConfig) ChildNames() []string {
+	return []string{ic.Shape}
+}
+
+// SetChildren satisfies the parentConfig interface
+func (ic *InstanceConfig) SetChildren(children []common.Traceable) error {
+	surface, ok := children[0].(Surface)
+	if !ok {
+		return errors.New(fmt.Sprintf("shape %s can not be instanced by %s.", ic.Shape, ic.Name))
+	}
+	ic.child = surface
+	return nil
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (ic *InstanceConfig) NewShape() (common.Traceable, error) {
+	return &Instance{
+		Name:     ic.Name,
+		Shape:    ic.child,
+		Material: ic.Material,
+	}, nil
+}
+
+// FromYaml generates Config from input yaml, shape names the shape to copy and
+// material when set replaces every material of the shape
+// satisfies the interface ShapesConfig (2/2)
+func (ic *InstanceConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	shape, err := config.Get("shape").String()
+	if err != nil {
+		return errors.New("instance requires a shape")
+	}
+	ic.Shape = shape
+
+	m, err := materialFromYaml(config, materials)
+	if err != nil {
+		return err
+	}
+	ic.Material = m
+
+	return nil
+}
+
+// Instance is a copy of Shape, when Material is set it is used instead of the
+// material of Shape
+type Instance struct {
+	Name     string
+	Shape    Surface
+	Material material.Material
+	surfaceHit
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene
+func (i *Instance) Intersect(ray *vmath.Ray) bool {
+	if !i.Shape.Intersect(ray) {
+		return false
+	}
+	ratio := i.Shape.GetIntersectionRatio()
+	i.surfaceHit = newSurfaceHit(i.Shape, ray.Origin.Add(ray.Direction.SMultiply(ratio)), ratio)
+	// the parts of one shape are told apart between its instances
+	i.part = [2]interface{}{i, i.part}
+	return true
+}
+
+// GetPosition satisfies requirements for Object
+// interface for a scene, an instance is where its shape is, the transform
+// block of an instance moves it by wrapping it in a Transformed
+func (i *Instance) GetPosition() vmath.Vector3d {
+	if object, ok := i.Shape.(common.Object); ok {
+		return object.GetPosition()
+	}
+	return vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+}
+
+func (i *Instance) GetName() string {
+	return i.Name
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (i *Instance) GetType() string {
+	return "instance"
+}
+
+// GetIntersectionRatio
+func (i *Instance) GetIntersectionRatio() float64 {
+	return i.intersectionRatio
+}
+
+// CalculateNorm returns the normal of Shape found by the last Intersect
+func (i *Instance) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	return i.norm
+}
+
+// CalculateUV returns the UV of Shape found by the last Intersect
+func (i *Instance) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	return i.uv
+}
+
+// CalculateFrame returns the directions u and v increase in across Shape
+// found by the last Intersect
+func (i *Instance) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	return i.tangent, i.bitangent
+}
+
+// ObjectPoint returns the hit of Shape found by the last Intersect in its
+// own space
+func (i *Instance) ObjectPoint(hit vmath.Vector3d) vmath.Vector3d {
+	return i.object
+}
+
+// ObjectNormal returns the normal of Shape found by the last Intersect in
+// its own space
+func (i *Instance) ObjectNormal(hit vmath.Vector3d) vmath.Vector3d {
+	return i.objectNorm
+}
+
+// GetMaterial returns Material if set, otherwise the material of Shape found by
+// the last Intersect
+func (i *Instance) GetMaterial() material.Material {
+	if i.Material != nil {
+		return i.Material
+	}
+	return i.material
+}
+
+func (i *Instance) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	h := i.surfaceHit
+	h.material = i.GetMaterial()
+	return shade(h, ray, objs)
+}
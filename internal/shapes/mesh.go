@@ -0,0 +1,240 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// MeshConfig defines a triangle mesh from a wavefront obj file for the
+// ShapeFactory
+type MeshConfig struct {
+	Name string
+	File string
+	// Subdivision is the number of Catmull-Clark steps the faces of the file
+	// are smoothed by, 0 renders the faces of the file as they are
+	Subdivision int
+	Material    material.Material
+}
+
+func (mc *MeshConfig) GetName() string {
+	return mc.Name
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (mc *MeshConfig) NewShape() (common.Traceable, error) {
+	polygons, err := loadOBJ(mc.File)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("mesh %s: %s", mc.Name, err.Error()))
+	}
+
+	var mesh *Mesh
+	if mc.Subdivision > 0 {
+		mesh, err = NewSubdivisionMesh(polygons, mc.Subdivision)
+	} else {
+		triangles, uvs := polygons.triangulate()
+		mesh, err = NewMesh(polygons.Vertices, triangles)
+		if err == nil {
+			mesh.UVs = uvs
+		}
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("mesh %s: %s", mc.Name, err.Error()))
+	}
+	mesh.Name = mc.Name
+	mesh.Material = mc.Material
+	return mesh, nil
+}
+
+// FromYaml generates Config from input yaml, file is an obj file and
+// subdivision the number of times it is smoothed
+// satisfies the interface ShapesConfig (2/2)
+func (mc *MeshConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	file, err := config.Get("file").String()
+	if err != nil {
+		return errors.New("mesh requires a file")
+	}
+	mc.File = file
+
+	if config.Get("subdivision").IsFound() {
+		subdivision, err := config.Get("subdivision").Int()
+		if err != nil || subdivision < 0 {
+			return errors.New("mesh subdivision must be 0 or more")
+		}
+		mc.Subdivision = subdivision
+	}
+
+	m, err := materialFromYaml(config, materials)
+	if err != nil {
+		return err
+	}
+	mc.Material = m
+
+	return nil
+}
+
+// Mesh is a set of triangles between Vertices found through a bvh, Normals
+// are interpolated across each triangle when there is one per vertex and UVs
+// are the texture coordinates at the corners of each triangle when there are
+// any
+type Mesh struct {
+	Name              string
+	Vertices          []vmath.Vector3d
+	Normals           []vmath.Vector3d
+	Triangles         [][3]int
+	UVs               [][3]vmath.Vector2d
+	PlaceHit          vmath.Vector3d
+	intersectionRatio float64
+
+	// the triangle last hit
+	hitTriangle int
+	bvh         *bvh
+
+	Material material.Material
+}
+
+// NewMesh makes a mesh of triangles between vertices, errors if a triangle
+// refers to a vertex that does not exist
+func NewMesh(vertices []vmath.Vector3d, triangles [][3]int) (*Mesh, error) {
+	if len(triangles) == 0 {
+		return nil, errors.New("mesh needs at least 1 triangle")
+	}
+	boxes := make([]aabb, len(triangles))
+	for index, triangle := range triangles {
+		boxes[index] = emptyBox()
+		for _, vertex := range triangle {
+			if vertex < 0 || vertex >= len(vertices) {
+				return nil, errors.New(fmt.Sprintf("mesh triangle %d refers to vertex %d which does not exist", index, vertex))
+			}
+			boxes[index] = boxes[index].grow(vertices[vertex])
+		}
+	}
+
+	return &Mesh{
+		Vertices:  vertices,
+		Triangles: triangles,
+		bvh:       newBVH(boxes),
+	}, nil
+}
+
+// corners returns the three vertices of triangle index
+func (m *Mesh) corners(index int) (vmath.Vector3d, vmath.Vector3d, vmath.Vector3d) {
+	triangle := m.Triangles[index]
+	return m.Vertices[triangle[0]], m.Vertices[triangle[1]], m.Vertices[triangle[2]]
+}
+
+// intersectTriangles returns the nearest triangle in front of the ray origin
+// and the ratio along ray it is hit at
+func (m *Mesh) intersectTriangles(ray *vmath.Ray) (int, float64, bool) {
+	return m.bvh.intersect(ray, func(index int) (float64, bool) {
+		v0, v1, v2 := m.corners(index)
+		t, _, _, ok := intersectTriangle(ray, v0, v1, v2)
+		return t, ok && t > 1e-9
+	})
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene
+func (m *Mesh) Intersect(ray *vmath.Ray) bool {
+	index, t, ok := m.intersectTriangles(ray)
+	if !ok {
+		return false
+	}
+	m.hitTriangle = index
+	m.intersectionRatio = t
+	m.PlaceHit = ray.Origin.Add(ray.Direction.SMultiply(t))
+	return true
+}
+
+// barycentric returns the weights of the second and third corner of the
+// triangle last hit at hit
+func (m *Mesh) barycentric(hit vmath.Vector3d) (float64, float64) {
+	v0, v1, v2 := m.corners(m.hitTriangle)
+	dual1, dual2 := dualBasis(v1.Subtract(v0), v2.Subtract(v0))
+	local := hit.Subtract(v0)
+	return dual1.Dot(local), dual2.Dot(local)
+}
+
+// GetPosition satisfies requirements for Object
+// interface for a scene
+func (m *Mesh) GetPosition() vmath.Vector3d {
+	return m.bvh.nodes[0].Box.center()
+}
+
+func (m *Mesh) GetName() string {
+	return m.Name
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (m *Mesh) GetType() string {
+	return "mesh"
+}
+
+// GetIntersectionRatio
+func (m *Mesh) GetIntersectionRatio() float64 {
+	return m.intersectionRatio
+}
+
+// CalculateNorm interpolates the vertex normals of the triangle last hit, or
+// returns its flat normal when the mesh has none
+func (m *Mesh) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	v0, v1, v2 := m.corners(m.hitTriangle)
+	flat := v1.Subtract(v0).Cross(v2.Subtract(v0))
+	flat.Normalize()
+	if len(m.Normals) != len(m.Vertices) {
+		return flat
+	}
+
+	b1, b2 := m.barycentric(hit)
+	triangle := m.Triangles[m.hitTriangle]
+	norm := m.Normals[triangle[0]].SMultiply(1 - b1 - b2).
+		Add(m.Normals[triangle[1]].SMultiply(b1)).
+		Add(m.Normals[triangle[2]].SMultiply(b2))
+	if norm.Normalize() != nil {
+		return flat
+	}
+
+	return norm
+}
+
+// CalculateUV interpolates the UVs at the corners of the triangle last hit,
+// without UVs every triangle covers the lower half of the unit square like a
+// Triangle
+func (m *Mesh) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	b1, b2 := m.barycentric(hit)
+	uvs := [3]vmath.Vector2d{{X: 0.0, Y: 0.0}, {X: 1.0, Y: 0.0}, {X: 0.0, Y: 1.0}}
+	if len(m.UVs) == len(m.Triangles) {
+		uvs = m.UVs[m.hitTriangle]
+	}
+	return uvs[0].SMultiply(1 - b1 - b2).
+		Add(uvs[1].SMultiply(b1)).
+		Add(uvs[2].SMultiply(b2))
+}
+
+// CalculateFrame returns the directions the UVs increase in across the
+// triangle last hit
+func (m *Mesh) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	v0, v1, v2 := m.corners(m.hitTriangle)
+	uvs := [3]vmath.Vector2d{{X: 0.0, Y: 0.0}, {X: 1.0, Y: 0.0}, {X: 0.0, Y: 1.0}}
+	if len(m.UVs) == len(m.Triangles) {
+		uvs = m.UVs[m.hitTriangle]
+	}
+	return uvFrame([3]vmath.Vector3d{v0, v1, v2}, uvs)
+}
+
+// GetMaterial returns the material the mesh is shaded with
+func (m *Mesh) GetMaterial() material.Material {
+	return m.Material
+}
+
+func (m *Mesh) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(m, m.PlaceHit, 0), ray, objs)
+}
@@ -0,0 +1,265 @@
+package shapes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// cubeOBJ is a cube from -1 to 1 with its faces wound outward
+const cubeOBJ = `
+# cube
+v -1 -1 -1
+v 1 -1 -1
+v 1 1 -1
+v -1 1 -1
+v -1 -1 1
+v 1 -1 1
+v 1 1 1
+v -1 1 1
+f 1 4 3 2
+f 5 6 7 8
+f 1 2 6 5
+f 3 4 8 7
+f 2 3 7 6
+f 1 5 8 4
+`
+
+func TestReadOBJ(t *testing.T) {
+	tests := []struct {
+		Description   string
+		OBJ           string
+		Expected      *polygonMesh
+		ExpectedError error
+	}{
+		{
+			Description: "quad with uvs and normals",
+			OBJ: `
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+vn 0 0 1
+f 1/1/1 2/2/1 3/3/1 4/4/1
+`,
+			Expected: &polygonMesh{
+				Vertices: []vmath.Vector3d{{X: 0, Y: 0, Z: 0}, {X: 1, Y: 0, Z: 0}, {X: 1, Y: 1, Z: 0}, {X: 0, Y: 1, Z: 0}},
+				UVs:      []vmath.Vector2d{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}},
+				Faces:    [][]int{{0, 1, 2, 3}},
+				FaceUVs:  [][]int{{0, 1, 2, 3}},
+			},
+		},
+		{
+			Description: "negative indices without uvs",
+			OBJ: `
+v 0 0 0
+v 1 0 0
+v 0 1 0
+f -3//1 -2//1 -1//1
+`,
+			Expected: &polygonMesh{
+				Vertices: []vmath.Vector3d{{X: 0, Y: 0, Z: 0}, {X: 1, Y: 0, Z: 0}, {X: 0, Y: 1, Z: 0}},
+				Faces:    [][]int{{0, 1, 2}},
+			},
+		},
+		{
+			Description:   "missing vertex",
+			OBJ:           "v 0 0 0\nv 1 0 0\nf 1 2 3\n",
+			ExpectedError: errors.New("obj line 3: vertex index 3 does not exist"),
+		},
+		{
+			Description:   "short face",
+			OBJ:           "v 0 0 0\nv 1 0 0\nf 1 2\n",
+			ExpectedError: errors.New("obj line 3: face requires 3 or more vertices"),
+		},
+		{
+			Description:   "bad vertex",
+			OBJ:           "v 0 zero 0\n",
+			ExpectedError: errors.New("obj line 1: value zero is not a number"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			mesh, err := readOBJ(strings.NewReader(test.OBJ))
+			if test.ExpectedError != nil {
+				assert.Equal(t, test.ExpectedError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, mesh)
+		})
+	}
+}
+
+func TestMeshConfigFromYaml(t *testing.T) {
+	tests := []struct {
+		Description   string
+		Yaml          string
+		Expected      *MeshConfig
+		ExpectedError error
+	}{
+		{
+			Description: "file with subdivision",
+			Yaml:        "file: cube.obj\nsubdivision: 2\n",
+			Expected:    &MeshConfig{File: "cube.obj", Subdivision: 2},
+		},
+		{
+			Description:   "missing file",
+			Yaml:          "subdivision: 2\n",
+			ExpectedError: errors.New("mesh requires a file"),
+		},
+		{
+			Description:   "negative subdivision",
+			Yaml:          "file: cube.obj\nsubdivision: -1\n",
+			ExpectedError: errors.New("mesh subdivision must be 0 or more"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml([]byte(test.Yaml))
+			require.NoError(t, err)
+			config := &MeshConfig{}
+			err = config.FromYaml(yaml, map[string]material.Material{})
+			if test.ExpectedError != nil {
+				assert.Equal(t, test.ExpectedError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, config)
+		})
+	}
+}
+
+func TestMeshIntersect(t *testing.T) {
+	polygons, err := readOBJ(strings.NewReader(cubeOBJ))
+	require.NoError(t, err)
+	triangles, _ := polygons.triangulate()
+	mesh, err := NewMesh(polygons.Vertices, triangles)
+	require.NoError(t, err)
+
+	tests := []struct {
+		Description   string
+		Ray           *vmath.Ray
+		Expected      bool
+		ExpectedRatio float64
+		ExpectedNorm  vmath.Vector3d
+	}{
+		{
+			Description:   "front face",
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{X: 0.5, Y: 0.25, Z: 5}, Direction: vmath.Vector3d{Z: -1}},
+			Expected:      true,
+			ExpectedRatio: 4,
+			ExpectedNorm:  vmath.Vector3d{Z: 1},
+		},
+		{
+			Description:   "side face with a long direction",
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{X: -5, Y: 0.5, Z: -0.5}, Direction: vmath.Vector3d{X: 2}},
+			Expected:      true,
+			ExpectedRatio: 2,
+			ExpectedNorm:  vmath.Vector3d{X: -1},
+		},
+		{
+			Description:   "from inside",
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{}, Direction: vmath.Vector3d{Y: 1}},
+			Expected:      true,
+			ExpectedRatio: 1,
+			ExpectedNorm:  vmath.Vector3d{Y: 1},
+		},
+		{
+			Description: "misses",
+			Ray:         &vmath.Ray{Origin: vmath.Vector3d{X: 1.5, Z: 5}, Direction: vmath.Vector3d{Z: -1}},
+		},
+		{
+			Description: "behind",
+			Ray:         &vmath.Ray{Origin: vmath.Vector3d{Z: 5}, Direction: vmath.Vector3d{Z: 1}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			require.Equal(t, test.Expected, mesh.Intersect(test.Ray))
+			if !test.Expected {
+				return
+			}
+			assert.InDelta(t, test.ExpectedRatio, mesh.GetIntersectionRatio(), 1e-9)
+			norm := mesh.CalculateNorm(mesh.PlaceHit)
+			assert.InDelta(t, test.ExpectedNorm.X, norm.X, 1e-9)
+			assert.InDelta(t, test.ExpectedNorm.Y, norm.Y, 1e-9)
+			assert.InDelta(t, test.ExpectedNorm.Z, norm.Z, 1e-9)
+		})
+	}
+}
+
+func TestNewMeshErrors(t *testing.T) {
+	_, err := NewMesh([]vmath.Vector3d{{}, {X: 1}}, [][3]int{{0, 1, 2}})
+	assert.Equal(t, errors.New("mesh triangle 0 refers to vertex 2 which does not exist"), err)
+	_, err = NewMesh([]vmath.Vector3d{{}, {X: 1}}, nil)
+	assert.Equal(t, errors.New("mesh needs at least 1 triangle"), err)
+}
+
+func TestCatmullClark(t *testing.T) {
+	polygons, err := readOBJ(strings.NewReader(cubeOBJ))
+	require.NoError(t, err)
+
+	once := catmullClark(polygons)
+	// a vertex per old vertex, edge and face and a quad per corner
+	assert.Len(t, once.Vertices, 8+12+6)
+	assert.Len(t, once.Faces, 24)
+	// the corners of a cube are pulled in to 5/9 of the way out
+	assert.InDelta(t, 5.0/9.0, once.Vertices[6].X, 1e-9)
+	assert.InDelta(t, 5.0/9.0, once.Vertices[6].Y, 1e-9)
+	assert.InDelta(t, 5.0/9.0, once.Vertices[6].Z, 1e-9)
+
+	// a flat grid stays flat and its limit normals point straight up
+	grid := &polygonMesh{}
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			grid.Vertices = append(grid.Vertices, vmath.Vector3d{X: float64(column), Y: float64(row)})
+		}
+	}
+	for row := 0; row < 3; row++ {
+		for column := 0; column < 3; column++ {
+			v := row*4 + column
+			grid.Faces = append(grid.Faces, []int{v, v + 1, v + 5, v + 4})
+		}
+	}
+	vertices, normals := limitSurface(catmullClark(grid))
+	for index := range vertices {
+		assert.InDelta(t, 0, vertices[index].Z, 1e-9)
+		assert.InDelta(t, 1, normals[index].Z, 1e-9)
+	}
+}
+
+func TestNewSubdivisionMesh(t *testing.T) {
+	polygons, err := readOBJ(strings.NewReader(cubeOBJ))
+	require.NoError(t, err)
+	mesh, err := NewSubdivisionMesh(polygons, 3)
+	require.NoError(t, err)
+
+	// the limit of a cube is a rounded blob inside of it with normals
+	// pointing away from its middle
+	for index, vertex := range mesh.Vertices {
+		assert.Less(t, vertex.Norm(), 1.0)
+		assert.Greater(t, vertex.Norm(), 0.5)
+		assert.Greater(t, mesh.Normals[index].Dot(vertex), 0.0)
+	}
+
+	ray := &vmath.Ray{Origin: vmath.Vector3d{Z: 5}, Direction: vmath.Vector3d{Z: -1}}
+	require.True(t, mesh.Intersect(ray))
+	norm := mesh.CalculateNorm(mesh.PlaceHit)
+	assert.InDelta(t, 1, norm.Z, 1e-6)
+	assert.Less(t, mesh.GetIntersectionRatio(), 4.5)
+}
@@ -0,0 +1,152 @@
+package shapes
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// polygonMesh is a mesh before it is split into triangles, each face lists
+// the indices of its Vertices in order around it, FaceUVs lists the index of
+// the UV of each corner of each face and is empty when the mesh has no UVs
+type polygonMesh struct {
+	Vertices []vmath.Vector3d
+	UVs      []vmath.Vector2d
+	Faces    [][]int
+	FaceUVs  [][]int
+}
+
+// loadOBJ reads the vertices, texture coordinates and faces of the wavefront
+// obj file at path, everything else in the file is ignored
+func loadOBJ(path string) (*polygonMesh, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readOBJ(file)
+}
+
+// readOBJ reads an obj file from r, see loadOBJ
+func readOBJ(r io.Reader) (*polygonMesh, error) {
+	mesh := &polygonMesh{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "v":
+			values, err := parseFloats(fields[1:], 3)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("obj line %d: %s", line, err.Error()))
+			}
+			mesh.Vertices = append(mesh.Vertices, vmath.Vector3d{X: values[0], Y: values[1], Z: values[2]})
+		case "vt":
+			values, err := parseFloats(fields[1:], 2)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("obj line %d: %s", line, err.Error()))
+			}
+			mesh.UVs = append(mesh.UVs, vmath.Vector2d{X: values[0], Y: values[1]})
+		case "f":
+			if len(fields) < 4 {
+				return nil, errors.New(fmt.Sprintf("obj line %d: face requires 3 or more vertices", line))
+			}
+			face, uvs := []int{}, []int{}
+			for _, corner := range fields[1:] {
+				// corners are v, v/vt, v//vn or v/vt/vn
+				indices := strings.Split(corner, "/")
+				v, err := objIndex(indices[0], len(mesh.Vertices))
+				if err != nil {
+					return nil, errors.New(fmt.Sprintf("obj line %d: vertex %s", line, err.Error()))
+				}
+				face = append(face, v)
+				if len(indices) > 1 && indices[1] != "" {
+					vt, err := objIndex(indices[1], len(mesh.UVs))
+					if err != nil {
+						return nil, errors.New(fmt.Sprintf("obj line %d: uv %s", line, err.Error()))
+					}
+					uvs = append(uvs, vt)
+				}
+			}
+			mesh.Faces = append(mesh.Faces, face)
+			mesh.FaceUVs = append(mesh.FaceUVs, uvs)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// UVs are only used when every corner of every face has one
+	for index, uvs := range mesh.FaceUVs {
+		if len(uvs) != len(mesh.Faces[index]) {
+			mesh.FaceUVs = nil
+			break
+		}
+	}
+
+	return mesh, nil
+}
+
+// parseFloats reads the first count fields as numbers
+func parseFloats(fields []string, count int) ([]float64, error) {
+	if len(fields) < count {
+		return nil, errors.New(fmt.Sprintf("requires %d values", count))
+	}
+	values := make([]float64, count)
+	for index := range values {
+		f, err := strconv.ParseFloat(fields[index], 64)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("value %s is not a number", fields[index]))
+		}
+		values[index] = f
+	}
+	return values, nil
+}
+
+// objIndex converts an obj index, which counts from 1 or back from the last
+// of count items when negative, into a slice index
+func objIndex(field string, count int) (int, error) {
+	index, err := strconv.Atoi(field)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("index %s is not a number", field))
+	}
+	if index < 0 {
+		index += count
+	} else {
+		index--
+	}
+	if index < 0 || index >= count {
+		return 0, errors.New(fmt.Sprintf("index %s does not exist", field))
+	}
+	return index, nil
+}
+
+// triangulate splits every face of m into a fan of triangles from its first
+// corner, along with the UVs of their corners when m has UVs
+func (m *polygonMesh) triangulate() ([][3]int, [][3]vmath.Vector2d) {
+	triangles := [][3]int{}
+	uvs := [][3]vmath.Vector2d{}
+	for index, face := range m.Faces {
+		for corner := 1; corner+1 < len(face); corner++ {
+			triangles = append(triangles, [3]int{face[0], face[corner], face[corner+1]})
+			if m.FaceUVs != nil {
+				faceUVs := m.FaceUVs[index]
+				uvs = append(uvs, [3]vmath.Vector2d{m.UVs[faceUVs[0]], m.UVs[faceUVs[corner]], m.UVs[faceUVs[corner+1]]})
+			}
+		}
+	}
+	if m.FaceUVs == nil {
+		uvs = nil
+	}
+	return triangles, uvs
+}
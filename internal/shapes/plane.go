@@ -21,6 +21,10 @@ type PlaneConfig struct {
 	Material material.Material
 }
 
+func (pc *PlaneConfig) GetName() string {
+	return pc.Name
+}
+
 // NewShape generates a Shape from the config object
 // satisfies the interface ShapesConfig (1/2)
 func (pc *PlaneConfig) NewShape() (common.Traceable, error) {
@@ -81,26 +85,27 @@ type Plane struct {
 }
 
 func NewPlane(pos vmath.Vector3d, normal vmath.Vector3d) *Plane {
-	plane := &Plane{
+	return &Plane{
 		P:    pos,
-		axis: []vmath.Vector3d{},
+		axis: normalAxis(normal),
 		a:    []float64{0.0, 0.0, 0.0, 1.0, 0.0},
 	}
-	// build 3 axis from supplied normal
+}
+
+// normalAxis builds 3 axis from the supplied normal, the normal is the last
+// axis and the other two are perpendicular to it and each other
+func normalAxis(normal vmath.Vector3d) []vmath.Vector3d {
 	normal.Normalize()
 	xaxis := normal.Cross(vmath.Vector3d{1.0, 0.0, 0.0})
-	if normal.Equals(vmath.Vector3d{1.0, 0.0, 0.0}) {
-		xaxis = normal.Cross(vmath.Vector3d{0.0, 1.0, 0.0})
+	if xaxis.IsZero() {
+		xaxis = normal.Cross(vmath.Vector3d{X: 0.0, Y: 1.0, Z: 0.0})
 	}
 	yaxis := xaxis.Cross(normal)
 	yaxis.Normalize()
 	xaxis = normal.Cross(yaxis)
 	xaxis.Normalize()
 
-	plane.axis = []vmath.Vector3d{xaxis, yaxis, normal}
-
-	return plane
-
+	return []vmath.Vector3d{xaxis, yaxis, normal}
 }
 
 // Intersect satisfies the qualifications for
@@ -116,6 +121,7 @@ func (p *Plane) Intersect(ray *vmath.Ray) bool {
 		return false
 	}
 
+	p.intersectionRatio = t
 	p.PlaceHit = ray.Origin.Add(ray.Direction.SMultiply(t))
 	return true
 }
@@ -136,34 +142,36 @@ func (p *Plane) GetType() string {
 	return "plane"
 }
 
+// CalculateNorm returns the normal the plane was built with
 func (p *Plane) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
-	ds := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
-	norm := p.axis[2].SMultiply(ds.Z).Add(p.axis[0].SMultiply(ds.X)).Add(p.axis[1].SMultiply(ds.Y))
-	norm.Normalize()
-	return norm
+	return p.axis[2]
 }
 
-func (p *Plane) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
-	color := &color.ColorValue{
-		Color: vmath.Vector3d{
-			X: 0.0,
-			Y: 0.0,
-			Z: 0.0,
-		},
-	}
-	nh := p.CalculateNorm(p.PlaceHit)     //normalized surface normal
-	nc := ray.Origin.Subtract(p.PlaceHit) //normalized camera and ph vector
-	nc.Normalize()
-	cameraAngle := nh.Dot(nc) //angle between camera and surface normal
-	for _, light := range objs.Lights {
-		nlh := light.ReturnLightVector(p.PlaceHit) // normalized light direction
-		lightAngle := nh.Dot(nlh)
-		color.Add(p.Material.ReturnColor(lightAngle, cameraAngle, 0, light))
+// CalculateUV returns how far hit is from the position of the plane along
+// its first two axes, so textures repeat every unit across the plane
+func (p *Plane) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	local := hit.Subtract(p.P)
+	return vmath.Vector2d{
+		X: p.axis[0].Dot(local),
+		Y: p.axis[1].Dot(local),
 	}
+}
+
+// CalculateFrame returns the first two axes of the plane, the directions u
+// and v increase in
+func (p *Plane) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	return p.axis[0], p.axis[1]
+}
 
-	return color
+// GetMaterial returns the material the plane is shaded with
+func (p *Plane) GetMaterial() material.Material {
+	return p.Material
+}
+
+func (p *Plane) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(p, p.PlaceHit, 0), ray, objs)
 }
 
 func (p *Plane) GetIntersectionRatio() float64 {
-	return 0.0
+	return p.intersectionRatio
 }
@@ -1 +1,72 @@
 package shapes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestPlaneIntersect(t *testing.T) {
+	tests := []struct {
+		Description   string
+		Expected      bool
+		ExpectedRatio float64
+		Normal        vmath.Vector3d
+		Ray           *vmath.Ray
+	}{
+		{
+			Description:   "Test Simple Hit",
+			Expected:      true,
+			ExpectedRatio: 4.0,
+			Normal:        vmath.Vector3d{X: 0, Y: 1, Z: 0},
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: 0, Y: 4, Z: 0},
+				Direction: vmath.Vector3d{X: 0, Y: -1, Z: 0},
+			},
+		},
+		{
+			Description: "Test parallel Miss",
+			Expected:    false,
+			Normal:      vmath.Vector3d{X: 0, Y: 1, Z: 0},
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: 0, Y: 4, Z: 0},
+				Direction: vmath.Vector3d{X: 1, Y: 0, Z: 0},
+			},
+		},
+		{
+			Description:   "Test negative X normal",
+			Expected:      true,
+			ExpectedRatio: 2.0,
+			Normal:        vmath.Vector3d{X: -1, Y: 0, Z: 0},
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: -2, Y: 0, Z: 0},
+				Direction: vmath.Vector3d{X: 1, Y: 0, Z: 0},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			plane := NewPlane(vmath.Vector3d{}, test.Normal)
+			assert.Equal(t, test.Expected, plane.Intersect(test.Ray))
+			if test.Expected {
+				assert.Equal(t, test.ExpectedRatio, plane.GetIntersectionRatio())
+			}
+		})
+	}
+}
+
+func TestPlaneCalculateNorm(t *testing.T) {
+	plane := NewPlane(vmath.Vector3d{X: 1, Y: 2, Z: 3}, vmath.Vector3d{X: 0, Y: 2, Z: 0})
+	hit := vmath.Vector3d{X: 4, Y: 2, Z: -1}
+	assert.Equal(t, vmath.Vector3d{X: 0, Y: 1, Z: 0}, plane.CalculateNorm(hit))
+
+	// uv is the distance along the first two axes from the plane position
+	uv := plane.CalculateUV(hit)
+	tangent, bitangent := plane.CalculateFrame(hit)
+	assert.InDelta(t, 25.0, uv.X*uv.X+uv.Y*uv.Y, 1e-12)
+	assert.InDelta(t, uv.X, tangent.Dot(hit.Subtract(plane.P)), 1e-12)
+	assert.InDelta(t, uv.Y, bitangent.Dot(hit.Subtract(plane.P)), 1e-12)
+}
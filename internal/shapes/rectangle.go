@@ -0,0 +1,138 @@
+package shapes
+
+import (
+	"errors"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// RectangleConfig defines a rectangle for the ShapeFactory
+type RectangleConfig struct {
+	Name     string
+	Corner   vmath.Vector3d
+	Edge1    vmath.Vector3d
+	Edge2    vmath.Vector3d
+	Material material.Material
+}
+
+func (rc *RectangleConfig) GetName() string {
+	return rc.Name
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (rc *RectangleConfig) NewShape() (common.Traceable, error) {
+	normal := rc.Edge1.Cross(rc.Edge2)
+	if normal.IsZero() {
+		return nil, errors.New("rectangle edges can not be zero or parallel")
+	}
+	rectangle := NewRectangle(rc.Corner, rc.Edge1, rc.Edge2)
+	rectangle.Name = rc.Name
+	rectangle.Material = rc.Material
+	return rectangle, nil
+}
+
+// FromYaml generates Config from input yaml
+// satisfies the interface ShapesConfig (2/2)
+func (rc *RectangleConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	for key, field := range map[string]*vmath.Vector3d{
+		"corner": &rc.Corner,
+		"edge1":  &rc.Edge1,
+		"edge2":  &rc.Edge2,
+	} {
+		if !config.Get(key).IsFound() {
+			continue
+		}
+		v, err := vector3dFromYaml(config.Get(key))
+		if err != nil {
+			return errors.New("rectangle " + key + ": " + err.Error())
+		}
+		*field = v
+	}
+
+	m, err := materialFromYaml(config, materials)
+	if err != nil {
+		return err
+	}
+	rc.Material = m
+
+	return nil
+}
+
+// Rectangle is a Plane bounded to the parallelogram spanned by Edge1 and Edge2
+// from its corner P
+type Rectangle struct {
+	*Plane
+	Edge1, Edge2 vmath.Vector3d
+	// dual basis of the edges, dotting a point on the plane with these gives
+	// its coordinates along each edge
+	dual1, dual2 vmath.Vector3d
+}
+
+// NewRectangle makes a rectangle from corner and the two edges leaving it, the
+// normal follows the right hand rule from edge1 to edge2
+func NewRectangle(corner vmath.Vector3d, edge1 vmath.Vector3d, edge2 vmath.Vector3d) *Rectangle {
+	normal := edge1.Cross(edge2)
+	dual1, dual2 := dualBasis(edge1, edge2)
+	return &Rectangle{
+		Plane: NewPlane(corner, normal),
+		Edge1: edge1,
+		Edge2: edge2,
+		dual1: dual1,
+		dual2: dual2,
+	}
+}
+
+// dualBasis returns the vectors d1, d2 in the plane of e1 and e2 for which
+// d1.e1 = d2.e2 = 1 and d1.e2 = d2.e1 = 0
+func dualBasis(e1 vmath.Vector3d, e2 vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	n := e1.Cross(e2)
+	nn := n.Normsqr()
+	return e2.Cross(n).Divide(nn), n.Cross(e1).Divide(nn)
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene
+func (r *Rectangle) Intersect(ray *vmath.Ray) bool {
+	if !r.Plane.Intersect(ray) {
+		return false
+	}
+
+	uv := r.CalculateUV(r.PlaceHit)
+	return uv.X >= 0 && uv.X <= 1 && uv.Y >= 0 && uv.Y <= 1
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (r *Rectangle) GetType() string {
+	return "rectangle"
+}
+
+// CalculateNorm returns the normal of the rectangle
+func (r *Rectangle) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	return r.axis[2]
+}
+
+// CalculateUV returns how far along Edge1 and Edge2 hit is
+func (r *Rectangle) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	local := hit.Subtract(r.P)
+	return vmath.Vector2d{
+		X: r.dual1.Dot(local),
+		Y: r.dual2.Dot(local),
+	}
+}
+
+// CalculateFrame returns the edges of the rectangle, the directions u and v
+// increase in
+func (r *Rectangle) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	return r.Edge1, r.Edge2
+}
+
+func (r *Rectangle) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(r, r.PlaceHit, 0), ray, objs)
+}
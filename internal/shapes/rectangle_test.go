@@ -0,0 +1,59 @@
+package shapes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestRectangleIntersect(t *testing.T) {
+	tests := []struct {
+		Description string
+		Expected    bool
+		ExpectedUV  vmath.Vector2d
+		Origin      vmath.Vector3d
+	}{
+		{
+			Description: "hits center",
+			Expected:    true,
+			ExpectedUV:  vmath.Vector2d{X: 0.5, Y: 0.5},
+			Origin:      vmath.Vector3d{X: 2.0, Y: 5.0, Z: 0.5},
+		},
+		{
+			Description: "hits near corner",
+			Expected:    true,
+			ExpectedUV:  vmath.Vector2d{X: 0.25, Y: 0.1},
+			Origin:      vmath.Vector3d{X: 1.0, Y: 5.0, Z: 0.1},
+		},
+		{
+			Description: "misses past edge2",
+			Expected:    false,
+			Origin:      vmath.Vector3d{X: 2.0, Y: 5.0, Z: 1.5},
+		},
+		{
+			Description: "misses behind corner",
+			Expected:    false,
+			Origin:      vmath.Vector3d{X: -0.5, Y: 5.0, Z: 0.5},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			// a 4x1 tile, edge1 x edge2 points the normal down
+			rectangle := NewRectangle(vmath.Vector3d{},
+				vmath.Vector3d{X: 4, Y: 0, Z: 0},
+				vmath.Vector3d{X: 0, Y: 0, Z: 1})
+			ray := &vmath.Ray{Origin: test.Origin, Direction: vmath.Vector3d{X: 0, Y: -1, Z: 0}}
+			assert.Equal(t, test.Expected, rectangle.Intersect(ray))
+			if test.Expected {
+				assert.InDelta(t, 5.0, rectangle.GetIntersectionRatio(), 1e-9)
+				assert.Equal(t, vmath.Vector3d{X: 0, Y: -1, Z: 0}, rectangle.CalculateNorm(rectangle.PlaceHit))
+				uv := rectangle.CalculateUV(rectangle.PlaceHit)
+				assert.InDelta(t, test.ExpectedUV.X, uv.X, 1e-9)
+				assert.InDelta(t, test.ExpectedUV.Y, uv.Y, 1e-9)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// SDFConfig defines a shape by a tree of signed distance functions for the
+// ShapeFactory
+type SDFConfig struct {
+	Name     string
+	Root     SDFNode
+	Material material.Material
+	// MaxSteps, Epsilon and MaxDistance bound the sphere tracing, StepScale
+	// shortens every step for trees with twist or bend which overestimate
+	// the distance
+	MaxSteps    int
+	Epsilon     float64
+	MaxDistance float64
+	StepScale   float64
+}
+
+func (sc *SDFConfig) GetName() string {
+	return sc.Name
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (sc *SDFConfig) NewShape() (common.Traceable, error) {
+	if sc.Root == nil {
+		return nil, errors.New(fmt.Sprintf("sdf %s requires a node.", sc.Name))
+	}
+	if sc.MaxSteps <= 0 || sc.Epsilon <= 0 || sc.MaxDistance <= 0 || sc.StepScale <= 0 {
+		return nil, errors.New("sdf max_steps, epsilon, max_distance and step_scale must be positive")
+	}
+	sdf := NewSDF(sc.Root)
+	sdf.Name = sc.Name
+	sdf.Material = sc.Material
+	sdf.MaxSteps = sc.MaxSteps
+	sdf.Epsilon = sc.Epsilon
+	sdf.MaxDistance = sc.MaxDistance
+	sdf.StepScale = sc.StepScale
+	return sdf, nil
+}
+
+// FromYaml generates Config from input yaml, node is the root of the tree of
+// distance functions
+// satisfies the interface ShapesConfig (2/2)
+func (sc *SDFConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	sc.MaxSteps = 256
+	sc.Epsilon = 1e-4
+	sc.MaxDistance = 1000.0
+	sc.StepScale = 1.0
+	if maxSteps, err := config.Get("max_steps").Int(); err == nil {
+		sc.MaxSteps = maxSteps
+	}
+	for key, value := range map[string]*float64{
+		"epsilon":      &sc.Epsilon,
+		"max_distance": &sc.MaxDistance,
+		"step_scale":   &sc.StepScale,
+	} {
+		if !config.Get(key).IsFound() {
+			continue
+		}
+		f, err := floatFromYaml(config.Get(key))
+		if err != nil {
+			return errors.New(fmt.Sprintf("sdf %s: %s", key, err.Error()))
+		}
+		*value = f
+	}
+
+	if !config.Get("node").IsFound() {
+		return errors.New("sdf requires a node")
+	}
+	root, err := sdfNodeFromYaml(config.Get("node"))
+	if err != nil {
+		return err
+	}
+	sc.Root = root
+
+	m, err := materialFromYaml(config, materials)
+	if err != nil {
+		return err
+	}
+	sc.Material = m
+
+	return nil
+}
+
+// SDF is the surface where the distance of Root is zero, found by sphere
+// tracing along the ray
+type SDF struct {
+	Name              string
+	Root              SDFNode
+	PlaceHit          vmath.Vector3d
+	intersectionRatio float64
+
+	MaxSteps    int
+	Epsilon     float64
+	MaxDistance float64
+	StepScale   float64
+
+	Material material.Material
+}
+
+// NewSDF makes a sdf of root with the default tracing limits
+func NewSDF(root SDFNode) *SDF {
+	return &SDF{
+		Root:        root,
+		MaxSteps:    256,
+		Epsilon:     1e-4,
+		MaxDistance: 1000.0,
+		StepScale:   1.0,
+	}
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene
+func (s *SDF) Intersect(ray *vmath.Ray) bool {
+	// march in world distance, the direction of a transformed ray is not
+	// normalized
+	length := ray.Direction.Norm()
+	if length == 0 {
+		return false
+	}
+	direction := ray.Direction.Divide(length)
+
+	distance := 0.0
+	for step := 0; step < s.MaxSteps && distance < s.MaxDistance; step++ {
+		d := s.Root.Distance(ray.Origin.Add(direction.SMultiply(distance)))
+		if math.Abs(d) < s.Epsilon && distance > s.Epsilon {
+			s.intersectionRatio = distance / length
+			s.PlaceHit = ray.Origin.Add(ray.Direction.SMultiply(s.intersectionRatio))
+			return true
+		}
+		// inside the surface the distance is negative, march out the same
+		distance += math.Max(math.Abs(d)*s.StepScale, s.Epsilon)
+	}
+	return false
+}
+
+// GetPosition satisfies requirements for Object
+// interface for a scene
+func (s *SDF) GetPosition() vmath.Vector3d {
+	return vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+}
+
+func (s *SDF) GetName() string {
+	return s.Name
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (s *SDF) GetType() string {
+	return "sdf"
+}
+
+// GetIntersectionRatio
+func (s *SDF) GetIntersectionRatio() float64 {
+	return s.intersectionRatio
+}
+
+// CalculateNorm takes the gradient of the distance by finite differences at
+// the four corners of a tetrahedron around hit
+func (s *SDF) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	h := s.Epsilon
+	grad := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	for _, k := range []vmath.Vector3d{
+		{X: 1, Y: -1, Z: -1},
+		{X: -1, Y: -1, Z: 1},
+		{X: -1, Y: 1, Z: -1},
+		{X: 1, Y: 1, Z: 1},
+	} {
+		grad = grad.Add(k.SMultiply(s.Root.Distance(hit.Add(k.SMultiply(h)))))
+	}
+	grad.Normalize()
+
+	return grad
+}
+
+// CalculateUV wraps uv around the origin like a sphere
+func (s *SDF) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	return sphericalUV(hit.Subtract(s.GetPosition()))
+}
+
+// GetMaterial returns the material the sdf is shaded with
+func (s *SDF) GetMaterial() material.Material {
+	return s.Material
+}
+
+func (s *SDF) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(s, s.PlaceHit, 0), ray, objs)
+}
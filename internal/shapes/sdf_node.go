@@ -0,0 +1,266 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/smallfish/simpleyaml"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// SDFNode is a signed distance function, negative inside the surface
+type SDFNode interface {
+	Distance(p vmath.Vector3d) float64
+}
+
+// SDFSphere is a sphere of Radius around P
+type SDFSphere struct {
+	P      vmath.Vector3d
+	Radius float64
+}
+
+func (s *SDFSphere) Distance(p vmath.Vector3d) float64 {
+	return p.Subtract(s.P).Norm() - s.Radius
+}
+
+// SDFBox is an axis aligned box around P with Half its size on each axis, a
+// Radius above zero rounds its edges without growing it
+type SDFBox struct {
+	P      vmath.Vector3d
+	Half   vmath.Vector3d
+	Radius float64
+}
+
+func (b *SDFBox) Distance(p vmath.Vector3d) float64 {
+	local := p.Subtract(b.P)
+	q := vmath.Vector3d{
+		X: math.Abs(local.X) - b.Half.X + b.Radius,
+		Y: math.Abs(local.Y) - b.Half.Y + b.Radius,
+		Z: math.Abs(local.Z) - b.Half.Z + b.Radius,
+	}
+	outside := vmath.Vector3d{X: math.Max(q.X, 0), Y: math.Max(q.Y, 0), Z: math.Max(q.Z, 0)}
+	inside := math.Min(math.Max(q.X, math.Max(q.Y, q.Z)), 0)
+	return outside.Norm() + inside - b.Radius
+}
+
+// SDFTorus is a ring of MajorRadius around P in the XZ plane with a tube of
+// MinorRadius
+type SDFTorus struct {
+	P                        vmath.Vector3d
+	MajorRadius, MinorRadius float64
+}
+
+func (t *SDFTorus) Distance(p vmath.Vector3d) float64 {
+	local := p.Subtract(t.P)
+	ring := math.Sqrt(local.X*local.X+local.Z*local.Z) - t.MajorRadius
+	return math.Sqrt(ring*ring+local.Y*local.Y) - t.MinorRadius
+}
+
+// SDFCapsule is every point within Radius of the segment from A to B
+type SDFCapsule struct {
+	A, B   vmath.Vector3d
+	Radius float64
+}
+
+func (c *SDFCapsule) Distance(p vmath.Vector3d) float64 {
+	pa := p.Subtract(c.A)
+	ba := c.B.Subtract(c.A)
+	h := 0.0
+	if ba.Dot(ba) > 0 {
+		h = math.Min(math.Max(pa.Dot(ba)/ba.Dot(ba), 0), 1)
+	}
+	return pa.Subtract(ba.SMultiply(h)).Norm() - c.Radius
+}
+
+// SDF operations combining the Nodes of a SDFCombine
+const (
+	SDFUnion     = "union"
+	SDFSubtract  = "subtract"
+	SDFIntersect = "intersect"
+)
+
+// SDFCombine folds Nodes together by Operation, the later nodes are removed
+// from the first by a subtract, K above zero blends the surfaces together over
+// about that distance
+type SDFCombine struct {
+	Operation string
+	Nodes     []SDFNode
+	K         float64
+}
+
+func (c *SDFCombine) Distance(p vmath.Vector3d) float64 {
+	d := c.Nodes[0].Distance(p)
+	for _, node := range c.Nodes[1:] {
+		d = combineDistance(c.Operation, d, node.Distance(p), c.K)
+	}
+	return d
+}
+
+// combineDistance joins the distances a and b with the polynomial smooth min
+// and max when k is above zero
+func combineDistance(operation string, a float64, b float64, k float64) float64 {
+	switch operation {
+	case SDFSubtract:
+		b = -b
+		fallthrough
+	case SDFIntersect:
+		if k <= 0 {
+			return math.Max(a, b)
+		}
+		h := math.Min(math.Max(0.5-0.5*(b-a)/k, 0), 1)
+		return b + (a-b)*h + k*h*(1-h)
+	default:
+		if k <= 0 {
+			return math.Min(a, b)
+		}
+		h := math.Min(math.Max(0.5+0.5*(b-a)/k, 0), 1)
+		return b + (a-b)*h - k*h*(1-h)
+	}
+}
+
+// SDFRepeat tiles Node every Period, an axis with a zero period is not
+// repeated
+type SDFRepeat struct {
+	Node   SDFNode
+	Period vmath.Vector3d
+}
+
+func (r *SDFRepeat) Distance(p vmath.Vector3d) float64 {
+	return r.Node.Distance(vmath.Vector3d{
+		X: repeatAxis(p.X, r.Period.X),
+		Y: repeatAxis(p.Y, r.Period.Y),
+		Z: repeatAxis(p.Z, r.Period.Z),
+	})
+}
+
+func repeatAxis(v float64, period float64) float64 {
+	if period <= 0 {
+		return v
+	}
+	return v - period*math.Round(v/period)
+}
+
+// SDFTwist rotates Node around the Y axis by Amount radians per unit of Y
+type SDFTwist struct {
+	Node   SDFNode
+	Amount float64
+}
+
+func (t *SDFTwist) Distance(p vmath.Vector3d) float64 {
+	sin, cos := math.Sincos(t.Amount * p.Y)
+	return t.Node.Distance(vmath.Vector3d{
+		X: cos*p.X - sin*p.Z,
+		Y: p.Y,
+		Z: sin*p.X + cos*p.Z,
+	})
+}
+
+// SDFBend curls Node around the Z axis by Amount radians per unit of X
+type SDFBend struct {
+	Node   SDFNode
+	Amount float64
+}
+
+func (b *SDFBend) Distance(p vmath.Vector3d) float64 {
+	sin, cos := math.Sincos(b.Amount * p.X)
+	return b.Node.Distance(vmath.Vector3d{
+		X: cos*p.X - sin*p.Y,
+		Y: sin*p.X + cos*p.Y,
+		Z: p.Z,
+	})
+}
+
+// sdfNodeFromYaml reads a node of a sdf tree, type picks a primitive (sphere,
+// box, rounded_box, torus, capsule), a combination of nodes (union, subtract,
+// intersect and their smooth_ versions) or an operation on the domain of one
+// node (repeat, twist, bend)
+func sdfNodeFromYaml(config *simpleyaml.Yaml) (SDFNode, error) {
+	t, err := config.Get("type").String()
+	if err != nil {
+		return nil, errors.New("sdf node requires a type")
+	}
+
+	// every number and vector read by a node, missing ones are left at zero
+	numbers := map[string]float64{}
+	vectors := map[string]vmath.Vector3d{}
+	for _, key := range []string{"radius", "major_radius", "minor_radius", "k", "amount"} {
+		if !config.Get(key).IsFound() {
+			continue
+		}
+		f, err := floatFromYaml(config.Get(key))
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("sdf %s %s: %s", t, key, err.Error()))
+		}
+		numbers[key] = f
+	}
+	for _, key := range []string{"position", "size", "a", "b", "period"} {
+		if !config.Get(key).IsFound() {
+			continue
+		}
+		v, err := vector3dFromYaml(config.Get(key))
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("sdf %s %s: %s", t, key, err.Error()))
+		}
+		vectors[key] = v
+	}
+
+	switch t {
+	case "sphere":
+		return &SDFSphere{P: vectors["position"], Radius: numbers["radius"]}, nil
+	case "box", "rounded_box":
+		size := vectors["size"]
+		if size.X <= 0 || size.Y <= 0 || size.Z <= 0 {
+			return nil, errors.New(fmt.Sprintf("sdf %s size must be positive on every axis", t))
+		}
+		box := &SDFBox{P: vectors["position"], Half: size.SMultiply(0.5), Radius: numbers["radius"]}
+		if box.Radius*2 > math.Min(size.X, math.Min(size.Y, size.Z)) {
+			return nil, errors.New(fmt.Sprintf("sdf %s radius can not be more than half its size", t))
+		}
+		return box, nil
+	case "torus":
+		return &SDFTorus{P: vectors["position"], MajorRadius: numbers["major_radius"], MinorRadius: numbers["minor_radius"]}, nil
+	case "capsule":
+		return &SDFCapsule{A: vectors["a"], B: vectors["b"], Radius: numbers["radius"]}, nil
+	case "union", "subtract", "intersect", "smooth_union", "smooth_subtract", "smooth_intersect":
+		combine := &SDFCombine{Operation: strings.TrimPrefix(t, "smooth_")}
+		if combine.Operation != t {
+			combine.K = numbers["k"]
+			if combine.K <= 0 {
+				return nil, errors.New(fmt.Sprintf("sdf %s requires a positive k", t))
+			}
+		}
+		count, err := config.Get("nodes").GetArraySize()
+		if err != nil || count < 2 {
+			return nil, errors.New(fmt.Sprintf("sdf %s requires 2 or more nodes", t))
+		}
+		for index := 0; index < count; index++ {
+			node, err := sdfNodeFromYaml(config.Get("nodes").GetIndex(index))
+			if err != nil {
+				return nil, err
+			}
+			combine.Nodes = append(combine.Nodes, node)
+		}
+		return combine, nil
+	case "repeat", "twist", "bend":
+		if !config.Get("node").IsFound() {
+			return nil, errors.New(fmt.Sprintf("sdf %s requires a node", t))
+		}
+		node, err := sdfNodeFromYaml(config.Get("node"))
+		if err != nil {
+			return nil, err
+		}
+		switch t {
+		case "repeat":
+			return &SDFRepeat{Node: node, Period: vectors["period"]}, nil
+		case "twist":
+			return &SDFTwist{Node: node, Amount: numbers["amount"]}, nil
+		default:
+			return &SDFBend{Node: node, Amount: numbers["amount"]}, nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("sdf node type %s is not supported", t))
+}
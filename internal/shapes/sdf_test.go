@@ -0,0 +1,293 @@
+package shapes
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestSDFDistance(t *testing.T) {
+	tests := []struct {
+		Description string
+		Node        SDFNode
+		Point       vmath.Vector3d
+		Expected    float64
+	}{
+		{
+			Description: "sphere outside",
+			Node:        &SDFSphere{P: vmath.Vector3d{X: 1}, Radius: 1},
+			Point:       vmath.Vector3d{X: 4},
+			Expected:    2,
+		},
+		{
+			Description: "box face",
+			Node:        &SDFBox{Half: vmath.Vector3d{X: 1, Y: 1, Z: 1}},
+			Point:       vmath.Vector3d{Y: 3},
+			Expected:    2,
+		},
+		{
+			Description: "box corner",
+			Node:        &SDFBox{Half: vmath.Vector3d{X: 1, Y: 1, Z: 1}},
+			Point:       vmath.Vector3d{X: 2, Y: 2, Z: 1},
+			Expected:    math.Sqrt2,
+		},
+		{
+			Description: "box inside",
+			Node:        &SDFBox{Half: vmath.Vector3d{X: 1, Y: 2, Z: 2}},
+			Point:       vmath.Vector3d{X: 0.5},
+			Expected:    -0.5,
+		},
+		{
+			Description: "rounded box corner",
+			Node:        &SDFBox{Half: vmath.Vector3d{X: 1, Y: 1, Z: 1}, Radius: 0.5},
+			Point:       vmath.Vector3d{X: 2, Y: 2},
+			Expected:    math.Sqrt(2*1.5*1.5) - 0.5,
+		},
+		{
+			Description: "torus",
+			Node:        &SDFTorus{MajorRadius: 2, MinorRadius: 0.5},
+			Point:       vmath.Vector3d{Z: 2, Y: 1},
+			Expected:    0.5,
+		},
+		{
+			Description: "capsule side",
+			Node:        &SDFCapsule{A: vmath.Vector3d{Y: -1}, B: vmath.Vector3d{Y: 1}, Radius: 0.5},
+			Point:       vmath.Vector3d{X: 2},
+			Expected:    1.5,
+		},
+		{
+			Description: "capsule end",
+			Node:        &SDFCapsule{A: vmath.Vector3d{Y: -1}, B: vmath.Vector3d{Y: 1}, Radius: 0.5},
+			Point:       vmath.Vector3d{Y: 3},
+			Expected:    1.5,
+		},
+		{
+			Description: "union is the nearest",
+			Node: &SDFCombine{Operation: SDFUnion, Nodes: []SDFNode{
+				&SDFSphere{P: vmath.Vector3d{X: -2}, Radius: 1},
+				&SDFSphere{P: vmath.Vector3d{X: 2}, Radius: 1},
+			}},
+			Point:    vmath.Vector3d{X: 4},
+			Expected: 1,
+		},
+		{
+			Description: "smooth union fills the gap",
+			Node: &SDFCombine{Operation: SDFUnion, K: 1, Nodes: []SDFNode{
+				&SDFSphere{P: vmath.Vector3d{X: -1.5}, Radius: 1},
+				&SDFSphere{P: vmath.Vector3d{X: 1.5}, Radius: 1},
+			}},
+			Point:    vmath.Vector3d{},
+			Expected: 0.25,
+		},
+		{
+			Description: "subtract leaves a shell",
+			Node: &SDFCombine{Operation: SDFSubtract, Nodes: []SDFNode{
+				&SDFSphere{Radius: 2},
+				&SDFSphere{Radius: 1},
+			}},
+			Point:    vmath.Vector3d{X: 0.25},
+			Expected: 0.75,
+		},
+		{
+			Description: "intersect",
+			Node: &SDFCombine{Operation: SDFIntersect, Nodes: []SDFNode{
+				&SDFSphere{P: vmath.Vector3d{X: -0.5}, Radius: 1},
+				&SDFSphere{P: vmath.Vector3d{X: 0.5}, Radius: 1},
+			}},
+			Point:    vmath.Vector3d{X: 1},
+			Expected: 0.5,
+		},
+		{
+			Description: "repeat",
+			Node:        &SDFRepeat{Node: &SDFSphere{Radius: 1}, Period: vmath.Vector3d{X: 10}},
+			Point:       vmath.Vector3d{X: 32},
+			Expected:    1,
+		},
+		{
+			Description: "twist",
+			Node:        &SDFTwist{Node: &SDFSphere{P: vmath.Vector3d{X: 2}, Radius: 1}, Amount: math.Pi / 2},
+			Point:       vmath.Vector3d{Z: -2, Y: 1},
+			Expected:    0,
+		},
+		{
+			Description: "bend",
+			Node:        &SDFBend{Node: &SDFSphere{Radius: 1}, Amount: 0},
+			Point:       vmath.Vector3d{Y: 3},
+			Expected:    2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			assert.InDelta(t, test.Expected, test.Node.Distance(test.Point), 1e-9)
+		})
+	}
+}
+
+func TestSDFIntersect(t *testing.T) {
+	tests := []struct {
+		Description   string
+		SDF           *SDF
+		Ray           *vmath.Ray
+		Expected      bool
+		ExpectedRatio float64
+		ExpectedNorm  vmath.Vector3d
+	}{
+		{
+			Description:   "sphere matches the analytic sphere",
+			SDF:           NewSDF(&SDFSphere{Radius: 1}),
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{Z: 10}, Direction: vmath.Vector3d{Z: -1}},
+			Expected:      true,
+			ExpectedRatio: 9,
+			ExpectedNorm:  vmath.Vector3d{Z: 1},
+		},
+		{
+			Description:   "unnormalized direction keeps the ratio",
+			SDF:           NewSDF(&SDFBox{Half: vmath.Vector3d{X: 1, Y: 1, Z: 1}}),
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{X: 10}, Direction: vmath.Vector3d{X: -3}},
+			Expected:      true,
+			ExpectedRatio: 3,
+			ExpectedNorm:  vmath.Vector3d{X: 1},
+		},
+		{
+			Description:   "inside goes out",
+			SDF:           NewSDF(&SDFSphere{Radius: 1}),
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{}, Direction: vmath.Vector3d{Y: 1}},
+			Expected:      true,
+			ExpectedRatio: 1,
+			ExpectedNorm:  vmath.Vector3d{Y: 1},
+		},
+		{
+			Description: "miss",
+			SDF:         NewSDF(&SDFSphere{Radius: 1}),
+			Ray:         &vmath.Ray{Origin: vmath.Vector3d{Y: 2, Z: 10}, Direction: vmath.Vector3d{Z: -1}},
+			Expected:    false,
+		},
+		{
+			Description: "beyond max distance",
+			SDF: func() *SDF {
+				s := NewSDF(&SDFSphere{Radius: 1})
+				s.MaxDistance = 5
+				return s
+			}(),
+			Ray:      &vmath.Ray{Origin: vmath.Vector3d{Z: 10}, Direction: vmath.Vector3d{Z: -1}},
+			Expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			assert.Equal(t, test.Expected, test.SDF.Intersect(test.Ray))
+			if test.Expected {
+				assert.InDelta(t, test.ExpectedRatio, test.SDF.GetIntersectionRatio(), 1e-3)
+				norm := test.SDF.CalculateNorm(test.SDF.PlaceHit)
+				assert.InDelta(t, test.ExpectedNorm.X, norm.X, 1e-3)
+				assert.InDelta(t, test.ExpectedNorm.Y, norm.Y, 1e-3)
+				assert.InDelta(t, test.ExpectedNorm.Z, norm.Z, 1e-3)
+			}
+		})
+	}
+}
+
+func TestSDFConfigFromYaml(t *testing.T) {
+	tests := []struct {
+		Description string
+		Bytes       []byte
+		Point       vmath.Vector3d
+		Expected    float64
+		ExpectedErr error
+	}{
+		{
+			Description: "tree of nodes",
+			Bytes: []byte(`
+max_steps: 64
+epsilon: 0.001
+node:
+  type: smooth_union
+  k: 0.5
+  nodes:
+    - type: sphere
+      radius: 1
+    - type: repeat
+      period: [4, 0, 0]
+      node:
+        type: rounded_box
+        size: [1, 1, 1]
+        radius: 0.1
+`),
+			Point:    vmath.Vector3d{Y: 3},
+			Expected: 2,
+		},
+		{
+			Description: "missing node",
+			Bytes: []byte(`
+max_steps: 64
+`),
+			ExpectedErr: errors.New("sdf requires a node"),
+		},
+		{
+			Description: "unknown node",
+			Bytes: []byte(`
+node:
+  type: cone
+`),
+			ExpectedErr: errors.New("sdf node type cone is not supported"),
+		},
+		{
+			Description: "union of one",
+			Bytes: []byte(`
+node:
+  type: union
+  nodes:
+    - type: sphere
+      radius: 1
+`),
+			ExpectedErr: errors.New("sdf union requires 2 or more nodes"),
+		},
+		{
+			Description: "smooth without k",
+			Bytes: []byte(`
+node:
+  type: smooth_subtract
+  nodes:
+    - type: sphere
+      radius: 1
+    - type: sphere
+      radius: 0.5
+`),
+			ExpectedErr: errors.New("sdf smooth_subtract requires a positive k"),
+		},
+		{
+			Description: "too round",
+			Bytes: []byte(`
+node:
+  type: rounded_box
+  size: [1, 1, 1]
+  radius: 0.6
+`),
+			ExpectedErr: errors.New("sdf rounded_box radius can not be more than half its size"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			config := &SDFConfig{}
+			err = config.FromYaml(yaml, map[string]material.Material{})
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, test.Expected, config.Root.Distance(test.Point), 1e-9)
+		})
+	}
+}
@@ -1,9 +1,17 @@
 package shapes
 
 import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
 	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/lights"
 	"github.com/chrispotter/trace/internal/material"
-	"github.com/smallfish/simpleyaml"
+	vmath "github.com/chrispotter/trace/internal/math"
 )
 
 // ShapesConfig is an interface to define all configs able to provide to
@@ -11,10 +19,183 @@ import (
 type ShapesConfig interface {
 	FromYaml(*simpleyaml.Yaml, map[string]material.Material) error
 	NewShape() (common.Traceable, error)
+	GetName() string
+}
+
+// parentConfig is a ShapesConfig made from other shapes in the scene, the
+// shapes it names are built first and handed to it instead of being rendered
+// on their own
+type parentConfig interface {
+	ShapesConfig
+	ChildNames() []string
+	SetChildren([]common.Traceable) error
+}
+
+// Crossing is a point along a ray where it enters or leaves a Solid
+type Crossing struct {
+	T     float64
+	Solid Solid
+	// Flip is set when the outward normal of Solid points into the shape
+	// this Crossing bounds, like the walls of a hole cut by a difference
+	Flip bool
+}
+
+// Interval is a span of a ray inside of a Solid, In.T < Out.T
+type Interval struct {
+	In, Out Crossing
+}
+
+// Surface is a Traceable that can report the normal and material at any point
+// on it, which is all a parent shape needs to shade it
+type Surface interface {
+	common.Traceable
+	CalculateNorm(hit vmath.Vector3d) vmath.Vector3d
+	GetMaterial() material.Material
+}
+
+// Framed is a Surface that knows the directions u and v increase in across
+// it, normal and bump maps are bent along them
+type Framed interface {
+	CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d)
+}
+
+// Placed is a Surface that is moved into the world from a space of its own,
+// procedural colors in object space are read in that space
+type Placed interface {
+	ObjectPoint(hit vmath.Vector3d) vmath.Vector3d
+	ObjectNormal(hit vmath.Vector3d) vmath.Vector3d
+}
+
+// Parted is a Surface made of other surfaces, Part tells apart which of them
+// the last Intersect hit so lines can be drawn between them
+type Parted interface {
+	Part() interface{}
+}
+
+// surfaceHit is the shading of a Surface saved as soon as it is hit, shapes
+// shared by groups and instances are intersected again by every parent before
+// the nearest hit is shaded so their own state can not be relied on
+type surfaceHit struct {
+	PlaceHit           vmath.Vector3d
+	object, objectNorm vmath.Vector3d
+	intersectionRatio  float64
+	norm               vmath.Vector3d
+	tangent, bitangent vmath.Vector3d
+	uv                 vmath.Vector2d
+	// uvAt reads the uv of the surface anywhere near the hit, nil when the
+	// surface has no uv
+	uvAt     func(vmath.Vector3d) vmath.Vector2d
+	material material.Material
+	// part is the surface hit, or the part of it hit when it is Parted
+	part interface{}
+}
+
+// newSurfaceHit saves the shading of surface at hit
+func newSurfaceHit(surface Surface, hit vmath.Vector3d, ratio float64) surfaceHit {
+	h := surfaceHit{
+		PlaceHit:          hit,
+		object:            hit,
+		intersectionRatio: ratio,
+		norm:              surface.CalculateNorm(hit),
+		material:          surface.GetMaterial(),
+		part:              surface,
+	}
+	h.objectNorm = h.norm
+	if parted, ok := surface.(Parted); ok {
+		h.part = parted.Part()
+	}
+	if uv, ok := surface.(interface {
+		CalculateUV(vmath.Vector3d) vmath.Vector2d
+	}); ok {
+		h.uv = uv.CalculateUV(hit)
+		h.uvAt = uv.CalculateUV
+	}
+	if framed, ok := surface.(Framed); ok {
+		h.tangent, h.bitangent = framed.CalculateFrame(hit)
+	}
+	if placed, ok := surface.(Placed); ok {
+		h.object = placed.ObjectPoint(hit)
+		h.objectNorm = placed.ObjectNormal(hit)
+	}
+	return h
+}
+
+// Part satisfies the Parted interface for the shapes that save their hit
+func (h surfaceHit) Part() interface{} {
+	return h.part
+}
+
+// shadingNorm is the saved normal bent by the normal and bump maps of m read
+// over the footprint of duvdx and duvdy
+func (h surfaceHit) shadingNorm(m material.Material, duvdx vmath.Vector2d, duvdy vmath.Vector2d) vmath.Vector3d {
+	mapped, ok := m.(material.Mapped)
+	if !ok || mapped.GetMaps() == nil {
+		return h.norm
+	}
+	return mapped.GetMaps().Perturb(h.norm, h.tangent, h.bitangent, h.uv, duvdx, duvdy)
 }
 
-// ShapeConfigFactory generates configs for any shape
-func ShapesConfigFactory(yaml *simpleyaml.Yaml, materials map[string]material.Material) ([]ShapesConfig, error) {
+// sphericalUV maps the direction d to the angle around the y axis as u and
+// the latitude from the bottom to the top as v
+func sphericalUV(d vmath.Vector3d) vmath.Vector2d {
+	d.Normalize()
+	return vmath.Vector2d{
+		X: 0.5 + math.Atan2(d.X, d.Z)/(2*math.Pi),
+		Y: 0.5 + math.Asin(math.Min(math.Max(d.Y, -1), 1))/math.Pi,
+	}
+}
+
+// footprint returns how far across the plane of the surface the
+// differentials of ray land from the hit, both are zero when the ray has no
+// differentials
+func (h surfaceHit) footprint(ray *vmath.Ray) (vmath.Vector3d, vmath.Vector3d) {
+	px, py, ok := ray.DifferentialHits(h.PlaceHit, h.norm)
+	if !ok {
+		return vmath.Vector3d{}, vmath.Vector3d{}
+	}
+	return px.Subtract(h.PlaceHit), py.Subtract(h.PlaceHit)
+}
+
+// uvChange is how much the uv changes moving d across the surface from the
+// hit, it is found over a small step so a seam where the uv wraps around is
+// not crossed, zero when the surface has no uv
+func (h surfaceHit) uvChange(d vmath.Vector3d) vmath.Vector2d {
+	if h.uvAt == nil {
+		return vmath.Vector2d{}
+	}
+	const step = 1e-3
+	change := h.uvAt(h.PlaceHit.Add(d.SMultiply(step))).Subtract(h.uv)
+	change.X -= math.Round(change.X)
+	change.Y -= math.Round(change.Y)
+	return change.Divide(step)
+}
+
+// uvFrame returns the directions u and v increase in across the triangle p
+// with the texture coordinates uv at its corners, both are zero when the
+// texture coordinates do not span an area
+func uvFrame(p [3]vmath.Vector3d, uv [3]vmath.Vector2d) (vmath.Vector3d, vmath.Vector3d) {
+	e1, e2 := p[1].Subtract(p[0]), p[2].Subtract(p[0])
+	d1, d2 := uv[1].Subtract(uv[0]), uv[2].Subtract(uv[0])
+	r := d1.X*d2.Y - d2.X*d1.Y
+	if r == 0 {
+		return vmath.Vector3d{}, vmath.Vector3d{}
+	}
+	tangent := e1.SMultiply(d2.Y).Subtract(e2.SMultiply(d1.Y)).Divide(r)
+	bitangent := e2.SMultiply(d1.X).Subtract(e1.SMultiply(d2.X)).Divide(r)
+	return tangent, bitangent
+}
+
+// Solid is a closed Surface that can report every span of a ray inside of
+// it rather than only the nearest hit, Intervals are sorted and cover the
+// whole line of the ray including behind its origin
+type Solid interface {
+	Surface
+	Intervals(ray *vmath.Ray) []Interval
+}
+
+// ShapeConfigFactory generates configs for any shape, cameras are the
+// positions of the cameras of the scene by name
+func ShapesConfigFactory(yaml *simpleyaml.Yaml, materials map[string]material.Material, cameras map[string]vmath.Vector3d) ([]ShapesConfig, error) {
 	configs := []ShapesConfig{}
 	keys, err := yaml.GetMapKeys()
 	if err != nil {
@@ -22,40 +203,263 @@ func ShapesConfigFactory(yaml *simpleyaml.Yaml, materials map[string]material.Ma
 	}
 	for _, name := range keys {
 		conf := yaml.Get(name)
-		if t, err := conf.Get("type").String(); err == nil {
-			switch t {
-			case "sphere":
-				sphereConfig := &SphereConfig{}
-				err := sphereConfig.FromYaml(conf, materials)
-				if err != nil {
-					return nil, err
-				}
-				configs = append(configs, sphereConfig)
-			case "plane":
-				planeConfig := &PlaneConfig{}
-				err := planeConfig.FromYaml(conf, materials)
-				if err != nil {
-					return nil, err
-				}
-				configs = append(configs, planeConfig)
+		t, err := conf.Get("type").String()
+		if err != nil {
+			continue
+		}
+
+		var shapeConfig ShapesConfig
+		switch t {
+		case "sphere":
+			shapeConfig = &SphereConfig{Name: name}
+		case "plane":
+			shapeConfig = &PlaneConfig{Name: name}
+		case "box":
+			shapeConfig = &BoxConfig{Name: name}
+		case "disk":
+			shapeConfig = &DiskConfig{Name: name}
+		case "rectangle":
+			shapeConfig = &RectangleConfig{Name: name}
+		case "triangle":
+			shapeConfig = &TriangleConfig{Name: name}
+		case "torus":
+			shapeConfig = &TorusConfig{Name: name}
+		case "csg":
+			shapeConfig = &CSGConfig{Name: name}
+		case "sdf":
+			shapeConfig = &SDFConfig{Name: name}
+		case "blobby":
+			shapeConfig = &BlobbyConfig{Name: name}
+		case "heightfield":
+			shapeConfig = &HeightfieldConfig{Name: name}
+		case "mesh":
+			shapeConfig = &MeshConfig{Name: name}
+		case "bezier":
+			shapeConfig = &BezierConfig{Name: name}
+		case "curves":
+			shapeConfig = &CurvesConfig{Name: name}
+		case "displaced":
+			shapeConfig = &DisplacedConfig{Name: name}
+		case "volume":
+			shapeConfig = &VolumeConfig{Name: name}
+		case "group":
+			shapeConfig = &GroupConfig{Name: name}
+		case "instance":
+			shapeConfig = &InstanceConfig{Name: name}
+		default:
+			continue
+		}
+		err = shapeConfig.FromYaml(conf, materials)
+		if err != nil {
+			return nil, err
+		}
+		if displaced, ok := shapeConfig.(*DisplacedConfig); ok && displaced.CameraName != "" {
+			camera, ok := cameras[displaced.CameraName]
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("camera %s does not exist in scene.", displaced.CameraName))
+			}
+			displaced.Camera = &camera
+		}
+
+		if conf.Get("transform").IsFound() {
+			transform, err := transformFromYaml(conf.Get("transform"))
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("shape %s transform: %s", name, err.Error()))
+			}
+			shapeConfig = &TransformedConfig{
+				ShapesConfig: shapeConfig,
+				Transform:    transform,
 			}
 		}
+		configs = append(configs, shapeConfig)
 	}
 
 	return configs, nil
 }
 
-// ShapesFactory will make shapes according to type
+// ShapesFactory will make shapes according to type, shapes named by a parent
+// config are built for that parent and left out of the result
 func ShapesFactory(configs []ShapesConfig) ([]common.Traceable, error) {
-	traceables := []common.Traceable{}
+	named := map[string]ShapesConfig{}
+	for _, config := range configs {
+		named[config.GetName()] = config
+	}
+
+	children := map[string]bool{}
 	for _, config := range configs {
-		var traceable common.Traceable
+		if parent, ok := config.(parentConfig); ok {
+			for _, name := range parent.ChildNames() {
+				children[name] = true
+			}
+		}
+	}
+
+	built := map[string]common.Traceable{}
+	building := map[string]bool{}
+	var build func(config ShapesConfig) (common.Traceable, error)
+	build = func(config ShapesConfig) (common.Traceable, error) {
+		name := config.GetName()
+		if traceable, ok := built[name]; ok {
+			return traceable, nil
+		}
+		if building[name] {
+			return nil, errors.New(fmt.Sprintf("shape %s references itself.", name))
+		}
+		building[name] = true
+		defer delete(building, name)
+
+		if parent, ok := config.(parentConfig); ok {
+			shapes := []common.Traceable{}
+			for _, childName := range parent.ChildNames() {
+				child, ok := named[childName]
+				if !ok {
+					return nil, errors.New(fmt.Sprintf("shape %s does not exist in scene.", childName))
+				}
+				shape, err := build(child)
+				if err != nil {
+					return nil, err
+				}
+				shapes = append(shapes, shape)
+			}
+			err := parent.SetChildren(shapes)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		traceable, err := config.NewShape()
 		if err != nil {
 			return nil, err
 		}
-		traceables = append(traceables, traceable)
+		built[name] = traceable
+		return traceable, nil
+	}
+
+	traceables := []common.Traceable{}
+	for _, config := range configs {
+		traceable, err := build(config)
+		if err != nil {
+			return nil, err
+		}
+		if !children[config.GetName()] {
+			traceables = append(traceables, traceable)
+		}
 	}
 
 	return traceables, nil
 }
+
+// vector3dFromYaml reads a three element list such as a position or normal
+func vector3dFromYaml(config *simpleyaml.Yaml) (vmath.Vector3d, error) {
+	v, err := floatsFromYaml(config)
+	if err != nil {
+		return vmath.Vector3d{}, err
+	}
+	if len(v) != 3 {
+		return vmath.Vector3d{}, errors.New("vector requires 3 values")
+	}
+
+	return vmath.Vector3d{X: v[0], Y: v[1], Z: v[2]}, nil
+}
+
+// floatFromYaml reads a single number, yaml integers are accepted as well
+func floatFromYaml(config *simpleyaml.Yaml) (float64, error) {
+	if f, err := config.Float(); err == nil {
+		return f, nil
+	}
+	i, err := config.Int()
+	if err != nil {
+		return 0, errors.New("value is not a number")
+	}
+	return float64(i), nil
+}
+
+// floatsFromYaml reads a list of numbers, yaml integers are accepted as well
+func floatsFromYaml(config *simpleyaml.Yaml) ([]float64, error) {
+	values, err := config.Array()
+	if err != nil {
+		return nil, err
+	}
+
+	v := make([]float64, len(values))
+	for index, value := range values {
+		switch n := value.(type) {
+		case float64:
+			v[index] = n
+		case int:
+			v[index] = float64(n)
+		default:
+			return nil, errors.New(fmt.Sprintf("vector value %v is not a number", value))
+		}
+	}
+
+	return v, nil
+}
+
+// materialFromYaml looks up the material named in config, a missing material
+// key returns a nil material and no error
+func materialFromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) (material.Material, error) {
+	name, err := config.Get("material").String()
+	if err != nil {
+		return nil, nil
+	}
+
+	m, ok := materials[name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("material %s does not exist in scene.", name))
+	}
+
+	return m, nil
+}
+
+// shade lights the material of h with every light in the scene, the light
+// of each is spread over pi the way a white diffuse surface facing a light
+// returns the color of the light
+func shade(h surfaceHit, ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	c := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	if h.material == nil {
+		return color.NewColorValue(c)
+	}
+
+	out := ray.Direction.UNegate()
+	out.Normalize()
+	px, py := h.footprint(ray)
+	duvdx, duvdy := h.uvChange(px), h.uvChange(py)
+	ctx := &material.ShadingContext{
+		Position:        h.PlaceHit,
+		ObjectPosition:  h.object,
+		ObjectNormal:    h.objectNorm,
+		Normal:          h.shadingNorm(h.material, duvdx, duvdy),
+		GeometricNormal: h.norm,
+		Tangent:         h.tangent,
+		Bitangent:       h.bitangent,
+		UV:              h.uv,
+		UVDx:            duvdx,
+		UVDy:            duvdy,
+		PositionDx:      px,
+		PositionDy:      py,
+		Out:             out,
+	}
+	bsdf := h.material.BSDF(ctx)
+	if ambient, ok := bsdf.(material.Ambient); ok {
+		c = c.Add(ambient.Ambient())
+	}
+	for _, light := range objs.Lights {
+		ctx.In = light.ReturnLightVector(h.PlaceHit)
+		ctx.LightDistance = math.Inf(1)
+		if _, ok := light.(*lights.DirectionalLight); !ok {
+			ctx.LightDistance = ctx.In.Norm()
+		}
+		ctx.In.Normalize()
+		// light on its way through fog or smoke is dimmed by it
+		through := throughVolumes(&vmath.Ray{Origin: h.PlaceHit, Direction: ctx.In}, ctx.LightDistance, objs)
+		radiance := light.GetColor().GetColor(0, 0).SMultiply(math.Pi).Compt(through)
+		c = c.Add(bsdf.Evaluate(ctx.In, ctx.Out).Compt(radiance))
+	}
+
+	return color.NewColorValue(vmath.Vector3d{
+		X: math.Min(c.X, 255.0),
+		Y: math.Min(c.Y, 255.0),
+		Z: math.Min(c.Z, 255.0),
+	})
+}
@@ -1 +1,304 @@
 package shapes
+
+import (
+	"image"
+	stdcolor "image/color"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/lights"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestSphericalUV(t *testing.T) {
+	blob := vmath.Vector3d{X: 2.0, Y: 0.0, Z: 0.0}
+	tests := []struct {
+		Description string
+		Surface     interface {
+			CalculateUV(vmath.Vector3d) vmath.Vector2d
+		}
+		Hit      vmath.Vector3d
+		Expected vmath.Vector2d
+	}{
+		{
+			Description: "sphere faces the middle of the uv down z",
+			Surface:     NewSphere(vmath.Vector3d{Y: 1.0}, 2.0),
+			Hit:         vmath.Vector3d{Y: 1.0, Z: 2.0},
+			Expected:    vmath.Vector2d{X: 0.5, Y: 0.5},
+		},
+		{
+			Description: "sphere top is the top of the uv",
+			Surface:     NewSphere(vmath.Vector3d{Y: 1.0}, 2.0),
+			Hit:         vmath.Vector3d{Y: 3.0},
+			Expected:    vmath.Vector2d{X: 0.5, Y: 1.0},
+		},
+		{
+			Description: "sdf wraps around the origin",
+			Surface:     NewSDF(&SDFSphere{Radius: 1}),
+			Hit:         vmath.Vector3d{X: 1.0},
+			Expected:    vmath.Vector2d{X: 0.75, Y: 0.5},
+		},
+		{
+			Description: "blobby wraps around the middle of its sources",
+			Surface:     NewBlobby([]BlobSource{{A: blob, B: blob, Radius: 2, Weight: 1}}, 0.125),
+			Hit:         vmath.Vector3d{X: 2.0, Y: -1.0},
+			Expected:    vmath.Vector2d{X: 0.5, Y: 0.0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			uv := test.Surface.CalculateUV(test.Hit)
+			assert.InDelta(t, test.Expected.X, uv.X, 1e-9)
+			assert.InDelta(t, test.Expected.Y, uv.Y, 1e-9)
+		})
+	}
+}
+
+func TestFootprint(t *testing.T) {
+	tests := []struct {
+		Description string
+		Surface     Surface
+		Hit         vmath.Vector3d
+		Ray         *vmath.Ray
+		// Expected is the length of the change in uv to the next pixel
+		// across and down
+		Expected [2]float64
+	}{
+		{
+			Description: "plane spreads the differentials over its uv",
+			Surface:     NewPlane(vmath.Vector3d{}, vmath.Vector3d{Y: 1.0}),
+			Hit:         vmath.Vector3d{},
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{Y: 2.0},
+				Direction: vmath.Vector3d{Y: -1.0},
+				Differentials: &vmath.RayDifferentials{
+					XOrigin:    vmath.Vector3d{Y: 2.0},
+					XDirection: vmath.Vector3d{X: 0.1, Y: -1.0},
+					YOrigin:    vmath.Vector3d{Y: 2.0},
+					YDirection: vmath.Vector3d{Y: -1.0, Z: 0.05},
+				},
+			},
+			Expected: [2]float64{0.2, 0.1},
+		},
+		{
+			Description: "sphere does not jump across the seam of its uv",
+			Surface:     NewSphere(vmath.Vector3d{}, 1.0),
+			Hit:         vmath.Vector3d{Z: -1.0},
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{Z: -3.0},
+				Direction: vmath.Vector3d{Z: 1.0},
+				Differentials: &vmath.RayDifferentials{
+					XOrigin:    vmath.Vector3d{Z: -3.0},
+					XDirection: vmath.Vector3d{X: 0.01, Z: 1.0},
+					YOrigin:    vmath.Vector3d{Z: -3.0},
+					YDirection: vmath.Vector3d{Y: 0.01, Z: 1.0},
+				},
+			},
+			Expected: [2]float64{0.02 / (2 * math.Pi), 0.02 / math.Pi},
+		},
+		{
+			Description: "ray without differentials has no footprint",
+			Surface:     NewPlane(vmath.Vector3d{}, vmath.Vector3d{Y: 1.0}),
+			Hit:         vmath.Vector3d{},
+			Ray:         &vmath.Ray{Origin: vmath.Vector3d{Y: 2.0}, Direction: vmath.Vector3d{Y: -1.0}},
+			Expected:    [2]float64{0.0, 0.0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			h := newSurfaceHit(test.Surface, test.Hit, 0)
+			px, py := h.footprint(test.Ray)
+			dx, dy := h.uvChange(px), h.uvChange(py)
+			assert.InDelta(t, test.Expected[0], dx.Norm(), 1e-4)
+			assert.InDelta(t, test.Expected[1], dy.Norm(), 1e-4)
+		})
+	}
+}
+
+func TestCalculateFrame(t *testing.T) {
+	rotated, err := NewTransformed(NewSphere(vmath.Vector3d{}, 1.0), vmath.RotateAxis(vmath.Vector3d{Z: 1.0}, 90))
+	require.NoError(t, err)
+
+	tests := []struct {
+		Description string
+		Surface     interface {
+			Surface
+			Framed
+			CalculateUV(vmath.Vector3d) vmath.Vector2d
+		}
+		Hit vmath.Vector3d
+	}{
+		{
+			Description: "plane",
+			Surface:     NewPlane(vmath.Vector3d{}, vmath.Vector3d{X: 1.0, Y: 1.0, Z: 0.0}),
+			Hit:         vmath.Vector3d{X: 1.0, Y: -1.0, Z: 2.0},
+		},
+		{
+			Description: "rectangle",
+			Surface:     NewRectangle(vmath.Vector3d{}, vmath.Vector3d{X: 2.0}, vmath.Vector3d{X: 1.0, Z: -1.0}),
+			Hit:         vmath.Vector3d{X: 1.0, Z: -0.5},
+		},
+		{
+			Description: "triangle",
+			Surface:     NewTriangle(vmath.Vector3d{}, vmath.Vector3d{Y: 2.0}, vmath.Vector3d{Z: 1.0}),
+			Hit:         vmath.Vector3d{Y: 0.5, Z: 0.25},
+		},
+		{
+			Description: "disk",
+			Surface:     &Disk{Plane: NewPlane(vmath.Vector3d{}, vmath.Vector3d{Z: 1.0}), Radius: 2.0},
+			Hit:         vmath.Vector3d{X: -1.0, Y: 0.5},
+		},
+		{
+			Description: "box",
+			Surface:     NewBox(vmath.Vector3d{}, vmath.Vector3d{X: 2.0, Y: 2.0, Z: 2.0}, vmath.Vector3d{Y: 30.0}),
+			Hit:         vmath.Vector3d{Y: 1.0, X: 0.2, Z: 0.3},
+		},
+		{
+			Description: "sphere",
+			Surface:     NewSphere(vmath.Vector3d{Y: 1.0}, 2.0),
+			Hit:         vmath.Vector3d{X: 1.0, Y: 2.0, Z: 1.414213562},
+		},
+		{
+			Description: "torus",
+			Surface:     NewTorus(vmath.Vector3d{}, vmath.Vector3d{Z: 1.0}, 2.0, 0.5),
+			Hit:         vmath.Vector3d{X: 2.0, Y: 0.3, Z: 0.4},
+		},
+		{
+			Description: "transformed sphere",
+			Surface:     rotated,
+			Hit:         vmath.Vector3d{X: 0.6, Y: 0.0, Z: 0.8},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			tangent, bitangent := test.Surface.CalculateFrame(test.Hit)
+			tangent.Normalize()
+			bitangent.Normalize()
+			uv := test.Surface.CalculateUV(test.Hit)
+
+			// stepping along each direction moves only its own coordinate
+			du := test.Surface.CalculateUV(test.Hit.Add(tangent.SMultiply(1e-5))).Subtract(uv)
+			dv := test.Surface.CalculateUV(test.Hit.Add(bitangent.SMultiply(1e-5))).Subtract(uv)
+			assert.Greater(t, du.X, 0.0)
+			assert.InDelta(t, 0.0, du.Y, 1e-7)
+			assert.Greater(t, dv.Y, 0.0)
+			assert.InDelta(t, 0.0, dv.X, 1e-7)
+		})
+	}
+}
+
+func TestShadingNorm(t *testing.T) {
+	// heights rising along u across the image
+	ramp := image.NewGray16(image.Rect(0, 0, 16, 1))
+	for x := 0; x < 16; x++ {
+		ramp.SetGray16(x, 0, stdcolor.Gray16{Y: uint16(x * 4096)})
+	}
+	lambert := &material.Lambert{
+		Ambient: &color.ColorValue{},
+		Diffuse: &color.ColorValue{},
+	}
+	plane := NewPlane(vmath.Vector3d{}, vmath.Vector3d{Y: 1.0})
+	plane.Material = lambert
+	hit := vmath.Vector3d{X: 0.5, Z: 0.5}
+
+	// without maps the normal of the plane is used
+	assert.Equal(t, plane.CalculateNorm(hit), newSurfaceHit(plane, hit, 0).shadingNorm(lambert, vmath.Vector2d{}, vmath.Vector2d{}))
+
+	lambert.Maps = &material.SurfaceMaps{BumpMap: color.NewImageValue(ramp), BumpScale: 0.5}
+	tangent, _ := plane.CalculateFrame(hit)
+	norm := newSurfaceHit(plane, hit, 0).shadingNorm(lambert, vmath.Vector2d{}, vmath.Vector2d{})
+	assert.InDelta(t, 1.0, norm.Norm(), 1e-12)
+	assert.Less(t, norm.Dot(tangent), -0.1)
+	assert.Greater(t, norm.Y, 0.5)
+
+	// a group keeps the frame of the child it hit
+	group := &Group{Children: []Surface{plane}}
+	require.True(t, group.Intersect(&vmath.Ray{
+		Origin:    vmath.Vector3d{X: 0.5, Y: 1.0, Z: 0.5},
+		Direction: vmath.Vector3d{Y: -1.0},
+	}))
+	grouped := group.shadingNorm(group.GetMaterial(), vmath.Vector2d{}, vmath.Vector2d{})
+	assert.InDelta(t, norm.X, grouped.X, 1e-12)
+	assert.InDelta(t, norm.Z, grouped.Z, 1e-12)
+}
+
+func TestShade(t *testing.T) {
+	// white on the left half of the image and black on the right
+	img := image.NewGray(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, stdcolor.White)
+	sphere := NewSphere(vmath.Vector3d{}, 1.0)
+	sphere.Material = &material.PBR{
+		BaseColor: material.Param{Image: color.NewImageValue(img), Channel: -1},
+		Roughness: material.Param{Value: vmath.Vector3d{X: 1.0}},
+	}
+	objs := &common.RenderableObjects{}
+
+	// u is a quarter around the sphere at -x and three quarters at x, both
+	// are lit and seen head on
+	for _, test := range []struct {
+		Hit      vmath.Vector3d
+		Expected float64
+	}{
+		{Hit: vmath.Vector3d{X: -1.0}, Expected: 255.0},
+		{Hit: vmath.Vector3d{X: 1.0}, Expected: 0.0},
+	} {
+		objs.Lights = []lights.Light{&lights.DirectionalLight{
+			V:         test.Hit.UNegate(),
+			Color:     &color.ColorValue{Color: vmath.Vector3d{X: 255.0, Y: 255.0, Z: 255.0}},
+			Intensity: 1.0,
+		}}
+		ray := &vmath.Ray{Origin: test.Hit.SMultiply(5.0), Direction: test.Hit.UNegate()}
+		c := shade(newSurfaceHit(sphere, test.Hit, 4.0), ray, objs).GetColor(0, 0)
+		assert.InDelta(t, test.Expected, c.X, 1e-9)
+	}
+
+	// ambient light is added once however many lights there are and the
+	// color is kept from passing 255
+	sphere.Material = &material.Phong{
+		Ambient:   &color.ColorValue{Color: vmath.Vector3d{X: 10.0, Y: 10.0, Z: 10.0}},
+		Diffuse:   &color.ColorValue{Color: vmath.Vector3d{X: 200.0}},
+		Specular:  &color.ColorValue{},
+		Shininess: 1.0,
+	}
+	light := &lights.DirectionalLight{
+		V:         vmath.Vector3d{Z: -1.0},
+		Color:     &color.ColorValue{Color: vmath.Vector3d{X: 255.0, Y: 255.0, Z: 255.0}},
+		Intensity: 1.0,
+	}
+	objs.Lights = []lights.Light{light, light}
+	ray := &vmath.Ray{Origin: vmath.Vector3d{Z: 5.0}, Direction: vmath.Vector3d{Z: -1.0}}
+	c := shade(newSurfaceHit(sphere, vmath.Vector3d{Z: 1.0}, 4.0), ray, objs).GetColor(0, 0)
+	assert.InDelta(t, 255.0, c.X, 1e-9)
+	assert.InDelta(t, 10.0, c.Y, 1e-9)
+
+	// each more light only adds its diffuse, for a mix as for a phong
+	sphere.Material.(*material.Phong).Diffuse = &color.ColorValue{Color: vmath.Vector3d{X: 20.0}}
+	lit := func(count int) vmath.Vector3d {
+		objs.Lights = []lights.Light{}
+		for index := 0; index < count; index++ {
+			objs.Lights = append(objs.Lights, light)
+		}
+		return shade(newSurfaceHit(sphere, vmath.Vector3d{Z: 1.0}, 4.0), ray, objs).GetColor(0, 0)
+	}
+	assert.InDelta(t, 30.0, lit(1).X, 1e-9)
+	assert.InDelta(t, 70.0, lit(3).X, 1e-9)
+	assert.InDelta(t, 10.0, lit(3).Y, 1e-9)
+	phong := sphere.Material
+	sphere.Material = &material.Mix{A: phong, B: phong, Factor: material.Param{Value: vmath.Vector3d{X: 0.5, Y: 0.5, Z: 0.5}}}
+	assert.InDelta(t, 70.0, lit(3).X, 1e-9)
+	assert.InDelta(t, 10.0, lit(3).Z, 1e-9)
+	objs.Lights = []lights.Light{light, light}
+
+	// without a material nothing is lit
+	sphere.Material = nil
+	assert.Equal(t, vmath.Vector3d{}, shade(newSurfaceHit(sphere, vmath.Vector3d{Z: 1.0}, 4.0), ray, objs).GetColor(0, 0))
+}
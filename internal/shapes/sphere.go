@@ -21,6 +21,10 @@ type SphereConfig struct {
 	Material material.Material
 }
 
+func (sc *SphereConfig) GetName() string {
+	return sc.Name
+}
+
 // NewShape generates a Shape from the config object
 // satisfies the interface ShapesConfig (1/2)
 func (sc *SphereConfig) NewShape() (common.Traceable, error) {
@@ -91,33 +95,47 @@ func NewSphere(pos vmath.Vector3d, rad float64) *Sphere {
 	}
 }
 
-// Intersect satisfies the qualifications for
-// Render object interface for a scene
-func (s *Sphere) Intersect(ray *vmath.Ray) bool {
-	a, b, c := 0.0, 0.0, 0.0
+// coefficients of the quadric along ray as a*t^2 + b*t + c
+func (s *Sphere) coefficients(ray *vmath.Ray) (float64, float64, float64) {
+	a, b, c := 0.0, 0.0, s.a[4]
 	for index, axis := range s.axis {
 		a += s.a[index] * math.Pow(axis.Dot(ray.Direction)/s.s[index], 2)
 		b += s.a[index] * axis.Dot(ray.Direction) * axis.Dot(ray.Origin.Subtract(s.P)) * 2.0 / math.Pow(s.s[index], 2)
-		c += s.a[index]*math.Pow(axis.Dot(ray.Origin.Subtract(s.P))/s.s[index], 2) + s.a[4]
+		c += s.a[index] * math.Pow(axis.Dot(ray.Origin.Subtract(s.P))/s.s[index], 2)
 	}
+	return a, b, c
+}
 
-	d := math.Pow(b, 2) - 4.0*a*c
-
-	quadPlus := (-b + math.Sqrt(d)/(2.0*a))
-	quadMinus := (-b - math.Sqrt(d)/(2.0*a))
-
-	s.intersectionRatio = quadMinus
-	if quadPlus < quadMinus || quadMinus < 0 {
-		s.intersectionRatio = quadPlus
-	}
-	if s.intersectionRatio > 0 && d > 0 {
-		s.PlaceHit = ray.Origin.Add(ray.Direction.SMultiply(s.intersectionRatio))
-		s.delta = d
-		return true
+// Intersect satisfies the qualifications for
+// Render object interface for a scene
+func (s *Sphere) Intersect(ray *vmath.Ray) bool {
+	a, b, c := s.coefficients(ray)
+	s.delta = math.Pow(b, 2) - 4.0*a*c
+
+	// roots are ascending so the first one in front of the ray is the near
+	// side, or the far side when the ray starts inside
+	for _, root := range vmath.SolveQuadratic(a, b, c) {
+		if root > 0 {
+			s.intersectionRatio = root
+			s.PlaceHit = ray.Origin.Add(ray.Direction.SMultiply(root))
+			return true
+		}
 	}
 	return false
 }
 
+// Intervals satisfies the Solid interface
+func (s *Sphere) Intervals(ray *vmath.Ray) []Interval {
+	roots := vmath.SolveQuadratic(s.coefficients(ray))
+	if len(roots) != 2 {
+		return nil
+	}
+	return []Interval{{
+		In:  Crossing{T: roots[0], Solid: s},
+		Out: Crossing{T: roots[1], Solid: s},
+	}}
+}
+
 // GetPosition satisfies requirements for Object
 // interface for a scene
 func (s *Sphere) GetPosition() vmath.Vector3d {
@@ -150,23 +168,35 @@ func (s *Sphere) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
 	return grad
 }
 
-func (s *Sphere) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
-	color := &color.ColorValue{
-		Color: vmath.Vector3d{
-			X: 0.0,
-			Y: 0.0,
-			Z: 0.0,
-		},
-	}
-	nh := s.CalculateNorm(s.PlaceHit)     //normalized surface normal
-	nc := ray.Origin.Subtract(s.PlaceHit) //normalized camera and ph vector
-	nc.Normalize()
-	cameraAngle := nh.Dot(nc) //angle between camera and surface normal
-	for _, light := range objs.Lights {
-		nlh := light.ReturnLightVector(s.PlaceHit) // normalized light direction
-		lightAngle := nh.Dot(nlh)
-		color.Add(s.Material.ReturnColor(lightAngle, cameraAngle, 0, light))
+// CalculateUV maps the angle around the second axis of the sphere to u and
+// the latitude from the bottom to the top of the sphere to v
+func (s *Sphere) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	return sphericalUV(s.local(hit))
+}
+
+// CalculateFrame returns the direction around the sphere and up it, the
+// directions u and v increase in
+func (s *Sphere) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	q := s.local(hit)
+	around := s.axis[0].SMultiply(s.s[0] * q.Z).Subtract(s.axis[2].SMultiply(s.s[2] * q.X))
+	return around, s.axis[1]
+}
+
+// local returns hit on the unit sphere in the axes of the sphere
+func (s *Sphere) local(hit vmath.Vector3d) vmath.Vector3d {
+	p := hit.Subtract(s.P)
+	return vmath.Vector3d{
+		X: s.axis[0].Dot(p) / s.s[0],
+		Y: s.axis[1].Dot(p) / s.s[1],
+		Z: s.axis[2].Dot(p) / s.s[2],
 	}
+}
 
-	return color
+// GetMaterial returns the material the sphere is shaded with
+func (s *Sphere) GetMaterial() material.Material {
+	return s.Material
+}
+
+func (s *Sphere) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(s, s.PlaceHit, 0), ray, objs)
 }
@@ -101,9 +101,9 @@ func TestSphereIntersect(t *testing.T) {
 			ExpectedHit: vmath.Vector3d{
 				X: 0.0,
 				Y: 0.0,
-				Z: -8.267949192431121,
+				Z: 1.0,
 			},
-			ExpectedRatio: 0,
+			ExpectedRatio: 9.0,
 			Ray: &vmath.Ray{
 				Origin: vmath.Vector3d{
 					X: 0.0,
@@ -137,11 +137,11 @@ func TestSphereIntersect(t *testing.T) {
 			Description: "Test Complex Hit",
 			Expected:    true,
 			ExpectedHit: vmath.Vector3d{
-				X: -0.1827287054960553,
-				Y: -0.3654574109921106,
-				Z: -8.272870549605528,
+				X: -0.09020553827757624,
+				Y: -0.18041107655515248,
+				Z: 0.9794461722423762,
 			},
-			ExpectedRatio: 18.277438196358435,
+			ExpectedRatio: 9.022808684392706,
 			Ray: &vmath.Ray{
 				Origin: vmath.Vector3d{
 					X: 0.0,
@@ -167,7 +167,17 @@ func TestSphereIntersect(t *testing.T) {
 			if test.Expected {
 				assert.Equal(t, test.ExpectedHit, sphere.PlaceHit)
 				assert.Equal(t, test.ExpectedRatio, sphere.intersectionRatio)
+				// the hit is on the surface of the sphere, the quadratic of
+				// the first sphere Intersect divided only the square root by
+				// 2a and added the constant once per axis so it was not
+				assert.InDelta(t, 1.0, sphere.PlaceHit.Norm(), 1e-9)
 			}
 		})
 	}
+
+	t.Run("Test ray from inside hits the far side", func(t *testing.T) {
+		sphere := NewSphere(vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}, 2.0)
+		require.True(t, sphere.Intersect(&vmath.Ray{Direction: vmath.Vector3d{X: 1.0}}))
+		assert.InDelta(t, 2.0, sphere.intersectionRatio, 1e-9)
+	})
 }
@@ -0,0 +1,273 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// NewSubdivisionMesh smooths polygons by levels steps of Catmull-Clark
+// subdivision and then moves every vertex onto the limit surface, which the
+// subdivision would reach after infinitely many steps, with the normal of the
+// limit surface at it
+func NewSubdivisionMesh(polygons *polygonMesh, levels int) (*Mesh, error) {
+	if len(polygons.Faces) == 0 {
+		return nil, errors.New("mesh needs at least 1 face")
+	}
+	for index, face := range polygons.Faces {
+		for _, vertex := range face {
+			if vertex < 0 || vertex >= len(polygons.Vertices) {
+				return nil, errors.New(fmt.Sprintf("mesh face %d refers to vertex %d which does not exist", index, vertex))
+			}
+		}
+	}
+
+	quads := polygons
+	for level := 0; level < levels; level++ {
+		quads = catmullClark(quads)
+	}
+	vertices, normals := limitSurface(quads)
+
+	limit := &polygonMesh{Vertices: vertices, Faces: quads.Faces}
+	triangles, _ := limit.triangulate()
+	mesh, err := NewMesh(vertices, triangles)
+	if err != nil {
+		return nil, err
+	}
+	mesh.Normals = normals
+	return mesh, nil
+}
+
+// meshTopology is which faces are around every edge and vertex of a
+// polygonMesh and which vertices share an edge with every vertex
+type meshTopology struct {
+	edges       [][2]int
+	edgeFaces   map[[2]int][]int
+	vertexFaces [][]int
+	neighbors   [][]int
+}
+
+// edgeKey is the same for both directions of the edge between a and b
+func edgeKey(a int, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+func newMeshTopology(m *polygonMesh) *meshTopology {
+	t := &meshTopology{
+		edgeFaces:   map[[2]int][]int{},
+		vertexFaces: make([][]int, len(m.Vertices)),
+		neighbors:   make([][]int, len(m.Vertices)),
+	}
+	for index, face := range m.Faces {
+		for corner, vertex := range face {
+			t.vertexFaces[vertex] = append(t.vertexFaces[vertex], index)
+			next := face[(corner+1)%len(face)]
+			key := edgeKey(vertex, next)
+			if _, ok := t.edgeFaces[key]; !ok {
+				t.edges = append(t.edges, key)
+				t.neighbors[vertex] = append(t.neighbors[vertex], next)
+				t.neighbors[next] = append(t.neighbors[next], vertex)
+			}
+			t.edgeFaces[key] = append(t.edgeFaces[key], index)
+		}
+	}
+	return t
+}
+
+// boundary returns the vertices sharing an edge with v that only one face
+// touches, a vertex inside the mesh has none and one on a simple boundary two
+func (t *meshTopology) boundary(v int) []int {
+	boundary := []int{}
+	for _, neighbor := range t.neighbors[v] {
+		if len(t.edgeFaces[edgeKey(v, neighbor)]) == 1 {
+			boundary = append(boundary, neighbor)
+		}
+	}
+	return boundary
+}
+
+// average returns the mean of the vertices of m at indices
+func average(m *polygonMesh, indices []int) vmath.Vector3d {
+	sum := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	for _, index := range indices {
+		sum = sum.Add(m.Vertices[index])
+	}
+	return sum.Divide(float64(len(indices)))
+}
+
+// catmullClark returns one step of Catmull-Clark subdivision of m, every face
+// of n corners becomes n quads, boundaries are kept sharp as cubic B-splines
+func catmullClark(m *polygonMesh) *polygonMesh {
+	t := newMeshTopology(m)
+
+	facePoints := make([]vmath.Vector3d, len(m.Faces))
+	for index, face := range m.Faces {
+		facePoints[index] = average(m, face)
+	}
+
+	// new vertices are the moved old ones, then a point per edge and then a
+	// point per face
+	edgeIndex := map[[2]int]int{}
+	vertices := make([]vmath.Vector3d, len(m.Vertices), len(m.Vertices)+len(t.edges)+len(m.Faces))
+	for _, edge := range t.edges {
+		edgeIndex[edge] = len(vertices)
+		point := m.Vertices[edge[0]].Add(m.Vertices[edge[1]])
+		faces := t.edgeFaces[edge]
+		if len(faces) == 2 {
+			point = point.Add(facePoints[faces[0]]).Add(facePoints[faces[1]]).Divide(4)
+		} else {
+			point = point.Divide(2)
+		}
+		vertices = append(vertices, point)
+	}
+	faceIndex := len(vertices)
+	vertices = append(vertices, facePoints...)
+
+	for v, p := range m.Vertices {
+		faces := t.vertexFaces[v]
+		boundary := t.boundary(v)
+		switch {
+		case len(boundary) == 2:
+			vertices[v] = m.Vertices[boundary[0]].Add(m.Vertices[boundary[1]]).Add(p.SMultiply(6)).Divide(8)
+		case len(faces) == 0 || len(boundary) > 0:
+			// lone vertices and corners where boundaries meet stay put
+			vertices[v] = p
+		default:
+			n := float64(len(faces))
+			f := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+			for _, face := range faces {
+				f = f.Add(facePoints[face])
+			}
+			r := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+			for _, neighbor := range t.neighbors[v] {
+				r = r.Add(p.Add(m.Vertices[neighbor]).Divide(2))
+			}
+			f = f.Divide(n)
+			r = r.Divide(float64(len(t.neighbors[v])))
+			vertices[v] = f.Add(r.SMultiply(2)).Add(p.SMultiply(n - 3)).Divide(n)
+		}
+	}
+
+	faces := [][]int{}
+	for index, face := range m.Faces {
+		for corner, vertex := range face {
+			next := face[(corner+1)%len(face)]
+			previous := face[(corner+len(face)-1)%len(face)]
+			faces = append(faces, []int{
+				vertex,
+				edgeIndex[edgeKey(vertex, next)],
+				faceIndex + index,
+				edgeIndex[edgeKey(previous, vertex)],
+			})
+		}
+	}
+
+	return &polygonMesh{Vertices: vertices, Faces: faces}
+}
+
+// faceNormal is the normal of a face by Newell's method, which is not thrown
+// off by faces that are not quite flat, its length is twice the face area
+func faceNormal(m *polygonMesh, face []int) vmath.Vector3d {
+	norm := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	for corner, vertex := range face {
+		a, b := m.Vertices[vertex], m.Vertices[face[(corner+1)%len(face)]]
+		norm = norm.Add(a.Cross(b))
+	}
+	return norm
+}
+
+// limitSurface returns where every vertex of the quad mesh m ends up on the
+// limit surface and the normal of the limit surface there, vertices on a
+// boundary are moved along it and take the average normal of their faces
+func limitSurface(m *polygonMesh) ([]vmath.Vector3d, []vmath.Vector3d) {
+	t := newMeshTopology(m)
+	vertices := make([]vmath.Vector3d, len(m.Vertices))
+	normals := make([]vmath.Vector3d, len(m.Vertices))
+	for v, p := range m.Vertices {
+		vertices[v] = p
+		around := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+		for _, face := range t.vertexFaces[v] {
+			around = around.Add(faceNormal(m, m.Faces[face]))
+		}
+		around.Normalize()
+		normals[v] = around
+
+		if boundary := t.boundary(v); len(boundary) > 0 {
+			if len(boundary) == 2 {
+				vertices[v] = m.Vertices[boundary[0]].Add(m.Vertices[boundary[1]]).Add(p.SMultiply(4)).Divide(6)
+			}
+			continue
+		}
+		edges, diagonals, ok := t.ring(m, v)
+		if !ok {
+			continue
+		}
+
+		// limit stencils of Halstead, Kass and DeRose for a vertex of valence n
+		n := len(edges)
+		fn := float64(n)
+		position := p.SMultiply(fn * fn)
+		tu := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+		tv := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+		a := 1 + math.Cos(2*math.Pi/fn) + math.Cos(math.Pi/fn)*math.Sqrt(2*(9+math.Cos(2*math.Pi/fn)))
+		for i := 0; i < n; i++ {
+			position = position.Add(edges[i].SMultiply(4)).Add(diagonals[i])
+			sin0, cos0 := math.Sincos(2 * math.Pi * float64(i) / fn)
+			sin1, cos1 := math.Sincos(2 * math.Pi * float64(i+1) / fn)
+			tu = tu.Add(edges[i].SMultiply(a * cos0)).Add(diagonals[i].SMultiply(cos0 + cos1))
+			tv = tv.Add(edges[i].SMultiply(a * sin0)).Add(diagonals[i].SMultiply(sin0 + sin1))
+		}
+		vertices[v] = position.Divide(fn * (fn + 5))
+
+		norm := tu.Cross(tv)
+		if norm.Normalize() != nil {
+			continue
+		}
+		if norm.Dot(around) < 0 {
+			norm = norm.UNegate()
+		}
+		normals[v] = norm
+	}
+	return vertices, normals
+}
+
+// ring returns the vertices around v in order, edges share an edge with v and
+// diagonals[i] is across the quad between edges[i] and edges[i+1], ok is false
+// when v is not surrounded by a single fan of quads
+func (t *meshTopology) ring(m *polygonMesh, v int) ([]vmath.Vector3d, []vmath.Vector3d, bool) {
+	faces := t.vertexFaces[v]
+	previous, diagonal, next := make([]int, len(faces)), make([]int, len(faces)), make([]int, len(faces))
+	byPrevious := map[int]int{}
+	for index, face := range faces {
+		corners := m.Faces[face]
+		if len(corners) != 4 {
+			return nil, nil, false
+		}
+		for corner, vertex := range corners {
+			if vertex == v {
+				previous[index] = corners[(corner+3)%4]
+				diagonal[index] = corners[(corner+2)%4]
+				next[index] = corners[(corner+1)%4]
+			}
+		}
+		byPrevious[previous[index]] = index
+	}
+
+	edges, diagonals := []vmath.Vector3d{}, []vmath.Vector3d{}
+	index := 0
+	for range faces {
+		edges = append(edges, m.Vertices[previous[index]])
+		diagonals = append(diagonals, m.Vertices[diagonal[index]])
+		following, ok := byPrevious[next[index]]
+		if !ok {
+			return nil, nil, false
+		}
+		index = following
+	}
+	return edges, diagonals, index == 0
+}
@@ -0,0 +1,261 @@
+package shapes
+
+import (
+	"errors"
+	"math"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// TorusConfig defines a torus for the ShapeFactory
+type TorusConfig struct {
+	Name        string
+	Position    vmath.Vector3d
+	Normal      vmath.Vector3d
+	MajorRadius float64
+	MinorRadius float64
+	Material    material.Material
+}
+
+func (tc *TorusConfig) GetName() string {
+	return tc.Name
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (tc *TorusConfig) NewShape() (common.Traceable, error) {
+	if tc.MajorRadius <= 0 || tc.MinorRadius <= 0 {
+		return nil, errors.New("torus radii must be positive")
+	}
+	normal := tc.Normal
+	if normal.IsZero() {
+		normal = vmath.Vector3d{X: 0.0, Y: 1.0, Z: 0.0}
+	}
+	torus := NewTorus(tc.Position, normal, tc.MajorRadius, tc.MinorRadius)
+	torus.Name = tc.Name
+	torus.Material = tc.Material
+	return torus, nil
+}
+
+// FromYaml generates Config from input yaml, normal is the axis the torus
+// wraps around and defaults to Y
+// satisfies the interface ShapesConfig (2/2)
+func (tc *TorusConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	if config.Get("position").IsFound() {
+		position, err := vector3dFromYaml(config.Get("position"))
+		if err != nil {
+			return errors.New("torus position: " + err.Error())
+		}
+		tc.Position = position
+	}
+	if config.Get("normal").IsFound() {
+		normal, err := vector3dFromYaml(config.Get("normal"))
+		if err != nil {
+			return errors.New("torus normal: " + err.Error())
+		}
+		tc.Normal = normal
+	}
+	if majorRadius, err := config.Get("major_radius").Float(); err == nil {
+		tc.MajorRadius = majorRadius
+	}
+	if minorRadius, err := config.Get("minor_radius").Float(); err == nil {
+		tc.MinorRadius = minorRadius
+	}
+
+	m, err := materialFromYaml(config, materials)
+	if err != nil {
+		return err
+	}
+	tc.Material = m
+
+	return nil
+}
+
+// Torus is a ring of radius MajorRadius around P with a tube of MinorRadius,
+// axis[2] is the axis the ring wraps around
+type Torus struct {
+	Name                     string
+	axis                     []vmath.Vector3d
+	PlaceHit                 vmath.Vector3d
+	P                        vmath.Vector3d
+	MajorRadius, MinorRadius float64
+	intersectionRatio        float64
+
+	Material material.Material
+}
+
+// NewTorus makes a torus centered on pos wrapping around normal
+func NewTorus(pos vmath.Vector3d, normal vmath.Vector3d, majorRadius float64, minorRadius float64) *Torus {
+	return &Torus{
+		P:           pos,
+		axis:        normalAxis(normal),
+		MajorRadius: majorRadius,
+		MinorRadius: minorRadius,
+	}
+}
+
+// local returns v in the frame of the torus
+func (t *Torus) local(v vmath.Vector3d) vmath.Vector3d {
+	return vmath.Vector3d{
+		X: t.axis[0].Dot(v),
+		Y: t.axis[1].Dot(v),
+		Z: t.axis[2].Dot(v),
+	}
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene
+func (t *Torus) Intersect(ray *vmath.Ray) bool {
+	roots := t.roots(ray)
+	for _, root := range roots {
+		if root > 0 {
+			t.intersectionRatio = root
+			t.PlaceHit = ray.Origin.Add(ray.Direction.SMultiply(root))
+			return true
+		}
+	}
+	return false
+}
+
+// roots returns every ratio along ray that crosses the torus in ascending
+// order
+func (t *Torus) roots(ray *vmath.Ray) []float64 {
+	o := t.local(ray.Origin.Subtract(t.P))
+	d := t.local(ray.Direction)
+	dd := d.Dot(d)
+	if dd == 0 {
+		return nil
+	}
+
+	// skip ahead to the bounding sphere, the quartic loses precision quickly
+	// when solved from a far away origin
+	bound := t.MajorRadius + t.MinorRadius
+	b := o.Dot(d)
+	c := o.Dot(o) - bound*bound
+	disc := b*b - dd*c
+	if disc < 0 {
+		return nil
+	}
+	start := (-b - math.Sqrt(disc)) / dd
+	o = o.Add(d.SMultiply(start))
+
+	R2 := t.MajorRadius * t.MajorRadius
+	od := o.Dot(d)
+	k := o.Dot(o) + R2 - t.MinorRadius*t.MinorRadius
+	roots := vmath.SolveQuartic(
+		dd*dd,
+		4*dd*od,
+		2*dd*k+4*od*od-4*R2*(d.X*d.X+d.Y*d.Y),
+		4*k*od-8*R2*(o.X*d.X+o.Y*d.Y),
+		k*k-4*R2*(o.X*o.X+o.Y*o.Y),
+	)
+	for index := range roots {
+		roots[index] += start
+	}
+	return roots
+}
+
+// Intervals satisfies the Solid interface
+func (t *Torus) Intervals(ray *vmath.Ray) []Interval {
+	roots := t.roots(ray)
+	intervals := []Interval{}
+	for index := 0; index < len(roots)-1; index++ {
+		// a ray grazing the tube only touches it, so check the ray is
+		// actually inside between each pair of crossings
+		middle := ray.Origin.Add(ray.Direction.SMultiply((roots[index] + roots[index+1]) / 2))
+		if !t.inside(middle) {
+			continue
+		}
+		intervals = append(intervals, Interval{
+			In:  Crossing{T: roots[index], Solid: t},
+			Out: Crossing{T: roots[index+1], Solid: t},
+		})
+		index++
+	}
+	return intervals
+}
+
+// inside reports if p is within the tube of the torus
+func (t *Torus) inside(p vmath.Vector3d) bool {
+	local := t.local(p.Subtract(t.P))
+	k := local.Dot(local) + t.MajorRadius*t.MajorRadius - t.MinorRadius*t.MinorRadius
+	return k*k-4*t.MajorRadius*t.MajorRadius*(local.X*local.X+local.Y*local.Y) < 0
+}
+
+// GetPosition satisfies requirements for Object
+// interface for a scene
+func (t *Torus) GetPosition() vmath.Vector3d {
+	return t.P
+}
+
+func (t *Torus) GetName() string {
+	return t.Name
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (t *Torus) GetType() string {
+	return "torus"
+}
+
+// GetIntersectionRatio
+func (t *Torus) GetIntersectionRatio() float64 {
+	return t.intersectionRatio
+}
+
+// CalculateNorm uses the gradient of
+// (x^2 + y^2 + z^2 + R^2 - r^2)^2 - 4R^2(x^2 + y^2)
+func (t *Torus) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	p := t.local(hit.Subtract(t.P))
+	k := p.Dot(p) - t.MajorRadius*t.MajorRadius - t.MinorRadius*t.MinorRadius
+	ds := []float64{
+		4.0 * p.X * k,
+		4.0 * p.Y * k,
+		4.0 * p.Z * (k + 2.0*t.MajorRadius*t.MajorRadius),
+	}
+	grad := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	for index, axis := range t.axis {
+		grad = grad.Add(axis.SMultiply(ds[index]))
+	}
+	grad.Normalize()
+
+	return grad
+}
+
+// CalculateUV maps the angle around the ring to u and the angle around the
+// tube to v
+func (t *Torus) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	p := t.local(hit.Subtract(t.P))
+	u := math.Atan2(p.Y, p.X)
+	v := math.Atan2(p.Z, math.Sqrt(p.X*p.X+p.Y*p.Y)-t.MajorRadius)
+	return vmath.Vector2d{
+		X: (u + math.Pi) / (2 * math.Pi),
+		Y: (v + math.Pi) / (2 * math.Pi),
+	}
+}
+
+// CalculateFrame returns the directions around the ring and around the tube,
+// the directions u and v increase in
+func (t *Torus) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	p := t.local(hit.Subtract(t.P))
+	around := t.axis[1].SMultiply(p.X).Subtract(t.axis[0].SMultiply(p.Y))
+	// the tube is circled in the plane of the axis and the ring direction
+	ring := t.axis[0].SMultiply(p.X).Add(t.axis[1].SMultiply(p.Y))
+	ring.Normalize()
+	tube := t.axis[2].SMultiply(math.Sqrt(p.X*p.X+p.Y*p.Y) - t.MajorRadius).Subtract(ring.SMultiply(p.Z))
+	return around, tube
+}
+
+// GetMaterial returns the material the torus is shaded with
+func (t *Torus) GetMaterial() material.Material {
+	return t.Material
+}
+
+func (t *Torus) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(t, t.PlaceHit, 0), ray, objs)
+}
@@ -0,0 +1,108 @@
+package shapes
+
+import (
+	"math"
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestTorusConfigFromYaml(t *testing.T) {
+	yaml, err := simpleyaml.NewYaml([]byte(`
+    position: [0.0, 1.0, 0.0]
+    normal: [0, 0, 1]
+    major_radius: 2.0
+    minor_radius: 0.5
+`))
+	require.NoError(t, err)
+	config := &TorusConfig{}
+	require.NoError(t, config.FromYaml(yaml, map[string]material.Material{}))
+	assert.Equal(t, &TorusConfig{
+		Position:    vmath.Vector3d{X: 0.0, Y: 1.0, Z: 0.0},
+		Normal:      vmath.Vector3d{X: 0.0, Y: 0.0, Z: 1.0},
+		MajorRadius: 2.0,
+		MinorRadius: 0.5,
+	}, config)
+}
+
+func TestTorusIntersect(t *testing.T) {
+	tests := []struct {
+		Description   string
+		Expected      bool
+		ExpectedRatio float64
+		ExpectedNorm  vmath.Vector3d
+		Ray           *vmath.Ray
+	}{
+		{
+			Description:   "hits outside of tube from far away",
+			Expected:      true,
+			ExpectedRatio: 997.0,
+			ExpectedNorm:  vmath.Vector3d{X: -1, Y: 0, Z: 0},
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: -1000, Y: 0, Z: 0},
+				Direction: vmath.Vector3d{X: 1, Y: 0, Z: 0},
+			},
+		},
+		{
+			Description: "passes through the hole",
+			Expected:    false,
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: 0, Y: 0, Z: 10},
+				Direction: vmath.Vector3d{X: 0, Y: 0, Z: -1},
+			},
+		},
+		{
+			Description:   "hits top of tube along the axis",
+			Expected:      true,
+			ExpectedRatio: 9.0,
+			ExpectedNorm:  vmath.Vector3d{X: 0, Y: 0, Z: 1},
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: 2, Y: 0, Z: 10},
+				Direction: vmath.Vector3d{X: 0, Y: 0, Z: -1},
+			},
+		},
+		{
+			Description:   "grazes top of tube",
+			Expected:      true,
+			ExpectedRatio: 8.0,
+			ExpectedNorm:  vmath.Vector3d{X: 0, Y: 0, Z: 1},
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: -10, Y: 0, Z: 1},
+				Direction: vmath.Vector3d{X: 1, Y: 0, Z: 0},
+			},
+		},
+		{
+			Description:   "starts inside the tube",
+			Expected:      true,
+			ExpectedRatio: 1.0,
+			ExpectedNorm:  vmath.Vector3d{X: 0, Y: 1, Z: 0},
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: 0, Y: 2, Z: 0},
+				Direction: vmath.Vector3d{X: 0, Y: 1, Z: 0},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			// torus in the xy plane so the local frame z is world z
+			torus := NewTorus(vmath.Vector3d{}, vmath.Vector3d{X: 0, Y: 0, Z: 1}, 2.0, 1.0)
+			hit := torus.Intersect(test.Ray)
+			assert.Equal(t, test.Expected, hit)
+			if test.Expected {
+				assert.InDelta(t, test.ExpectedRatio, torus.GetIntersectionRatio(), 1e-6)
+				norm := torus.CalculateNorm(torus.PlaceHit)
+				assert.InDelta(t, test.ExpectedNorm.X, norm.X, 1e-3)
+				assert.InDelta(t, test.ExpectedNorm.Y, norm.Y, 1e-3)
+				assert.InDelta(t, test.ExpectedNorm.Z, norm.Z, 1e-3)
+				uv := torus.CalculateUV(torus.PlaceHit)
+				assert.False(t, math.IsNaN(uv.X) || math.IsNaN(uv.Y))
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// TransformedConfig wraps the config of any shape that has a transform block
+// in the scene, the shape is built as usual and then placed by Transform
+type TransformedConfig struct {
+	ShapesConfig
+	Transform vmath.Matrix4
+}
+
+// ChildNames satisfies the parentConfig interface by passing through to the
+// wrapped config, shapes without children have none
+func (tc *TransformedConfig) ChildNames() []string {
+	if parent, ok := tc.ShapesConfig.(parentConfig); ok {
+		return parent.ChildNames()
+	}
+	return nil
+}
+
+// SetChildren satisfies the parentConfig interface by passing through to the
+// wrapped config
+func (tc *TransformedConfig) SetChildren(children []common.Traceable) error {
+	if parent, ok := tc.ShapesConfig.(parentConfig); ok {
+		return parent.SetChildren(children)
+	}
+	return nil
+}
+
+// NewShape builds the wrapped shape and places it by Transform, closed shapes
+// stay a Solid so they can still be used in a csg
+func (tc *TransformedConfig) NewShape() (common.Traceable, error) {
+	traceable, err := tc.ShapesConfig.NewShape()
+	if err != nil {
+		return nil, err
+	}
+	surface, ok := traceable.(Surface)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("shape %s can not be transformed.", tc.GetName()))
+	}
+
+	transformed, err := NewTransformed(surface, tc.Transform)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("shape %s transform: %s", tc.GetName(), err.Error()))
+	}
+	if solid, ok := surface.(Solid); ok {
+		return transformed.wrap(solid), nil
+	}
+	return transformed, nil
+}
+
+// transformFromYaml reads a transform block, either a raw row major matrix of
+// 16 values or any of scale, rotate, quaternion and translate which are
+// applied in that order, rotate is in degrees around the X, Y and then Z axis
+// and quaternion is [w, x, y, z]
+func transformFromYaml(config *simpleyaml.Yaml) (vmath.Matrix4, error) {
+	if config.Get("matrix").IsFound() {
+		for _, key := range []string{"scale", "rotate", "quaternion", "translate"} {
+			if config.Get(key).IsFound() {
+				return vmath.Matrix4{}, errors.New(fmt.Sprintf("matrix can not be combined with %s", key))
+			}
+		}
+		values, err := floatsFromYaml(config.Get("matrix"))
+		if err != nil {
+			return vmath.Matrix4{}, err
+		}
+		if len(values) != 16 {
+			return vmath.Matrix4{}, errors.New("matrix requires 16 values")
+		}
+		m := vmath.Matrix4{}
+		for index, value := range values {
+			m[index/4][index%4] = value
+		}
+		return m, nil
+	}
+
+	m := vmath.Identity4()
+	if config.Get("scale").IsFound() {
+		scale, err := scaleFromYaml(config.Get("scale"))
+		if err != nil {
+			return vmath.Matrix4{}, errors.New("scale: " + err.Error())
+		}
+		m = vmath.Scale(scale).Multiply(m)
+	}
+	if config.Get("rotate").IsFound() {
+		rotate, err := vector3dFromYaml(config.Get("rotate"))
+		if err != nil {
+			return vmath.Matrix4{}, errors.New("rotate: " + err.Error())
+		}
+		m = vmath.Euler(rotate).Multiply(m)
+	}
+	if config.Get("quaternion").IsFound() {
+		values, err := floatsFromYaml(config.Get("quaternion"))
+		if err != nil {
+			return vmath.Matrix4{}, errors.New("quaternion: " + err.Error())
+		}
+		if len(values) != 4 {
+			return vmath.Matrix4{}, errors.New("quaternion requires 4 values")
+		}
+		q := vmath.Quaternion{W: values[0], X: values[1], Y: values[2], Z: values[3]}
+		err = q.Normalize()
+		if err != nil {
+			return vmath.Matrix4{}, errors.New("quaternion: " + err.Error())
+		}
+		m = q.Matrix4().Multiply(m)
+	}
+	if config.Get("translate").IsFound() {
+		translate, err := vector3dFromYaml(config.Get("translate"))
+		if err != nil {
+			return vmath.Matrix4{}, errors.New("translate: " + err.Error())
+		}
+		m = vmath.Translate(translate).Multiply(m)
+	}
+
+	return m, nil
+}
+
+// scaleFromYaml reads either one uniform scale or a scale per axis
+func scaleFromYaml(config *simpleyaml.Yaml) (vmath.Vector3d, error) {
+	if s, err := floatFromYaml(config); err == nil {
+		return vmath.Vector3d{X: s, Y: s, Z: s}, nil
+	}
+	return vector3dFromYaml(config)
+}
+
+// Transformed places Shape in the scene by a Matrix4, rays are moved into the
+// space of Shape with their direction left unnormalized so the intersection
+// ratio found there is the same along the original ray
+type Transformed struct {
+	Shape             Surface
+	PlaceHit          vmath.Vector3d
+	intersectionRatio float64
+
+	toWorld  vmath.Matrix4
+	toObject vmath.Matrix4
+	// normals are moved by the inverse transpose so they stay perpendicular
+	// to the surface under non uniform scale
+	toWorldNormal vmath.Matrix4
+}
+
+// NewTransformed places shape by transform, errors if transform can not be
+// inverted
+func NewTransformed(shape Surface, transform vmath.Matrix4) (*Transformed, error) {
+	inverse, err := transform.Inverse()
+	if err != nil {
+		return nil, err
+	}
+	return &Transformed{
+		Shape:         shape,
+		toWorld:       transform,
+		toObject:      inverse,
+		toWorldNormal: inverse.Transpose(),
+	}, nil
+}
+
+// wrap places solid by the same transform as t, keeping it a Solid
+func (t *Transformed) wrap(solid Solid) *TransformedSolid {
+	return &TransformedSolid{
+		Transformed: &Transformed{
+			Shape:         solid,
+			toWorld:       t.toWorld,
+			toObject:      t.toObject,
+			toWorldNormal: t.toWorldNormal,
+		},
+	}
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene
+func (t *Transformed) Intersect(ray *vmath.Ray) bool {
+	if !t.Shape.Intersect(t.toObject.MultiplyRay(ray)) {
+		return false
+	}
+	t.intersectionRatio = t.Shape.GetIntersectionRatio()
+	t.PlaceHit = ray.Origin.Add(ray.Direction.SMultiply(t.intersectionRatio))
+	return true
+}
+
+// GetIntersectionRatio
+func (t *Transformed) GetIntersectionRatio() float64 {
+	return t.intersectionRatio
+}
+
+// GetPosition satisfies requirements for Object
+// interface for a scene
+func (t *Transformed) GetPosition() vmath.Vector3d {
+	if object, ok := t.Shape.(common.Object); ok {
+		return t.toWorld.MultiplyPoint(object.GetPosition())
+	}
+	return t.toWorld.MultiplyPoint(vmath.Vector3d{})
+}
+
+func (t *Transformed) GetName() string {
+	if object, ok := t.Shape.(common.Object); ok {
+		return object.GetName()
+	}
+	return ""
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene, a transformed shape keeps the type of its Shape
+func (t *Transformed) GetType() string {
+	if object, ok := t.Shape.(common.Object); ok {
+		return object.GetType()
+	}
+	return ""
+}
+
+// CalculateNorm moves hit into the space of Shape and its normal back out
+func (t *Transformed) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	norm := t.toWorldNormal.MultiplyDirection(t.Shape.CalculateNorm(t.toObject.MultiplyPoint(hit)))
+	norm.Normalize()
+	return norm
+}
+
+// CalculateUV returns the UV of Shape at hit, UVs are unchanged by the
+// transform
+func (t *Transformed) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	if uv, ok := t.Shape.(interface {
+		CalculateUV(vmath.Vector3d) vmath.Vector2d
+	}); ok {
+		return uv.CalculateUV(t.toObject.MultiplyPoint(hit))
+	}
+	return vmath.Vector2d{}
+}
+
+// CalculateFrame moves hit into the space of Shape and its frame back out
+func (t *Transformed) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	if framed, ok := t.Shape.(Framed); ok {
+		tangent, bitangent := framed.CalculateFrame(t.toObject.MultiplyPoint(hit))
+		return t.toWorld.MultiplyDirection(tangent), t.toWorld.MultiplyDirection(bitangent)
+	}
+	return vmath.Vector3d{}, vmath.Vector3d{}
+}
+
+// ObjectPoint moves hit into the space of Shape
+func (t *Transformed) ObjectPoint(hit vmath.Vector3d) vmath.Vector3d {
+	local := t.toObject.MultiplyPoint(hit)
+	if placed, ok := t.Shape.(Placed); ok {
+		return placed.ObjectPoint(local)
+	}
+	return local
+}
+
+// ObjectNormal returns the normal of Shape at hit in its own space
+func (t *Transformed) ObjectNormal(hit vmath.Vector3d) vmath.Vector3d {
+	local := t.toObject.MultiplyPoint(hit)
+	if placed, ok := t.Shape.(Placed); ok {
+		return placed.ObjectNormal(local)
+	}
+	return t.Shape.CalculateNorm(local)
+}
+
+// Part returns the part of Shape hit by the last Intersect
+// Satisfies Parted interface
+func (t *Transformed) Part() interface{} {
+	if parted, ok := t.Shape.(Parted); ok {
+		return parted.Part()
+	}
+	return t
+}
+
+// GetMaterial returns the material of Shape
+func (t *Transformed) GetMaterial() material.Material {
+	return t.Shape.GetMaterial()
+}
+
+func (t *Transformed) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(t, t.PlaceHit, 0), ray, objs)
+}
+
+// TransformedSolid is a Transformed closed shape
+type TransformedSolid struct {
+	*Transformed
+}
+
+// Intervals satisfies the Solid interface, every crossing is placed by the
+// same transform so its normal comes back out in world space
+func (ts *TransformedSolid) Intervals(ray *vmath.Ray) []Interval {
+	intervals := ts.Shape.(Solid).Intervals(ts.toObject.MultiplyRay(ray))
+	for index := range intervals {
+		intervals[index].In.Solid = ts.wrap(intervals[index].In.Solid)
+		intervals[index].Out.Solid = ts.wrap(intervals[index].Out.Solid)
+	}
+	return intervals
+}
@@ -0,0 +1,278 @@
+package shapes
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestTransformedIntersect(t *testing.T) {
+	ellipsoid := func() *Transformed {
+		transformed, err := NewTransformed(NewSphere(vmath.Vector3d{}, 1.0), vmath.Scale(vmath.Vector3d{X: 2, Y: 1, Z: 1}))
+		require.NoError(t, err)
+		return transformed
+	}
+
+	tests := []struct {
+		Description   string
+		Shape         *Transformed
+		Ray           *vmath.Ray
+		Expected      bool
+		ExpectedRatio float64
+		ExpectedNorm  vmath.Vector3d
+	}{
+		{
+			Description: "scaled sphere is stretched along x",
+			Shape:       ellipsoid(),
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: -10},
+				Direction: vmath.Vector3d{X: 1},
+			},
+			Expected:      true,
+			ExpectedRatio: 8,
+			ExpectedNorm:  vmath.Vector3d{X: -1},
+		},
+		{
+			Description: "ellipsoid normal uses the inverse transpose",
+			Shape:       ellipsoid(),
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{X: math.Sqrt2, Y: 10},
+				Direction: vmath.Vector3d{Y: -1},
+			},
+			Expected:      true,
+			ExpectedRatio: 10 - math.Sqrt2/2,
+			// gradient of x^2/4 + y^2 is (x/2, 2y)
+			ExpectedNorm: vmath.Vector3d{X: 1 / math.Sqrt(5), Y: 2 / math.Sqrt(5)},
+		},
+		{
+			Description: "ellipsoid is missed where the sphere was",
+			Shape:       ellipsoid(),
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{Y: 1.5, Z: -10},
+				Direction: vmath.Vector3d{Z: 1},
+			},
+			Expected: false,
+		},
+		{
+			Description: "rotated and moved box",
+			Shape: func() *Transformed {
+				transformed, err := NewTransformed(
+					NewBox(vmath.Vector3d{}, vmath.Vector3d{X: 4, Y: 2, Z: 2}, vmath.Vector3d{}),
+					vmath.Translate(vmath.Vector3d{Y: 5}).Multiply(vmath.Euler(vmath.Vector3d{Z: 90})),
+				)
+				require.NoError(t, err)
+				return transformed
+			}(),
+			Ray: &vmath.Ray{
+				Origin:    vmath.Vector3d{Y: 20},
+				Direction: vmath.Vector3d{Y: -2},
+			},
+			Expected:      true,
+			ExpectedRatio: 6.5,
+			ExpectedNorm:  vmath.Vector3d{Y: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			assert.Equal(t, test.Expected, test.Shape.Intersect(test.Ray))
+			if test.Expected {
+				assert.InDelta(t, test.ExpectedRatio, test.Shape.GetIntersectionRatio(), 1e-9)
+				norm := test.Shape.CalculateNorm(test.Shape.PlaceHit)
+				assert.InDelta(t, test.ExpectedNorm.X, norm.X, 1e-9)
+				assert.InDelta(t, test.ExpectedNorm.Y, norm.Y, 1e-9)
+				assert.InDelta(t, test.ExpectedNorm.Z, norm.Z, 1e-9)
+			}
+		})
+	}
+}
+
+func TestTransformedCSG(t *testing.T) {
+	moved, err := NewTransformed(NewSphere(vmath.Vector3d{}, 1.0), vmath.Translate(vmath.Vector3d{X: 0.5}))
+	require.NoError(t, err)
+	csg := &CSG{Operation: CSGDifference, Children: []Solid{
+		moved.wrap(moved.Shape.(Solid)),
+		NewSphere(vmath.Vector3d{X: -0.5}, 1.0),
+	}}
+
+	ray := &vmath.Ray{Origin: vmath.Vector3d{X: -10}, Direction: vmath.Vector3d{X: 1}}
+	require.True(t, csg.Intersect(ray))
+	assert.InDelta(t, 10.5, csg.GetIntersectionRatio(), 1e-9)
+	norm := csg.CalculateNorm(csg.PlaceHit)
+	assert.InDelta(t, -1, norm.X, 1e-9)
+}
+
+func TestTransformFromYaml(t *testing.T) {
+	tests := []struct {
+		Description string
+		Bytes       []byte
+		Point       vmath.Vector3d
+		Expected    vmath.Vector3d
+		ExpectedErr error
+	}{
+		{
+			Description: "scale rotate then translate",
+			Bytes: []byte(`
+translate: [1, 2, 3]
+rotate: [0, 0, 90]
+scale: 2
+`),
+			Point:    vmath.Vector3d{X: 1},
+			Expected: vmath.Vector3d{X: 1, Y: 4, Z: 3},
+		},
+		{
+			Description: "per axis scale",
+			Bytes: []byte(`
+scale: [1, 2.5, 3]
+`),
+			Point:    vmath.Vector3d{X: 1, Y: 1, Z: 1},
+			Expected: vmath.Vector3d{X: 1, Y: 2.5, Z: 3},
+		},
+		{
+			Description: "quaternion is normalized",
+			Bytes: []byte(`
+quaternion: [1, 0, 0, 1]
+`),
+			Point:    vmath.Vector3d{X: 1},
+			Expected: vmath.Vector3d{Y: 1},
+		},
+		{
+			Description: "raw matrix",
+			Bytes: []byte(`
+matrix: [1, 0, 0, 5,
+         0, 1, 0, 0,
+         0, 0, 1, 0,
+         0, 0, 0, 1]
+`),
+			Point:    vmath.Vector3d{X: 1},
+			Expected: vmath.Vector3d{X: 6},
+		},
+		{
+			Description: "matrix mixed with translate",
+			Bytes: []byte(`
+matrix: [1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1]
+translate: [1, 2, 3]
+`),
+			ExpectedErr: errors.New("matrix can not be combined with translate"),
+		},
+		{
+			Description: "short matrix",
+			Bytes: []byte(`
+matrix: [1, 0, 0, 0]
+`),
+			ExpectedErr: errors.New("matrix requires 16 values"),
+		},
+		{
+			Description: "short translate",
+			Bytes: []byte(`
+translate: [1, 2]
+`),
+			ExpectedErr: errors.New("translate: vector requires 3 values"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			m, err := transformFromYaml(yaml)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			result := m.MultiplyPoint(test.Point)
+			assert.InDelta(t, test.Expected.X, result.X, 1e-9)
+			assert.InDelta(t, test.Expected.Y, result.Y, 1e-9)
+			assert.InDelta(t, test.Expected.Z, result.Z, 1e-9)
+		})
+	}
+}
+
+func TestTransformedShapesFactory(t *testing.T) {
+	tests := []struct {
+		Description  string
+		Bytes        []byte
+		ExpectedType interface{}
+		ExpectedErr  error
+	}{
+		{
+			Description: "transformed sphere stays a solid",
+			Bytes: []byte(`
+egg:
+  type: sphere
+  radius: 1.0
+  transform:
+    scale: [1, 1.5, 1]
+`),
+			ExpectedType: &TransformedSolid{},
+		},
+		{
+			Description: "transformed disk",
+			Bytes: []byte(`
+lid:
+  type: disk
+  normal: [0, 1, 0]
+  radius: 1.0
+  transform:
+    rotate: [90, 0, 0]
+`),
+			ExpectedType: &Transformed{},
+		},
+		{
+			Description: "transformed csg",
+			Bytes: []byte(`
+ball:
+  type: sphere
+  radius: 1.0
+  transform:
+    translate: [0.5, 0, 0]
+block:
+  type: box
+  size: [1.5, 1.5, 1.5]
+part:
+  type: csg
+  operation: intersection
+  shapes: [ball, block]
+  transform:
+    rotate: [0, 45, 0]
+`),
+			ExpectedType: &TransformedSolid{},
+		},
+		{
+			Description: "singular transform",
+			Bytes: []byte(`
+flat:
+  type: sphere
+  radius: 1.0
+  transform:
+    scale: [1, 0, 1]
+`),
+			ExpectedErr: errors.New("shape flat transform: matrix is not invertible"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			configs, err := ShapesConfigFactory(yaml, map[string]material.Material{}, nil)
+			require.NoError(t, err)
+			traceables, err := ShapesFactory(configs)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, traceables, 1)
+			assert.IsType(t, test.ExpectedType, traceables[0])
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// TriangleConfig defines a triangle for the ShapeFactory
+type TriangleConfig struct {
+	Name     string
+	Vertices []vmath.Vector3d
+	UVs      []vmath.Vector2d
+	Material material.Material
+}
+
+func (tc *TriangleConfig) GetName() string {
+	return tc.Name
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (tc *TriangleConfig) NewShape() (common.Traceable, error) {
+	if len(tc.Vertices) != 3 {
+		return nil, errors.New("triangle requires 3 vertices")
+	}
+	normal := tc.Vertices[1].Subtract(tc.Vertices[0]).Cross(tc.Vertices[2].Subtract(tc.Vertices[0]))
+	if normal.IsZero() {
+		return nil, errors.New("triangle vertices can not be in a line")
+	}
+	triangle := NewTriangle(tc.Vertices[0], tc.Vertices[1], tc.Vertices[2])
+	if len(tc.UVs) == 3 {
+		copy(triangle.UVs[:], tc.UVs)
+	}
+	triangle.Name = tc.Name
+	triangle.Material = tc.Material
+	return triangle, nil
+}
+
+// FromYaml generates Config from input yaml
+// satisfies the interface ShapesConfig (2/2)
+func (tc *TriangleConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	if config.Get("vertices").IsFound() {
+		size, err := config.Get("vertices").GetArraySize()
+		if err != nil || size != 3 {
+			return errors.New("triangle requires 3 vertices")
+		}
+		for index := 0; index < size; index++ {
+			v, err := vector3dFromYaml(config.Get("vertices").GetIndex(index))
+			if err != nil {
+				return errors.New(fmt.Sprintf("triangle vertex %d: %s", index, err.Error()))
+			}
+			tc.Vertices = append(tc.Vertices, v)
+		}
+	}
+	if config.Get("uvs").IsFound() {
+		size, err := config.Get("uvs").GetArraySize()
+		if err != nil || size != 3 {
+			return errors.New("triangle requires 3 uvs")
+		}
+		for index := 0; index < size; index++ {
+			uv, err := floatsFromYaml(config.Get("uvs").GetIndex(index))
+			if err != nil {
+				return errors.New(fmt.Sprintf("triangle uv %d is not a number", index))
+			}
+			if len(uv) != 2 {
+				return errors.New(fmt.Sprintf("triangle uv %d requires 2 values", index))
+			}
+			tc.UVs = append(tc.UVs, vmath.Vector2d{X: uv[0], Y: uv[1]})
+		}
+	}
+
+	m, err := materialFromYaml(config, materials)
+	if err != nil {
+		return err
+	}
+	tc.Material = m
+
+	return nil
+}
+
+// Triangle is a Plane bounded by three vertices, UVs are the texture
+// coordinates at each vertex
+type Triangle struct {
+	*Plane
+	Vertices [3]vmath.Vector3d
+	UVs      [3]vmath.Vector2d
+	// dual basis of the two edges leaving the first vertex
+	dual1, dual2 vmath.Vector3d
+}
+
+// NewTriangle makes a triangle facing the right hand normal of v0, v1, v2
+func NewTriangle(v0 vmath.Vector3d, v1 vmath.Vector3d, v2 vmath.Vector3d) *Triangle {
+	e1 := v1.Subtract(v0)
+	e2 := v2.Subtract(v0)
+	dual1, dual2 := dualBasis(e1, e2)
+	return &Triangle{
+		Plane:    NewPlane(v0, e1.Cross(e2)),
+		Vertices: [3]vmath.Vector3d{v0, v1, v2},
+		UVs: [3]vmath.Vector2d{
+			{X: 0.0, Y: 0.0},
+			{X: 1.0, Y: 0.0},
+			{X: 0.0, Y: 1.0},
+		},
+		dual1: dual1,
+		dual2: dual2,
+	}
+}
+
+// barycentric returns the weights of the second and third vertex at hit
+func (t *Triangle) barycentric(hit vmath.Vector3d) (float64, float64) {
+	local := hit.Subtract(t.P)
+	return t.dual1.Dot(local), t.dual2.Dot(local)
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene
+func (t *Triangle) Intersect(ray *vmath.Ray) bool {
+	if !t.Plane.Intersect(ray) {
+		return false
+	}
+
+	b1, b2 := t.barycentric(t.PlaceHit)
+	return b1 >= 0 && b2 >= 0 && b1+b2 <= 1
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (t *Triangle) GetType() string {
+	return "triangle"
+}
+
+// CalculateNorm returns the normal of the triangle
+func (t *Triangle) CalculateNorm(hit vmath.Vector3d) vmath.Vector3d {
+	return t.axis[2]
+}
+
+// CalculateUV interpolates the vertex UVs at hit
+func (t *Triangle) CalculateUV(hit vmath.Vector3d) vmath.Vector2d {
+	b1, b2 := t.barycentric(hit)
+	return t.UVs[0].SMultiply(1 - b1 - b2).
+		Add(t.UVs[1].SMultiply(b1)).
+		Add(t.UVs[2].SMultiply(b2))
+}
+
+// CalculateFrame returns the directions the vertex UVs increase in across
+// the triangle
+func (t *Triangle) CalculateFrame(hit vmath.Vector3d) (vmath.Vector3d, vmath.Vector3d) {
+	return uvFrame(t.Vertices, t.UVs)
+}
+
+func (t *Triangle) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return shade(newSurfaceHit(t, t.PlaceHit, 0), ray, objs)
+}
+
+// intersectTriangle finds where ray crosses the triangle v0, v1, v2 from
+// either side without building a Triangle, it returns the ratio along ray and
+// the weights of v1 and v2 at the hit
+func intersectTriangle(ray *vmath.Ray, v0 vmath.Vector3d, v1 vmath.Vector3d, v2 vmath.Vector3d) (float64, float64, float64, bool) {
+	e1 := v1.Subtract(v0)
+	e2 := v2.Subtract(v0)
+	p := ray.Direction.Cross(e2)
+	det := e1.Dot(p)
+	if det == 0 {
+		return 0, 0, 0, false
+	}
+
+	s := ray.Origin.Subtract(v0)
+	b1 := s.Dot(p) / det
+	if b1 < 0 || b1 > 1 {
+		return 0, 0, 0, false
+	}
+	q := s.Cross(e1)
+	b2 := ray.Direction.Dot(q) / det
+	if b2 < 0 || b1+b2 > 1 {
+		return 0, 0, 0, false
+	}
+
+	return e2.Dot(q) / det, b1, b2, true
+}
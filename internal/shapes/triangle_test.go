@@ -0,0 +1,85 @@
+package shapes
+
+import (
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+func TestTriangleConfigFromYaml(t *testing.T) {
+	// uvs of whole numbers are read as numbers too
+	yaml, err := simpleyaml.NewYaml([]byte(`
+    vertices:
+      - [0.0, 0.0, 0.0]
+      - [1.0, 0.0, 0.0]
+      - [0.0, 1.0, 0.0]
+    uvs:
+      - [0, 0]
+      - [0.5, 0]
+      - [0, 0.5]
+`))
+	require.NoError(t, err)
+	config := &TriangleConfig{}
+	require.NoError(t, config.FromYaml(yaml, map[string]material.Material{}))
+	assert.Equal(t, &TriangleConfig{
+		Vertices: []vmath.Vector3d{
+			{X: 0.0, Y: 0.0, Z: 0.0},
+			{X: 1.0, Y: 0.0, Z: 0.0},
+			{X: 0.0, Y: 1.0, Z: 0.0},
+		},
+		UVs: []vmath.Vector2d{
+			{X: 0.0, Y: 0.0},
+			{X: 0.5, Y: 0.0},
+			{X: 0.0, Y: 0.5},
+		},
+	}, config)
+}
+
+func TestTriangleIntersect(t *testing.T) {
+	tests := []struct {
+		Description string
+		Expected    bool
+		ExpectedUV  vmath.Vector2d
+		Origin      vmath.Vector3d
+	}{
+		{
+			Description: "hits inside",
+			Expected:    true,
+			ExpectedUV:  vmath.Vector2d{X: 0.25, Y: 0.25},
+			Origin:      vmath.Vector3d{X: 0.25, Y: 0.25, Z: 3.0},
+		},
+		{
+			Description: "hits vertex",
+			Expected:    true,
+			ExpectedUV:  vmath.Vector2d{X: 1.0, Y: 0.0},
+			Origin:      vmath.Vector3d{X: 1.0, Y: 0.0, Z: 3.0},
+		},
+		{
+			Description: "misses past hypotenuse",
+			Expected:    false,
+			Origin:      vmath.Vector3d{X: 0.75, Y: 0.75, Z: 3.0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			triangle := NewTriangle(vmath.Vector3d{X: 0, Y: 0, Z: 0},
+				vmath.Vector3d{X: 1, Y: 0, Z: 0},
+				vmath.Vector3d{X: 0, Y: 1, Z: 0})
+			ray := &vmath.Ray{Origin: test.Origin, Direction: vmath.Vector3d{X: 0, Y: 0, Z: -1}}
+			assert.Equal(t, test.Expected, triangle.Intersect(ray))
+			if test.Expected {
+				assert.InDelta(t, 3.0, triangle.GetIntersectionRatio(), 1e-9)
+				assert.Equal(t, vmath.Vector3d{X: 0, Y: 0, Z: 1}, triangle.CalculateNorm(triangle.PlaceHit))
+				uv := triangle.CalculateUV(triangle.PlaceHit)
+				assert.InDelta(t, test.ExpectedUV.X, uv.X, 1e-9)
+				assert.InDelta(t, test.ExpectedUV.Y, uv.Y, 1e-9)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/smallfish/simpleyaml"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/lights"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+	"github.com/chrispotter/trace/internal/volume"
+)
+
+// VolumeConfig defines a participating medium like fog or smoke filling
+// another shape in the scene for the ShapeFactory
+type VolumeConfig struct {
+	Name       string
+	Shape      string
+	Absorption vmath.Vector3d
+	Scattering vmath.Vector3d
+	G          float64
+	Step       float64
+	Density    volume.Density
+
+	child Solid
+}
+
+func (vc *VolumeConfig) GetName() string {
+	return vc.Name
+}
+
+// ChildNames satisfies the parentConfig interface
+func (vc *VolumeConfig) ChildNames() []string {
+	return []string{vc.Shape}
+}
+
+// SetChildren satisfies the parentConfig interface, the shape has to be a
+// closed Solid for the inside of it to be known
+func (vc *VolumeConfig) SetChildren(children []common.Traceable) error {
+	solid, ok := children[0].(Solid)
+	if !ok {
+		return errors.New(fmt.Sprintf("shape %s can not be filled by volume %s, it is not a closed solid.", vc.Shape, vc.Name))
+	}
+	vc.child = solid
+	return nil
+}
+
+// NewShape generates a Shape from the config object
+// satisfies the interface ShapesConfig (1/2)
+func (vc *VolumeConfig) NewShape() (common.Traceable, error) {
+	medium, err := volume.NewMedium(vc.Absorption, vc.Scattering, vc.G, vc.Density, vc.Step)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("volume %s: %s", vc.Name, err.Error()))
+	}
+	return &Volume{
+		Name:   vc.Name,
+		Shape:  vc.child,
+		Medium: medium,
+	}, nil
+}
+
+// FromYaml generates Config from input yaml, shape names the solid to fill,
+// absorption and scattering are a single number or one per color channel and
+// density is a constant, noise or grid block, a constant of 1 when missing
+// satisfies the interface ShapesConfig (2/2)
+func (vc *VolumeConfig) FromYaml(config *simpleyaml.Yaml, materials map[string]material.Material) error {
+	shape, err := config.Get("shape").String()
+	if err != nil {
+		return errors.New("volume requires a shape")
+	}
+	vc.Shape = shape
+
+	vc.Absorption, err = scaleFromYaml(config.Get("absorption"))
+	if err != nil {
+		return errors.New("volume requires absorption")
+	}
+	vc.Scattering, err = scaleFromYaml(config.Get("scattering"))
+	if err != nil {
+		return errors.New("volume requires scattering")
+	}
+
+	if config.Get("g").IsFound() {
+		vc.G, err = floatFromYaml(config.Get("g"))
+		if err != nil {
+			return errors.New("volume g is not a number")
+		}
+	}
+
+	vc.Step = 0.1
+	if config.Get("step").IsFound() {
+		vc.Step, err = floatFromYaml(config.Get("step"))
+		if err != nil {
+			return errors.New("volume step is not a number")
+		}
+	}
+
+	if config.Get("density").IsFound() {
+		vc.Density, err = densityFromYaml(config.Get("density"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// densityFromYaml reads the density block of a volume
+func densityFromYaml(config *simpleyaml.Yaml) (volume.Density, error) {
+	t, err := config.Get("type").String()
+	if err != nil {
+		return nil, errors.New("volume density requires a type")
+	}
+
+	switch t {
+	case "constant":
+		value, err := floatFromYaml(config.Get("value"))
+		if err != nil {
+			return nil, errors.New("constant density requires a value")
+		}
+		return &volume.Constant{Value: value}, nil
+	case "noise":
+		noise := &volume.Noise{Scale: 1.0, Octaves: 4, Multiplier: 1.0}
+		for _, field := range []struct {
+			key   string
+			value *float64
+		}{
+			{"scale", &noise.Scale},
+			{"threshold", &noise.Threshold},
+			{"multiplier", &noise.Multiplier},
+		} {
+			if config.Get(field.key).IsFound() {
+				*field.value, err = floatFromYaml(config.Get(field.key))
+				if err != nil {
+					return nil, errors.New(fmt.Sprintf("noise density %s is not a number", field.key))
+				}
+			}
+		}
+		if config.Get("octaves").IsFound() {
+			noise.Octaves, err = config.Get("octaves").Int()
+			if err != nil || noise.Octaves < 1 {
+				return nil, errors.New("noise density octaves must be 1 or more")
+			}
+		}
+		if noise.Threshold < 0 || noise.Threshold >= 1 {
+			return nil, errors.New("noise density threshold must be from 0 to less than 1")
+		}
+		return noise, nil
+	case "grid":
+		file, err := config.Get("file").String()
+		if err != nil {
+			return nil, errors.New("grid density requires a file")
+		}
+		size, err := floatsFromYaml(config.Get("resolution"))
+		if err != nil || len(size) != 3 {
+			return nil, errors.New("grid density requires a resolution of 3 values")
+		}
+		resolution := [3]int{int(size[0]), int(size[1]), int(size[2])}
+		position, err := vector3dFromYaml(config.Get("position"))
+		if err != nil {
+			return nil, errors.New("grid density requires a position")
+		}
+		extent, err := scaleFromYaml(config.Get("size"))
+		if err != nil {
+			return nil, errors.New("grid density requires a size")
+		}
+		return volume.LoadGrid(file, resolution, position, extent)
+	default:
+		return nil, errors.New(fmt.Sprintf("volume density %s is not one of constant, noise or grid.", t))
+	}
+}
+
+// Volume is a Medium filling the inside of Shape, the shapes behind it are
+// seen through the medium dimmed by it and every light in the scene is
+// scattered towards the camera from inside of it, the lights are dimmed by
+// every medium on their way in and blocked by the other shapes of the scene,
+// surfaces in or behind a medium are lit through it the same way
+//
+// only light coming straight from the lights is scattered since there is no
+// path tracing, and a Volume is not a Surface so it can not be transformed,
+// grouped or instanced, its Shape is placed in the world instead
+type Volume struct {
+	Name              string
+	Shape             Solid
+	Medium            *volume.Medium
+	PlaceHit          vmath.Vector3d
+	intersectionRatio float64
+}
+
+// inside returns the spans of ray inside of Shape in front of its origin
+func (v *Volume) inside(ray *vmath.Ray) []Interval {
+	spans := []Interval{}
+	for _, interval := range v.Shape.Intervals(ray) {
+		if interval.Out.T <= 0 {
+			continue
+		}
+		interval.In.T = math.Max(interval.In.T, 0)
+		spans = append(spans, interval)
+	}
+	return spans
+}
+
+// Intersect satisfies the qualifications for
+// Render object interface for a scene, a ray starting in the medium hits it
+// right away
+func (v *Volume) Intersect(ray *vmath.Ray) bool {
+	spans := v.inside(ray)
+	if len(spans) == 0 {
+		return false
+	}
+	v.intersectionRatio = spans[0].In.T
+	v.PlaceHit = ray.Origin.Add(ray.Direction.SMultiply(v.intersectionRatio))
+	return true
+}
+
+// GetPosition satisfies requirements for Object
+// interface for a scene
+func (v *Volume) GetPosition() vmath.Vector3d {
+	if object, ok := v.Shape.(common.Object); ok {
+		return object.GetPosition()
+	}
+	return vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+}
+
+func (v *Volume) GetName() string {
+	return v.Name
+}
+
+// GetType satisfies requirements for Object
+// interface for a scene
+func (v *Volume) GetType() string {
+	return "volume"
+}
+
+// GetIntersectionRatio
+func (v *Volume) GetIntersectionRatio() float64 {
+	return v.intersectionRatio
+}
+
+// behind returns the nearest other shape ray hits past where it enters the
+// volume and the ratio it is hit at
+func (v *Volume) behind(ray *vmath.Ray, objs *common.RenderableObjects) (common.Traceable, float64) {
+	var nearest common.Traceable
+	ratio := math.Inf(1)
+	for _, shape := range objs.Shapes {
+		if shape == common.Traceable(v) || !shape.Intersect(ray) {
+			continue
+		}
+		t := shape.GetIntersectionRatio()
+		if t > v.intersectionRatio && t < ratio {
+			nearest, ratio = shape, t
+		}
+	}
+	return nearest, ratio
+}
+
+// blocked returns whether a shape that is not a volume is hit by ray before
+// distance, volumes dim the light instead
+func (v *Volume) blocked(ray *vmath.Ray, distance float64, objs *common.RenderableObjects) bool {
+	for _, shape := range objs.Shapes {
+		if _, ok := shape.(*Volume); ok || !shape.Intersect(ray) {
+			continue
+		}
+		if t := shape.GetIntersectionRatio(); t > 1e-6 && t < distance {
+			return true
+		}
+	}
+	return false
+}
+
+// Transmittance is the share of light of each channel that makes it
+// through the medium along ray before distance
+func (v *Volume) Transmittance(ray *vmath.Ray, distance float64) vmath.Vector3d {
+	transmittance := vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}
+	for _, span := range v.inside(ray) {
+		if span.In.T >= distance {
+			break
+		}
+		transmittance = transmittance.Compt(v.Medium.Transmittance(ray, span.In.T, math.Min(span.Out.T, distance)))
+	}
+	return transmittance
+}
+
+// throughVolumes is the share of light of each channel that makes it
+// through every volume of the scene along ray before distance
+func throughVolumes(ray *vmath.Ray, distance float64, objs *common.RenderableObjects) vmath.Vector3d {
+	transmittance := vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}
+	for _, shape := range objs.Shapes {
+		if v, ok := shape.(*Volume); ok {
+			transmittance = transmittance.Compt(v.Transmittance(ray, distance))
+		}
+	}
+	return transmittance
+}
+
+// lightThrough is the light of every light in the scene reaching p through
+// the medium and scattered back along direction towards the camera
+func (v *Volume) lightThrough(p vmath.Vector3d, direction vmath.Vector3d, objs *common.RenderableObjects) vmath.Vector3d {
+	light := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	for _, l := range objs.Lights {
+		toLight := l.ReturnLightVector(p)
+		distance := math.Inf(1)
+		if _, ok := l.(*lights.DirectionalLight); !ok {
+			distance = toLight.Norm()
+		}
+		toLight.Normalize()
+		shadow := &vmath.Ray{Origin: p, Direction: toLight}
+		if v.blocked(shadow, distance, objs) {
+			continue
+		}
+		transmittance := throughVolumes(shadow, distance, objs)
+		// the phase function is of the angle light is turned by, from
+		// travelling away from the light to travelling back along direction
+		phase := v.Medium.Phase(toLight.Dot(direction))
+		light = light.Add(l.GetColor().GetColor(0, 0).Compt(transmittance).SMultiply(phase))
+	}
+	return light
+}
+
+// ReturnColor marches ray through the medium up to the shape behind it,
+// adding the light scattered towards the camera to the dimmed color of that
+// shape
+func (v *Volume) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	direction := ray.Direction
+	direction.Normalize()
+	inscatter := func(p vmath.Vector3d) vmath.Vector3d {
+		return v.lightThrough(p, direction, objs)
+	}
+
+	shape, end := v.behind(ray, objs)
+	transmittance := vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}
+	radiance := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	for _, span := range v.inside(ray) {
+		if span.In.T >= end {
+			break
+		}
+		radiance = radiance.Add(v.Medium.Integrate(ray, span.In.T, math.Min(span.Out.T, end), &transmittance, inscatter))
+	}
+
+	if shape != nil {
+		// the shape is intersected again since any shape tested after it
+		// may have been a parent sharing its state
+		shape.Intersect(ray)
+		radiance = radiance.Add(shape.ReturnColor(ray, objs).GetColor(0, 0).Compt(transmittance))
+	}
+
+	return color.NewColorValue(radiance)
+}
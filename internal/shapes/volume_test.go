@@ -0,0 +1,397 @@
+package shapes
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/smallfish/simpleyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chrispotter/trace/internal/color"
+	"github.com/chrispotter/trace/internal/common"
+	"github.com/chrispotter/trace/internal/lights"
+	"github.com/chrispotter/trace/internal/material"
+	vmath "github.com/chrispotter/trace/internal/math"
+	"github.com/chrispotter/trace/internal/volume"
+)
+
+// wall is a shape of a single color across the plane z = Z
+type wall struct {
+	Z     float64
+	Color vmath.Vector3d
+	ratio float64
+}
+
+func (w *wall) Intersect(ray *vmath.Ray) bool {
+	if ray.Direction.Z == 0 {
+		return false
+	}
+	w.ratio = (w.Z - ray.Origin.Z) / ray.Direction.Z
+	return w.ratio > 0
+}
+
+func (w *wall) GetIntersectionRatio() float64 {
+	return w.ratio
+}
+
+func (w *wall) ReturnColor(ray *vmath.Ray, objs *common.RenderableObjects) color.Color {
+	return color.NewColorValue(w.Color)
+}
+
+func TestVolumeIntersect(t *testing.T) {
+	fog := &Volume{
+		Shape:  NewSphere(vmath.Vector3d{Z: -5.0}, 2.0),
+		Medium: &volume.Medium{Density: &volume.Constant{Value: 1.0}, Step: 0.1},
+	}
+
+	tests := []struct {
+		Description   string
+		Ray           *vmath.Ray
+		Expected      bool
+		ExpectedRatio float64
+	}{
+		{
+			Description:   "ray from outside enters at the surface",
+			Ray:           &vmath.Ray{Direction: vmath.Vector3d{Z: -1.0}},
+			Expected:      true,
+			ExpectedRatio: 3.0,
+		},
+		{
+			Description:   "ray from inside is in the medium right away",
+			Ray:           &vmath.Ray{Origin: vmath.Vector3d{Z: -4.0}, Direction: vmath.Vector3d{Z: -1.0}},
+			Expected:      true,
+			ExpectedRatio: 0.0,
+		},
+		{
+			Description: "ray leaving the medium behind misses",
+			Ray:         &vmath.Ray{Direction: vmath.Vector3d{Z: 1.0}},
+		},
+		{
+			Description: "ray past the medium misses",
+			Ray:         &vmath.Ray{Direction: vmath.Vector3d{X: 1.0}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			assert.Equal(t, test.Expected, fog.Intersect(test.Ray))
+			if test.Expected {
+				assert.InDelta(t, test.ExpectedRatio, fog.GetIntersectionRatio(), 1e-9)
+			}
+		})
+	}
+}
+
+func TestVolumeReturnColor(t *testing.T) {
+	ray := &vmath.Ray{Direction: vmath.Vector3d{Z: -1.0}}
+	// a box from z = -2 to z = -4 in front of a wall at z = -10
+	block := NewBox(vmath.Vector3d{Z: -3.0}, vmath.Vector3d{X: 2.0, Y: 2.0, Z: 2.0}, vmath.Vector3d{})
+	back := &wall{Z: -10.0, Color: vmath.Vector3d{X: 200.0, Y: 100.0, Z: 50.0}}
+	light := &lights.DirectionalLight{
+		V:         vmath.Vector3d{X: -1.0},
+		Intensity: 1.0,
+		Color:     &color.ColorValue{Color: vmath.Vector3d{X: 255.0, Y: 255.0, Z: 255.0}},
+	}
+
+	t.Run("absorbing medium dims the shape behind it", func(t *testing.T) {
+		medium, err := volume.NewMedium(vmath.Vector3d{X: 0.1, Y: 0.2, Z: 0.3}, vmath.Vector3d{}, 0.0, nil, 0.1)
+		require.NoError(t, err)
+		fog := &Volume{Shape: block, Medium: medium}
+		objs := &common.RenderableObjects{Shapes: []common.Traceable{fog, back}, Lights: []lights.Light{light}}
+
+		require.True(t, fog.Intersect(ray))
+		c := fog.ReturnColor(ray, objs).GetColor(0, 0)
+		assert.InDelta(t, 200.0*math.Exp(-0.2), c.X, 1e-9)
+		assert.InDelta(t, 100.0*math.Exp(-0.4), c.Y, 1e-9)
+		assert.InDelta(t, 50.0*math.Exp(-0.6), c.Z, 1e-9)
+	})
+
+	t.Run("scattering medium lights up with the lights", func(t *testing.T) {
+		medium, err := volume.NewMedium(vmath.Vector3d{}, vmath.Vector3d{X: 0.5, Y: 0.5, Z: 0.5}, 0.0, nil, 0.1)
+		require.NoError(t, err)
+		fog := &Volume{Shape: block, Medium: medium}
+		objs := &common.RenderableObjects{Shapes: []common.Traceable{fog}, Lights: []lights.Light{light}}
+
+		require.True(t, fog.Intersect(ray))
+		c := fog.ReturnColor(ray, objs).GetColor(0, 0)
+		// the light crosses the medium sideways so every point is lit a
+		// little less, the total is bounded by the unshadowed medium
+		unshadowed := 255.0 / (4 * math.Pi) * (1 - math.Exp(-0.5*2))
+		assert.Greater(t, c.X, 0.5*unshadowed)
+		assert.Less(t, c.X, unshadowed)
+		assert.InDelta(t, c.X, c.Z, 1e-9)
+	})
+
+	t.Run("shape between the medium and the light shadows it", func(t *testing.T) {
+		medium, err := volume.NewMedium(vmath.Vector3d{}, vmath.Vector3d{X: 0.5, Y: 0.5, Z: 0.5}, 0.0, nil, 0.1)
+		require.NoError(t, err)
+		fog := &Volume{Shape: block, Medium: medium}
+		// the light shines along -x so a box off to +x blocks all of it
+		blocker := NewBox(vmath.Vector3d{X: 5.0, Z: -3.0}, vmath.Vector3d{X: 2.0, Y: 4.0, Z: 6.0}, vmath.Vector3d{})
+		objs := &common.RenderableObjects{Shapes: []common.Traceable{fog, blocker}, Lights: []lights.Light{light}}
+
+		require.True(t, fog.Intersect(ray))
+		c := fog.ReturnColor(ray, objs).GetColor(0, 0)
+		assert.Equal(t, vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}, c)
+	})
+
+	t.Run("shape shadowing half of a homogeneous medium", func(t *testing.T) {
+		medium, err := volume.NewMedium(vmath.Vector3d{}, vmath.Vector3d{X: 0.5, Y: 0.5, Z: 0.5}, 0.0, nil, 0.1)
+		require.NoError(t, err)
+		fog := &Volume{Shape: block, Medium: medium}
+		// the box off to +x shadows the medium from z = -2 to z = -3, only
+		// the far half scatters light
+		blocker := NewBox(vmath.Vector3d{X: 5.0, Z: -1.5}, vmath.Vector3d{X: 2.0, Y: 4.0, Z: 3.0}, vmath.Vector3d{})
+		objs := &common.RenderableObjects{Shapes: []common.Traceable{fog, blocker}, Lights: []lights.Light{light}}
+
+		require.True(t, fog.Intersect(ray))
+		c := fog.ReturnColor(ray, objs).GetColor(0, 0)
+		// light crosses a unit of medium sideways and the scattered light
+		// is dimmed by the shadowed half in front of it
+		expected := 255.0 / (4 * math.Pi) * math.Exp(-0.5) * (math.Exp(-0.5) - math.Exp(-1.0))
+		assert.InDelta(t, expected, c.X, 1e-9)
+	})
+
+	t.Run("volume between the medium and the light dims it", func(t *testing.T) {
+		medium, err := volume.NewMedium(vmath.Vector3d{}, vmath.Vector3d{X: 0.5, Y: 0.5, Z: 0.5}, 0.0, nil, 0.1)
+		require.NoError(t, err)
+		fog := &Volume{Shape: block, Medium: medium}
+		smoke, err := volume.NewMedium(vmath.Vector3d{X: 0.5, Y: 0.5, Z: 0.5}, vmath.Vector3d{}, 0.0, nil, 0.1)
+		require.NoError(t, err)
+		// the light crosses 2 units of the smoke off to +x on its way in
+		shade := &Volume{Shape: NewBox(vmath.Vector3d{X: 5.0, Z: -3.0}, vmath.Vector3d{X: 2.0, Y: 4.0, Z: 6.0}, vmath.Vector3d{}), Medium: smoke}
+
+		require.True(t, fog.Intersect(ray))
+		alone := fog.ReturnColor(ray, &common.RenderableObjects{Shapes: []common.Traceable{fog}, Lights: []lights.Light{light}}).GetColor(0, 0)
+		require.True(t, fog.Intersect(ray))
+		c := fog.ReturnColor(ray, &common.RenderableObjects{Shapes: []common.Traceable{fog, shade}, Lights: []lights.Light{light}}).GetColor(0, 0)
+		assert.Greater(t, alone.X, 0.0)
+		assert.InDelta(t, alone.X*math.Exp(-1.0), c.X, 1e-9)
+	})
+
+	t.Run("surface lit through the medium is dimmed by it", func(t *testing.T) {
+		medium, err := volume.NewMedium(vmath.Vector3d{X: 0.5, Y: 0.5, Z: 0.5}, vmath.Vector3d{}, 0.0, nil, 0.1)
+		require.NoError(t, err)
+		fog := &Volume{Shape: block, Medium: medium}
+		// a box off to -x faces the light across the 2 units of the medium
+		lit := NewBox(vmath.Vector3d{X: -3.0, Z: -3.0}, vmath.Vector3d{X: 2.0, Y: 2.0, Z: 2.0}, vmath.Vector3d{})
+		// the toon lambert steps to its Ambient color on the lit side
+		lit.Material = &material.Lambert{
+			Ambient: &color.ColorValue{Color: vmath.Vector3d{X: 100.0, Y: 100.0, Z: 100.0}},
+			Diffuse: &color.ColorValue{Color: vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}},
+			SH:      1.0,
+		}
+		toLit := &vmath.Ray{Origin: vmath.Vector3d{X: -1.5, Z: -3.0}, Direction: vmath.Vector3d{X: -1.0}}
+
+		require.True(t, lit.Intersect(toLit))
+		clear := lit.ReturnColor(toLit, &common.RenderableObjects{Shapes: []common.Traceable{lit}, Lights: []lights.Light{light}}).GetColor(0, 0)
+		require.True(t, lit.Intersect(toLit))
+		c := lit.ReturnColor(toLit, &common.RenderableObjects{Shapes: []common.Traceable{fog, lit}, Lights: []lights.Light{light}}).GetColor(0, 0)
+		assert.Greater(t, clear.X, 0.0)
+		assert.Less(t, clear.X, 255.0)
+		assert.InDelta(t, clear.X*math.Exp(-1.0), c.X, 1e-9)
+	})
+
+	t.Run("shape in front of the far side cuts the medium short", func(t *testing.T) {
+		medium, err := volume.NewMedium(vmath.Vector3d{X: 0.5, Y: 0.5, Z: 0.5}, vmath.Vector3d{}, 0.0, nil, 0.1)
+		require.NoError(t, err)
+		fog := &Volume{Shape: block, Medium: medium}
+		inside := &wall{Z: -3.0, Color: vmath.Vector3d{X: 100.0, Y: 100.0, Z: 100.0}}
+		objs := &common.RenderableObjects{Shapes: []common.Traceable{fog, back, inside}}
+
+		require.True(t, fog.Intersect(ray))
+		c := fog.ReturnColor(ray, objs).GetColor(0, 0)
+		assert.InDelta(t, 100.0*math.Exp(-0.5), c.X, 1e-9)
+	})
+}
+
+func TestVolumeConfigFromYaml(t *testing.T) {
+	tests := []struct {
+		Description string
+		Bytes       []byte
+		Expected    *VolumeConfig
+		ExpectedErr error
+	}{
+		{
+			Description: "homogeneous medium with defaults",
+			Bytes: []byte(`
+shape: ball
+absorption: 0.1
+scattering: [0.2, 0.3, 0.4]
+`),
+			Expected: &VolumeConfig{
+				Shape:      "ball",
+				Absorption: vmath.Vector3d{X: 0.1, Y: 0.1, Z: 0.1},
+				Scattering: vmath.Vector3d{X: 0.2, Y: 0.3, Z: 0.4},
+				Step:       0.1,
+			},
+		},
+		{
+			Description: "noise density",
+			Bytes: []byte(`
+shape: ball
+absorption: 0
+scattering: 1
+g: 0.5
+step: 0.25
+density:
+  type: noise
+  scale: 2
+  octaves: 3
+  threshold: 0.4
+`),
+			Expected: &VolumeConfig{
+				Shape:      "ball",
+				Scattering: vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0},
+				G:          0.5,
+				Step:       0.25,
+				Density:    &volume.Noise{Scale: 2.0, Octaves: 3, Threshold: 0.4, Multiplier: 1.0},
+			},
+		},
+		{
+			Description: "constant density",
+			Bytes: []byte(`
+shape: ball
+absorption: 0
+scattering: 1
+density:
+  type: constant
+  value: 0.5
+`),
+			Expected: &VolumeConfig{
+				Shape:      "ball",
+				Scattering: vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0},
+				Step:       0.1,
+				Density:    &volume.Constant{Value: 0.5},
+			},
+		},
+		{
+			Description: "missing shape",
+			Bytes: []byte(`
+absorption: 0.1
+scattering: 0.1
+`),
+			ExpectedErr: errors.New("volume requires a shape"),
+		},
+		{
+			Description: "missing scattering",
+			Bytes: []byte(`
+shape: ball
+absorption: 0.1
+`),
+			ExpectedErr: errors.New("volume requires scattering"),
+		},
+		{
+			Description: "unknown density",
+			Bytes: []byte(`
+shape: ball
+absorption: 0.1
+scattering: 0.1
+density:
+  type: cloud
+`),
+			ExpectedErr: errors.New("volume density cloud is not one of constant, noise or grid."),
+		},
+		{
+			Description: "missing grid file",
+			Bytes: []byte(`
+shape: ball
+absorption: 0.1
+scattering: 0.1
+density:
+  type: grid
+  resolution: [2, 2, 2]
+`),
+			ExpectedErr: errors.New("grid density requires a file"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			config := &VolumeConfig{}
+			err = config.FromYaml(yaml, map[string]material.Material{})
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, config)
+		})
+	}
+}
+
+func TestVolumeShapesFactory(t *testing.T) {
+	tests := []struct {
+		Description   string
+		Bytes         []byte
+		ExpectedTypes []string
+		ExpectedErr   error
+	}{
+		{
+			Description: "the filled shape is only rendered through the volume",
+			Bytes: []byte(`
+ball:
+  type: sphere
+  radius: 1.0
+fog:
+  type: volume
+  shape: ball
+  absorption: 0.1
+  scattering: 0.1
+`),
+			ExpectedTypes: []string{"volume"},
+		},
+		{
+			Description: "open shape",
+			Bytes: []byte(`
+floor:
+  type: plane
+fog:
+  type: volume
+  shape: floor
+  absorption: 0.1
+  scattering: 0.1
+`),
+			ExpectedErr: errors.New("shape floor can not be filled by volume fog, it is not a closed solid."),
+		},
+		{
+			Description: "invalid medium",
+			Bytes: []byte(`
+ball:
+  type: sphere
+  radius: 1.0
+fog:
+  type: volume
+  shape: ball
+  absorption: 0.1
+  scattering: 0.1
+  g: 1.5
+`),
+			ExpectedErr: errors.New("volume fog: g must be between -1 and 1"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			yaml, err := simpleyaml.NewYaml(test.Bytes)
+			require.NoError(t, err)
+			configs, err := ShapesConfigFactory(yaml, map[string]material.Material{}, nil)
+			require.NoError(t, err)
+			shapes, err := ShapesFactory(configs)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			types := []string{}
+			for _, shape := range shapes {
+				types = append(types, shape.(common.Object).GetType())
+			}
+			assert.Equal(t, test.ExpectedTypes, types)
+		})
+	}
+}
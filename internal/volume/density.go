@@ -0,0 +1,133 @@
+package volume
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// Density is how thick a medium is at every point, the coefficients of a
+// Medium are given for a density of 1
+type Density interface {
+	At(p vmath.Vector3d) float64
+}
+
+// Constant is a homogeneous medium of the same density everywhere
+type Constant struct {
+	Value float64
+}
+
+func (c *Constant) At(p vmath.Vector3d) float64 {
+	return c.Value
+}
+
+// Noise is a heterogeneous medium like smoke or clouds from fractal Perlin
+// noise, Scale is the frequency of the first octave, noise is mapped to 0 to
+// 1 and anything below Threshold is left empty
+type Noise struct {
+	Scale      float64
+	Octaves    int
+	Threshold  float64
+	Multiplier float64
+}
+
+func (n *Noise) At(p vmath.Vector3d) float64 {
+	value := (vmath.FBM(p.SMultiply(n.Scale), n.Octaves, 2.0, 0.5) + 1) / 2
+	if value <= n.Threshold {
+		return 0
+	}
+	return (value - n.Threshold) / (1 - n.Threshold) * n.Multiplier
+}
+
+// Grid is a heterogeneous medium from a grid of voxels spread over the box
+// from P to P + Size, densities are interpolated between voxel centers and
+// are zero outside of the box
+type Grid struct {
+	// Values are indexed by x, then y and then z, x changes fastest
+	Values     []float64
+	Resolution [3]int
+	P          vmath.Vector3d
+	Size       vmath.Vector3d
+}
+
+// NewGrid spreads values over size from pos, there is one value per voxel
+// of resolution
+func NewGrid(values []float64, resolution [3]int, pos vmath.Vector3d, size vmath.Vector3d) (*Grid, error) {
+	if resolution[0] <= 0 || resolution[1] <= 0 || resolution[2] <= 0 {
+		return nil, errors.New("grid resolution must be positive")
+	}
+	if size.X <= 0 || size.Y <= 0 || size.Z <= 0 {
+		return nil, errors.New("grid size must be positive on every axis")
+	}
+	if len(values) != resolution[0]*resolution[1]*resolution[2] {
+		return nil, errors.New(fmt.Sprintf("grid needs %d values but has %d", resolution[0]*resolution[1]*resolution[2], len(values)))
+	}
+	return &Grid{Values: values, Resolution: resolution, P: pos, Size: size}, nil
+}
+
+// LoadGrid reads a raw voxel file of little endian float32 densities, x
+// changing fastest, and spreads it over size from pos
+func LoadGrid(path string, resolution [3]int, pos vmath.Vector3d, size vmath.Vector3d) (*Grid, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadGrid(file, resolution, pos, size)
+}
+
+// ReadGrid reads a raw voxel grid from r, see LoadGrid
+func ReadGrid(r io.Reader, resolution [3]int, pos vmath.Vector3d, size vmath.Vector3d) (*Grid, error) {
+	count := resolution[0] * resolution[1] * resolution[2]
+	if count <= 0 {
+		return nil, errors.New("grid resolution must be positive")
+	}
+	raw := make([]float32, count)
+	if err := binary.Read(r, binary.LittleEndian, raw); err != nil {
+		return nil, errors.New(fmt.Sprintf("grid needs %d values: %s", count, err.Error()))
+	}
+	values := make([]float64, count)
+	for index, value := range raw {
+		values[index] = float64(value)
+	}
+	return NewGrid(values, resolution, pos, size)
+}
+
+// voxel is the density of the voxel at x, y, z, clamped to the grid
+func (g *Grid) voxel(x int, y int, z int) float64 {
+	x = int(math.Min(math.Max(float64(x), 0), float64(g.Resolution[0]-1)))
+	y = int(math.Min(math.Max(float64(y), 0), float64(g.Resolution[1]-1)))
+	z = int(math.Min(math.Max(float64(z), 0), float64(g.Resolution[2]-1)))
+	return g.Values[(z*g.Resolution[1]+y)*g.Resolution[0]+x]
+}
+
+func (g *Grid) At(p vmath.Vector3d) float64 {
+	local := p.Subtract(g.P)
+	if local.X < 0 || local.Y < 0 || local.Z < 0 || local.X > g.Size.X || local.Y > g.Size.Y || local.Z > g.Size.Z {
+		return 0
+	}
+
+	// position in voxels with voxel centers on whole numbers
+	gx := local.X/g.Size.X*float64(g.Resolution[0]) - 0.5
+	gy := local.Y/g.Size.Y*float64(g.Resolution[1]) - 0.5
+	gz := local.Z/g.Size.Z*float64(g.Resolution[2]) - 0.5
+	x, y, z := math.Floor(gx), math.Floor(gy), math.Floor(gz)
+	fx, fy, fz := gx-x, gy-y, gz-z
+	ix, iy, iz := int(x), int(y), int(z)
+
+	density := 0.0
+	for corner := 0; corner < 8; corner++ {
+		dx, dy, dz := corner&1, (corner>>1)&1, (corner>>2)&1
+		weight := (1 - fx + float64(dx)*(2*fx-1)) *
+			(1 - fy + float64(dy)*(2*fy-1)) *
+			(1 - fz + float64(dz)*(2*fz-1))
+		density += weight * g.voxel(ix+dx, iy+dy, iz+dz)
+	}
+	return density
+}
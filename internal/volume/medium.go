@@ -0,0 +1,140 @@
+package volume
+
+import (
+	"errors"
+	"math"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+)
+
+// Medium is a participating medium like fog or smoke, light passing through
+// it is absorbed and scattered in proportion to its Density, Absorption and
+// Scattering are per color channel and per unit of length at a density of 1
+type Medium struct {
+	Absorption vmath.Vector3d
+	Scattering vmath.Vector3d
+	// G is the asymmetry of the Henyey-Greenstein phase function, above 0
+	// light is mostly scattered forward and below 0 back towards where it
+	// came from
+	G       float64
+	Density Density
+	// Step is the length of every step the medium is marched by, the light
+	// through a homogeneous medium is known without marching
+	Step float64
+}
+
+// NewMedium makes a medium, errors if it would absorb or scatter a negative
+// amount or could not be marched through
+func NewMedium(absorption vmath.Vector3d, scattering vmath.Vector3d, g float64, density Density, step float64) (*Medium, error) {
+	for _, c := range []float64{absorption.X, absorption.Y, absorption.Z, scattering.X, scattering.Y, scattering.Z} {
+		if c < 0 {
+			return nil, errors.New("absorption and scattering can not be negative")
+		}
+	}
+	if g <= -1 || g >= 1 {
+		return nil, errors.New("g must be between -1 and 1")
+	}
+	if step <= 0 {
+		return nil, errors.New("step must be positive")
+	}
+	if density == nil {
+		density = &Constant{Value: 1.0}
+	}
+	return &Medium{
+		Absorption: absorption,
+		Scattering: scattering,
+		G:          g,
+		Density:    density,
+		Step:       step,
+	}, nil
+}
+
+// Extinction is the light lost per unit of length at a density of 1, by
+// absorption and by scattering out of the ray
+func (m *Medium) Extinction() vmath.Vector3d {
+	return m.Absorption.Add(m.Scattering)
+}
+
+// HenyeyGreenstein is the share of light scattered by cosTheta from the
+// direction it was travelling, per unit of solid angle
+func HenyeyGreenstein(g float64, cosTheta float64) float64 {
+	denom := 1 + g*g - 2*g*cosTheta
+	return (1 - g*g) / (4 * math.Pi * denom * math.Sqrt(denom))
+}
+
+// Phase is the Henyey-Greenstein phase function of the medium
+func (m *Medium) Phase(cosTheta float64) float64 {
+	return HenyeyGreenstein(m.G, cosTheta)
+}
+
+// steps calls visit with the middle of every step of ray from t0 to t1 and
+// the length of the step, a homogeneous medium is taken in one step unless
+// march is set since only the light reaching it changes along the ray
+func (m *Medium) steps(ray *vmath.Ray, t0 float64, t1 float64, march bool, visit func(p vmath.Vector3d, length float64)) {
+	scale := ray.Direction.Norm()
+	if t1 <= t0 || scale == 0 {
+		return
+	}
+	count := 1
+	if _, ok := m.Density.(*Constant); march || !ok {
+		count = int(math.Ceil((t1 - t0) * scale / m.Step))
+	}
+	dt := (t1 - t0) / float64(count)
+	for step := 0; step < count; step++ {
+		t := t0 + (float64(step)+0.5)*dt
+		visit(ray.Origin.Add(ray.Direction.SMultiply(t)), dt*scale)
+	}
+}
+
+// Transmittance is the share of light of each channel that makes it through
+// the medium along ray from t0 to t1
+func (m *Medium) Transmittance(ray *vmath.Ray, t0 float64, t1 float64) vmath.Vector3d {
+	depth := 0.0
+	m.steps(ray, t0, t1, false, func(p vmath.Vector3d, length float64) {
+		depth += m.Density.At(p) * length
+	})
+	return attenuate(m.Extinction(), depth)
+}
+
+// Integrate marches ray from t0 to t1, scattering the light inscatter gives
+// at every point towards the start of the ray, transmittance is the share of
+// light left so far and is updated to what is left past t1
+func (m *Medium) Integrate(ray *vmath.Ray, t0 float64, t1 float64, transmittance *vmath.Vector3d, inscatter func(p vmath.Vector3d) vmath.Vector3d) vmath.Vector3d {
+	radiance := vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}
+	extinction := m.Extinction()
+	m.steps(ray, t0, t1, true, func(p vmath.Vector3d, length float64) {
+		density := m.Density.At(p)
+		if density <= 0 {
+			return
+		}
+		// light scattered anywhere in the step is dimmed by the rest of
+		// the step, which integrates to σs·(1 - e^-σt·d) / σt per channel
+		step := attenuate(extinction, density*length)
+		scattered := vmath.Vector3d{
+			X: scatteredShare(m.Scattering.X, extinction.X, step.X, density*length),
+			Y: scatteredShare(m.Scattering.Y, extinction.Y, step.Y, density*length),
+			Z: scatteredShare(m.Scattering.Z, extinction.Z, step.Z, density*length),
+		}
+		radiance = radiance.Add(transmittance.Compt(scattered).Compt(inscatter(p)))
+		*transmittance = transmittance.Compt(step)
+	})
+	return radiance
+}
+
+// attenuate is e^-(extinction·depth) per channel
+func attenuate(extinction vmath.Vector3d, depth float64) vmath.Vector3d {
+	return vmath.Vector3d{
+		X: math.Exp(-extinction.X * depth),
+		Y: math.Exp(-extinction.Y * depth),
+		Z: math.Exp(-extinction.Z * depth),
+	}
+}
+
+// scatteredShare is how much of the light scattered along a step of optical
+// thickness depth makes it out of the step
+func scatteredShare(scattering float64, extinction float64, transmittance float64, depth float64) float64 {
+	if extinction == 0 {
+		return scattering * depth
+	}
+	return scattering / extinction * (1 - transmittance)
+}
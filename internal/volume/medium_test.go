@@ -0,0 +1,195 @@
+package volume
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	vmath "github.com/chrispotter/trace/internal/math"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMedium(t *testing.T) {
+	one := vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}
+	var tests = []struct {
+		Description string
+		Absorption  vmath.Vector3d
+		G           float64
+		Step        float64
+		ExpectedErr error
+	}{
+		{
+			Description: "Test medium defaults to a constant density",
+			Absorption:  one,
+			Step:        0.1,
+		},
+		{
+			Description: "Test negative absorption returns error",
+			Absorption:  vmath.Vector3d{X: 1.0, Y: -1.0, Z: 1.0},
+			Step:        0.1,
+			ExpectedErr: errors.New("absorption and scattering can not be negative"),
+		},
+		{
+			Description: "Test g of 1 returns error",
+			Absorption:  one,
+			G:           1.0,
+			Step:        0.1,
+			ExpectedErr: errors.New("g must be between -1 and 1"),
+		},
+		{
+			Description: "Test zero step returns error",
+			Absorption:  one,
+			ExpectedErr: errors.New("step must be positive"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			medium, err := NewMedium(test.Absorption, one, test.G, nil, test.Step)
+			if test.ExpectedErr != nil {
+				assert.Equal(t, test.ExpectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, &Constant{Value: 1.0}, medium.Density)
+		})
+	}
+}
+
+func TestHenyeyGreenstein(t *testing.T) {
+	for _, g := range []float64{-0.7, 0.0, 0.3, 0.9} {
+		// the phase function is a distribution over the sphere
+		total, count := 0.0, 20000
+		for index := 0; index < count; index++ {
+			cos := -1 + (float64(index)+0.5)*2/float64(count)
+			total += HenyeyGreenstein(g, cos) * 2 * math.Pi * 2 / float64(count)
+		}
+		assert.InDelta(t, 1.0, total, 1e-3, "g %v", g)
+	}
+	assert.InDelta(t, 1/(4*math.Pi), HenyeyGreenstein(0, 0.4), 1e-12)
+	assert.Greater(t, HenyeyGreenstein(0.5, 1), HenyeyGreenstein(0.5, -1))
+}
+
+func TestMediumTransmittance(t *testing.T) {
+	ray := &vmath.Ray{
+		Origin:    vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0},
+		Direction: vmath.Vector3d{X: 0.0, Y: 0.0, Z: -2.0},
+	}
+
+	medium, err := NewMedium(vmath.Vector3d{X: 0.1, Y: 0.2, Z: 0.3}, vmath.Vector3d{X: 0.1, Y: 0.1, Z: 0.1}, 0.0, &Constant{Value: 0.5}, 0.1)
+	require.NoError(t, err)
+	// the direction is two long so t from 1 to 3 is a distance of 4
+	tr := medium.Transmittance(ray, 1.0, 3.0)
+	assert.InDelta(t, math.Exp(-0.2*0.5*4), tr.X, 1e-12)
+	assert.InDelta(t, math.Exp(-0.3*0.5*4), tr.Y, 1e-12)
+	assert.InDelta(t, math.Exp(-0.4*0.5*4), tr.Z, 1e-12)
+
+	// a grid of the same density everywhere is marched to the same answer
+	values := make([]float64, 8)
+	for index := range values {
+		values[index] = 0.5
+	}
+	grid, err := NewGrid(values, [3]int{2, 2, 2}, vmath.Vector3d{X: -5.0, Y: -5.0, Z: -10.0}, vmath.Vector3d{X: 10.0, Y: 10.0, Z: 10.0})
+	require.NoError(t, err)
+	medium.Density = grid
+	marched := medium.Transmittance(ray, 1.0, 3.0)
+	assert.InDelta(t, tr.X, marched.X, 1e-12)
+	assert.InDelta(t, tr.Z, marched.Z, 1e-12)
+}
+
+func TestMediumIntegrate(t *testing.T) {
+	ray := &vmath.Ray{
+		Origin:    vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0},
+		Direction: vmath.Vector3d{X: 1.0, Y: 0.0, Z: 0.0},
+	}
+	light := vmath.Vector3d{X: 100.0, Y: 50.0, Z: 10.0}
+	inscatter := func(p vmath.Vector3d) vmath.Vector3d { return light }
+
+	homogeneous, err := NewMedium(vmath.Vector3d{X: 0.2, Y: 0.2, Z: 0.2}, vmath.Vector3d{X: 0.3, Y: 0.3, Z: 0.3}, 0.0, nil, 0.1)
+	require.NoError(t, err)
+	transmittance := vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}
+	radiance := homogeneous.Integrate(ray, 0.0, 2.0, &transmittance, inscatter)
+
+	// constant light scattered in a homogeneous medium has a closed form
+	share := 0.3 / 0.5 * (1 - math.Exp(-0.5*2))
+	assert.InDelta(t, light.X*share, radiance.X, 1e-9)
+	assert.InDelta(t, light.Y*share, radiance.Y, 1e-9)
+	assert.InDelta(t, math.Exp(-0.5*2), transmittance.X, 1e-12)
+
+	// marching in steps adds up to the same light
+	values := []float64{1.0, 1.0}
+	grid, err := NewGrid(values, [3]int{2, 1, 1}, vmath.Vector3d{X: -1.0, Y: -1.0, Z: -1.0}, vmath.Vector3d{X: 4.0, Y: 2.0, Z: 2.0})
+	require.NoError(t, err)
+	heterogeneous, err := NewMedium(homogeneous.Absorption, homogeneous.Scattering, 0.0, grid, 0.1)
+	require.NoError(t, err)
+	transmittance = vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0}
+	marched := heterogeneous.Integrate(ray, 0.0, 2.0, &transmittance, inscatter)
+	assert.InDelta(t, radiance.X, marched.X, 1e-9)
+	assert.InDelta(t, math.Exp(-0.5*2), transmittance.X, 1e-12)
+}
+
+func TestGrid(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, binary.Write(&buffer, binary.LittleEndian, []float32{0, 1, 2, 3, 4, 5, 6, 7}))
+	grid, err := ReadGrid(&buffer, [3]int{2, 2, 2}, vmath.Vector3d{X: 0.0, Y: 0.0, Z: 0.0}, vmath.Vector3d{X: 2.0, Y: 2.0, Z: 2.0})
+	require.NoError(t, err)
+
+	var tests = []struct {
+		Description string
+		P           vmath.Vector3d
+		Expected    float64
+	}{
+		{
+			Description: "Test voxel center",
+			P:           vmath.Vector3d{X: 1.5, Y: 0.5, Z: 0.5},
+			Expected:    1.0,
+		},
+		{
+			Description: "Test middle of the grid is the average",
+			P:           vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0},
+			Expected:    3.5,
+		},
+		{
+			Description: "Test between voxels along y",
+			P:           vmath.Vector3d{X: 0.5, Y: 1.0, Z: 1.5},
+			Expected:    5.0,
+		},
+		{
+			Description: "Test edge of the grid holds the outer voxel",
+			P:           vmath.Vector3d{X: 0.0, Y: 2.0, Z: 0.5},
+			Expected:    2.0,
+		},
+		{
+			Description: "Test outside of the grid is empty",
+			P:           vmath.Vector3d{X: 1.0, Y: 2.5, Z: 1.0},
+			Expected:    0.0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			assert.InDelta(t, test.Expected, grid.At(test.P), 1e-12)
+		})
+	}
+
+	_, err = ReadGrid(bytes.NewReader([]byte{0, 0, 0, 0}), [3]int{2, 1, 1}, vmath.Vector3d{}, vmath.Vector3d{X: 1.0, Y: 1.0, Z: 1.0})
+	assert.Equal(t, errors.New("grid needs 2 values: unexpected EOF"), err)
+}
+
+func TestNoise(t *testing.T) {
+	noise := &Noise{Scale: 1.0, Octaves: 3, Threshold: 0.5, Multiplier: 2.0}
+	// noise is 0 on the lattice which maps to the threshold
+	assert.Equal(t, 0.0, noise.At(vmath.Vector3d{X: 1.0, Y: 2.0, Z: 3.0}))
+
+	high := 0.0
+	for index := 0; index < 500; index++ {
+		f := float64(index)
+		density := noise.At(vmath.Vector3d{X: f * 0.13, Y: f * 0.07, Z: f * 0.011})
+		assert.GreaterOrEqual(t, density, 0.0)
+		high = math.Max(high, density)
+	}
+	assert.Greater(t, high, 0.0)
+}